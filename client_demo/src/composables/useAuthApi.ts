@@ -16,6 +16,14 @@ interface OTPVerifyResponse {
   token: string; // Firebase Custom Token
 }
 
+/**
+ * Sign-up Verify Response
+ */
+interface SignupVerifyResponse {
+  token: string; // Firebase Custom Token
+  isNewUser: boolean; // Whether the account was created by this verification
+}
+
 /**
  * API Error Response
  */
@@ -23,12 +31,44 @@ interface ApiErrorResponse {
   error: string;
 }
 
+/**
+ * Read the wait time of a rate-limited (429) response.
+ * @param response - API response
+ * @returns Seconds to wait, or null if the response is not rate limited
+ */
+const parseRetryAfter = (response: Response): number | null => {
+  if (response.status !== 429) {
+    return null;
+  }
+
+  const seconds = Number(response.headers.get("Retry-After"));
+  return Number.isFinite(seconds) && seconds > 0 ? seconds : null;
+};
+
 /**
  * Composable for authentication API calls
  */
 export const useAuthApi = () => {
   const loading = ref(false);
   const error = ref<string>("");
+  // Seconds to wait before retrying, set when the last request was rate limited
+  const retryAfter = ref<number | null>(null);
+
+  /**
+   * Set the error message of a failed response.
+   * @param response - Failed API response
+   * @param fallback - Message used when the response has no error
+   */
+  const handleErrorResponse = async (response: Response, fallback: string) => {
+    retryAfter.value = parseRetryAfter(response);
+    if (retryAfter.value !== null) {
+      error.value = `リクエストが多すぎます。${retryAfter.value}秒後に再度お試しください`;
+      return;
+    }
+
+    const errorData: ApiErrorResponse = await response.json();
+    error.value = errorData.error || fallback;
+  };
 
   /**
    * Request OTP code
@@ -40,6 +80,7 @@ export const useAuthApi = () => {
   ): Promise<OTPRequestResponse | null> => {
     loading.value = true;
     error.value = "";
+    retryAfter.value = null;
 
     try {
       const response = await fetch(getApiUrl(API_ENDPOINTS.AUTH.OTP_REQUEST), {
@@ -51,8 +92,7 @@ export const useAuthApi = () => {
       });
 
       if (!response.ok) {
-        const errorData: ApiErrorResponse = await response.json();
-        error.value = errorData.error || "OTPリクエストに失敗しました";
+        await handleErrorResponse(response, "OTPリクエストに失敗しました");
         return null;
       }
 
@@ -79,6 +119,7 @@ export const useAuthApi = () => {
   ): Promise<string | null> => {
     loading.value = true;
     error.value = "";
+    retryAfter.value = null;
 
     try {
       const response = await fetch(getApiUrl(API_ENDPOINTS.AUTH.OTP_VERIFY), {
@@ -90,8 +131,7 @@ export const useAuthApi = () => {
       });
 
       if (!response.ok) {
-        const errorData: ApiErrorResponse = await response.json();
-        error.value = errorData.error || "OTP検証に失敗しました";
+        await handleErrorResponse(response, "OTP検証に失敗しました");
         return null;
       }
 
@@ -106,10 +146,97 @@ export const useAuthApi = () => {
     }
   };
 
+  /**
+   * Request a sign-up OTP code (also sent to registered addresses)
+   * @param email - User email address
+   * @returns OTP request response
+   */
+  const requestSignup = async (
+    email: string,
+  ): Promise<OTPRequestResponse | null> => {
+    loading.value = true;
+    error.value = "";
+    retryAfter.value = null;
+
+    try {
+      const response = await fetch(
+        getApiUrl(API_ENDPOINTS.AUTH.SIGNUP_REQUEST),
+        {
+          method: "POST",
+          headers: {
+            "Content-Type": "application/json",
+          },
+          body: JSON.stringify({ email }),
+        },
+      );
+
+      if (!response.ok) {
+        await handleErrorResponse(response, "登録リクエストに失敗しました");
+        return null;
+      }
+
+      const data: OTPRequestResponse = await response.json();
+      return data;
+    } catch (err) {
+      error.value = "ネットワークエラーが発生しました";
+      console.error("Signup request error:", err);
+      return null;
+    } finally {
+      loading.value = false;
+    }
+  };
+
+  /**
+   * Verify a sign-up OTP code, creating the account on first verification
+   * @param email - User email address
+   * @param otp - OTP code
+   * @param displayName - Optional display name for a new account
+   * @returns Sign-up verify response
+   */
+  const verifySignup = async (
+    email: string,
+    otp: string,
+    displayName: string,
+  ): Promise<SignupVerifyResponse | null> => {
+    loading.value = true;
+    error.value = "";
+    retryAfter.value = null;
+
+    try {
+      const response = await fetch(
+        getApiUrl(API_ENDPOINTS.AUTH.SIGNUP_VERIFY),
+        {
+          method: "POST",
+          headers: {
+            "Content-Type": "application/json",
+          },
+          body: JSON.stringify({ email, otp, displayName }),
+        },
+      );
+
+      if (!response.ok) {
+        await handleErrorResponse(response, "登録に失敗しました");
+        return null;
+      }
+
+      const data: SignupVerifyResponse = await response.json();
+      return data;
+    } catch (err) {
+      error.value = "ネットワークエラーが発生しました";
+      console.error("Signup verify error:", err);
+      return null;
+    } finally {
+      loading.value = false;
+    }
+  };
+
   return {
     loading,
     error,
+    retryAfter,
     requestOTP,
     verifyOTP,
+    requestSignup,
+    verifySignup,
   };
 };
@@ -43,6 +43,8 @@ export const API_ENDPOINTS = {
   AUTH: {
     OTP_REQUEST: "/auth/otp",
     OTP_VERIFY: "/auth/verify",
+    SIGNUP_REQUEST: "/auth/signup",
+    SIGNUP_VERIFY: "/auth/signup/verify",
     HEALTH: "/health",
   },
 } as const;
@@ -2,60 +2,694 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
 	"log"
+	"maps"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	firebaseapp "firebase.google.com/go/v4"
+	_ "github.com/jackc/pgx/v5/stdlib" // Registers the "pgx" database/sql driver
+	"github.com/redis/go-redis/v9"
+	_ "modernc.org/sqlite" // Registers the "sqlite" database/sql driver
 
 	"custom_auth_api/internal/config"
+	"custom_auth_api/internal/domain/claims"
+	"custom_auth_api/internal/domain/emailpolicy"
+	domainemailsender "custom_auth_api/internal/domain/emailsender"
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/identity"
+	"custom_auth_api/internal/domain/repository"
+	"custom_auth_api/internal/domain/vo/email"
+	"custom_auth_api/internal/domain/vo/invitetoken"
+	"custom_auth_api/internal/domain/vo/ipaddress"
+	"custom_auth_api/internal/domain/vo/otp"
 	"custom_auth_api/internal/infrastructure/emailsender"
 	"custom_auth_api/internal/infrastructure/firebase"
+	"custom_auth_api/internal/infrastructure/jwt"
 	"custom_auth_api/internal/infrastructure/persistence"
 	"custom_auth_api/internal/interface/handler"
+	"custom_auth_api/internal/interface/middleware"
 	"custom_auth_api/internal/interface/router"
 	"custom_auth_api/internal/usecase"
 )
 
 func main() {
-	ctx := context.Background()
+	// Cancelled on exit to stop background goroutines
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	// Load environment configuration
 	env, err := config.LoadEnv()
 	if err != nil {
-		log.Fatalf("Failed to load environment configuration: %v", err)
+		log.Fatalf("Failed to load environment configuration: %v", err) //nolint:gocritic // log.Fatalf is intentional
 	}
 
-	// Initialize Firebase and Firestore client
-	firestoreClient, authClient, err := firebase.NewClient(ctx)
-	if err != nil {
-		log.Fatalf("Failed to initialize Firebase: %v", err)
-	}
-	// Ensure Firestore client is properly closed on shutdown
-	defer func() {
-		err := firestoreClient.Close()
+	// Initialize the Firebase app. The standalone identity backend runs without Firebase;
+	// the configuration rejects the Firestore stores in that mode, so app stays nil.
+	var app *firebaseapp.App
+	if env.IdentityBackend == config.IdentityBackendFirebase {
+		app, err = firebase.NewApp(ctx)
 		if err != nil {
-			log.Printf("Error closing Firestore client: %v", err)
+			log.Fatalf("Failed to initialize Firebase: %v", err) //nolint:gocritic // log.Fatalf is intentional
 		}
-	}()
+	}
+
+	claimsProvider, closeClaimsProvider, err := newClaimsProvider(ctx, env, app)
+	if err != nil {
+		log.Fatalf("Failed to initialize claims provider: %v", err) //nolint:gocritic // log.Fatalf is intentional
+	}
+	defer closeClaimsProvider()
+
+	otpHasher, err := newOTPHasher(env)
+	if err != nil {
+		log.Fatalf("Failed to initialize OTP hasher: %v", err) //nolint:gocritic // log.Fatalf is intentional
+	}
+	repos, closeSessionStore, err := newStores(ctx, env, app, otpHasher)
+	if err != nil {
+		log.Fatalf("Failed to initialize OTP session store: %v", err) //nolint:gocritic // log.Fatalf is intentional
+	}
+	// Ensure the session store is properly closed on shutdown
+	defer closeSessionStore()
+	backend, err := newIdentityBackend(ctx, env, app, repos)
+	if err != nil {
+		log.Fatalf("Failed to initialize identity backend: %v", err) //nolint:gocritic // log.Fatalf is intentional
+	}
 
 	// Initialize services
-	authService := usecase.NewAuthService(authClient)
-	otpSessionRepo := persistence.NewOTPSessionRepository(firestoreClient)
-	emailSender := emailsender.NewDummyEmailSender()
-	otpService := usecase.NewOTPService(otpSessionRepo, emailSender)
+	authService := usecase.NewAuthServiceWithOptions(
+		backend.users,
+		backend.tokens,
+		usecase.AuthServiceOptions{ClaimsProvider: claimsProvider},
+	)
+	emailSender, err := newEmailSender(env)
+	if err != nil {
+		log.Fatalf("Failed to initialize email sender: %v", err) //nolint:gocritic // log.Fatalf is intentional
+	}
+	ipHasher, err := newIPHasher(env)
+	if err != nil {
+		log.Fatalf("Failed to initialize IP hasher: %v", err) //nolint:gocritic // log.Fatalf is intentional
+	}
+	limits, err := newRateLimitStore(ctx, env)
+	if err != nil {
+		log.Fatalf("Failed to initialize rate limit store: %v", err) //nolint:gocritic // log.Fatalf is intentional
+	}
+	defer limits.close()
+	domainPolicy, err := newEmailDomainPolicy(ctx, env)
+	if err != nil {
+		log.Fatalf("Failed to initialize email domain policy: %v", err) //nolint:gocritic // log.Fatalf is intentional
+	}
+	otpService := usecase.NewOTPServiceWithOptions(repos.otpSessions, emailSender, usecase.OTPServiceOptions{
+		Throttle:     limits.otpThrottle,
+		Binding:      entity.BindingPolicy(env.OTPSessionBinding),
+		IPHasher:     ipHasher,
+		Email:        emailOptions(env),
+		DomainPolicy: domainPolicy,
+	})
+	invitationSigner, err := newInvitationSigner(env)
+	if err != nil {
+		log.Fatalf("Failed to initialize invitation token signer: %v", err) //nolint:gocritic // log.Fatalf is intentional
+	}
+	invitationService, err := usecase.NewInvitationService(repos.invitations, invitationSigner, emailSender,
+		usecase.InvitationServiceOptions{
+			TTL:       time.Duration(env.InvitationTTLHours) * time.Hour,
+			AcceptURL: env.InvitationAcceptURL,
+		})
+	if err != nil {
+		log.Fatalf("Failed to initialize invitation service: %v", err) //nolint:gocritic // log.Fatalf is intentional
+	}
 
 	// Initialize handlers
 	handlers := &router.Handlers{
-		OTPRequest: handler.NewOTPRequestHandler(otpService, authService),
-		OTPVerify:  handler.NewOTPVerifyHandler(otpService, authService),
+		OTPRequest: handler.NewOTPRequestHandler(otpService, authService, handler.OTPRequestOptions{
+			Mode:                handler.OTPRequestMode(env.OTPRequestMode),
+			MinDuration:         time.Duration(env.OTPRequestMinDurationMillis) * time.Millisecond,
+			BackgroundTimeout:   time.Duration(env.OTPRequestBackgroundTimeoutSeconds) * time.Second,
+			MaxBackground:       env.OTPRequestMaxBackground,
+			NotifyUnknownEmails: env.OTPNotifyUnknownEmails,
+		}),
+		OTPVerify: handler.NewOTPVerifyHandler(otpService, authService),
+		Signup:    handler.NewSignupHandler(otpService, authService, handler.RegistrationMode(env.RegistrationMode)),
+		Invitation: handler.NewInvitationHandler(
+			invitationService,
+			otpService,
+			authService,
+			handler.RegistrationMode(env.RegistrationMode),
+		),
+		JWKS:      backend.jwks,
+		AdminAuth: middleware.AdminAuthMiddleware(authService),
 	}
 
 	// Setup router with all middleware and routes
-	r := router.NewRouter(env, handlers)
+	r := router.NewRouter(ctx, env, handlers, limits.newRateLimiter)
 
 	// Start the server
-	serverAddr := ":" + env.Port
-	log.Printf("Server starting on port %s (environment: %s)", serverAddr, env.Environment)
+	server := &http.Server{
+		Addr:              ":" + env.Port,
+		Handler:           r,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+	log.Printf("Server starting on port %s (environment: %s)", server.Addr, env.Environment)
+
+	err = serve(ctx, server)
+	if err != nil {
+		log.Printf("Server failed: %v", err)
+	}
+
+	// Let background OTP requests finish so pending emails are not dropped
+	handlers.OTPRequest.Wait()
+}
+
+const (
+	// readHeaderTimeout bounds how long clients may take to send request headers.
+	readHeaderTimeout = 10 * time.Second
+
+	// shutdownTimeout bounds how long in-flight requests may take to finish on shutdown.
+	shutdownTimeout = 30 * time.Second
+)
+
+// serve runs server until it fails or the process receives SIGINT or SIGTERM,
+// then shuts it down gracefully.
+func serve(ctx context.Context, server *http.Server) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+
+	err := server.Shutdown(shutdownCtx)
+	if err != nil {
+		return fmt.Errorf("failed to shut down server: %w", err)
+	}
+
+	err = <-serveErr
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// stores holds the repositories kept in the store configured by SESSION_STORE.
+type stores struct {
+	otpSessions repository.OTPSessionRepository
+	invitations repository.InvitationRepository
+	users       identity.UserDirectory // Users of the standalone identity backend (memory and sql only)
+}
+
+// newStores creates the OTP session and invitation repositories of the store configured by SESSION_STORE.
+// The returned function releases the store's resources on shutdown.
+func newStores(
+	ctx context.Context,
+	env *config.Env,
+	app *firebaseapp.App,
+	hasher *otp.Hasher,
+) (*stores, func(), error) {
+	switch env.SessionStore {
+	case config.SessionStoreMemory:
+		evictionInterval := time.Duration(env.SessionEvictionIntervalSeconds) * time.Second
+
+		sessions := persistence.NewMemoryOTPSessionRepository(hasher)
+		go sessions.RunEviction(ctx, evictionInterval)
+
+		invitations := persistence.NewMemoryInvitationRepository()
+		go invitations.RunEviction(ctx, evictionInterval)
+
+		log.Println("OTP: storing sessions and invitations in memory (single-node only, lost on restart)")
+
+		return &stores{
+			otpSessions: sessions,
+			invitations: invitations,
+			users:       persistence.NewMemoryUserDirectory(),
+		}, func() {}, nil
+	case config.SessionStoreRedis:
+		options, err := redis.ParseURL(env.RedisURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+		}
+
+		client := redis.NewClient(options)
+		closeClient := func() {
+			err := client.Close()
+			if err != nil {
+				log.Printf("Error closing Redis client: %v", err)
+			}
+		}
+
+		log.Printf("OTP: storing sessions and invitations in Redis at %s", options.Addr)
+
+		return &stores{
+			otpSessions: persistence.NewRedisOTPSessionRepository(client, hasher),
+			invitations: persistence.NewRedisInvitationRepository(client),
+			users:       nil,
+		}, closeClient, nil
+	case config.SessionStoreSQL:
+		return newSQLStores(ctx, env, hasher)
+	}
+
+	firestoreClient, err := firebase.NewFirestoreClient(ctx, app)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	closeClient := func() {
+		err := firestoreClient.Close()
+		if err != nil {
+			log.Printf("Error closing Firestore client: %v", err)
+		}
+	}
+
+	return &stores{
+		otpSessions: persistence.NewOTPSessionRepository(firestoreClient, hasher),
+		invitations: persistence.NewInvitationRepository(firestoreClient),
+		users:       nil,
+	}, closeClient, nil
+}
+
+// newSQLStores opens the configured database, applies pending migrations
+// and starts purging expired sessions and invitations in the background.
+func newSQLStores(
+	ctx context.Context,
+	env *config.Env,
+	hasher *otp.Hasher,
+) (*stores, func(), error) {
+	driverName, dialect := "sqlite", persistence.SQLDialectSQLite
+	if env.SQLDriver == config.SQLDriverPostgres {
+		driverName, dialect = "pgx", persistence.SQLDialectPostgres
+	}
+
+	db, err := sql.Open(driverName, env.SQLDSN)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open sql database: %w", err)
+	}
+
+	closeDB := func() {
+		err := db.Close()
+		if err != nil {
+			log.Printf("Error closing SQL database: %v", err)
+		}
+	}
+
+	err = persistence.MigrateSQL(ctx, db, dialect)
+	if err != nil {
+		closeDB()
+
+		return nil, nil, fmt.Errorf("failed to migrate sql database: %w", err)
+	}
+
+	sessions, err := persistence.NewSQLOTPSessionRepository(db, dialect, hasher)
+	if err != nil {
+		closeDB()
+
+		return nil, nil, err
+	}
+
+	invitations, err := persistence.NewSQLInvitationRepository(db, dialect)
+	if err != nil {
+		closeDB()
+
+		return nil, nil, err
+	}
+
+	users, err := persistence.NewSQLUserDirectory(db, dialect)
+	if err != nil {
+		closeDB()
+
+		return nil, nil, err
+	}
+
+	purgeInterval := time.Duration(env.SessionEvictionIntervalSeconds) * time.Second
+
+	go sessions.RunPurge(ctx, purgeInterval, func(err error) {
+		log.Printf("OTP: failed to purge expired sessions: %v", err)
+	})
+	go invitations.RunPurge(ctx, purgeInterval, func(err error) {
+		log.Printf("Invitation: failed to purge expired invitations: %v", err)
+	})
 
-	err = r.Run(serverAddr)
+	log.Printf("OTP: storing sessions and invitations in SQL database (driver: %s)", env.SQLDriver)
+
+	return &stores{otpSessions: sessions, invitations: invitations, users: users}, closeDB, nil
+}
+
+// identityBackend holds where users are stored and who issues their tokens.
+type identityBackend struct {
+	users  identity.UserDirectory
+	tokens identity.TokenIssuer
+	jwks   *handler.JWKSHandler // Publishes the signing keys of the standalone backend, nil otherwise
+}
+
+// newIdentityBackend creates the identity backend configured by IDENTITY_BACKEND.
+// The standalone backend keeps its users in the store configured by SESSION_STORE.
+func newIdentityBackend(
+	ctx context.Context,
+	env *config.Env,
+	app *firebaseapp.App,
+	repos *stores,
+) (*identityBackend, error) {
+	if env.IdentityBackend == config.IdentityBackendFirebase {
+		authClient, err := firebase.NewAuthClient(ctx, app)
+		if err != nil {
+			return nil, err
+		}
+
+		return &identityBackend{
+			users:  firebase.NewUserDirectory(authClient),
+			tokens: firebase.NewTokenIssuer(authClient),
+			jwks:   nil,
+		}, nil
+	}
+
+	issuer, err := newJWTIssuer(env)
 	if err != nil {
-		log.Fatalf("Server failed to start: %v", err) //nolint:gocritic // log.Fatalf is intentional
+		return nil, err
+	}
+
+	log.Printf("Identity: standalone, storing users in the %s store and issuing JWTs as %s for %s",
+		env.SessionStore, env.JWTIssuer, env.JWTAudience)
+
+	return &identityBackend{users: repos.users, tokens: issuer, jwks: handler.NewJWKSHandler(issuer)}, nil
+}
+
+// newJWTIssuer creates the issuer of the standalone identity backend's access tokens.
+// Without JWT_SIGNING_KEYS (development only) an ephemeral key is generated,
+// so issued tokens stop verifying after a restart.
+func newJWTIssuer(env *config.Env) (*jwt.Issuer, error) {
+	options := jwt.Options{
+		Issuer:   env.JWTIssuer,
+		Audience: env.JWTAudience,
+		TTL:      time.Duration(env.JWTTTLSeconds) * time.Second,
+		Now:      nil,
 	}
+
+	if len(env.JWTSigningKeyFiles) == 0 {
+		key, err := jwt.GenerateSigningKey("ephemeral")
+		if err != nil {
+			return nil, err
+		}
+
+		log.Println("Identity: JWT_SIGNING_KEYS not set, using an ephemeral signing key (development only)")
+
+		return jwt.NewIssuer("ephemeral", []*jwt.SigningKey{key}, options)
+	}
+
+	keys := make([]*jwt.SigningKey, 0, len(env.JWTSigningKeyFiles))
+
+	for _, keyID := range slices.Sorted(maps.Keys(env.JWTSigningKeyFiles)) {
+		key, err := jwt.LoadSigningKey(keyID, env.JWTSigningKeyFiles[keyID])
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	issuer, err := jwt.NewIssuer(env.JWTActiveKeyID, keys, options)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jwt signing keys: %w", err)
+	}
+
+	return issuer, nil
+}
+
+// rateLimitStore holds the limiters kept in the store configured by RATE_LIMIT_STORE.
+type rateLimitStore struct {
+	newRateLimiter middleware.RateLimiterFactory // Nil selects the router's in-memory limiters
+	otpThrottle    usecase.OTPThrottle
+	close          func()
+}
+
+// newRateLimitStore selects where IP rate limits and OTP email limits are kept (RATE_LIMIT_STORE).
+// In memory every limit applies per instance; Redis shares them across replicas.
+func newRateLimitStore(ctx context.Context, env *config.Env) (*rateLimitStore, error) {
+	policy := usecase.OTPRequestPolicy{
+		Cooldown:        time.Duration(env.OTPResendCooldownSeconds) * time.Second,
+		DailyLimit:      env.OTPDailyLimitPerEmail,
+		GlobalPerMinute: env.OTPGlobalRequestsPerMinute,
+	}
+
+	if env.RateLimitStore != config.RateLimitStoreRedis {
+		otpThrottle := usecase.NewOTPRequestThrottle(policy)
+		go otpThrottle.RunCleanup(ctx, time.Duration(env.RateLimitCleanupIntervalMinutes)*time.Minute)
+
+		return &rateLimitStore{newRateLimiter: nil, otpThrottle: otpThrottle, close: func() {}}, nil
+	}
+
+	options, err := redis.ParseURL(env.RedisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+	}
+
+	client := redis.NewClient(options)
+	closeClient := func() {
+		err := client.Close()
+		if err != nil {
+			log.Printf("Error closing Redis client: %v", err)
+		}
+	}
+
+	log.Printf("Rate limit: sharing limits through Redis at %s", options.Addr)
+
+	return &rateLimitStore{
+		newRateLimiter: middleware.NewRedisRateLimiterFactory(client),
+		otpThrottle:    persistence.NewRedisOTPRequestThrottle(client, policy),
+		close:          closeClient,
+	}, nil
+}
+
+// newEmailSender selects the EmailSender implementation configured by EMAIL_SENDER.
+func newEmailSender(env *config.Env) (domainemailsender.EmailSender, error) {
+	if env.EmailSender != config.EmailSenderSMTP {
+		return emailsender.NewDummyEmailSender(), nil
+	}
+
+	renderer, err := domainemailsender.NewTemplateRenderer(
+		env.EmailTemplateDir,
+		domainemailsender.Locale(env.EmailDefaultLocale),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load email templates: %w", err)
+	}
+
+	sender, err := emailsender.NewSMTPEmailSender(emailsender.SMTPConfig{
+		Host:          env.SMTPHost,
+		Port:          env.SMTPPort,
+		Username:      env.SMTPUsername,
+		Password:      env.SMTPPassword,
+		AuthMechanism: emailsender.AuthMechanism(env.SMTPAuthMechanism),
+		From:          env.SMTPFrom,
+		ReplyTo:       env.SMTPReplyTo,
+		TLSMode:       emailsender.TLSMode(env.SMTPTLSMode),
+		TLSConfig:     nil,
+		Timeout:       time.Duration(env.SMTPTimeoutSeconds) * time.Second,
+	}, renderer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create smtp email sender: %w", err)
+	}
+
+	log.Printf("Email: sending via SMTP server %s:%d (tls: %s)", env.SMTPHost, env.SMTPPort, env.SMTPTLSMode)
+
+	return sender, nil
+}
+
+// newOTPHasher creates the HMAC hasher used to store OTP codes.
+// Without OTP_HASH_KEYS (development only) an ephemeral key is generated,
+// so pending sessions do not survive a restart.
+func newOTPHasher(env *config.Env) (*otp.Hasher, error) {
+	if len(env.OTPHashKeys) == 0 {
+		key := make([]byte, otp.MinHashKeyLength)
+
+		_, err := rand.Read(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate ephemeral otp hash key: %w", err)
+		}
+
+		log.Println("OTP: OTP_HASH_KEYS not set, using an ephemeral hash key (development only)")
+
+		return otp.NewHasher("ephemeral", map[string][]byte{"ephemeral": key})
+	}
+
+	hasher, err := otp.NewHasher(env.OTPHashActiveKeyID, env.OTPHashKeys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid otp hash keys: %w", err)
+	}
+
+	return hasher, nil
+}
+
+// emailOptions returns how email addresses are validated and canonicalized for sessions and throttling.
+func emailOptions(env *config.Env) email.Options {
+	var rules []email.ProviderRule
+	if env.EmailFoldProviderAliases {
+		rules = []email.ProviderRule{email.GmailRule()}
+	}
+
+	return email.Options{
+		ProviderRules:        rules,
+		AllowQuotedLocalPart: env.EmailAllowQuotedLocalPart,
+		AllowUTF8LocalPart:   env.EmailAllowSMTPUTF8,
+	}
+}
+
+// newClaimsProvider creates the provider of extra custom token claims, or nil for none.
+// A role mapping file is reloaded in the background when it changes.
+// The returned function releases the provider's resources on shutdown.
+func newClaimsProvider(ctx context.Context, env *config.Env, app *firebaseapp.App) (claims.Provider, func(), error) {
+	switch env.ClaimsProvider {
+	case config.ClaimsProviderFile:
+		mapping, err := claims.NewFileRoleMapping(env.ClaimsRolesFile)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if env.ClaimsRolesReloadSeconds > 0 {
+			go mapping.RunReload(ctx, time.Duration(env.ClaimsRolesReloadSeconds)*time.Second, func(err error) {
+				log.Printf("Claims: keeping the previous role mapping: %v", err)
+			})
+		}
+
+		log.Printf("Claims: granting roles from %s", env.ClaimsRolesFile)
+
+		return mapping, func() {}, nil
+	case config.ClaimsProviderFirestore:
+		firestoreClient, err := firebase.NewFirestoreClient(ctx, app)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		closeClient := func() {
+			err := firestoreClient.Close()
+			if err != nil {
+				log.Printf("Error closing Firestore client: %v", err)
+			}
+		}
+
+		log.Printf("Claims: granting roles from the Firestore collection %s", env.ClaimsFirestoreCollection)
+
+		return persistence.NewFirestoreRoleProvider(firestoreClient, env.ClaimsFirestoreCollection), closeClient, nil
+	}
+
+	return nil, func() {}, nil
+}
+
+// newEmailDomainPolicy builds the allow/deny/disposable domain policy.
+// A disposable list loaded from a file is reloaded in the background when it changes.
+func newEmailDomainPolicy(ctx context.Context, env *config.Env) (*emailpolicy.Policy, error) {
+	allow, err := emailpolicy.NewDomainSet(env.EmailDomainAllowlist)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EMAIL_DOMAIN_ALLOWLIST: %w", err)
+	}
+
+	deny, err := emailpolicy.NewDomainSet(env.EmailDomainDenylist)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EMAIL_DOMAIN_DENYLIST: %w", err)
+	}
+
+	var disposable emailpolicy.DomainMatcher
+
+	switch {
+	case !env.EmailBlockDisposable:
+		disposable = nil
+	case env.EmailDisposableDomainsFile != "":
+		fileSet, err := emailpolicy.NewFileDomainSet(env.EmailDisposableDomainsFile)
+		if err != nil {
+			return nil, err
+		}
+
+		if env.EmailDisposableReloadSeconds > 0 {
+			go fileSet.RunReload(ctx, time.Duration(env.EmailDisposableReloadSeconds)*time.Second, func(err error) {
+				log.Printf("Email: keeping the previous disposable domain list: %v", err)
+			})
+		}
+
+		log.Printf("Email: blocking %d disposable domains from %s", fileSet.Len(), env.EmailDisposableDomainsFile)
+		disposable = fileSet
+	default:
+		bundled := emailpolicy.BundledDisposableDomains()
+		log.Printf("Email: blocking %d bundled disposable domains", bundled.Len())
+		disposable = bundled
+	}
+
+	return emailpolicy.NewPolicy(emailpolicy.PolicyOptions{
+		Allow:         allow,
+		Deny:          deny,
+		Disposable:    disposable,
+		AllowlistOnly: env.EmailDomainAllowlistOnly,
+	}), nil
+}
+
+// newIPHasher creates the HMAC hasher used to record client IPs on sessions.
+// Without IP_HASH_KEYS (development only) an ephemeral key is generated,
+// so IP binding does not survive a restart.
+func newIPHasher(env *config.Env) (*ipaddress.Hasher, error) {
+	options := ipaddress.HasherOptions{
+		IPv4PrefixBits: env.IPHashIPv4Prefix,
+		IPv6PrefixBits: env.IPHashIPv6Prefix,
+	}
+
+	if len(env.IPHashKeys) == 0 {
+		key := make([]byte, ipaddress.MinHashKeyLength)
+
+		_, err := rand.Read(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate ephemeral ip hash key: %w", err)
+		}
+
+		log.Println("IP: IP_HASH_KEYS not set, using an ephemeral hash key (development only)")
+
+		return ipaddress.NewHasher("ephemeral", map[string][]byte{"ephemeral": key}, options)
+	}
+
+	hasher, err := ipaddress.NewHasher(env.IPHashActiveKeyID, env.IPHashKeys, options)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ip hash keys: %w", err)
+	}
+
+	return hasher, nil
+}
+
+// newInvitationSigner creates the HMAC signer of invitation tokens.
+// Without INVITATION_TOKEN_KEYS (development only) an ephemeral key is generated,
+// so invitation links stop working after a restart.
+func newInvitationSigner(env *config.Env) (*invitetoken.Signer, error) {
+	if len(env.InvitationTokenKeys) == 0 {
+		key := make([]byte, invitetoken.MinSigningKeyLength)
+
+		_, err := rand.Read(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate ephemeral invitation signing key: %w", err)
+		}
+
+		log.Println("Invitation: INVITATION_TOKEN_KEYS not set, using an ephemeral signing key (development only)")
+
+		return invitetoken.NewSigner("ephemeral", map[string][]byte{"ephemeral": key})
+	}
+
+	signer, err := invitetoken.NewSigner(env.InvitationTokenActiveKeyID, env.InvitationTokenKeys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid invitation token keys: %w", err)
+	}
+
+	return signer, nil
 }
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"net/netip"
 	"os"
 	"strconv"
 	"strings"
@@ -10,8 +12,147 @@ import (
 
 // Configuration errors.
 var (
-	ErrAllowedOriginsRequired = errors.New("ALLOWED_ORIGINS environment variable is required in production")
-	ErrInvalidIntegerValue    = errors.New("environment variable must be a valid integer")
+	ErrAllowedOriginsRequired    = errors.New("ALLOWED_ORIGINS environment variable is required in production")
+	ErrInvalidIntegerValue       = errors.New("environment variable must be a valid integer")
+	ErrInvalidBooleanValue       = errors.New("environment variable must be a valid boolean")
+	ErrUnsupportedEmailSender    = errors.New("EMAIL_SENDER must be one of: dummy, smtp")
+	ErrSMTPHostRequired          = errors.New("SMTP_HOST environment variable is required when EMAIL_SENDER=smtp")
+	ErrSMTPFromRequired          = errors.New("SMTP_FROM environment variable is required when EMAIL_SENDER=smtp")
+	ErrOTPHashKeysRequired       = errors.New("OTP_HASH_KEYS environment variable is required in production")
+	ErrInvalidOTPHashKeys        = errors.New("OTP_HASH_KEYS must be a comma-separated list of <keyID>:<base64 key>")
+	ErrUnsupportedSessionStore   = errors.New("SESSION_STORE must be one of: firestore, memory, redis, sql")
+	ErrRedisURLRequired          = errors.New("REDIS_URL environment variable is required when SESSION_STORE or RATE_LIMIT_STORE is redis")
+	ErrUnsupportedRateLimitStore = errors.New("RATE_LIMIT_STORE must be one of: memory, redis")
+	ErrInvalidRateLimitPolicy    = errors.New("rate limit requests per minute and burst must be positive")
+	ErrInvalidExemptCIDR         = errors.New("RATE_LIMIT_EXEMPT_CIDRS must be a comma-separated list of CIDRs or IP addresses")
+	ErrInvalidTrustedProxy       = errors.New("TRUSTED_PROXIES must be a comma-separated list of CIDRs or IP addresses")
+	ErrUnsupportedClientIPHeader = errors.New(
+		"CLIENT_IP_HEADER must be one of: X-Forwarded-For, X-Real-IP, Forwarded, CF-Connecting-IP",
+	)
+	ErrSQLDSNRequired            = errors.New("SQL_DSN environment variable is required when SESSION_STORE=sql")
+	ErrUnsupportedSQLDriver      = errors.New("SQL_DRIVER must be one of: sqlite, postgres")
+	ErrUnsupportedOTPRequestMode = errors.New("OTP_REQUEST_MODE must be one of: direct, padded, async")
+	ErrInvalidEvictionInterval   = errors.New("SESSION_EVICTION_INTERVAL_SECONDS must be positive")
+	ErrInvalidRateLimitMaxKeys   = errors.New("RATE_LIMIT_MAX_KEYS must be positive")
+	ErrInvalidOTPRequestLimit    = errors.New("OTP request limits must not be negative")
+	ErrInvalidOTPMaxBackground   = errors.New("OTP_REQUEST_MAX_BACKGROUND must be positive")
+	ErrUnsupportedOTPBinding     = errors.New("OTP_SESSION_BINDING must be one of: none, user_agent, ip, strict")
+	ErrIPHashKeysRequired        = errors.New("IP_HASH_KEYS environment variable is required in production")
+	ErrInvalidIPHashKeys         = errors.New("IP_HASH_KEYS must be a comma-separated list of <keyID>:<base64 key>")
+	ErrInvalidIPHashPrefix       = errors.New("IP_HASH_IPV4_PREFIX must be 8-32 and IP_HASH_IPV6_PREFIX 8-128")
+	ErrEmailAllowlistRequired    = errors.New("EMAIL_DOMAIN_ALLOWLIST is required when EMAIL_DOMAIN_ALLOWLIST_ONLY=true")
+	ErrInvalidDisposableReload   = errors.New("EMAIL_DISPOSABLE_RELOAD_INTERVAL_SECONDS must not be negative")
+	ErrUnsupportedRegistration   = errors.New("REGISTRATION_MODE must be one of: closed, open, invite")
+	ErrInvalidInvitationTTL      = errors.New("INVITATION_TTL_HOURS must be positive")
+	ErrInvitationKeysRequired    = errors.New(
+		"INVITATION_TOKEN_KEYS environment variable is required in production unless REGISTRATION_MODE=closed",
+	)
+	ErrInvalidInvitationKeys = errors.New(
+		"INVITATION_TOKEN_KEYS must be a comma-separated list of <keyID>:<base64 key>",
+	)
+	ErrInvitationURLRequired = errors.New(
+		"INVITATION_ACCEPT_URL environment variable is required in production unless REGISTRATION_MODE=closed",
+	)
+	ErrUnsupportedClaimsProvider  = errors.New("CLAIMS_PROVIDER must be one of: none, file, firestore")
+	ErrClaimsRolesFileRequired    = errors.New("CLAIMS_ROLES_FILE environment variable is required when CLAIMS_PROVIDER=file")
+	ErrInvalidClaimsReload        = errors.New("CLAIMS_ROLES_RELOAD_INTERVAL_SECONDS must not be negative")
+	ErrUnsupportedIdentityBackend = errors.New("IDENTITY_BACKEND must be one of: firebase, standalone")
+	ErrStandaloneSessionStore     = errors.New("IDENTITY_BACKEND=standalone requires SESSION_STORE memory or sql")
+	ErrStandaloneClaimsProvider   = errors.New("IDENTITY_BACKEND=standalone does not support CLAIMS_PROVIDER=firestore")
+	ErrInvalidJWTTTL              = errors.New("JWT_TTL_SECONDS must be positive")
+	ErrJWTSigningKeysRequired     = errors.New(
+		"JWT_SIGNING_KEYS environment variable is required in production when IDENTITY_BACKEND=standalone",
+	)
+	ErrInvalidJWTSigningKeys = errors.New("JWT_SIGNING_KEYS must be a comma-separated list of <keyID>:<PEM file path>")
+	ErrJWTIssuerRequired     = errors.New(
+		"JWT_ISSUER and JWT_AUDIENCE environment variables are required in production when IDENTITY_BACKEND=standalone",
+	)
+)
+
+// Email sender names accepted by EMAIL_SENDER.
+const (
+	EmailSenderDummy = "dummy"
+	EmailSenderSMTP  = "smtp"
+)
+
+// OTP session store names accepted by SESSION_STORE.
+const (
+	SessionStoreFirestore = "firestore"
+	SessionStoreMemory    = "memory"
+	SessionStoreRedis     = "redis"
+	SessionStoreSQL       = "sql"
+)
+
+// Rate limiter store names accepted by RATE_LIMIT_STORE.
+const (
+	RateLimitStoreMemory = "memory"
+	RateLimitStoreRedis  = "redis"
+)
+
+// Rate limit policy names. Each policy is configured by RATE_LIMIT_<NAME>_REQUESTS_PER_MINUTE
+// and RATE_LIMIT_<NAME>_BURST, falling back to RATE_LIMIT_REQUESTS_PER_MINUTE.
+const (
+	RateLimitPolicyOTP    = "otp"    // POST /auth/otp (sends email)
+	RateLimitPolicyVerify = "verify" // POST /auth/verify (brute-force target)
+	RateLimitPolicyAdmin  = "admin"  // Admin endpoints
+)
+
+// rateLimitPolicyNames lists the policies loaded by LoadEnv.
+var rateLimitPolicyNames = []string{RateLimitPolicyOTP, RateLimitPolicyVerify, RateLimitPolicyAdmin}
+
+// RateLimitPolicy is the token bucket applied to one route group.
+type RateLimitPolicy struct {
+	RequestsPerMinute int // Refill rate
+	Burst             int // Bucket size
+}
+
+// Headers accepted by CLIENT_IP_HEADER (matched case-insensitively).
+const (
+	ClientIPHeaderXForwardedFor  = "X-Forwarded-For"
+	ClientIPHeaderXRealIP        = "X-Real-IP"
+	ClientIPHeaderForwarded      = "Forwarded"
+	ClientIPHeaderCFConnectingIP = "CF-Connecting-IP"
+)
+
+// OTP request modes accepted by OTP_REQUEST_MODE.
+const (
+	OTPRequestModeDirect = "direct"
+	OTPRequestModePadded = "padded"
+	OTPRequestModeAsync  = "async"
+)
+
+// Registration modes accepted by REGISTRATION_MODE.
+const (
+	RegistrationModeClosed = "closed" // Only existing users can sign in
+	RegistrationModeOpen   = "open"   // Anyone can sign up via POST /auth/signup
+	RegistrationModeInvite = "invite" // Only invited addresses can sign up
+)
+
+// Client binding policies accepted by OTP_SESSION_BINDING.
+const (
+	OTPSessionBindingNone      = "none"
+	OTPSessionBindingUserAgent = "user_agent"
+	OTPSessionBindingIP        = "ip"
+	OTPSessionBindingStrict    = "strict"
+)
+
+// Claims providers accepted by CLAIMS_PROVIDER.
+const (
+	ClaimsProviderNone      = "none"      // Only the authentication claims
+	ClaimsProviderFile      = "file"      // Roles from a JSON file (CLAIMS_ROLES_FILE)
+	ClaimsProviderFirestore = "firestore" // Roles from Firestore documents keyed by user ID
+)
+
+// Identity backends accepted by IDENTITY_BACKEND.
+const (
+	IdentityBackendFirebase   = "firebase"   // Users in Firebase Authentication, Firebase custom tokens
+	IdentityBackendStandalone = "standalone" // Users in the session store, JWTs signed with local keys
+)
+
+// SQL drivers accepted by SQL_DRIVER.
+const (
+	SQLDriverSQLite   = "sqlite"
+	SQLDriverPostgres = "postgres"
 )
 
 // Default configuration values.
@@ -20,6 +161,38 @@ const (
 	defaultEnvironment                     = "development"
 	defaultRateLimitRequestsPerMinute      = 5
 	defaultRateLimitCleanupIntervalMinutes = 10
+	defaultRateLimitMaxKeys                = 100000
+	defaultRateLimitStore                  = RateLimitStoreMemory
+	defaultClientIPHeader                  = ClientIPHeaderXForwardedFor
+	defaultEmailSender                     = EmailSenderDummy
+	defaultEmailLocale                     = "ja"
+	defaultSMTPPort                        = 587
+	defaultSMTPTLSMode                     = "starttls"
+	defaultSMTPAuthMechanism               = "plain"
+	defaultSMTPTimeoutSeconds              = 10
+	defaultSessionStore                    = SessionStoreFirestore
+	defaultSessionEvictionIntervalSeconds  = 60
+	defaultSQLDriver                       = SQLDriverSQLite
+	defaultOTPRequestMode                  = OTPRequestModeDirect
+	defaultOTPResendCooldownSeconds        = 60
+	defaultOTPDailyLimitPerEmail           = 10
+	defaultOTPRequestMinDurationMillis     = 1000
+	defaultOTPRequestBackgroundTimeoutSecs = 30
+	defaultOTPRequestMaxBackground         = 100
+	defaultOTPSessionBinding               = OTPSessionBindingNone
+	defaultIPHashIPv4Prefix                = 32
+	defaultIPHashIPv6Prefix                = 128
+	defaultDisposableReloadIntervalSeconds = 60
+	defaultRegistrationMode                = RegistrationModeClosed
+	defaultInvitationTTLHours              = 7 * 24
+	defaultInvitationAcceptURL             = "http://localhost:5173/"
+	defaultClaimsProvider                  = ClaimsProviderNone
+	defaultClaimsRolesReloadSeconds        = 60
+	defaultClaimsFirestoreCollection       = "roles"
+	defaultIdentityBackend                 = IdentityBackendFirebase
+	defaultJWTIssuer                       = "http://localhost:8000"
+	defaultJWTAudience                     = "custom-auth-api"
+	defaultJWTTTLSeconds                   = 15 * 60
 )
 
 // Env holds all environment-based configuration values.
@@ -34,20 +207,181 @@ type Env struct {
 	// CORS configuration
 	AllowedOrigins []string
 
+	// Client IP resolution behind proxies
+	TrustedProxies []netip.Prefix // Peers whose client IP header is believed (none by default)
+	ClientIPHeader string         // Header carrying the client IP, one of the ClientIPHeader* constants
+
 	// Rate limiting configuration
 	RateLimitRequestsPerMinute      int
 	RateLimitCleanupIntervalMinutes int
+	RateLimitMaxKeys                int                        // Upper bound on client IPs tracked by the in-memory rate limiter
+	RateLimitStore                  string                     // memory (per instance) or redis (shared across replicas); also holds OTP email limits
+	RateLimitPolicies               map[string]RateLimitPolicy // Per route group, keyed by RateLimitPolicy* names
+	RateLimitExemptCIDRs            []netip.Prefix             // Clients never rate limited (e.g. health probers)
+
+	// Email delivery configuration (dummy/smtp)
+	EmailSender string
+
+	// Email template configuration
+	EmailTemplateDir   string // Optional directory overriding the embedded templates
+	EmailDefaultLocale string // Locale used when the request does not specify one (ja/en)
+
+	// SMTP configuration (used when EmailSender is "smtp")
+	SMTPHost           string
+	SMTPPort           int
+	SMTPUsername       string
+	SMTPPassword       string
+	SMTPAuthMechanism  string // plain/login
+	SMTPFrom           string
+	SMTPReplyTo        string
+	SMTPTLSMode        string // none/starttls/implicit
+	SMTPTimeoutSeconds int
+
+	// OTP hashing configuration (HMAC-SHA256 keys by key ID)
+	OTPHashKeys        map[string][]byte
+	OTPHashActiveKeyID string // Defaults to the first key in OTP_HASH_KEYS
+
+	// Client IP hashing configuration (HMAC-SHA256 keys by key ID)
+	IPHashKeys        map[string][]byte
+	IPHashActiveKeyID string // Defaults to the first key in IP_HASH_KEYS
+	IPHashIPv4Prefix  int    // Bits of IPv4 addresses kept before hashing (32 = full address)
+	IPHashIPv6Prefix  int    // Bits of IPv6 addresses kept before hashing (128 = full address)
+
+	// OTP session storage configuration (firestore/memory/redis/sql)
+	SessionStore                   string
+	SessionEvictionIntervalSeconds int    // Sweep interval for expired sessions in the memory and sql stores
+	RedisURL                       string // redis:// or rediss:// URL (used when SessionStore or RateLimitStore is "redis")
+	SQLDriver                      string // sqlite/postgres (used when SessionStore is "sql")
+	SQLDSN                         string
+
+	// Email enumeration protection for POST /auth/otp (direct/padded/async)
+	OTPRequestMode                     string
+	OTPRequestMinDurationMillis        int  // Minimum response time in padded mode
+	OTPRequestBackgroundTimeoutSeconds int  // Timeout for background processing in padded and async modes
+	OTPRequestMaxBackground            int  // Concurrent background requests in padded and async modes
+	OTPNotifyUnknownEmails             bool // Send a sign-in notice to unregistered addresses
+
+	// OTP email throttling (0 disables a limit). Kept in RateLimitStore: with the memory
+	// store every limit applies per instance, with redis it is shared across replicas.
+	OTPResendCooldownSeconds   int // Minimum time between emails to the same address
+	OTPDailyLimitPerEmail      int // Maximum emails per address in a rolling 24 hours
+	OTPGlobalRequestsPerMinute int // Maximum emails per minute across all addresses
+
+	// Client properties a verification must share with the OTP request (none/user_agent/ip/strict)
+	OTPSessionBinding string
+
+	// Fold provider-specific aliases (Gmail dots and +tags) into one mailbox for sessions and limits
+	EmailFoldProviderAliases bool
+
+	// Address syntax accepted beyond dot-atom ASCII local parts
+	EmailAllowQuotedLocalPart bool // Accept quoted local parts ("john doe"@example.com)
+	EmailAllowSMTPUTF8        bool // Accept non-ASCII local parts; the SMTP server must support SMTPUTF8
+
+	// Email domain policy ("example.com" or "*.example.com" patterns)
+	EmailDomainAllowlist         []string // Always accepted, even if denied or disposable
+	EmailDomainDenylist          []string // Always rejected unless allowlisted
+	EmailDomainAllowlistOnly     bool     // Reject every domain that is not allowlisted
+	EmailBlockDisposable         bool     // Reject disposable mailbox providers
+	EmailDisposableDomainsFile   string   // Optional file replacing the bundled disposable list
+	EmailDisposableReloadSeconds int      // How often the file is checked for changes (0 disables)
+
+	// Who can create an account by verifying an OTP (closed/open/invite)
+	RegistrationMode string
+
+	// Invitation-based registration (enabled when RegistrationMode is invite or open)
+	InvitationTTLHours         int
+	InvitationAcceptURL        string // Page linked from invitation emails; the token is added as ?token=
+	InvitationTokenKeys        map[string][]byte
+	InvitationTokenActiveKeyID string // Defaults to the first key in INVITATION_TOKEN_KEYS
+
+	// Extra claims in custom tokens (none/file/firestore)
+	ClaimsProvider            string
+	ClaimsRolesFile           string // Role mapping used by the file provider
+	ClaimsRolesReloadSeconds  int    // How often the role mapping is checked for changes (0 disables)
+	ClaimsFirestoreCollection string // Collection of roles/{uid} documents used by the firestore provider
+
+	// Where users are stored and who issues their tokens (firebase/standalone)
+	IdentityBackend string
+
+	// Access tokens of the standalone identity backend (ES256/EdDSA PEM private key files by key ID)
+	JWTSigningKeyFiles map[string]string
+	JWTActiveKeyID     string // Defaults to the first key in JWT_SIGNING_KEYS
+	JWTIssuer          string // "iss" claim, e.g. the public URL of the API
+	JWTAudience        string // "aud" claim, the services accepting the tokens
+	JWTTTLSeconds      int
 }
 
 // LoadEnv loads and validates all environment variables.
 // Returns an error if required environment variables are missing or invalid.
 func LoadEnv() (*Env, error) {
 	env := &Env{
-		Port:                            getEnvOrDefault("PORT", defaultPort),
-		Environment:                     getEnvOrDefault("ENV", defaultEnvironment),
-		AllowedOrigins:                  nil, // Will be set below for production
-		RateLimitRequestsPerMinute:      0,   // Will be set below
-		RateLimitCleanupIntervalMinutes: 0,   // Will be set below
+		Port:                               getEnvOrDefault("PORT", defaultPort),
+		Environment:                        getEnvOrDefault("ENV", defaultEnvironment),
+		AllowedOrigins:                     nil, // Will be set below for production
+		RateLimitRequestsPerMinute:         0,   // Will be set below
+		RateLimitCleanupIntervalMinutes:    0,   // Will be set below
+		RateLimitMaxKeys:                   0,   // Will be set below
+		RateLimitStore:                     getEnvOrDefault("RATE_LIMIT_STORE", defaultRateLimitStore),
+		TrustedProxies:                     nil, // Will be set below
+		ClientIPHeader:                     "",  // Will be set below
+		RateLimitPolicies:                  nil, // Will be set below
+		RateLimitExemptCIDRs:               nil, // Will be set below
+		EmailSender:                        getEnvOrDefault("EMAIL_SENDER", defaultEmailSender),
+		EmailTemplateDir:                   os.Getenv("EMAIL_TEMPLATE_DIR"),
+		EmailDefaultLocale:                 getEnvOrDefault("EMAIL_DEFAULT_LOCALE", defaultEmailLocale),
+		SMTPHost:                           os.Getenv("SMTP_HOST"),
+		SMTPPort:                           0, // Will be set below
+		SMTPUsername:                       os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:                       os.Getenv("SMTP_PASSWORD"),
+		SMTPAuthMechanism:                  getEnvOrDefault("SMTP_AUTH_MECHANISM", defaultSMTPAuthMechanism),
+		SMTPFrom:                           os.Getenv("SMTP_FROM"),
+		SMTPReplyTo:                        os.Getenv("SMTP_REPLY_TO"),
+		SMTPTLSMode:                        getEnvOrDefault("SMTP_TLS_MODE", defaultSMTPTLSMode),
+		SMTPTimeoutSeconds:                 0,   // Will be set below
+		OTPHashKeys:                        nil, // Will be set below
+		OTPHashActiveKeyID:                 os.Getenv("OTP_HASH_ACTIVE_KEY_ID"),
+		IPHashKeys:                         nil, // Will be set below
+		IPHashActiveKeyID:                  os.Getenv("IP_HASH_ACTIVE_KEY_ID"),
+		IPHashIPv4Prefix:                   0, // Will be set below
+		IPHashIPv6Prefix:                   0, // Will be set below
+		SessionStore:                       getEnvOrDefault("SESSION_STORE", defaultSessionStore),
+		SessionEvictionIntervalSeconds:     0, // Will be set below
+		RedisURL:                           os.Getenv("REDIS_URL"),
+		SQLDriver:                          getEnvOrDefault("SQL_DRIVER", defaultSQLDriver),
+		SQLDSN:                             os.Getenv("SQL_DSN"),
+		OTPRequestMode:                     getEnvOrDefault("OTP_REQUEST_MODE", defaultOTPRequestMode),
+		OTPRequestMinDurationMillis:        0,     // Will be set below
+		OTPRequestBackgroundTimeoutSeconds: 0,     // Will be set below
+		OTPRequestMaxBackground:            0,     // Will be set below
+		OTPNotifyUnknownEmails:             false, // Will be set below
+		OTPResendCooldownSeconds:           0,     // Will be set below
+		OTPDailyLimitPerEmail:              0,     // Will be set below
+		OTPGlobalRequestsPerMinute:         0,     // Will be set below
+		OTPSessionBinding:                  strings.ToLower(getEnvOrDefault("OTP_SESSION_BINDING", defaultOTPSessionBinding)),
+		EmailFoldProviderAliases:           false, // Will be set below
+		EmailAllowQuotedLocalPart:          false, // Will be set below
+		EmailAllowSMTPUTF8:                 false, // Will be set below
+		EmailDomainAllowlist:               parseList(os.Getenv("EMAIL_DOMAIN_ALLOWLIST")),
+		EmailDomainDenylist:                parseList(os.Getenv("EMAIL_DOMAIN_DENYLIST")),
+		EmailDomainAllowlistOnly:           false, // Will be set below
+		EmailBlockDisposable:               false, // Will be set below
+		EmailDisposableDomainsFile:         os.Getenv("EMAIL_DISPOSABLE_DOMAINS_FILE"),
+		EmailDisposableReloadSeconds:       0, // Will be set below
+		RegistrationMode:                   strings.ToLower(getEnvOrDefault("REGISTRATION_MODE", defaultRegistrationMode)),
+		InvitationTTLHours:                 0,   // Will be set below
+		InvitationAcceptURL:                "",  // Will be set below
+		InvitationTokenKeys:                nil, // Will be set below
+		InvitationTokenActiveKeyID:         os.Getenv("INVITATION_TOKEN_ACTIVE_KEY_ID"),
+		ClaimsProvider:                     strings.ToLower(getEnvOrDefault("CLAIMS_PROVIDER", defaultClaimsProvider)),
+		ClaimsRolesFile:                    os.Getenv("CLAIMS_ROLES_FILE"),
+		ClaimsRolesReloadSeconds:           0, // Will be set below
+		ClaimsFirestoreCollection:          getEnvOrDefault("CLAIMS_FIRESTORE_COLLECTION", defaultClaimsFirestoreCollection),
+		IdentityBackend:                    strings.ToLower(getEnvOrDefault("IDENTITY_BACKEND", defaultIdentityBackend)),
+		JWTSigningKeyFiles:                 nil, // Will be set below
+		JWTActiveKeyID:                     os.Getenv("JWT_ACTIVE_KEY_ID"),
+		JWTIssuer:                          "", // Will be set below
+		JWTAudience:                        "", // Will be set below
+		JWTTTLSeconds:                      0,  // Will be set below
 	}
 
 	// Validate and load CORS origins
@@ -60,6 +394,11 @@ func LoadEnv() (*Env, error) {
 	}
 	// In development, AllowedOrigins will be empty and handled by CORS middleware
 
+	err := loadClientIPConfig(env)
+	if err != nil {
+		return nil, err
+	}
+
 	// Load rate limiting configuration with defaults
 	requestsPerMinute, err := getEnvAsInt("RATE_LIMIT_REQUESTS_PER_MINUTE", defaultRateLimitRequestsPerMinute)
 	if err != nil {
@@ -73,9 +412,616 @@ func LoadEnv() (*Env, error) {
 	}
 	env.RateLimitCleanupIntervalMinutes = cleanupInterval
 
+	maxKeys, err := getEnvAsInt("RATE_LIMIT_MAX_KEYS", defaultRateLimitMaxKeys)
+	if err != nil {
+		return nil, err
+	}
+	if maxKeys <= 0 {
+		return nil, ErrInvalidRateLimitMaxKeys
+	}
+	env.RateLimitMaxKeys = maxKeys
+
+	switch env.RateLimitStore {
+	case RateLimitStoreMemory:
+	case RateLimitStoreRedis:
+		if env.RedisURL == "" {
+			return nil, ErrRedisURLRequired
+		}
+	default:
+		return nil, fmt.Errorf("%w (got %q)", ErrUnsupportedRateLimitStore, env.RateLimitStore)
+	}
+
+	err = loadRateLimitPolicies(env)
+	if err != nil {
+		return nil, err
+	}
+
+	err = loadEmailSenderConfig(env)
+	if err != nil {
+		return nil, err
+	}
+
+	err = loadOTPHashConfig(env)
+	if err != nil {
+		return nil, err
+	}
+
+	err = loadIPHashConfig(env)
+	if err != nil {
+		return nil, err
+	}
+
+	err = loadSessionStoreConfig(env)
+	if err != nil {
+		return nil, err
+	}
+
+	err = loadOTPRequestConfig(env)
+	if err != nil {
+		return nil, err
+	}
+
+	err = loadOTPThrottleConfig(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch env.OTPSessionBinding {
+	case OTPSessionBindingNone, OTPSessionBindingUserAgent, OTPSessionBindingIP, OTPSessionBindingStrict:
+	default:
+		return nil, fmt.Errorf("%w (got %q)", ErrUnsupportedOTPBinding, env.OTPSessionBinding)
+	}
+
+	err = loadEmailAddressConfig(env)
+	if err != nil {
+		return nil, err
+	}
+
+	err = loadEmailDomainPolicyConfig(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch env.RegistrationMode {
+	case RegistrationModeClosed, RegistrationModeOpen, RegistrationModeInvite:
+	default:
+		return nil, fmt.Errorf("%w (got %q)", ErrUnsupportedRegistration, env.RegistrationMode)
+	}
+
+	err = loadInvitationConfig(env)
+	if err != nil {
+		return nil, err
+	}
+
+	err = loadClaimsConfig(env)
+	if err != nil {
+		return nil, err
+	}
+
+	err = loadIdentityConfig(env)
+	if err != nil {
+		return nil, err
+	}
+
 	return env, nil
 }
 
+// loadIdentityConfig validates the identity backend and loads the token settings of the
+// standalone backend. Its users are kept in the session store, so that store must be able
+// to hold them, and the signing keys, issuer and audience are required in production.
+func loadIdentityConfig(env *Env) error {
+	switch env.IdentityBackend {
+	case IdentityBackendFirebase:
+		return nil
+	case IdentityBackendStandalone:
+	default:
+		return fmt.Errorf("%w (got %q)", ErrUnsupportedIdentityBackend, env.IdentityBackend)
+	}
+
+	if env.SessionStore != SessionStoreMemory && env.SessionStore != SessionStoreSQL {
+		return fmt.Errorf("%w (got %q)", ErrStandaloneSessionStore, env.SessionStore)
+	}
+
+	if env.ClaimsProvider == ClaimsProviderFirestore {
+		return ErrStandaloneClaimsProvider
+	}
+
+	ttlSeconds, err := getEnvAsInt("JWT_TTL_SECONDS", defaultJWTTTLSeconds)
+	if err != nil {
+		return err
+	}
+	if ttlSeconds <= 0 {
+		return fmt.Errorf("%w (got %d)", ErrInvalidJWTTTL, ttlSeconds)
+	}
+	env.JWTTTLSeconds = ttlSeconds
+
+	env.JWTIssuer = os.Getenv("JWT_ISSUER")
+	env.JWTAudience = os.Getenv("JWT_AUDIENCE")
+	if env.JWTIssuer == "" || env.JWTAudience == "" {
+		if env.IsProduction() {
+			return ErrJWTIssuerRequired
+		}
+		env.JWTIssuer = getEnvOrDefault("JWT_ISSUER", defaultJWTIssuer)
+		env.JWTAudience = getEnvOrDefault("JWT_AUDIENCE", defaultJWTAudience)
+	}
+
+	raw := os.Getenv("JWT_SIGNING_KEYS")
+	if raw == "" {
+		if env.IsProduction() {
+			return ErrJWTSigningKeysRequired
+		}
+
+		return nil
+	}
+
+	env.JWTSigningKeyFiles = make(map[string]string)
+	firstKeyID := ""
+
+	for entry := range strings.SplitSeq(raw, ",") {
+		keyID, path, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found || keyID == "" || path == "" {
+			return fmt.Errorf("%w (got %q)", ErrInvalidJWTSigningKeys, entry)
+		}
+
+		env.JWTSigningKeyFiles[keyID] = path
+
+		if firstKeyID == "" {
+			firstKeyID = keyID
+		}
+	}
+
+	if env.JWTActiveKeyID == "" {
+		env.JWTActiveKeyID = firstKeyID
+	}
+
+	return nil
+}
+
+// loadClaimsConfig validates the claims provider and its settings.
+func loadClaimsConfig(env *Env) error {
+	switch env.ClaimsProvider {
+	case ClaimsProviderNone, ClaimsProviderFirestore:
+	case ClaimsProviderFile:
+		if env.ClaimsRolesFile == "" {
+			return ErrClaimsRolesFileRequired
+		}
+	default:
+		return fmt.Errorf("%w (got %q)", ErrUnsupportedClaimsProvider, env.ClaimsProvider)
+	}
+
+	reloadInterval, err := getEnvAsInt("CLAIMS_ROLES_RELOAD_INTERVAL_SECONDS", defaultClaimsRolesReloadSeconds)
+	if err != nil {
+		return err
+	}
+
+	if reloadInterval < 0 {
+		return fmt.Errorf("%w (got %d)", ErrInvalidClaimsReload, reloadInterval)
+	}
+	env.ClaimsRolesReloadSeconds = reloadInterval
+
+	return nil
+}
+
+// loadInvitationConfig loads invitation expiry, the accept page and the token signing keys.
+// The keys and the accept page are required in production unless registration is closed.
+func loadInvitationConfig(env *Env) error {
+	ttlHours, err := getEnvAsInt("INVITATION_TTL_HOURS", defaultInvitationTTLHours)
+	if err != nil {
+		return err
+	}
+	if ttlHours <= 0 {
+		return fmt.Errorf("%w (got %d)", ErrInvalidInvitationTTL, ttlHours)
+	}
+	env.InvitationTTLHours = ttlHours
+
+	required := env.IsProduction() && env.RegistrationMode != RegistrationModeClosed
+
+	env.InvitationAcceptURL = os.Getenv("INVITATION_ACCEPT_URL")
+	if env.InvitationAcceptURL == "" {
+		if required {
+			return ErrInvitationURLRequired
+		}
+		env.InvitationAcceptURL = defaultInvitationAcceptURL
+	}
+
+	raw := os.Getenv("INVITATION_TOKEN_KEYS")
+	if raw == "" {
+		if required {
+			return ErrInvitationKeysRequired
+		}
+
+		return nil
+	}
+
+	keys, firstKeyID, err := parseHashKeys(raw, ErrInvalidInvitationKeys)
+	if err != nil {
+		return err
+	}
+	env.InvitationTokenKeys = keys
+
+	if env.InvitationTokenActiveKeyID == "" {
+		env.InvitationTokenActiveKeyID = firstKeyID
+	}
+
+	return nil
+}
+
+// loadEmailAddressConfig loads how email addresses are validated and canonicalized.
+func loadEmailAddressConfig(env *Env) error {
+	foldAliases, err := getEnvAsBool("EMAIL_FOLD_PROVIDER_ALIASES", false)
+	if err != nil {
+		return err
+	}
+
+	allowQuoted, err := getEnvAsBool("EMAIL_ALLOW_QUOTED_LOCAL_PART", false)
+	if err != nil {
+		return err
+	}
+
+	allowSMTPUTF8, err := getEnvAsBool("EMAIL_ALLOW_SMTPUTF8", false)
+	if err != nil {
+		return err
+	}
+
+	env.EmailFoldProviderAliases = foldAliases
+	env.EmailAllowQuotedLocalPart = allowQuoted
+	env.EmailAllowSMTPUTF8 = allowSMTPUTF8
+
+	return nil
+}
+
+// loadEmailDomainPolicyConfig loads the allowed, denied and disposable email domain settings.
+// Domain patterns are validated when the policy is built.
+func loadEmailDomainPolicyConfig(env *Env) error {
+	allowlistOnly, err := getEnvAsBool("EMAIL_DOMAIN_ALLOWLIST_ONLY", false)
+	if err != nil {
+		return err
+	}
+
+	if allowlistOnly && len(env.EmailDomainAllowlist) == 0 {
+		return ErrEmailAllowlistRequired
+	}
+
+	blockDisposable, err := getEnvAsBool("EMAIL_BLOCK_DISPOSABLE", false)
+	if err != nil {
+		return err
+	}
+
+	reloadInterval, err := getEnvAsInt("EMAIL_DISPOSABLE_RELOAD_INTERVAL_SECONDS", defaultDisposableReloadIntervalSeconds)
+	if err != nil {
+		return err
+	}
+
+	if reloadInterval < 0 {
+		return fmt.Errorf("%w (got %d)", ErrInvalidDisposableReload, reloadInterval)
+	}
+
+	env.EmailDomainAllowlistOnly = allowlistOnly
+	env.EmailBlockDisposable = blockDisposable
+	env.EmailDisposableReloadSeconds = reloadInterval
+
+	return nil
+}
+
+// loadClientIPConfig loads the trusted proxies and the header they put the client IP in.
+func loadClientIPConfig(env *Env) error {
+	prefixes, err := parseCIDRList(os.Getenv("TRUSTED_PROXIES"), ErrInvalidTrustedProxy)
+	if err != nil {
+		return err
+	}
+	env.TrustedProxies = prefixes
+
+	header := getEnvOrDefault("CLIENT_IP_HEADER", defaultClientIPHeader)
+	for _, supported := range []string{
+		ClientIPHeaderXForwardedFor, ClientIPHeaderXRealIP, ClientIPHeaderForwarded, ClientIPHeaderCFConnectingIP,
+	} {
+		if strings.EqualFold(header, supported) {
+			env.ClientIPHeader = supported
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w (got %q)", ErrUnsupportedClientIPHeader, header)
+}
+
+// loadRateLimitPolicies loads the per-route-group rate limit policies and the exempt CIDRs.
+func loadRateLimitPolicies(env *Env) error {
+	env.RateLimitPolicies = make(map[string]RateLimitPolicy, len(rateLimitPolicyNames))
+
+	for _, name := range rateLimitPolicyNames {
+		prefix := "RATE_LIMIT_" + strings.ToUpper(name)
+
+		requestsPerMinute, err := getEnvAsInt(prefix+"_REQUESTS_PER_MINUTE", env.RateLimitRequestsPerMinute)
+		if err != nil {
+			return err
+		}
+
+		burst, err := getEnvAsInt(prefix+"_BURST", requestsPerMinute)
+		if err != nil {
+			return err
+		}
+
+		if requestsPerMinute <= 0 || burst <= 0 {
+			return fmt.Errorf("%w (policy %q)", ErrInvalidRateLimitPolicy, name)
+		}
+
+		env.RateLimitPolicies[name] = RateLimitPolicy{RequestsPerMinute: requestsPerMinute, Burst: burst}
+	}
+
+	exemptCIDRs, err := parseCIDRList(os.Getenv("RATE_LIMIT_EXEMPT_CIDRS"), ErrInvalidExemptCIDR)
+	if err != nil {
+		return err
+	}
+	env.RateLimitExemptCIDRs = exemptCIDRs
+
+	return nil
+}
+
+// parseCIDRList parses a comma-separated list of CIDRs and IP addresses.
+// Invalid entries are reported by wrapping invalidErr.
+func parseCIDRList(value string, invalidErr error) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+
+	for entry := range strings.SplitSeq(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		prefix, err := parseCIDROrIP(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%w (got %q)", invalidErr, entry)
+		}
+
+		prefixes = append(prefixes, prefix)
+	}
+
+	return prefixes, nil
+}
+
+// parseList splits a comma-separated list, trimming entries and dropping empty ones.
+func parseList(value string) []string {
+	var entries []string
+
+	for entry := range strings.SplitSeq(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// parseCIDROrIP parses "192.0.2.0/24" or a single address such as "192.0.2.1".
+func parseCIDROrIP(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	addr = addr.Unmap()
+
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// loadOTPRequestConfig loads the email enumeration protection settings for POST /auth/otp.
+func loadOTPRequestConfig(env *Env) error {
+	switch env.OTPRequestMode {
+	case OTPRequestModeDirect, OTPRequestModePadded, OTPRequestModeAsync:
+	default:
+		return fmt.Errorf("%w (got %q)", ErrUnsupportedOTPRequestMode, env.OTPRequestMode)
+	}
+
+	minDuration, err := getEnvAsInt("OTP_REQUEST_MIN_DURATION_MS", defaultOTPRequestMinDurationMillis)
+	if err != nil {
+		return err
+	}
+	env.OTPRequestMinDurationMillis = minDuration
+
+	backgroundTimeout, err := getEnvAsInt("OTP_REQUEST_BACKGROUND_TIMEOUT_SECONDS", defaultOTPRequestBackgroundTimeoutSecs)
+	if err != nil {
+		return err
+	}
+	env.OTPRequestBackgroundTimeoutSeconds = backgroundTimeout
+
+	maxBackground, err := getEnvAsInt("OTP_REQUEST_MAX_BACKGROUND", defaultOTPRequestMaxBackground)
+	if err != nil {
+		return err
+	}
+	if maxBackground <= 0 {
+		return ErrInvalidOTPMaxBackground
+	}
+	env.OTPRequestMaxBackground = maxBackground
+
+	notifyUnknown, err := getEnvAsBool("OTP_NOTIFY_UNKNOWN_EMAILS", false)
+	if err != nil {
+		return err
+	}
+	env.OTPNotifyUnknownEmails = notifyUnknown
+
+	return nil
+}
+
+// loadSessionStoreConfig validates the OTP session store selection.
+func loadSessionStoreConfig(env *Env) error {
+	evictionInterval, err := getEnvAsInt("SESSION_EVICTION_INTERVAL_SECONDS", defaultSessionEvictionIntervalSeconds)
+	if err != nil {
+		return err
+	}
+	if evictionInterval <= 0 {
+		return ErrInvalidEvictionInterval
+	}
+	env.SessionEvictionIntervalSeconds = evictionInterval
+
+	switch env.SessionStore {
+	case SessionStoreFirestore, SessionStoreMemory:
+		return nil
+	case SessionStoreRedis:
+		if env.RedisURL == "" {
+			return ErrRedisURLRequired
+		}
+
+		return nil
+	case SessionStoreSQL:
+		if env.SQLDriver != SQLDriverSQLite && env.SQLDriver != SQLDriverPostgres {
+			return fmt.Errorf("%w (got %q)", ErrUnsupportedSQLDriver, env.SQLDriver)
+		}
+		if env.SQLDSN == "" {
+			return ErrSQLDSNRequired
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("%w (got %q)", ErrUnsupportedSessionStore, env.SessionStore)
+	}
+}
+
+// loadOTPHashConfig parses OTP_HASH_KEYS ("<keyID>:<base64 key>,...").
+// Keys are mandatory in production; in development an ephemeral key may be generated by the caller.
+func loadOTPHashConfig(env *Env) error {
+	raw := os.Getenv("OTP_HASH_KEYS")
+	if raw == "" {
+		if env.IsProduction() {
+			return ErrOTPHashKeysRequired
+		}
+
+		return nil
+	}
+
+	keys, firstKeyID, err := parseHashKeys(raw, ErrInvalidOTPHashKeys)
+	if err != nil {
+		return err
+	}
+	env.OTPHashKeys = keys
+
+	if env.OTPHashActiveKeyID == "" {
+		env.OTPHashActiveKeyID = firstKeyID
+	}
+
+	return nil
+}
+
+// loadIPHashConfig parses IP_HASH_KEYS ("<keyID>:<base64 key>,...") and the truncation prefixes.
+// Keys are mandatory in production; in development an ephemeral key may be generated by the caller.
+func loadIPHashConfig(env *Env) error {
+	ipv4Prefix, err := getEnvAsInt("IP_HASH_IPV4_PREFIX", defaultIPHashIPv4Prefix)
+	if err != nil {
+		return err
+	}
+
+	ipv6Prefix, err := getEnvAsInt("IP_HASH_IPV6_PREFIX", defaultIPHashIPv6Prefix)
+	if err != nil {
+		return err
+	}
+
+	if ipv4Prefix < 8 || ipv4Prefix > 32 || ipv6Prefix < 8 || ipv6Prefix > 128 {
+		return fmt.Errorf("%w (got /%d and /%d)", ErrInvalidIPHashPrefix, ipv4Prefix, ipv6Prefix)
+	}
+	env.IPHashIPv4Prefix = ipv4Prefix
+	env.IPHashIPv6Prefix = ipv6Prefix
+
+	raw := os.Getenv("IP_HASH_KEYS")
+	if raw == "" {
+		if env.IsProduction() {
+			return ErrIPHashKeysRequired
+		}
+
+		return nil
+	}
+
+	keys, firstKeyID, err := parseHashKeys(raw, ErrInvalidIPHashKeys)
+	if err != nil {
+		return err
+	}
+	env.IPHashKeys = keys
+
+	if env.IPHashActiveKeyID == "" {
+		env.IPHashActiveKeyID = firstKeyID
+	}
+
+	return nil
+}
+
+// parseHashKeys parses "<keyID>:<base64 key>,..." into keys by ID and returns the first key ID.
+// Malformed entries are reported as invalidErr.
+func parseHashKeys(raw string, invalidErr error) (map[string][]byte, string, error) {
+	keys := make(map[string][]byte)
+	firstKeyID := ""
+
+	for entry := range strings.SplitSeq(raw, ",") {
+		keyID, encoded, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found || keyID == "" {
+			return nil, "", invalidErr
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: key %q is not valid base64", invalidErr, keyID)
+		}
+
+		keys[keyID] = key
+
+		if firstKeyID == "" {
+			firstKeyID = keyID
+		}
+	}
+
+	return keys, firstKeyID, nil
+}
+
+// loadEmailSenderConfig validates the email sender selection and loads SMTP settings.
+// TLS mode and auth mechanism values are validated by the SMTP sender itself.
+func loadEmailSenderConfig(env *Env) error {
+	smtpPort, err := getEnvAsInt("SMTP_PORT", defaultSMTPPort)
+	if err != nil {
+		return err
+	}
+	env.SMTPPort = smtpPort
+
+	smtpTimeout, err := getEnvAsInt("SMTP_TIMEOUT_SECONDS", defaultSMTPTimeoutSeconds)
+	if err != nil {
+		return err
+	}
+	env.SMTPTimeoutSeconds = smtpTimeout
+
+	switch env.EmailSender {
+	case EmailSenderDummy:
+		return nil
+	case EmailSenderSMTP:
+		if env.SMTPHost == "" {
+			return ErrSMTPHostRequired
+		}
+		if env.SMTPFrom == "" {
+			return ErrSMTPFromRequired
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("%w (got %q)", ErrUnsupportedEmailSender, env.EmailSender)
+	}
+}
+
+// RateLimitPolicy returns the named rate limit policy, falling back to
+// RateLimitRequestsPerMinute when the policy was not loaded (e.g. in tests building Env by hand).
+func (e *Env) RateLimitPolicy(name string) RateLimitPolicy {
+	if policy, ok := e.RateLimitPolicies[name]; ok {
+		return policy
+	}
+
+	return RateLimitPolicy{RequestsPerMinute: e.RateLimitRequestsPerMinute, Burst: e.RateLimitRequestsPerMinute}
+}
+
 // IsProduction returns true if the environment is set to production.
 func (e *Env) IsProduction() bool {
 	return e.Environment == "production"
@@ -86,6 +1032,34 @@ func (e *Env) IsDevelopment() bool {
 	return e.Environment == "development"
 }
 
+// loadOTPThrottleConfig loads the per-address and global limits on OTP emails.
+func loadOTPThrottleConfig(env *Env) error {
+	cooldown, err := getEnvAsInt("OTP_RESEND_COOLDOWN_SECONDS", defaultOTPResendCooldownSeconds)
+	if err != nil {
+		return err
+	}
+
+	dailyLimit, err := getEnvAsInt("OTP_DAILY_LIMIT_PER_EMAIL", defaultOTPDailyLimitPerEmail)
+	if err != nil {
+		return err
+	}
+
+	globalPerMinute, err := getEnvAsInt("OTP_GLOBAL_REQUESTS_PER_MINUTE", 0)
+	if err != nil {
+		return err
+	}
+
+	if cooldown < 0 || dailyLimit < 0 || globalPerMinute < 0 {
+		return ErrInvalidOTPRequestLimit
+	}
+
+	env.OTPResendCooldownSeconds = cooldown
+	env.OTPDailyLimitPerEmail = dailyLimit
+	env.OTPGlobalRequestsPerMinute = globalPerMinute
+
+	return nil
+}
+
 // getEnvOrDefault retrieves an environment variable or returns a default value.
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -110,3 +1084,19 @@ func getEnvAsInt(key string, defaultValue int) (int, error) {
 
 	return value, nil
 }
+
+// getEnvAsBool retrieves an environment variable as a boolean or returns a default value.
+// Accepts the values understood by strconv.ParseBool (1, t, true, 0, f, false, ...).
+func getEnvAsBool(key string, defaultValue bool) (bool, error) {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue, nil
+	}
+
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s", ErrInvalidBooleanValue, key)
+	}
+
+	return value, nil
+}
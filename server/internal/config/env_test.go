@@ -1,7 +1,10 @@
 package config_test
 
 import (
+	"errors"
+	"maps"
 	"os"
+	"slices"
 	"testing"
 
 	"custom_auth_api/internal/config"
@@ -9,6 +12,15 @@ import (
 
 const (
 	envProduction = "production"
+
+	// testOTPHashKeys is a valid OTP_HASH_KEYS value with one 32-byte key.
+	testOTPHashKeys = "k1:MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+
+	// testIPHashKeys is a valid IP_HASH_KEYS value with one 32-byte key.
+	testIPHashKeys = "i1:ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
+
+	// testInvitationKeys is a valid INVITATION_TOKEN_KEYS value with one 32-byte key.
+	testInvitationKeys = "v1:aW52aXRhdGlvbi1zaWduaW5nLWtleS0wMTIzNDU2Nzg="
 )
 
 func TestLoadEnv_Success(t *testing.T) {
@@ -35,6 +47,12 @@ func TestLoadEnv_Success(t *testing.T) {
 		if env.RateLimitCleanupIntervalMinutes != 10 {
 			t.Errorf("expected 10 minute cleanup interval, got %d", env.RateLimitCleanupIntervalMinutes)
 		}
+		if env.RateLimitMaxKeys != 100000 {
+			t.Errorf("expected 100000 max keys, got %d", env.RateLimitMaxKeys)
+		}
+		if env.RateLimitStore != config.RateLimitStoreMemory {
+			t.Errorf("expected memory rate limit store, got %s", env.RateLimitStore)
+		}
 	})
 
 	t.Run("loads custom values from environment variables", func(t *testing.T) {
@@ -43,6 +61,8 @@ func TestLoadEnv_Success(t *testing.T) {
 		t.Setenv("PORT", "9000")
 		t.Setenv("ENV", envProduction)
 		t.Setenv("ALLOWED_ORIGINS", "https://example.com,https://app.example.com")
+		t.Setenv("OTP_HASH_KEYS", testOTPHashKeys)
+		t.Setenv("IP_HASH_KEYS", testIPHashKeys)
 		t.Setenv("RATE_LIMIT_REQUESTS_PER_MINUTE", "10")
 		t.Setenv("RATE_LIMIT_CLEANUP_INTERVAL_MINUTES", "20")
 
@@ -100,6 +120,8 @@ func TestLoadEnv_ProductionValidation(t *testing.T) {
 		clearEnv(t)
 		t.Setenv("ENV", envProduction)
 		t.Setenv("ALLOWED_ORIGINS", "https://example.com")
+		t.Setenv("OTP_HASH_KEYS", testOTPHashKeys)
+		t.Setenv("IP_HASH_KEYS", testIPHashKeys)
 
 		// Act
 		env, err := config.LoadEnv()
@@ -148,50 +170,1683 @@ func TestLoadEnv_InvalidRateLimitValues(t *testing.T) {
 			t.Error("expected nil env when error occurs")
 		}
 	})
+
+	t.Run("returns error when RATE_LIMIT_MAX_KEYS is not positive", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("RATE_LIMIT_MAX_KEYS", "0")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if !errors.Is(err, config.ErrInvalidRateLimitMaxKeys) {
+			t.Errorf("expected ErrInvalidRateLimitMaxKeys, got %v", err)
+		}
+		if env != nil {
+			t.Error("expected nil env when error occurs")
+		}
+	})
 }
 
-func TestEnv_IsProduction(t *testing.T) {
-	t.Parallel()
+func TestLoadEnv_RateLimitStore(t *testing.T) {
+	t.Run("loads the redis store", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("RATE_LIMIT_STORE", "redis")
+		t.Setenv("REDIS_URL", "redis://localhost:6379/0")
 
-	t.Run("returns true when environment is production", func(t *testing.T) {
-		t.Parallel()
-		env := &config.Env{Environment: envProduction}
-		if !env.IsProduction() {
-			t.Error("expected IsProduction to return true")
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.RateLimitStore != config.RateLimitStoreRedis {
+			t.Errorf("expected redis rate limit store, got %s", env.RateLimitStore)
 		}
 	})
 
-	t.Run("returns false when environment is not production", func(t *testing.T) {
-		t.Parallel()
-		env := &config.Env{Environment: "development"}
-		if env.IsProduction() {
-			t.Error("expected IsProduction to return false")
+	testCases := []struct {
+		name        string
+		vars        map[string]string
+		expectedErr error
+	}{
+		{
+			name:        "returns error for unsupported RATE_LIMIT_STORE",
+			vars:        map[string]string{"RATE_LIMIT_STORE": "memcached"},
+			expectedErr: config.ErrUnsupportedRateLimitStore,
+		},
+		{
+			name:        "returns error when REDIS_URL is missing for the redis store",
+			vars:        map[string]string{"RATE_LIMIT_STORE": "redis"},
+			expectedErr: config.ErrRedisURLRequired,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			clearEnv(t)
+			for key, value := range tc.vars {
+				t.Setenv(key, value)
+			}
+
+			// Act
+			env, err := config.LoadEnv()
+
+			// Assert
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected %v, got %v", tc.expectedErr, err)
+			}
+			if env != nil {
+				t.Error("expected nil env when error occurs")
+			}
+		})
+	}
+}
+
+func TestLoadEnv_ClientIP(t *testing.T) {
+	t.Run("trusts no proxies by default", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(env.TrustedProxies) != 0 {
+			t.Errorf("expected no trusted proxies, got %v", env.TrustedProxies)
+		}
+		if env.ClientIPHeader != config.ClientIPHeaderXForwardedFor {
+			t.Errorf("expected X-Forwarded-For, got %s", env.ClientIPHeader)
+		}
+	})
+
+	t.Run("loads trusted proxies and normalizes the header name", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8,130.211.0.0/22")
+		t.Setenv("CLIENT_IP_HEADER", "cf-connecting-ip")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(env.TrustedProxies) != 2 || env.TrustedProxies[1].String() != "130.211.0.0/22" {
+			t.Errorf("unexpected trusted proxies %v", env.TrustedProxies)
+		}
+		if env.ClientIPHeader != config.ClientIPHeaderCFConnectingIP {
+			t.Errorf("expected CF-Connecting-IP, got %s", env.ClientIPHeader)
 		}
 	})
+
+	testCases := []struct {
+		name        string
+		vars        map[string]string
+		expectedErr error
+	}{
+		{
+			name:        "returns error for an invalid trusted proxy",
+			vars:        map[string]string{"TRUSTED_PROXIES": "10.0.0.0/8,proxy.internal"},
+			expectedErr: config.ErrInvalidTrustedProxy,
+		},
+		{
+			name:        "returns error for an unsupported header",
+			vars:        map[string]string{"CLIENT_IP_HEADER": "True-Client-IP"},
+			expectedErr: config.ErrUnsupportedClientIPHeader,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			clearEnv(t)
+			for key, value := range tc.vars {
+				t.Setenv(key, value)
+			}
+
+			// Act
+			env, err := config.LoadEnv()
+
+			// Assert
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected %v, got %v", tc.expectedErr, err)
+			}
+			if env != nil {
+				t.Error("expected nil env when error occurs")
+			}
+		})
+	}
 }
 
-func TestEnv_IsDevelopment(t *testing.T) {
-	t.Parallel()
+func TestLoadEnv_RateLimitPolicies(t *testing.T) {
+	t.Run("policies default to RATE_LIMIT_REQUESTS_PER_MINUTE", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("RATE_LIMIT_REQUESTS_PER_MINUTE", "7")
 
-	t.Run("returns true when environment is development", func(t *testing.T) {
-		t.Parallel()
-		env := &config.Env{Environment: "development"}
-		if !env.IsDevelopment() {
-			t.Error("expected IsDevelopment to return true")
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		for _, name := range []string{config.RateLimitPolicyOTP, config.RateLimitPolicyVerify, config.RateLimitPolicyAdmin} {
+			policy := env.RateLimitPolicy(name)
+			if policy.RequestsPerMinute != 7 || policy.Burst != 7 {
+				t.Errorf("expected policy %s to be 7/min with burst 7, got %+v", name, policy)
+			}
+		}
+		if len(env.RateLimitExemptCIDRs) != 0 {
+			t.Errorf("expected no exempt CIDRs, got %v", env.RateLimitExemptCIDRs)
 		}
 	})
 
-	t.Run("returns false when environment is not development", func(t *testing.T) {
-		t.Parallel()
-		env := &config.Env{Environment: envProduction}
-		if env.IsDevelopment() {
-			t.Error("expected IsDevelopment to return false")
+	t.Run("loads per-policy limits and exempt CIDRs", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("RATE_LIMIT_OTP_REQUESTS_PER_MINUTE", "2")
+		t.Setenv("RATE_LIMIT_VERIFY_REQUESTS_PER_MINUTE", "10")
+		t.Setenv("RATE_LIMIT_VERIFY_BURST", "3")
+		t.Setenv("RATE_LIMIT_EXEMPT_CIDRS", "10.0.0.0/8, 192.0.2.7,2001:db8::/32")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if policy := env.RateLimitPolicy(config.RateLimitPolicyOTP); policy.RequestsPerMinute != 2 || policy.Burst != 2 {
+			t.Errorf("unexpected otp policy %+v", policy)
+		}
+		if policy := env.RateLimitPolicy(config.RateLimitPolicyVerify); policy.RequestsPerMinute != 10 || policy.Burst != 3 {
+			t.Errorf("unexpected verify policy %+v", policy)
+		}
+		if policy := env.RateLimitPolicy(config.RateLimitPolicyAdmin); policy.RequestsPerMinute != 5 {
+			t.Errorf("unexpected admin policy %+v", policy)
+		}
+		expected := []string{"10.0.0.0/8", "192.0.2.7/32", "2001:db8::/32"}
+		if len(env.RateLimitExemptCIDRs) != len(expected) {
+			t.Fatalf("expected %d exempt CIDRs, got %v", len(expected), env.RateLimitExemptCIDRs)
+		}
+		for i, prefix := range env.RateLimitExemptCIDRs {
+			if prefix.String() != expected[i] {
+				t.Errorf("expected exempt CIDR %s, got %s", expected[i], prefix)
+			}
 		}
 	})
+
+	testCases := []struct {
+		name        string
+		vars        map[string]string
+		expectedErr error
+	}{
+		{
+			name:        "returns error for a non-positive policy limit",
+			vars:        map[string]string{"RATE_LIMIT_VERIFY_REQUESTS_PER_MINUTE": "0"},
+			expectedErr: config.ErrInvalidRateLimitPolicy,
+		},
+		{
+			name:        "returns error for a non-integer burst",
+			vars:        map[string]string{"RATE_LIMIT_OTP_BURST": "many"},
+			expectedErr: config.ErrInvalidIntegerValue,
+		},
+		{
+			name:        "returns error for an invalid exempt CIDR",
+			vars:        map[string]string{"RATE_LIMIT_EXEMPT_CIDRS": "10.0.0.0/33"},
+			expectedErr: config.ErrInvalidExemptCIDR,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			clearEnv(t)
+			for key, value := range tc.vars {
+				t.Setenv(key, value)
+			}
+
+			// Act
+			env, err := config.LoadEnv()
+
+			// Assert
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected %v, got %v", tc.expectedErr, err)
+			}
+			if env != nil {
+				t.Error("expected nil env when error occurs")
+			}
+		})
+	}
+}
+
+func TestLoadEnv_EmailSender(t *testing.T) {
+	t.Run("defaults to the dummy sender", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.EmailSender != config.EmailSenderDummy {
+			t.Errorf("expected email sender dummy, got %s", env.EmailSender)
+		}
+		if env.SMTPPort != 587 {
+			t.Errorf("expected default SMTP port 587, got %d", env.SMTPPort)
+		}
+		if env.SMTPTLSMode != "starttls" {
+			t.Errorf("expected default SMTP TLS mode starttls, got %s", env.SMTPTLSMode)
+		}
+		if env.SMTPTimeoutSeconds != 10 {
+			t.Errorf("expected default SMTP timeout 10, got %d", env.SMTPTimeoutSeconds)
+		}
+	})
+
+	t.Run("loads SMTP settings", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("EMAIL_SENDER", "smtp")
+		t.Setenv("SMTP_HOST", "smtp.example.com")
+		t.Setenv("SMTP_PORT", "465")
+		t.Setenv("SMTP_USERNAME", "mailer")
+		t.Setenv("SMTP_PASSWORD", "secret")
+		t.Setenv("SMTP_AUTH_MECHANISM", "login")
+		t.Setenv("SMTP_FROM", "no-reply@example.com")
+		t.Setenv("SMTP_REPLY_TO", "support@example.com")
+		t.Setenv("SMTP_TLS_MODE", "implicit")
+		t.Setenv("SMTP_TIMEOUT_SECONDS", "30")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.EmailSender != config.EmailSenderSMTP {
+			t.Errorf("expected email sender smtp, got %s", env.EmailSender)
+		}
+		if env.SMTPHost != "smtp.example.com" || env.SMTPPort != 465 {
+			t.Errorf("unexpected SMTP address %s:%d", env.SMTPHost, env.SMTPPort)
+		}
+		if env.SMTPUsername != "mailer" || env.SMTPPassword != "secret" || env.SMTPAuthMechanism != "login" {
+			t.Errorf("unexpected SMTP credentials %s/%s (%s)", env.SMTPUsername, env.SMTPPassword, env.SMTPAuthMechanism)
+		}
+		if env.SMTPFrom != "no-reply@example.com" || env.SMTPReplyTo != "support@example.com" {
+			t.Errorf("unexpected SMTP addresses from=%s reply-to=%s", env.SMTPFrom, env.SMTPReplyTo)
+		}
+		if env.SMTPTLSMode != "implicit" {
+			t.Errorf("expected SMTP TLS mode implicit, got %s", env.SMTPTLSMode)
+		}
+		if env.SMTPTimeoutSeconds != 30 {
+			t.Errorf("expected SMTP timeout 30, got %d", env.SMTPTimeoutSeconds)
+		}
+	})
+
+	testCases := []struct {
+		name        string
+		vars        map[string]string
+		expectedErr error
+	}{
+		{
+			name:        "returns error for unsupported EMAIL_SENDER",
+			vars:        map[string]string{"EMAIL_SENDER": "carrier-pigeon"},
+			expectedErr: config.ErrUnsupportedEmailSender,
+		},
+		{
+			name:        "returns error when SMTP_HOST is missing",
+			vars:        map[string]string{"EMAIL_SENDER": "smtp", "SMTP_FROM": "no-reply@example.com"},
+			expectedErr: config.ErrSMTPHostRequired,
+		},
+		{
+			name:        "returns error when SMTP_FROM is missing",
+			vars:        map[string]string{"EMAIL_SENDER": "smtp", "SMTP_HOST": "smtp.example.com"},
+			expectedErr: config.ErrSMTPFromRequired,
+		},
+		{
+			name:        "returns error when SMTP_PORT is not an integer",
+			vars:        map[string]string{"SMTP_PORT": "smtp"},
+			expectedErr: config.ErrInvalidIntegerValue,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			clearEnv(t)
+			for key, value := range tc.vars {
+				t.Setenv(key, value)
+			}
+
+			// Act
+			env, err := config.LoadEnv()
+
+			// Assert
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if env != nil {
+				t.Error("expected nil env when error occurs")
+			}
+		})
+	}
+}
+
+func TestLoadEnv_OTPHashKeys(t *testing.T) {
+	t.Run("keys are optional in development", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(env.OTPHashKeys) != 0 {
+			t.Errorf("expected no OTP hash keys, got %d", len(env.OTPHashKeys))
+		}
+	})
+
+	t.Run("returns error when keys are missing in production", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("ENV", envProduction)
+		t.Setenv("ALLOWED_ORIGINS", "https://example.com")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if !errors.Is(err, config.ErrOTPHashKeysRequired) {
+			t.Errorf("expected ErrOTPHashKeysRequired, got %v", err)
+		}
+		if env != nil {
+			t.Error("expected nil env when error occurs")
+		}
+	})
+
+	t.Run("parses multiple keys and defaults the active key to the first", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("OTP_HASH_KEYS", testOTPHashKeys+", k0:b2xkLWtleQ==")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(env.OTPHashKeys) != 2 {
+			t.Fatalf("expected 2 OTP hash keys, got %d", len(env.OTPHashKeys))
+		}
+		if string(env.OTPHashKeys["k0"]) != "old-key" {
+			t.Errorf("expected key k0 to decode to old-key, got %q", env.OTPHashKeys["k0"])
+		}
+		if env.OTPHashActiveKeyID != "k1" {
+			t.Errorf("expected active key k1, got %s", env.OTPHashActiveKeyID)
+		}
+	})
+
+	t.Run("uses OTP_HASH_ACTIVE_KEY_ID when set", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("OTP_HASH_KEYS", testOTPHashKeys+",k2:bmV3LWtleQ==")
+		t.Setenv("OTP_HASH_ACTIVE_KEY_ID", "k2")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.OTPHashActiveKeyID != "k2" {
+			t.Errorf("expected active key k2, got %s", env.OTPHashActiveKeyID)
+		}
+	})
+
+	for _, value := range []string{"no-separator", ":bWlzc2luZy1pZA==", "k1:not base64!"} {
+		t.Run("returns error for malformed value "+value, func(t *testing.T) {
+			// Arrange
+			clearEnv(t)
+			t.Setenv("OTP_HASH_KEYS", value)
+
+			// Act
+			env, err := config.LoadEnv()
+
+			// Assert
+			if !errors.Is(err, config.ErrInvalidOTPHashKeys) {
+				t.Errorf("expected ErrInvalidOTPHashKeys, got %v", err)
+			}
+			if env != nil {
+				t.Error("expected nil env when error occurs")
+			}
+		})
+	}
+}
+
+func TestLoadEnv_IPHashConfig(t *testing.T) {
+	t.Run("defaults to full addresses without keys in development", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(env.IPHashKeys) != 0 {
+			t.Errorf("expected no IP hash keys, got %d", len(env.IPHashKeys))
+		}
+		if env.IPHashIPv4Prefix != 32 || env.IPHashIPv6Prefix != 128 {
+			t.Errorf("expected /32 and /128, got /%d and /%d", env.IPHashIPv4Prefix, env.IPHashIPv6Prefix)
+		}
+	})
+
+	t.Run("returns error when keys are missing in production", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("ENV", envProduction)
+		t.Setenv("ALLOWED_ORIGINS", "https://example.com")
+		t.Setenv("OTP_HASH_KEYS", testOTPHashKeys)
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if !errors.Is(err, config.ErrIPHashKeysRequired) {
+			t.Errorf("expected ErrIPHashKeysRequired, got %v", err)
+		}
+		if env != nil {
+			t.Error("expected nil env when error occurs")
+		}
+	})
+
+	t.Run("loads keys, active key and prefixes", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("IP_HASH_KEYS", testIPHashKeys+",i2:bmV3LWtleQ==")
+		t.Setenv("IP_HASH_ACTIVE_KEY_ID", "i2")
+		t.Setenv("IP_HASH_IPV4_PREFIX", "24")
+		t.Setenv("IP_HASH_IPV6_PREFIX", "64")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(env.IPHashKeys) != 2 || string(env.IPHashKeys["i2"]) != "new-key" {
+			t.Errorf("unexpected IP hash keys: %v", env.IPHashKeys)
+		}
+		if env.IPHashActiveKeyID != "i2" {
+			t.Errorf("expected active key i2, got %s", env.IPHashActiveKeyID)
+		}
+		if env.IPHashIPv4Prefix != 24 || env.IPHashIPv6Prefix != 64 {
+			t.Errorf("expected /24 and /64, got /%d and /%d", env.IPHashIPv4Prefix, env.IPHashIPv6Prefix)
+		}
+	})
+
+	t.Run("returns error for malformed keys", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("IP_HASH_KEYS", "no-separator")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if !errors.Is(err, config.ErrInvalidIPHashKeys) {
+			t.Errorf("expected ErrInvalidIPHashKeys, got %v", err)
+		}
+		if env != nil {
+			t.Error("expected nil env when error occurs")
+		}
+	})
+
+	for _, prefix := range []struct{ name, value string }{
+		{name: "IP_HASH_IPV4_PREFIX", value: "33"},
+		{name: "IP_HASH_IPV6_PREFIX", value: "4"},
+	} {
+		t.Run("returns error for out of range "+prefix.name, func(t *testing.T) {
+			// Arrange
+			clearEnv(t)
+			t.Setenv(prefix.name, prefix.value)
+
+			// Act
+			env, err := config.LoadEnv()
+
+			// Assert
+			if !errors.Is(err, config.ErrInvalidIPHashPrefix) {
+				t.Errorf("expected ErrInvalidIPHashPrefix, got %v", err)
+			}
+			if env != nil {
+				t.Error("expected nil env when error occurs")
+			}
+		})
+	}
+}
+
+func TestLoadEnv_SessionStore(t *testing.T) {
+	t.Run("defaults to firestore", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.SessionStore != config.SessionStoreFirestore {
+			t.Errorf("expected session store firestore, got %s", env.SessionStore)
+		}
+		if env.SessionEvictionIntervalSeconds != 60 {
+			t.Errorf("expected 60 second eviction interval, got %d", env.SessionEvictionIntervalSeconds)
+		}
+	})
+
+	t.Run("selects the memory store", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("SESSION_STORE", "memory")
+		t.Setenv("SESSION_EVICTION_INTERVAL_SECONDS", "5")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.SessionStore != config.SessionStoreMemory {
+			t.Errorf("expected session store memory, got %s", env.SessionStore)
+		}
+		if env.SessionEvictionIntervalSeconds != 5 {
+			t.Errorf("expected 5 second eviction interval, got %d", env.SessionEvictionIntervalSeconds)
+		}
+	})
+
+	t.Run("selects the redis store", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("SESSION_STORE", "redis")
+		t.Setenv("REDIS_URL", "redis://localhost:6379/0")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.SessionStore != config.SessionStoreRedis {
+			t.Errorf("expected session store redis, got %s", env.SessionStore)
+		}
+		if env.RedisURL != "redis://localhost:6379/0" {
+			t.Errorf("expected redis url to be loaded, got %s", env.RedisURL)
+		}
+	})
+
+	t.Run("returns error when redis url is missing", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("SESSION_STORE", "redis")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if !errors.Is(err, config.ErrRedisURLRequired) {
+			t.Errorf("expected ErrRedisURLRequired, got %v", err)
+		}
+		if env != nil {
+			t.Error("expected nil env when error occurs")
+		}
+	})
+
+	t.Run("selects the sql store with sqlite by default", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("SESSION_STORE", "sql")
+		t.Setenv("SQL_DSN", "file:sessions.db")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.SQLDriver != config.SQLDriverSQLite {
+			t.Errorf("expected sql driver sqlite, got %s", env.SQLDriver)
+		}
+		if env.SQLDSN != "file:sessions.db" {
+			t.Errorf("expected sql dsn to be loaded, got %s", env.SQLDSN)
+		}
+	})
+
+	t.Run("returns error when sql dsn is missing", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("SESSION_STORE", "sql")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if !errors.Is(err, config.ErrSQLDSNRequired) {
+			t.Errorf("expected ErrSQLDSNRequired, got %v", err)
+		}
+		if env != nil {
+			t.Error("expected nil env when error occurs")
+		}
+	})
+
+	t.Run("returns error for unsupported sql driver", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("SESSION_STORE", "sql")
+		t.Setenv("SQL_DRIVER", "mysql")
+		t.Setenv("SQL_DSN", "user@/db")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if !errors.Is(err, config.ErrUnsupportedSQLDriver) {
+			t.Errorf("expected ErrUnsupportedSQLDriver, got %v", err)
+		}
+		if env != nil {
+			t.Error("expected nil env when error occurs")
+		}
+	})
+
+	t.Run("returns error for unsupported store", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("SESSION_STORE", "mongodb")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if !errors.Is(err, config.ErrUnsupportedSessionStore) {
+			t.Errorf("expected ErrUnsupportedSessionStore, got %v", err)
+		}
+		if env != nil {
+			t.Error("expected nil env when error occurs")
+		}
+	})
+
+	t.Run("returns error for non-positive eviction interval", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("SESSION_STORE", "memory")
+		t.Setenv("SESSION_EVICTION_INTERVAL_SECONDS", "0")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if !errors.Is(err, config.ErrInvalidEvictionInterval) {
+			t.Errorf("expected ErrInvalidEvictionInterval, got %v", err)
+		}
+		if env != nil {
+			t.Error("expected nil env when error occurs")
+		}
+	})
+}
+
+func TestLoadEnv_EmailFoldProviderAliases(t *testing.T) {
+	t.Run("defaults to disabled", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.EmailFoldProviderAliases {
+			t.Error("expected provider alias folding to be disabled")
+		}
+	})
+
+	t.Run("loads enabled value", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("EMAIL_FOLD_PROVIDER_ALIASES", "true")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !env.EmailFoldProviderAliases {
+			t.Error("expected provider alias folding to be enabled")
+		}
+	})
+
+	t.Run("returns error for invalid boolean", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("EMAIL_FOLD_PROVIDER_ALIASES", "sometimes")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if !errors.Is(err, config.ErrInvalidBooleanValue) {
+			t.Errorf("expected ErrInvalidBooleanValue, got %v", err)
+		}
+		if env != nil {
+			t.Error("expected nil env when error occurs")
+		}
+	})
+}
+
+func TestLoadEnv_EmailAddressSyntax(t *testing.T) {
+	t.Run("defaults to dot-atom ASCII local parts", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.EmailAllowQuotedLocalPart {
+			t.Error("expected quoted local parts to be rejected by default")
+		}
+		if env.EmailAllowSMTPUTF8 {
+			t.Error("expected SMTPUTF8 local parts to be rejected by default")
+		}
+	})
+
+	t.Run("loads enabled values", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("EMAIL_ALLOW_QUOTED_LOCAL_PART", "true")
+		t.Setenv("EMAIL_ALLOW_SMTPUTF8", "true")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !env.EmailAllowQuotedLocalPart {
+			t.Error("expected quoted local parts to be allowed")
+		}
+		if !env.EmailAllowSMTPUTF8 {
+			t.Error("expected SMTPUTF8 local parts to be allowed")
+		}
+	})
+
+	t.Run("returns error for invalid boolean", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("EMAIL_ALLOW_SMTPUTF8", "maybe")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if !errors.Is(err, config.ErrInvalidBooleanValue) {
+			t.Errorf("expected ErrInvalidBooleanValue, got %v", err)
+		}
+		if env != nil {
+			t.Error("expected nil env when error occurs")
+		}
+	})
+}
+
+func TestLoadEnv_EmailDomainPolicy(t *testing.T) {
+	t.Run("defaults to accepting every domain", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(env.EmailDomainAllowlist) != 0 || len(env.EmailDomainDenylist) != 0 {
+			t.Errorf("expected empty domain lists, got %v and %v", env.EmailDomainAllowlist, env.EmailDomainDenylist)
+		}
+		if env.EmailDomainAllowlistOnly || env.EmailBlockDisposable {
+			t.Error("expected allowlist-only and disposable blocking to be disabled")
+		}
+		if env.EmailDisposableReloadSeconds != 60 {
+			t.Errorf("expected reload interval 60, got %d", env.EmailDisposableReloadSeconds)
+		}
+	})
+
+	t.Run("loads lists and settings", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("EMAIL_DOMAIN_ALLOWLIST", " partner.example , *.corp.example ,")
+		t.Setenv("EMAIL_DOMAIN_DENYLIST", "spam.example")
+		t.Setenv("EMAIL_DOMAIN_ALLOWLIST_ONLY", "true")
+		t.Setenv("EMAIL_BLOCK_DISPOSABLE", "true")
+		t.Setenv("EMAIL_DISPOSABLE_DOMAINS_FILE", "/etc/auth/disposable.txt")
+		t.Setenv("EMAIL_DISPOSABLE_RELOAD_INTERVAL_SECONDS", "0")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !slices.Equal(env.EmailDomainAllowlist, []string{"partner.example", "*.corp.example"}) {
+			t.Errorf("unexpected allowlist %v", env.EmailDomainAllowlist)
+		}
+		if !slices.Equal(env.EmailDomainDenylist, []string{"spam.example"}) {
+			t.Errorf("unexpected denylist %v", env.EmailDomainDenylist)
+		}
+		if !env.EmailDomainAllowlistOnly || !env.EmailBlockDisposable {
+			t.Error("expected allowlist-only and disposable blocking to be enabled")
+		}
+		if env.EmailDisposableDomainsFile != "/etc/auth/disposable.txt" {
+			t.Errorf("unexpected disposable domains file %q", env.EmailDisposableDomainsFile)
+		}
+		if env.EmailDisposableReloadSeconds != 0 {
+			t.Errorf("expected reloading to be disabled, got %d", env.EmailDisposableReloadSeconds)
+		}
+	})
+
+	t.Run("requires an allowlist in allowlist-only mode", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("EMAIL_DOMAIN_ALLOWLIST_ONLY", "true")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if !errors.Is(err, config.ErrEmailAllowlistRequired) {
+			t.Errorf("expected ErrEmailAllowlistRequired, got %v", err)
+		}
+		if env != nil {
+			t.Error("expected nil env when error occurs")
+		}
+	})
+
+	t.Run("returns error for a negative reload interval", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("EMAIL_DISPOSABLE_RELOAD_INTERVAL_SECONDS", "-1")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if !errors.Is(err, config.ErrInvalidDisposableReload) {
+			t.Errorf("expected ErrInvalidDisposableReload, got %v", err)
+		}
+		if env != nil {
+			t.Error("expected nil env when error occurs")
+		}
+	})
+}
+
+func TestLoadEnv_RegistrationMode(t *testing.T) {
+	t.Run("defaults to closed", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.RegistrationMode != config.RegistrationModeClosed {
+			t.Errorf("expected registration mode %q, got %q", config.RegistrationModeClosed, env.RegistrationMode)
+		}
+	})
+
+	t.Run("loads mode case-insensitively", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("REGISTRATION_MODE", "Open")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.RegistrationMode != config.RegistrationModeOpen {
+			t.Errorf("expected registration mode %q, got %q", config.RegistrationModeOpen, env.RegistrationMode)
+		}
+	})
+
+	t.Run("returns error for unsupported mode", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("REGISTRATION_MODE", "public")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if !errors.Is(err, config.ErrUnsupportedRegistration) {
+			t.Errorf("expected ErrUnsupportedRegistration, got %v", err)
+		}
+		if env != nil {
+			t.Error("expected nil env when error occurs")
+		}
+	})
+}
+
+func TestLoadEnv_InvitationConfig(t *testing.T) {
+	t.Run("defaults in development mode", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.InvitationTTLHours != 168 {
+			t.Errorf("expected invitation TTL 168 hours, got %d", env.InvitationTTLHours)
+		}
+		if env.InvitationAcceptURL != "http://localhost:5173/" {
+			t.Errorf("expected the local client as accept URL, got %q", env.InvitationAcceptURL)
+		}
+		if env.InvitationTokenKeys != nil {
+			t.Errorf("expected no invitation keys, got %v", env.InvitationTokenKeys)
+		}
+	})
+
+	t.Run("loads TTL, accept URL, keys and active key", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("INVITATION_TTL_HOURS", "48")
+		t.Setenv("INVITATION_ACCEPT_URL", "https://app.example.com/invite")
+		t.Setenv("INVITATION_TOKEN_KEYS", testInvitationKeys+",v2:bmV3LWtleQ==")
+		t.Setenv("INVITATION_TOKEN_ACTIVE_KEY_ID", "v2")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.InvitationTTLHours != 48 || env.InvitationAcceptURL != "https://app.example.com/invite" {
+			t.Errorf("unexpected invitation TTL %d or accept URL %q", env.InvitationTTLHours, env.InvitationAcceptURL)
+		}
+		if len(env.InvitationTokenKeys) != 2 || env.InvitationTokenActiveKeyID != "v2" {
+			t.Errorf("unexpected invitation keys %v with active key %q", env.InvitationTokenKeys, env.InvitationTokenActiveKeyID)
+		}
+	})
+
+	t.Run("returns error for non-positive TTL", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("INVITATION_TTL_HOURS", "0")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if !errors.Is(err, config.ErrInvalidInvitationTTL) {
+			t.Errorf("expected ErrInvalidInvitationTTL, got %v", err)
+		}
+		if env != nil {
+			t.Error("expected nil env when error occurs")
+		}
+	})
+
+	t.Run("returns error for malformed keys", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("INVITATION_TOKEN_KEYS", "v1")
+
+		// Act
+		_, err := config.LoadEnv()
+
+		// Assert
+		if !errors.Is(err, config.ErrInvalidInvitationKeys) {
+			t.Errorf("expected ErrInvalidInvitationKeys, got %v", err)
+		}
+	})
+
+	testCases := []struct {
+		name        string
+		mode        string
+		acceptURL   string
+		keys        string
+		expectedErr error
+	}{
+		{name: "closed mode needs nothing", mode: "closed", acceptURL: "", keys: "", expectedErr: nil},
+		{name: "invite mode needs an accept URL", mode: "invite", acceptURL: "", keys: testInvitationKeys, expectedErr: config.ErrInvitationURLRequired},
+		{name: "open mode needs keys", mode: "open", acceptURL: "https://app.example.com/", keys: "", expectedErr: config.ErrInvitationKeysRequired},
+		{name: "invite mode with both", mode: "invite", acceptURL: "https://app.example.com/", keys: testInvitationKeys, expectedErr: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run("production "+tc.name, func(t *testing.T) {
+			// Arrange
+			clearEnv(t)
+			t.Setenv("ENV", envProduction)
+			t.Setenv("ALLOWED_ORIGINS", "https://example.com")
+			t.Setenv("OTP_HASH_KEYS", testOTPHashKeys)
+			t.Setenv("IP_HASH_KEYS", testIPHashKeys)
+			t.Setenv("REGISTRATION_MODE", tc.mode)
+			t.Setenv("INVITATION_ACCEPT_URL", tc.acceptURL)
+			t.Setenv("INVITATION_TOKEN_KEYS", tc.keys)
+
+			// Act
+			_, err := config.LoadEnv()
+
+			// Assert
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadEnv_OTPSessionBinding(t *testing.T) {
+	t.Run("defaults to no binding", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.OTPSessionBinding != config.OTPSessionBindingNone {
+			t.Errorf("expected no binding, got %s", env.OTPSessionBinding)
+		}
+	})
+
+	t.Run("loads binding case-insensitively", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("OTP_SESSION_BINDING", "Strict")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.OTPSessionBinding != config.OTPSessionBindingStrict {
+			t.Errorf("expected strict binding, got %s", env.OTPSessionBinding)
+		}
+	})
+
+	t.Run("returns error for unsupported binding", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("OTP_SESSION_BINDING", "device")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if !errors.Is(err, config.ErrUnsupportedOTPBinding) {
+			t.Errorf("expected ErrUnsupportedOTPBinding, got %v", err)
+		}
+		if env != nil {
+			t.Error("expected nil env when error occurs")
+		}
+	})
+}
+
+func TestLoadEnv_OTPRequestMode(t *testing.T) {
+	t.Run("defaults to direct mode", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.OTPRequestMode != config.OTPRequestModeDirect {
+			t.Errorf("expected direct mode, got %s", env.OTPRequestMode)
+		}
+		if env.OTPRequestMinDurationMillis != 1000 {
+			t.Errorf("expected 1000ms minimum duration, got %d", env.OTPRequestMinDurationMillis)
+		}
+		if env.OTPRequestBackgroundTimeoutSeconds != 30 {
+			t.Errorf("expected 30 second background timeout, got %d", env.OTPRequestBackgroundTimeoutSeconds)
+		}
+		if env.OTPRequestMaxBackground != 100 {
+			t.Errorf("expected 100 background requests, got %d", env.OTPRequestMaxBackground)
+		}
+		if env.OTPNotifyUnknownEmails {
+			t.Error("expected unknown email notices to be disabled")
+		}
+	})
+
+	t.Run("loads custom values", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("OTP_REQUEST_MODE", "padded")
+		t.Setenv("OTP_REQUEST_MIN_DURATION_MS", "1500")
+		t.Setenv("OTP_REQUEST_BACKGROUND_TIMEOUT_SECONDS", "5")
+		t.Setenv("OTP_REQUEST_MAX_BACKGROUND", "8")
+		t.Setenv("OTP_NOTIFY_UNKNOWN_EMAILS", "true")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.OTPRequestMode != config.OTPRequestModePadded {
+			t.Errorf("expected padded mode, got %s", env.OTPRequestMode)
+		}
+		if env.OTPRequestMinDurationMillis != 1500 {
+			t.Errorf("expected 1500ms minimum duration, got %d", env.OTPRequestMinDurationMillis)
+		}
+		if env.OTPRequestBackgroundTimeoutSeconds != 5 {
+			t.Errorf("expected 5 second background timeout, got %d", env.OTPRequestBackgroundTimeoutSeconds)
+		}
+		if env.OTPRequestMaxBackground != 8 {
+			t.Errorf("expected 8 background requests, got %d", env.OTPRequestMaxBackground)
+		}
+		if !env.OTPNotifyUnknownEmails {
+			t.Error("expected unknown email notices to be enabled")
+		}
+	})
+
+	t.Run("returns error for unsupported mode", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("OTP_REQUEST_MODE", "silent")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if !errors.Is(err, config.ErrUnsupportedOTPRequestMode) {
+			t.Errorf("expected ErrUnsupportedOTPRequestMode, got %v", err)
+		}
+		if env != nil {
+			t.Error("expected nil env when error occurs")
+		}
+	})
+
+	t.Run("returns error for non-positive background limit", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("OTP_REQUEST_MAX_BACKGROUND", "0")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if !errors.Is(err, config.ErrInvalidOTPMaxBackground) {
+			t.Errorf("expected ErrInvalidOTPMaxBackground, got %v", err)
+		}
+		if env != nil {
+			t.Error("expected nil env when error occurs")
+		}
+	})
+
+	t.Run("returns error for invalid boolean", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("OTP_NOTIFY_UNKNOWN_EMAILS", "maybe")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if !errors.Is(err, config.ErrInvalidBooleanValue) {
+			t.Errorf("expected ErrInvalidBooleanValue, got %v", err)
+		}
+		if env != nil {
+			t.Error("expected nil env when error occurs")
+		}
+	})
+}
+
+func TestLoadEnv_OTPThrottle(t *testing.T) {
+	t.Run("loads default limits", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.OTPResendCooldownSeconds != 60 {
+			t.Errorf("expected 60 second cooldown, got %d", env.OTPResendCooldownSeconds)
+		}
+		if env.OTPDailyLimitPerEmail != 10 {
+			t.Errorf("expected daily limit of 10, got %d", env.OTPDailyLimitPerEmail)
+		}
+		if env.OTPGlobalRequestsPerMinute != 0 {
+			t.Errorf("expected global limit to be disabled, got %d", env.OTPGlobalRequestsPerMinute)
+		}
+	})
+
+	t.Run("loads custom limits", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("OTP_RESEND_COOLDOWN_SECONDS", "30")
+		t.Setenv("OTP_DAILY_LIMIT_PER_EMAIL", "0")
+		t.Setenv("OTP_GLOBAL_REQUESTS_PER_MINUTE", "600")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.OTPResendCooldownSeconds != 30 {
+			t.Errorf("expected 30 second cooldown, got %d", env.OTPResendCooldownSeconds)
+		}
+		if env.OTPDailyLimitPerEmail != 0 {
+			t.Errorf("expected daily limit to be disabled, got %d", env.OTPDailyLimitPerEmail)
+		}
+		if env.OTPGlobalRequestsPerMinute != 600 {
+			t.Errorf("expected global limit of 600, got %d", env.OTPGlobalRequestsPerMinute)
+		}
+	})
+
+	t.Run("returns error for negative limits", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("OTP_RESEND_COOLDOWN_SECONDS", "-1")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if !errors.Is(err, config.ErrInvalidOTPRequestLimit) {
+			t.Errorf("expected ErrInvalidOTPRequestLimit, got %v", err)
+		}
+		if env != nil {
+			t.Error("expected nil env when error occurs")
+		}
+	})
+}
+
+func TestEnv_IsProduction(t *testing.T) {
+	t.Parallel()
+
+	t.Run("returns true when environment is production", func(t *testing.T) {
+		t.Parallel()
+		env := &config.Env{Environment: envProduction}
+		if !env.IsProduction() {
+			t.Error("expected IsProduction to return true")
+		}
+	})
+
+	t.Run("returns false when environment is not production", func(t *testing.T) {
+		t.Parallel()
+		env := &config.Env{Environment: "development"}
+		if env.IsProduction() {
+			t.Error("expected IsProduction to return false")
+		}
+	})
+}
+
+func TestEnv_IsDevelopment(t *testing.T) {
+	t.Parallel()
+
+	t.Run("returns true when environment is development", func(t *testing.T) {
+		t.Parallel()
+		env := &config.Env{Environment: "development"}
+		if !env.IsDevelopment() {
+			t.Error("expected IsDevelopment to return true")
+		}
+	})
+
+	t.Run("returns false when environment is not development", func(t *testing.T) {
+		t.Parallel()
+		env := &config.Env{Environment: envProduction}
+		if env.IsDevelopment() {
+			t.Error("expected IsDevelopment to return false")
+		}
+	})
+}
+
+// clearEnv clears all environment variables used by the config package.
+// This ensures tests are isolated and don't interfere with each other.
+func TestLoadEnv_ClaimsConfig(t *testing.T) {
+	t.Run("defaults to no claims provider", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.ClaimsProvider != config.ClaimsProviderNone {
+			t.Errorf("expected no claims provider, got %q", env.ClaimsProvider)
+		}
+		if env.ClaimsRolesReloadSeconds != 60 || env.ClaimsFirestoreCollection != "roles" {
+			t.Errorf("unexpected reload interval %d or collection %q", env.ClaimsRolesReloadSeconds, env.ClaimsFirestoreCollection)
+		}
+	})
+
+	t.Run("loads the file provider case-insensitively", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("CLAIMS_PROVIDER", "File")
+		t.Setenv("CLAIMS_ROLES_FILE", "/etc/auth/roles.json")
+		t.Setenv("CLAIMS_ROLES_RELOAD_INTERVAL_SECONDS", "0")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.ClaimsProvider != config.ClaimsProviderFile || env.ClaimsRolesFile != "/etc/auth/roles.json" {
+			t.Errorf("unexpected claims provider %q with roles file %q", env.ClaimsProvider, env.ClaimsRolesFile)
+		}
+		if env.ClaimsRolesReloadSeconds != 0 {
+			t.Errorf("expected reloading disabled, got %d", env.ClaimsRolesReloadSeconds)
+		}
+	})
+
+	t.Run("loads the firestore collection", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("CLAIMS_PROVIDER", "firestore")
+		t.Setenv("CLAIMS_FIRESTORE_COLLECTION", "user_roles")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.ClaimsProvider != config.ClaimsProviderFirestore || env.ClaimsFirestoreCollection != "user_roles" {
+			t.Errorf("unexpected claims provider %q with collection %q", env.ClaimsProvider, env.ClaimsFirestoreCollection)
+		}
+	})
+
+	testCases := []struct {
+		name     string
+		vars     map[string]string
+		expected error
+	}{
+		{
+			name:     "unsupported provider",
+			vars:     map[string]string{"CLAIMS_PROVIDER": "ldap"},
+			expected: config.ErrUnsupportedClaimsProvider,
+		},
+		{
+			name:     "file provider without a file",
+			vars:     map[string]string{"CLAIMS_PROVIDER": "file"},
+			expected: config.ErrClaimsRolesFileRequired,
+		},
+		{
+			name:     "negative reload interval",
+			vars:     map[string]string{"CLAIMS_ROLES_RELOAD_INTERVAL_SECONDS": "-1"},
+			expected: config.ErrInvalidClaimsReload,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run("returns error for "+tc.name, func(t *testing.T) {
+			// Arrange
+			clearEnv(t)
+			for key, value := range tc.vars {
+				t.Setenv(key, value)
+			}
+
+			// Act
+			env, err := config.LoadEnv()
+
+			// Assert
+			if !errors.Is(err, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, err)
+			}
+			if env != nil {
+				t.Error("expected nil env when error occurs")
+			}
+		})
+	}
+}
+
+func TestLoadEnv_IdentityBackend(t *testing.T) {
+	t.Run("defaults to firebase without token settings", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.IdentityBackend != config.IdentityBackendFirebase {
+			t.Errorf("expected the firebase backend, got %q", env.IdentityBackend)
+		}
+		if env.JWTSigningKeyFiles != nil || env.JWTIssuer != "" || env.JWTTTLSeconds != 0 {
+			t.Errorf("expected no token settings, got %v %q %d", env.JWTSigningKeyFiles, env.JWTIssuer, env.JWTTTLSeconds)
+		}
+	})
+
+	t.Run("defaults standalone token settings in development mode", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("IDENTITY_BACKEND", "Standalone")
+		t.Setenv("SESSION_STORE", "memory")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.IdentityBackend != config.IdentityBackendStandalone {
+			t.Errorf("expected the standalone backend, got %q", env.IdentityBackend)
+		}
+		if env.JWTIssuer != "http://localhost:8000" || env.JWTAudience != "custom-auth-api" || env.JWTTTLSeconds != 900 {
+			t.Errorf("unexpected issuer %q, audience %q or TTL %d", env.JWTIssuer, env.JWTAudience, env.JWTTTLSeconds)
+		}
+		if env.JWTSigningKeyFiles != nil {
+			t.Errorf("expected no signing keys, got %v", env.JWTSigningKeyFiles)
+		}
+	})
+
+	t.Run("loads signing keys, active key, issuer, audience and TTL", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("IDENTITY_BACKEND", "standalone")
+		t.Setenv("SESSION_STORE", "sql")
+		t.Setenv("SQL_DSN", "file:auth.db")
+		t.Setenv("JWT_SIGNING_KEYS", "2026-09:/etc/auth/2026-09.pem, 2026-10:/etc/auth/2026-10.pem")
+		t.Setenv("JWT_ACTIVE_KEY_ID", "2026-10")
+		t.Setenv("JWT_ISSUER", "https://auth.example.com")
+		t.Setenv("JWT_AUDIENCE", "internal-tools")
+		t.Setenv("JWT_TTL_SECONDS", "300")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		expectedKeys := map[string]string{"2026-09": "/etc/auth/2026-09.pem", "2026-10": "/etc/auth/2026-10.pem"}
+		if !maps.Equal(env.JWTSigningKeyFiles, expectedKeys) || env.JWTActiveKeyID != "2026-10" {
+			t.Errorf("unexpected signing keys %v with active key %q", env.JWTSigningKeyFiles, env.JWTActiveKeyID)
+		}
+		if env.JWTIssuer != "https://auth.example.com" || env.JWTAudience != "internal-tools" || env.JWTTTLSeconds != 300 {
+			t.Errorf("unexpected issuer %q, audience %q or TTL %d", env.JWTIssuer, env.JWTAudience, env.JWTTTLSeconds)
+		}
+	})
+
+	t.Run("defaults the active key to the first key", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("IDENTITY_BACKEND", "standalone")
+		t.Setenv("SESSION_STORE", "memory")
+		t.Setenv("JWT_SIGNING_KEYS", "b:/keys/b.pem,a:/keys/a.pem")
+
+		// Act
+		env, err := config.LoadEnv()
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if env.JWTActiveKeyID != "b" {
+			t.Errorf("expected active key b, got %q", env.JWTActiveKeyID)
+		}
+	})
+
+	testCases := []struct {
+		name     string
+		vars     map[string]string
+		expected error
+	}{
+		{
+			name:     "unsupported backend",
+			vars:     map[string]string{"IDENTITY_BACKEND": "ldap"},
+			expected: config.ErrUnsupportedIdentityBackend,
+		},
+		{
+			name:     "standalone with the firestore session store",
+			vars:     map[string]string{"IDENTITY_BACKEND": "standalone"},
+			expected: config.ErrStandaloneSessionStore,
+		},
+		{
+			name: "standalone with the firestore claims provider",
+			vars: map[string]string{
+				"IDENTITY_BACKEND": "standalone", "SESSION_STORE": "memory", "CLAIMS_PROVIDER": "firestore",
+			},
+			expected: config.ErrStandaloneClaimsProvider,
+		},
+		{
+			name:     "non-positive TTL",
+			vars:     map[string]string{"IDENTITY_BACKEND": "standalone", "SESSION_STORE": "memory", "JWT_TTL_SECONDS": "0"},
+			expected: config.ErrInvalidJWTTTL,
+		},
+		{
+			name:     "malformed signing keys",
+			vars:     map[string]string{"IDENTITY_BACKEND": "standalone", "SESSION_STORE": "memory", "JWT_SIGNING_KEYS": "k1"},
+			expected: config.ErrInvalidJWTSigningKeys,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run("returns error for "+tc.name, func(t *testing.T) {
+			// Arrange
+			clearEnv(t)
+			for key, value := range tc.vars {
+				t.Setenv(key, value)
+			}
+
+			// Act
+			env, err := config.LoadEnv()
+
+			// Assert
+			if !errors.Is(err, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, err)
+			}
+			if env != nil {
+				t.Error("expected nil env when error occurs")
+			}
+		})
+	}
+
+	productionCases := []struct {
+		name        string
+		keys        string
+		issuer      string
+		expectedErr error
+	}{
+		{name: "needs signing keys", keys: "", issuer: "https://auth.example.com", expectedErr: config.ErrJWTSigningKeysRequired},
+		{name: "needs an issuer", keys: "k1:/keys/k1.pem", issuer: "", expectedErr: config.ErrJWTIssuerRequired},
+		{name: "with keys and issuer", keys: "k1:/keys/k1.pem", issuer: "https://auth.example.com", expectedErr: nil},
+	}
+
+	for _, tc := range productionCases {
+		t.Run("production "+tc.name, func(t *testing.T) {
+			// Arrange
+			clearEnv(t)
+			t.Setenv("ENV", envProduction)
+			t.Setenv("ALLOWED_ORIGINS", "https://example.com")
+			t.Setenv("OTP_HASH_KEYS", testOTPHashKeys)
+			t.Setenv("IP_HASH_KEYS", testIPHashKeys)
+			t.Setenv("IDENTITY_BACKEND", "standalone")
+			t.Setenv("SESSION_STORE", "memory")
+			t.Setenv("JWT_SIGNING_KEYS", tc.keys)
+			t.Setenv("JWT_ISSUER", tc.issuer)
+			t.Setenv("JWT_AUDIENCE", "internal-tools")
+
+			// Act
+			_, err := config.LoadEnv()
+
+			// Assert
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
 }
 
-// clearEnv clears all environment variables used by the config package.
-// This ensures tests are isolated and don't interfere with each other.
 func clearEnv(t *testing.T) {
 	t.Helper()
 	_ = os.Unsetenv("PORT")
@@ -199,4 +1854,67 @@ func clearEnv(t *testing.T) {
 	_ = os.Unsetenv("ALLOWED_ORIGINS")
 	_ = os.Unsetenv("RATE_LIMIT_REQUESTS_PER_MINUTE")
 	_ = os.Unsetenv("RATE_LIMIT_CLEANUP_INTERVAL_MINUTES")
+	_ = os.Unsetenv("RATE_LIMIT_MAX_KEYS")
+	_ = os.Unsetenv("RATE_LIMIT_STORE")
+	_ = os.Unsetenv("RATE_LIMIT_EXEMPT_CIDRS")
+	_ = os.Unsetenv("TRUSTED_PROXIES")
+	_ = os.Unsetenv("CLIENT_IP_HEADER")
+	for _, name := range []string{"OTP", "VERIFY", "ADMIN"} {
+		_ = os.Unsetenv("RATE_LIMIT_" + name + "_REQUESTS_PER_MINUTE")
+		_ = os.Unsetenv("RATE_LIMIT_" + name + "_BURST")
+	}
+	_ = os.Unsetenv("EMAIL_SENDER")
+	_ = os.Unsetenv("SMTP_HOST")
+	_ = os.Unsetenv("SMTP_PORT")
+	_ = os.Unsetenv("SMTP_USERNAME")
+	_ = os.Unsetenv("SMTP_PASSWORD")
+	_ = os.Unsetenv("SMTP_AUTH_MECHANISM")
+	_ = os.Unsetenv("SMTP_FROM")
+	_ = os.Unsetenv("SMTP_REPLY_TO")
+	_ = os.Unsetenv("SMTP_TLS_MODE")
+	_ = os.Unsetenv("SMTP_TIMEOUT_SECONDS")
+	_ = os.Unsetenv("OTP_HASH_KEYS")
+	_ = os.Unsetenv("OTP_HASH_ACTIVE_KEY_ID")
+	_ = os.Unsetenv("IP_HASH_KEYS")
+	_ = os.Unsetenv("IP_HASH_ACTIVE_KEY_ID")
+	_ = os.Unsetenv("IP_HASH_IPV4_PREFIX")
+	_ = os.Unsetenv("IP_HASH_IPV6_PREFIX")
+	_ = os.Unsetenv("SESSION_STORE")
+	_ = os.Unsetenv("SESSION_EVICTION_INTERVAL_SECONDS")
+	_ = os.Unsetenv("REDIS_URL")
+	_ = os.Unsetenv("SQL_DRIVER")
+	_ = os.Unsetenv("SQL_DSN")
+	_ = os.Unsetenv("OTP_REQUEST_MODE")
+	_ = os.Unsetenv("OTP_REQUEST_MIN_DURATION_MS")
+	_ = os.Unsetenv("OTP_REQUEST_BACKGROUND_TIMEOUT_SECONDS")
+	_ = os.Unsetenv("OTP_REQUEST_MAX_BACKGROUND")
+	_ = os.Unsetenv("OTP_NOTIFY_UNKNOWN_EMAILS")
+	_ = os.Unsetenv("OTP_RESEND_COOLDOWN_SECONDS")
+	_ = os.Unsetenv("OTP_DAILY_LIMIT_PER_EMAIL")
+	_ = os.Unsetenv("OTP_GLOBAL_REQUESTS_PER_MINUTE")
+	_ = os.Unsetenv("OTP_SESSION_BINDING")
+	_ = os.Unsetenv("EMAIL_FOLD_PROVIDER_ALIASES")
+	_ = os.Unsetenv("EMAIL_ALLOW_QUOTED_LOCAL_PART")
+	_ = os.Unsetenv("EMAIL_ALLOW_SMTPUTF8")
+	_ = os.Unsetenv("EMAIL_DOMAIN_ALLOWLIST")
+	_ = os.Unsetenv("EMAIL_DOMAIN_DENYLIST")
+	_ = os.Unsetenv("EMAIL_DOMAIN_ALLOWLIST_ONLY")
+	_ = os.Unsetenv("EMAIL_BLOCK_DISPOSABLE")
+	_ = os.Unsetenv("EMAIL_DISPOSABLE_DOMAINS_FILE")
+	_ = os.Unsetenv("EMAIL_DISPOSABLE_RELOAD_INTERVAL_SECONDS")
+	_ = os.Unsetenv("REGISTRATION_MODE")
+	_ = os.Unsetenv("INVITATION_TTL_HOURS")
+	_ = os.Unsetenv("INVITATION_ACCEPT_URL")
+	_ = os.Unsetenv("INVITATION_TOKEN_KEYS")
+	_ = os.Unsetenv("INVITATION_TOKEN_ACTIVE_KEY_ID")
+	_ = os.Unsetenv("CLAIMS_PROVIDER")
+	_ = os.Unsetenv("CLAIMS_ROLES_FILE")
+	_ = os.Unsetenv("CLAIMS_ROLES_RELOAD_INTERVAL_SECONDS")
+	_ = os.Unsetenv("CLAIMS_FIRESTORE_COLLECTION")
+	_ = os.Unsetenv("IDENTITY_BACKEND")
+	_ = os.Unsetenv("JWT_SIGNING_KEYS")
+	_ = os.Unsetenv("JWT_ACTIVE_KEY_ID")
+	_ = os.Unsetenv("JWT_ISSUER")
+	_ = os.Unsetenv("JWT_AUDIENCE")
+	_ = os.Unsetenv("JWT_TTL_SECONDS")
 }
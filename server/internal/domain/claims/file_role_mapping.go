@@ -0,0 +1,51 @@
+package claims
+
+import (
+	"context"
+	"time"
+
+	"custom_auth_api/internal/pkg/filereload"
+)
+
+// FileRoleMapping is a RoleMapping loaded from a file that can be reloaded while in use.
+//
+// Reload swaps in the new mapping atomically, so Claims never sees a partially
+// loaded file. If the file becomes unreadable or invalid, the last good mapping is kept.
+type FileRoleMapping struct {
+	file *filereload.File[RoleMapping]
+}
+
+// NewFileRoleMapping loads the role mapping at path (JSON, see ParseRoleMapping).
+func NewFileRoleMapping(path string) (*FileRoleMapping, error) {
+	file, err := filereload.New(path, "role mapping", ParseRoleMapping)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileRoleMapping{file: file}, nil
+}
+
+// Roles returns the roles granted to address by the most recently loaded mapping.
+func (m *FileRoleMapping) Roles(address string) []string {
+	return m.file.Load().Roles(address)
+}
+
+// Claims returns the subject's roles from the most recently loaded mapping.
+func (m *FileRoleMapping) Claims(ctx context.Context, subject Subject) (map[string]any, error) {
+	return m.file.Load().Claims(ctx, subject)
+}
+
+// Reload re-reads the file if its modification time or size changed since the last load
+// and reports whether a new mapping was loaded.
+func (m *FileRoleMapping) Reload() (bool, error) {
+	return m.file.Reload()
+}
+
+// RunReload checks the file for changes every interval until ctx is done.
+// It blocks, so run it in its own goroutine. Failures are reported to onError, which may be nil;
+// the previous mapping stays in effect.
+func (m *FileRoleMapping) RunReload(ctx context.Context, interval time.Duration, onError func(error)) {
+	m.file.RunReload(ctx, interval, onError)
+}
+
+var _ Provider = (*FileRoleMapping)(nil)
@@ -0,0 +1,41 @@
+// Package claims supplies the authorization claims added to the tokens minted for users.
+package claims
+
+import "context"
+
+// Claims set on the tokens minted for users.
+const (
+	// RolesClaim lists the roles granted to a user.
+	RolesClaim = "roles"
+
+	// AdminClaim marks users allowed to call admin endpoints when set to true.
+	AdminClaim = "admin"
+
+	// AuthMethodsClaim lists how the user authenticated, like the "amr" claim of RFC 8176.
+	// Firebase reserves "amr" and "auth_time" for its own ID token claims.
+	AuthMethodsClaim = "auth_methods"
+
+	// AuthTimeClaim is when the user authenticated, in seconds since the Unix epoch.
+	AuthTimeClaim = "authenticated_at"
+)
+
+// Subject identifies the user a token is minted for.
+type Subject struct {
+	UID   string
+	Email string // May be empty for users without an email address
+}
+
+// Provider supplies claims for a user at token minting time.
+// It returns nil or an empty map when the user has no claims. An error prevents the
+// token from being minted, so a provider outage cannot hand out tokens without roles.
+type Provider interface {
+	Claims(ctx context.Context, subject Subject) (map[string]any, error)
+}
+
+// ProviderFunc adapts a function to a Provider, e.g. to hook an external authorization service.
+type ProviderFunc func(ctx context.Context, subject Subject) (map[string]any, error)
+
+// Claims calls f(ctx, subject).
+func (f ProviderFunc) Claims(ctx context.Context, subject Subject) (map[string]any, error) {
+	return f(ctx, subject)
+}
@@ -0,0 +1,138 @@
+package claims
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"custom_auth_api/internal/domain/emailpolicy"
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/vo/email"
+)
+
+// ErrInvalidRoleMapping is returned for a role mapping that cannot be parsed.
+var ErrInvalidRoleMapping = errors.New("invalid role mapping")
+
+// roleMappingDocument is the JSON form of a RoleMapping.
+type roleMappingDocument struct {
+	Users   map[string][]string `json:"users"`
+	Domains map[string][]string `json:"domains"`
+}
+
+// domainRoles grants roles to the addresses of a domain pattern.
+type domainRoles struct {
+	pattern string
+	domains *emailpolicy.DomainSet
+	roles   []string
+}
+
+// RoleMapping is an immutable Provider granting roles by email address and by email domain.
+//
+// A user receives the roles of their address followed by the roles of every matching
+// domain pattern, without duplicates. Domain patterns follow emailpolicy.DomainSet:
+// "example.com" matches only that domain and "*.example.com" matches its subdomains.
+type RoleMapping struct {
+	users   map[string][]string // By canonical address
+	domains []domainRoles       // Sorted by pattern
+}
+
+// ParseRoleMapping reads a role mapping in JSON form:
+//
+//	{
+//	  "users":   {"alice@example.com": ["admin"]},
+//	  "domains": {"example.com": ["member"], "*.corp.example": ["staff"]}
+//	}
+//
+// Addresses are matched in canonical form and roles are validated like invitation roles.
+// Returns an error wrapping ErrInvalidRoleMapping for malformed input.
+func ParseRoleMapping(r io.Reader) (*RoleMapping, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var doc roleMappingDocument
+
+	err := decoder.Decode(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRoleMapping, err)
+	}
+
+	mapping := &RoleMapping{
+		users:   make(map[string][]string, len(doc.Users)),
+		domains: make([]domainRoles, 0, len(doc.Domains)),
+	}
+
+	for address, roles := range doc.Users {
+		userEmail, err := email.NewEmail(address)
+		if err != nil {
+			return nil, fmt.Errorf("%w: user %q: %w", ErrInvalidRoleMapping, address, err)
+		}
+
+		normalized, err := entity.NormalizeRoles(roles)
+		if err != nil {
+			return nil, fmt.Errorf("%w: user %q: %w", ErrInvalidRoleMapping, address, err)
+		}
+
+		mapping.users[userEmail.Canonical()] = normalized
+	}
+
+	for pattern, roles := range doc.Domains {
+		domains, err := emailpolicy.NewDomainSet([]string{pattern})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRoleMapping, err)
+		}
+
+		normalized, err := entity.NormalizeRoles(roles)
+		if err != nil {
+			return nil, fmt.Errorf("%w: domain %q: %w", ErrInvalidRoleMapping, pattern, err)
+		}
+
+		mapping.domains = append(mapping.domains, domainRoles{pattern: pattern, domains: domains, roles: normalized})
+	}
+
+	// Map iteration order is random; keep the order of granted roles stable
+	slices.SortFunc(mapping.domains, func(a, b domainRoles) int {
+		return strings.Compare(a.pattern, b.pattern)
+	})
+
+	return mapping, nil
+}
+
+// Roles returns the roles granted to address, or nil if it cannot be parsed or has none.
+func (m *RoleMapping) Roles(address string) []string {
+	userEmail, err := email.NewEmail(address)
+	if err != nil {
+		return nil
+	}
+
+	roles := slices.Clone(m.users[userEmail.Canonical()])
+
+	for _, entry := range m.domains {
+		if !entry.domains.Contains(userEmail.Domain()) {
+			continue
+		}
+
+		for _, role := range entry.roles {
+			if !slices.Contains(roles, role) {
+				roles = append(roles, role)
+			}
+		}
+	}
+
+	return roles
+}
+
+// Claims returns the subject's roles as the RolesClaim, or no claims if it has none.
+func (m *RoleMapping) Claims(_ context.Context, subject Subject) (map[string]any, error) {
+	roles := m.Roles(subject.Email)
+	if len(roles) == 0 {
+		return map[string]any{}, nil
+	}
+
+	return map[string]any{RolesClaim: roles}, nil
+}
+
+var _ Provider = (*RoleMapping)(nil)
@@ -0,0 +1,228 @@
+package claims_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"custom_auth_api/internal/domain/claims"
+	"custom_auth_api/internal/domain/entity"
+)
+
+const testRoleMapping = `{
+	"users":   {"Alice@Example.com": ["admin", "member"]},
+	"domains": {"example.com": ["member", "staff"], "*.partner.example": ["partner"]}
+}`
+
+func TestParseRoleMapping_Roles(t *testing.T) {
+	t.Parallel()
+
+	mapping, err := claims.ParseRoleMapping(strings.NewReader(testRoleMapping))
+	if err != nil {
+		t.Fatalf("ParseRoleMapping() error = %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		address  string
+		expected []string
+	}{
+		{name: "user and domain roles without duplicates", address: "alice@example.com", expected: []string{"admin", "member", "staff"}},
+		{name: "domain roles only", address: "bob@example.com", expected: []string{"member", "staff"}},
+		{name: "wildcard domain", address: "carol@eu.partner.example", expected: []string{"partner"}},
+		{name: "wildcard excludes the domain itself", address: "dave@partner.example", expected: nil},
+		{name: "unknown address", address: "eve@other.example", expected: nil},
+		{name: "unparsable address", address: "", expected: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			roles := mapping.Roles(tc.address)
+
+			// Assert
+			if !slices.Equal(roles, tc.expected) {
+				t.Errorf("Roles(%q) = %v, want %v", tc.address, roles, tc.expected)
+			}
+		})
+	}
+}
+
+func TestRoleMapping_Claims(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	mapping, err := claims.ParseRoleMapping(strings.NewReader(testRoleMapping))
+	if err != nil {
+		t.Fatalf("ParseRoleMapping() error = %v", err)
+	}
+
+	// Act
+	granted, err := mapping.Claims(context.Background(), claims.Subject{UID: "u1", Email: "bob@example.com"})
+	none, noneErr := mapping.Claims(context.Background(), claims.Subject{UID: "u2", Email: ""})
+
+	// Assert
+	if err != nil || noneErr != nil {
+		t.Fatalf("Claims() errors = %v, %v", err, noneErr)
+	}
+
+	if roles, _ := granted[claims.RolesClaim].([]string); !slices.Equal(roles, []string{"member", "staff"}) {
+		t.Errorf("expected roles [member staff], got %v", granted)
+	}
+
+	if len(none) != 0 {
+		t.Errorf("expected no claims for a user without an address, got %v", none)
+	}
+}
+
+func TestParseRoleMapping_Invalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{name: "malformed JSON", content: `{"users": `},
+		{name: "unknown field", content: `{"groups": {}}`},
+		{name: "invalid address", content: `{"users": {"not-an-email": ["admin"]}}`},
+		{name: "invalid domain pattern", content: `{"domains": {"not a domain": ["admin"]}}`},
+		{name: "invalid role", content: `{"users": {"alice@example.com": ["has space"]}}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			_, err := claims.ParseRoleMapping(strings.NewReader(tc.content))
+
+			// Assert
+			if !errors.Is(err, claims.ErrInvalidRoleMapping) {
+				t.Errorf("expected ErrInvalidRoleMapping, got %v", err)
+			}
+		})
+	}
+
+	t.Run("invalid role is reported", func(t *testing.T) {
+		t.Parallel()
+
+		// Act
+		_, err := claims.ParseRoleMapping(strings.NewReader(`{"domains": {"example.com": ["has space"]}}`))
+
+		// Assert
+		if !errors.Is(err, entity.ErrInvalidRole) {
+			t.Errorf("expected ErrInvalidRole, got %v", err)
+		}
+	})
+}
+
+func TestProviderFunc(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	provider := claims.ProviderFunc(func(_ context.Context, subject claims.Subject) (map[string]any, error) {
+		return map[string]any{"tenant": subject.UID}, nil
+	})
+
+	// Act
+	granted, err := provider.Claims(context.Background(), claims.Subject{UID: "tenant-1", Email: ""})
+
+	// Assert
+	if err != nil || granted["tenant"] != "tenant-1" {
+		t.Errorf("Claims() = %v, %v, want the hook's claims", granted, err)
+	}
+}
+
+func writeRoleMapping(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write role mapping: %v", err)
+	}
+
+	// Set the time explicitly: two writes within the file system's timestamp resolution look unchanged
+	err = os.Chtimes(path, modTime, modTime)
+	if err != nil {
+		t.Fatalf("failed to set modification time: %v", err)
+	}
+}
+
+func TestFileRoleMapping_Reload(t *testing.T) {
+	t.Parallel()
+
+	t.Run("loads changes", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		path := filepath.Join(t.TempDir(), "roles.json")
+		start := time.Now().Add(-time.Hour)
+		writeRoleMapping(t, path, `{"users": {"alice@example.com": ["member"]}}`, start)
+
+		mapping, err := claims.NewFileRoleMapping(path)
+		if err != nil {
+			t.Fatalf("NewFileRoleMapping() error = %v", err)
+		}
+
+		writeRoleMapping(t, path, `{"users": {"alice@example.com": ["admin"]}}`, start.Add(time.Minute))
+
+		// Act
+		reloaded, err := mapping.Reload()
+
+		// Assert
+		if err != nil || !reloaded {
+			t.Fatalf("expected the changed file to be reloaded, got reloaded=%v err=%v", reloaded, err)
+		}
+
+		if roles := mapping.Roles("alice@example.com"); !slices.Equal(roles, []string{"admin"}) {
+			t.Errorf("expected the new mapping to replace the old one, got %v", roles)
+		}
+	})
+
+	t.Run("keeps the previous mapping when the file becomes invalid", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		path := filepath.Join(t.TempDir(), "roles.json")
+		start := time.Now().Add(-time.Hour)
+		writeRoleMapping(t, path, `{"users": {"alice@example.com": ["member"]}}`, start)
+
+		mapping, err := claims.NewFileRoleMapping(path)
+		if err != nil {
+			t.Fatalf("NewFileRoleMapping() error = %v", err)
+		}
+
+		writeRoleMapping(t, path, `{"users": `, start.Add(time.Minute))
+
+		// Act
+		_, err = mapping.Reload()
+
+		// Assert
+		if !errors.Is(err, claims.ErrInvalidRoleMapping) {
+			t.Fatalf("expected ErrInvalidRoleMapping, got %v", err)
+		}
+
+		if roles := mapping.Roles("alice@example.com"); !slices.Equal(roles, []string{"member"}) {
+			t.Errorf("expected the previous mapping to stay in effect, got %v", roles)
+		}
+	})
+
+	t.Run("fails for a missing file", func(t *testing.T) {
+		t.Parallel()
+
+		// Act
+		_, err := claims.NewFileRoleMapping(filepath.Join(t.TempDir(), "missing.json"))
+
+		// Assert
+		if err == nil {
+			t.Error("expected an error for a missing file")
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package emailpolicy
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"time"
+
+	"custom_auth_api/internal/pkg/filereload"
+)
+
+//go:embed disposable_domains.txt
+var bundledDisposableDomains []byte
+
+// BundledDisposableDomains returns the disposable-domain list shipped with the server.
+func BundledDisposableDomains() *DomainSet {
+	set, err := ParseDomainSet(bytes.NewReader(bundledDisposableDomains))
+	if err != nil {
+		// The bundled list is covered by tests, so this only happens on a bad edit
+		panic(fmt.Sprintf("invalid bundled disposable domain list: %v", err))
+	}
+
+	return set
+}
+
+// FileDomainSet is a DomainSet loaded from a file that can be reloaded while in use.
+//
+// Reload swaps in the new set atomically, so Contains never sees a partially
+// loaded list. If the file becomes unreadable or invalid, the last good set is kept.
+type FileDomainSet struct {
+	file *filereload.File[DomainSet]
+}
+
+// NewFileDomainSet loads the domain list at path (one pattern per line, see ParseDomainSet).
+func NewFileDomainSet(path string) (*FileDomainSet, error) {
+	file, err := filereload.New(path, "domain list", ParseDomainSet)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileDomainSet{file: file}, nil
+}
+
+// Contains reports whether domain is in the most recently loaded list.
+func (s *FileDomainSet) Contains(domain string) bool {
+	return s.file.Load().Contains(domain)
+}
+
+// Len returns the number of patterns in the most recently loaded list.
+func (s *FileDomainSet) Len() int {
+	return s.file.Load().Len()
+}
+
+// Reload re-reads the file if its modification time or size changed since the last load
+// and reports whether a new list was loaded.
+func (s *FileDomainSet) Reload() (bool, error) {
+	return s.file.Reload()
+}
+
+// RunReload checks the file for changes every interval until ctx is done.
+// It blocks, so run it in its own goroutine. Failures are reported to onError, which may be nil;
+// the previous list stays in effect.
+func (s *FileDomainSet) RunReload(ctx context.Context, interval time.Duration, onError func(error)) {
+	s.file.RunReload(ctx, interval, onError)
+}
@@ -0,0 +1,136 @@
+package emailpolicy_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"custom_auth_api/internal/domain/emailpolicy"
+)
+
+func TestBundledDisposableDomains(t *testing.T) {
+	t.Parallel()
+
+	// Act
+	set := emailpolicy.BundledDisposableDomains()
+
+	// Assert
+	if set.Len() == 0 {
+		t.Fatal("expected the bundled list to contain domains")
+	}
+
+	for _, domain := range []string{"mailinator.com", "inbox.mailinator.com", "yopmail.com"} {
+		if !set.Contains(domain) {
+			t.Errorf("expected the bundled list to contain %q", domain)
+		}
+	}
+}
+
+func writeDomainList(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write domain list: %v", err)
+	}
+
+	// Set the time explicitly: two writes within the file system's timestamp resolution look unchanged
+	err = os.Chtimes(path, modTime, modTime)
+	if err != nil {
+		t.Fatalf("failed to set modification time: %v", err)
+	}
+}
+
+func TestFileDomainSet_Reload(t *testing.T) {
+	t.Parallel()
+
+	t.Run("loads changes", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		path := filepath.Join(t.TempDir(), "disposable.txt")
+		start := time.Now().Add(-time.Hour)
+		writeDomainList(t, path, "first.example\n", start)
+
+		set, err := emailpolicy.NewFileDomainSet(path)
+		if err != nil {
+			t.Fatalf("NewFileDomainSet() error = %v", err)
+		}
+
+		writeDomainList(t, path, "second.example\n", start.Add(time.Minute))
+
+		// Act
+		reloaded, err := set.Reload()
+
+		// Assert
+		if err != nil || !reloaded {
+			t.Fatalf("expected the changed file to be reloaded, got reloaded=%v err=%v", reloaded, err)
+		}
+
+		if set.Contains("first.example") || !set.Contains("second.example") {
+			t.Error("expected the new list to replace the old one")
+		}
+	})
+
+	t.Run("skips unchanged files", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		path := filepath.Join(t.TempDir(), "disposable.txt")
+		writeDomainList(t, path, "first.example\n", time.Now().Add(-time.Hour))
+
+		set, err := emailpolicy.NewFileDomainSet(path)
+		if err != nil {
+			t.Fatalf("NewFileDomainSet() error = %v", err)
+		}
+
+		// Act
+		reloaded, err := set.Reload()
+
+		// Assert
+		if err != nil || reloaded {
+			t.Errorf("expected an unchanged file not to be reloaded, got reloaded=%v err=%v", reloaded, err)
+		}
+	})
+
+	t.Run("keeps the previous list when the file becomes invalid", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		path := filepath.Join(t.TempDir(), "disposable.txt")
+		start := time.Now().Add(-time.Hour)
+		writeDomainList(t, path, "first.example\n", start)
+
+		set, err := emailpolicy.NewFileDomainSet(path)
+		if err != nil {
+			t.Fatalf("NewFileDomainSet() error = %v", err)
+		}
+
+		writeDomainList(t, path, "not a domain\n", start.Add(time.Minute))
+
+		// Act
+		_, err = set.Reload()
+
+		// Assert
+		if err == nil {
+			t.Fatal("expected an error for the invalid file")
+		}
+
+		if !set.Contains("first.example") {
+			t.Error("expected the previous list to stay in effect")
+		}
+	})
+
+	t.Run("fails for a missing file", func(t *testing.T) {
+		t.Parallel()
+
+		// Act
+		_, err := emailpolicy.NewFileDomainSet(filepath.Join(t.TempDir(), "missing.txt"))
+
+		// Assert
+		if err == nil {
+			t.Error("expected an error for a missing file")
+		}
+	})
+}
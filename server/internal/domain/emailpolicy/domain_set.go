@@ -0,0 +1,128 @@
+// Package emailpolicy decides which email domains may sign in, independently of address syntax.
+package emailpolicy
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// wildcardPrefix marks a pattern that matches every subdomain of a domain.
+const wildcardPrefix = "*."
+
+// ErrInvalidDomainPattern is returned for a domain list entry that is not a host name or "*.<host name>".
+var ErrInvalidDomainPattern = errors.New("invalid email domain pattern")
+
+// DomainMatcher reports whether a domain is in a set.
+// Domains are passed in canonical form: lowercase IDNA ASCII, as returned by email.Email.Domain.
+type DomainMatcher interface {
+	Contains(domain string) bool
+}
+
+// DomainSet is an immutable set of domain patterns.
+//
+// A pattern is either an exact domain ("example.com"), which matches only that
+// domain, or a wildcard ("*.example.com"), which matches every subdomain of
+// example.com but not example.com itself. List both to cover a domain and its subdomains.
+type DomainSet struct {
+	exact     map[string]struct{}
+	wildcards map[string]struct{}
+}
+
+// NewDomainSet creates a DomainSet from patterns. Internationalized domains are
+// converted to their ASCII (punycode) form.
+func NewDomainSet(patterns []string) (*DomainSet, error) {
+	set := &DomainSet{exact: map[string]struct{}{}, wildcards: map[string]struct{}{}}
+
+	for _, pattern := range patterns {
+		err := set.add(pattern)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return set, nil
+}
+
+// ParseDomainSet reads one pattern per line. Blank lines and lines starting with "#" are ignored.
+func ParseDomainSet(r io.Reader) (*DomainSet, error) {
+	set := &DomainSet{exact: map[string]struct{}{}, wildcards: map[string]struct{}{}}
+	scanner := bufio.NewScanner(r)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		pattern := strings.TrimSpace(scanner.Text())
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+
+		err := set.add(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read domain list: %w", err)
+	}
+
+	return set, nil
+}
+
+// Contains reports whether domain matches an exact pattern or is a subdomain of a wildcard pattern.
+// A nil set contains nothing.
+func (s *DomainSet) Contains(domain string) bool {
+	if s == nil {
+		return false
+	}
+
+	if _, ok := s.exact[domain]; ok {
+		return true
+	}
+
+	// Walk up the parents: a.b.example.com → b.example.com → example.com → com
+	for parent := domain; ; {
+		_, rest, found := strings.Cut(parent, ".")
+		if !found {
+			return false
+		}
+
+		if _, ok := s.wildcards[rest]; ok {
+			return true
+		}
+
+		parent = rest
+	}
+}
+
+// Len returns the number of patterns in the set.
+func (s *DomainSet) Len() int {
+	return len(s.exact) + len(s.wildcards)
+}
+
+// add normalizes and stores one pattern.
+func (s *DomainSet) add(pattern string) error {
+	target := s.exact
+
+	domain := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(pattern)), ".")
+	if rest, ok := strings.CutPrefix(domain, wildcardPrefix); ok {
+		target = s.wildcards
+		domain = rest
+	}
+
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil || ascii == "" || strings.Contains(ascii, "*") {
+		return fmt.Errorf("%w (got %q)", ErrInvalidDomainPattern, pattern)
+	}
+
+	target[ascii] = struct{}{}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package emailpolicy_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"custom_auth_api/internal/domain/emailpolicy"
+)
+
+func TestDomainSet_Contains(t *testing.T) {
+	t.Parallel()
+
+	set, err := emailpolicy.NewDomainSet([]string{"Example.COM.", "*.corp.example", "*.bücher.example"})
+	if err != nil {
+		t.Fatalf("NewDomainSet() error = %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		domain   string
+		expected bool
+	}{
+		{name: "exact match", domain: "example.com", expected: true},
+		{name: "exact pattern does not match subdomains", domain: "mail.example.com", expected: false},
+		{name: "wildcard matches a subdomain", domain: "eu.corp.example", expected: true},
+		{name: "wildcard matches nested subdomains", domain: "a.b.corp.example", expected: true},
+		{name: "wildcard does not match the domain itself", domain: "corp.example", expected: false},
+		{name: "wildcard does not match a suffix that is not a label", domain: "notcorp.example", expected: false},
+		{name: "internationalized patterns match punycode", domain: "shop.xn--bcher-kva.example", expected: true},
+		{name: "unlisted domain", domain: "example.org", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			contains := set.Contains(tc.domain)
+
+			// Assert
+			if contains != tc.expected {
+				t.Errorf("expected Contains(%q) to be %v", tc.domain, tc.expected)
+			}
+		})
+	}
+}
+
+func TestNewDomainSet_InvalidPattern(t *testing.T) {
+	t.Parallel()
+
+	for _, pattern := range []string{"", "*", "ex*ample.com", "exa_mple.com"} {
+		t.Run(pattern, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			_, err := emailpolicy.NewDomainSet([]string{pattern})
+
+			// Assert
+			if !errors.Is(err, emailpolicy.ErrInvalidDomainPattern) {
+				t.Errorf("expected ErrInvalidDomainPattern, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseDomainSet(t *testing.T) {
+	t.Parallel()
+
+	t.Run("skips comments and blank lines", func(t *testing.T) {
+		t.Parallel()
+
+		// Act
+		set, err := emailpolicy.ParseDomainSet(strings.NewReader("# disposable\n\nmailinator.com\n  *.yopmail.com  \n"))
+
+		// Assert
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if set.Len() != 2 {
+			t.Errorf("expected 2 patterns, got %d", set.Len())
+		}
+
+		if !set.Contains("mailinator.com") || !set.Contains("x.yopmail.com") {
+			t.Error("expected both patterns to be loaded")
+		}
+	})
+
+	t.Run("reports the line of an invalid pattern", func(t *testing.T) {
+		t.Parallel()
+
+		// Act
+		_, err := emailpolicy.ParseDomainSet(strings.NewReader("mailinator.com\nnot a domain\n"))
+
+		// Assert
+		if !errors.Is(err, emailpolicy.ErrInvalidDomainPattern) {
+			t.Fatalf("expected ErrInvalidDomainPattern, got %v", err)
+		}
+
+		if !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("expected the error to name line 2, got %v", err)
+		}
+	})
+}
+
+func TestDomainSet_NilContainsNothing(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	var set *emailpolicy.DomainSet
+
+	// Act & Assert
+	if set.Contains("example.com") {
+		t.Error("expected a nil set to contain nothing")
+	}
+}
@@ -0,0 +1,92 @@
+package emailpolicy
+
+import (
+	"errors"
+	"fmt"
+
+	"custom_auth_api/internal/domain/vo/email"
+)
+
+// ErrDomainRejected is matched by every *RejectionError.
+var ErrDomainRejected = errors.New("email domain is not accepted")
+
+// Rules that can reject a domain, reported in RejectionError.Rule.
+const (
+	RuleDenylist       = "denylist"
+	RuleNotAllowlisted = "not_allowlisted"
+	RuleDisposable     = "disposable"
+)
+
+// RejectionError reports that the policy rejected an address.
+// Rule and Domain are meant for logs; responses should only reveal that the address was rejected.
+type RejectionError struct {
+	Rule   string
+	Domain string
+}
+
+// Error returns the rejection including the rule that matched.
+func (e *RejectionError) Error() string {
+	return fmt.Sprintf("%s: %s (rule %s)", ErrDomainRejected.Error(), e.Domain, e.Rule)
+}
+
+// Is reports whether target is ErrDomainRejected.
+func (e *RejectionError) Is(target error) bool {
+	return target == ErrDomainRejected
+}
+
+// PolicyOptions configures a Policy. Nil matchers are skipped.
+type PolicyOptions struct {
+	// Allow lists domains that are always accepted, even if denied or disposable.
+	Allow DomainMatcher
+
+	// Deny lists domains that are always rejected unless allowed.
+	Deny DomainMatcher
+
+	// Disposable lists throwaway mailbox providers, rejected unless allowed.
+	Disposable DomainMatcher
+
+	// AllowlistOnly rejects every domain that is not in Allow.
+	AllowlistOnly bool
+}
+
+// Policy decides whether an address's domain may be used to sign in.
+//
+// Rules are checked in order: allowed domains are accepted, then denied domains are
+// rejected, then (with AllowlistOnly) every other domain is rejected, then disposable
+// domains are rejected. Everything else is accepted.
+type Policy struct {
+	options PolicyOptions
+}
+
+// NewPolicy creates a Policy.
+func NewPolicy(options PolicyOptions) *Policy {
+	return &Policy{options: options}
+}
+
+// Check returns a *RejectionError (matching ErrDomainRejected) if the policy rejects address.
+func (p *Policy) Check(address *email.Email) error {
+	domain := address.Domain()
+
+	if matches(p.options.Allow, domain) {
+		return nil
+	}
+
+	if matches(p.options.Deny, domain) {
+		return &RejectionError{Rule: RuleDenylist, Domain: domain}
+	}
+
+	if p.options.AllowlistOnly {
+		return &RejectionError{Rule: RuleNotAllowlisted, Domain: domain}
+	}
+
+	if matches(p.options.Disposable, domain) {
+		return &RejectionError{Rule: RuleDisposable, Domain: domain}
+	}
+
+	return nil
+}
+
+// matches reports whether a non-nil matcher contains domain.
+func matches(matcher DomainMatcher, domain string) bool {
+	return matcher != nil && matcher.Contains(domain)
+}
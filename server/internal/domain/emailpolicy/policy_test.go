@@ -0,0 +1,119 @@
+package emailpolicy_test
+
+import (
+	"errors"
+	"testing"
+
+	"custom_auth_api/internal/domain/emailpolicy"
+	"custom_auth_api/internal/domain/vo/email"
+)
+
+func newDomainSet(t *testing.T, patterns ...string) *emailpolicy.DomainSet {
+	t.Helper()
+
+	set, err := emailpolicy.NewDomainSet(patterns)
+	if err != nil {
+		t.Fatalf("NewDomainSet() error = %v", err)
+	}
+
+	return set
+}
+
+func TestPolicy_Check(t *testing.T) {
+	t.Parallel()
+
+	allow := newDomainSet(t, "partner.example", "trusted.mailinator.com")
+	deny := newDomainSet(t, "*.spam.example", "partner.example")
+	disposable := newDomainSet(t, "mailinator.com", "*.mailinator.com")
+
+	testCases := []struct {
+		name          string
+		address       string
+		allowlistOnly bool
+		expectedRule  string
+	}{
+		{name: "accepts unlisted domains", address: "user@example.com", allowlistOnly: false, expectedRule: ""},
+		{name: "rejects denied subdomains", address: "user@a.spam.example", allowlistOnly: false, expectedRule: emailpolicy.RuleDenylist},
+		{name: "rejects disposable domains", address: "user@Mailinator.com", allowlistOnly: false, expectedRule: emailpolicy.RuleDisposable},
+		{name: "allowlist overrides the denylist", address: "user@partner.example", allowlistOnly: false, expectedRule: ""},
+		{name: "allowlist overrides the disposable list", address: "user@trusted.mailinator.com", allowlistOnly: false, expectedRule: ""},
+		{name: "allowlist-only rejects unlisted domains", address: "user@example.com", allowlistOnly: true, expectedRule: emailpolicy.RuleNotAllowlisted},
+		{name: "allowlist-only accepts listed domains", address: "user@partner.example", allowlistOnly: true, expectedRule: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			policy := emailpolicy.NewPolicy(emailpolicy.PolicyOptions{
+				Allow:         allow,
+				Deny:          deny,
+				Disposable:    disposable,
+				AllowlistOnly: tc.allowlistOnly,
+			})
+
+			address, err := email.NewEmail(tc.address)
+			if err != nil {
+				t.Fatalf("NewEmail() error = %v", err)
+			}
+
+			// Act
+			err = policy.Check(address)
+
+			// Assert
+			if tc.expectedRule == "" {
+				if err != nil {
+					t.Errorf("expected address to be accepted, got %v", err)
+				}
+
+				return
+			}
+
+			var rejection *emailpolicy.RejectionError
+			if !errors.As(err, &rejection) {
+				t.Fatalf("expected *RejectionError, got %v", err)
+			}
+
+			if rejection.Rule != tc.expectedRule {
+				t.Errorf("expected rule %q, got %q", tc.expectedRule, rejection.Rule)
+			}
+
+			if !errors.Is(err, emailpolicy.ErrDomainRejected) {
+				t.Errorf("expected %v to match ErrDomainRejected", err)
+			}
+		})
+	}
+}
+
+func TestPolicy_CheckUsesCanonicalDomain(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	policy := emailpolicy.NewPolicy(emailpolicy.PolicyOptions{
+		Allow:         nil,
+		Deny:          newDomainSet(t, "gmail.com", "bücher.example"),
+		Disposable:    nil,
+		AllowlistOnly: false,
+	})
+	options := email.Options{
+		ProviderRules:        []email.ProviderRule{email.GmailRule()},
+		AllowQuotedLocalPart: false,
+		AllowUTF8LocalPart:   false,
+	}
+
+	for _, address := range []string{"user@googlemail.com", "user@BÜCHER.example"} {
+		userEmail, err := email.NewEmailWithOptions(address, options)
+		if err != nil {
+			t.Fatalf("NewEmailWithOptions(%q) error = %v", address, err)
+		}
+
+		// Act
+		err = policy.Check(userEmail)
+
+		// Assert
+		if !errors.Is(err, emailpolicy.ErrDomainRejected) {
+			t.Errorf("expected %q to be rejected by its canonical domain, got %v", address, err)
+		}
+	}
+}
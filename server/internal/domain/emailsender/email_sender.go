@@ -1,8 +1,31 @@
 package emailsender
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // EmailSender defines the interface for sending emails.
 type EmailSender interface {
-	SendOTP(ctx context.Context, toEmail, otp string) error
+	// SendOTP sends a one-time code to an address.
+	SendOTP(ctx context.Context, toEmail string, otp OTP) error
+
+	// SendSignInNotice tells an address with no account that someone tried to sign in with it.
+	SendSignInNotice(ctx context.Context, toEmail string) error
+
+	// SendInvitation invites an address to register through the invitation's accept link.
+	SendInvitation(ctx context.Context, toEmail string, invitation Invitation) error
+}
+
+// OTP describes an OTP email.
+type OTP struct {
+	Code      string    // The one-time code
+	ExpiresAt time.Time // When the session the code belongs to expires
+}
+
+// Invitation describes an invitation email.
+type Invitation struct {
+	Inviter   string    // Who created the invitation
+	AcceptURL string    // Link carrying the signed invitation token
+	ExpiresAt time.Time // When the invitation can no longer be accepted
 }
@@ -0,0 +1,93 @@
+package emailsender
+
+import (
+	"context"
+
+	"golang.org/x/text/language"
+)
+
+// Locale identifies the language an email is rendered in.
+type Locale string
+
+// Supported locales. Most users are Japanese, so Japanese is the default.
+const (
+	LocaleJapanese Locale = "ja"
+	LocaleEnglish  Locale = "en"
+
+	DefaultLocale = LocaleJapanese
+)
+
+// supportedLocales lists the locales with embedded templates.
+// The first entry is the matcher's fallback.
+var supportedLocales = []Locale{LocaleJapanese, LocaleEnglish}
+
+var localeMatcher = newLocaleMatcher()
+
+// localeContextKey is the context key for the recipient's locale.
+type localeContextKey struct{}
+
+// ParseLocale returns the supported locale for a language tag such as "en-US".
+// Returns false if the tag is malformed or the language is not supported.
+func ParseLocale(tag string) (Locale, bool) {
+	parsed, err := language.Parse(tag)
+	if err != nil {
+		return "", false
+	}
+
+	base, _ := parsed.Base()
+
+	for _, locale := range supportedLocales {
+		if base.String() == string(locale) {
+			return locale, true
+		}
+	}
+
+	return "", false
+}
+
+// NegotiateLocale picks the email locale for a request.
+// An explicit per-user preference wins; otherwise the Accept-Language header is
+// matched against the supported locales. Returns false if neither yields a
+// supported locale, in which case the renderer's default locale applies.
+func NegotiateLocale(preference, acceptLanguage string) (Locale, bool) {
+	if locale, ok := ParseLocale(preference); ok {
+		return locale, true
+	}
+
+	if acceptLanguage == "" {
+		return "", false
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return "", false
+	}
+
+	_, index, confidence := localeMatcher.Match(tags...)
+	if confidence == language.No {
+		return "", false
+	}
+
+	return supportedLocales[index], true
+}
+
+// ContextWithLocale returns a copy of ctx carrying the recipient's locale.
+func ContextWithLocale(ctx context.Context, locale Locale) context.Context {
+	return context.WithValue(ctx, localeContextKey{}, locale)
+}
+
+// LocaleFromContext returns the recipient's locale stored in ctx, if any.
+func LocaleFromContext(ctx context.Context) (Locale, bool) {
+	locale, ok := ctx.Value(localeContextKey{}).(Locale)
+
+	return locale, ok
+}
+
+func newLocaleMatcher() language.Matcher {
+	tags := make([]language.Tag, 0, len(supportedLocales))
+	for _, locale := range supportedLocales {
+		tags = append(tags, language.Make(string(locale)))
+	}
+
+	return language.NewMatcher(tags)
+}
@@ -0,0 +1,106 @@
+package emailsender_test
+
+import (
+	"context"
+	"testing"
+
+	"custom_auth_api/internal/domain/emailsender"
+)
+
+func TestNegotiateLocale(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		preference     string
+		acceptLanguage string
+		wantLocale     emailsender.Locale
+		wantOK         bool
+	}{
+		{
+			name:           "explicit preference wins over Accept-Language",
+			preference:     "en",
+			acceptLanguage: "ja,en;q=0.8",
+			wantLocale:     emailsender.LocaleEnglish,
+			wantOK:         true,
+		},
+		{
+			name:           "regional preference maps to base language",
+			preference:     "ja-JP",
+			acceptLanguage: "",
+			wantLocale:     emailsender.LocaleJapanese,
+			wantOK:         true,
+		},
+		{
+			name:           "unsupported preference falls through to Accept-Language",
+			preference:     "fr",
+			acceptLanguage: "en-US,en;q=0.9",
+			wantLocale:     emailsender.LocaleEnglish,
+			wantOK:         true,
+		},
+		{
+			name:           "Accept-Language quality values are respected",
+			preference:     "",
+			acceptLanguage: "en;q=0.5,ja;q=0.9",
+			wantLocale:     emailsender.LocaleJapanese,
+			wantOK:         true,
+		},
+		{
+			name:           "unsupported Accept-Language yields no locale",
+			preference:     "",
+			acceptLanguage: "de-DE,fr;q=0.8",
+			wantLocale:     "",
+			wantOK:         false,
+		},
+		{
+			name:           "no preference and no header yields no locale",
+			preference:     "",
+			acceptLanguage: "",
+			wantLocale:     "",
+			wantOK:         false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			locale, ok := emailsender.NegotiateLocale(tc.preference, tc.acceptLanguage)
+
+			// Assert
+			if ok != tc.wantOK || locale != tc.wantLocale {
+				t.Errorf("expected (%q, %v), got (%q, %v)", tc.wantLocale, tc.wantOK, locale, ok)
+			}
+		})
+	}
+}
+
+func TestLocaleContext(t *testing.T) {
+	t.Parallel()
+
+	t.Run("round-trips the locale through the context", func(t *testing.T) {
+		t.Parallel()
+
+		// Act
+		ctx := emailsender.ContextWithLocale(context.Background(), emailsender.LocaleEnglish)
+		locale, ok := emailsender.LocaleFromContext(ctx)
+
+		// Assert
+		if !ok || locale != emailsender.LocaleEnglish {
+			t.Errorf("expected (en, true), got (%q, %v)", locale, ok)
+		}
+	})
+
+	t.Run("reports missing locale", func(t *testing.T) {
+		t.Parallel()
+
+		// Act
+		_, ok := emailsender.LocaleFromContext(context.Background())
+
+		// Assert
+		if ok {
+			t.Error("expected no locale in empty context")
+		}
+	})
+}
@@ -0,0 +1,286 @@
+package emailsender
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Template names. Each locale directory contains one file per part:
+//
+//	<locale>/otp_subject.txt  subject line (text/template)
+//	<locale>/otp.txt          plain text body (text/template)
+//	<locale>/otp.html         HTML body (html/template)
+const (
+	TemplateOTP          = "otp"
+	TemplateSignInNotice = "sign_in_notice"
+	TemplateInvitation   = "invitation"
+
+	subjectSuffix = "_subject.txt"
+	textSuffix    = ".txt"
+	htmlSuffix    = ".html"
+)
+
+//go:embed templates
+var embeddedTemplates embed.FS
+
+// templateNames lists the templates every supported locale must provide.
+var templateNames = []string{TemplateOTP, TemplateSignInNotice, TemplateInvitation}
+
+// Template errors.
+var (
+	ErrTemplateNotFound = errors.New("email template not found")
+	ErrTemplateRender   = errors.New("failed to render email template")
+)
+
+// Message is a rendered email ready to be handed to a transport.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// OTPTemplateData is the data available to the OTP templates.
+type OTPTemplateData struct {
+	Email            string
+	OTP              string
+	ExpiresIn        time.Duration
+	ExpiresInMinutes int
+	ExpiresAt        time.Time
+	Locale           Locale
+}
+
+// SignInNoticeTemplateData is the data available to the sign-in notice templates,
+// sent when someone requests an OTP for an address with no account.
+type SignInNoticeTemplateData struct {
+	Email       string
+	AttemptedAt time.Time
+	Locale      Locale
+}
+
+// InvitationTemplateData is the data available to the invitation templates.
+type InvitationTemplateData struct {
+	Email     string
+	Inviter   string
+	AcceptURL string
+	ExpiresAt time.Time
+	Locale    Locale
+}
+
+// messageTemplates holds the parsed parts of one template in one locale.
+type messageTemplates struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// TemplateRenderer renders localized multipart emails from Go templates.
+//
+// Embedded defaults are always loaded. When an override directory is given,
+// any file found there with the same relative path (e.g. "ja/otp.html")
+// replaces the embedded one.
+type TemplateRenderer struct {
+	templates     map[Locale]map[string]*messageTemplates
+	defaultLocale Locale
+	now           func() time.Time
+}
+
+// NewTemplateRenderer loads the embedded templates and applies overrides from overrideDir.
+// overrideDir may be empty to use only the embedded templates.
+func NewTemplateRenderer(overrideDir string, defaultLocale Locale) (*TemplateRenderer, error) {
+	embedded, err := fs.Sub(embeddedTemplates, "templates")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embedded templates: %w", err)
+	}
+
+	var override fs.FS
+	if overrideDir != "" {
+		override = os.DirFS(overrideDir)
+	}
+
+	renderer := &TemplateRenderer{
+		templates:     make(map[Locale]map[string]*messageTemplates),
+		defaultLocale: defaultLocale,
+		now:           time.Now,
+	}
+
+	for _, locale := range supportedLocales {
+		renderer.templates[locale] = make(map[string]*messageTemplates, len(templateNames))
+
+		for _, name := range templateNames {
+			parsed, err := parseMessageTemplates(embedded, override, locale, name)
+			if err != nil {
+				return nil, err
+			}
+
+			renderer.templates[locale][name] = parsed
+		}
+	}
+
+	if _, ok := renderer.templates[defaultLocale]; !ok {
+		return nil, fmt.Errorf("%w: no templates for default locale %q", ErrTemplateNotFound, defaultLocale)
+	}
+
+	return renderer, nil
+}
+
+// RenderOTP renders the OTP email for toEmail in the given locale.
+// The expiry shown is the session's, so an email rendered late does not promise more time.
+// Falls back to the default locale if locale is empty or unsupported.
+func (r *TemplateRenderer) RenderOTP(locale Locale, toEmail string, otp OTP) (*Message, error) {
+	locale = r.resolveLocale(locale)
+	expiresIn := max(otp.ExpiresAt.Sub(r.now()).Round(time.Minute), 0)
+
+	data := OTPTemplateData{
+		Email:            toEmail,
+		OTP:              otp.Code,
+		ExpiresIn:        expiresIn,
+		ExpiresInMinutes: int(expiresIn.Minutes()),
+		ExpiresAt:        otp.ExpiresAt,
+		Locale:           locale,
+	}
+
+	return r.render(locale, TemplateOTP, toEmail, data)
+}
+
+// RenderSignInNotice renders the notice sent to an unregistered address after a sign-in attempt.
+// Falls back to the default locale if locale is empty or unsupported.
+func (r *TemplateRenderer) RenderSignInNotice(locale Locale, toEmail string) (*Message, error) {
+	locale = r.resolveLocale(locale)
+
+	data := SignInNoticeTemplateData{
+		Email:       toEmail,
+		AttemptedAt: r.now(),
+		Locale:      locale,
+	}
+
+	return r.render(locale, TemplateSignInNotice, toEmail, data)
+}
+
+// RenderInvitation renders the invitation email for toEmail in the given locale.
+// Falls back to the default locale if locale is empty or unsupported.
+func (r *TemplateRenderer) RenderInvitation(locale Locale, toEmail string, invitation Invitation) (*Message, error) {
+	locale = r.resolveLocale(locale)
+
+	data := InvitationTemplateData{
+		Email:     toEmail,
+		Inviter:   invitation.Inviter,
+		AcceptURL: invitation.AcceptURL,
+		ExpiresAt: invitation.ExpiresAt,
+		Locale:    locale,
+	}
+
+	return r.render(locale, TemplateInvitation, toEmail, data)
+}
+
+// DefaultLocale returns the locale used when none is requested.
+func (r *TemplateRenderer) DefaultLocale() Locale {
+	return r.defaultLocale
+}
+
+func (r *TemplateRenderer) resolveLocale(locale Locale) Locale {
+	if _, ok := r.templates[locale]; ok {
+		return locale
+	}
+
+	return r.defaultLocale
+}
+
+func (r *TemplateRenderer) render(locale Locale, name, toEmail string, data any) (*Message, error) {
+	tmpl, ok := r.templates[locale][name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s/%s", ErrTemplateNotFound, locale, name)
+	}
+
+	var subject, text, html bytes.Buffer
+
+	err := tmpl.subject.Execute(&subject, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: subject: %w", ErrTemplateRender, err)
+	}
+
+	err = tmpl.text.Execute(&text, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: text: %w", ErrTemplateRender, err)
+	}
+
+	err = tmpl.html.Execute(&html, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: html: %w", ErrTemplateRender, err)
+	}
+
+	return &Message{
+		To:      toEmail,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// parseMessageTemplates parses the subject, text and HTML parts of a template,
+// preferring files from override over the embedded defaults.
+func parseMessageTemplates(embedded, override fs.FS, locale Locale, name string) (*messageTemplates, error) {
+	base := path.Join(string(locale), name)
+
+	subjectSrc, err := readTemplate(embedded, override, base+subjectSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	textSrc, err := readTemplate(embedded, override, base+textSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	htmlSrc, err := readTemplate(embedded, override, base+htmlSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := texttemplate.New(base + subjectSuffix).Option("missingkey=error").Parse(subjectSrc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", base+subjectSuffix, err)
+	}
+
+	text, err := texttemplate.New(base + textSuffix).Option("missingkey=error").Parse(textSrc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", base+textSuffix, err)
+	}
+
+	html, err := htmltemplate.New(base + htmlSuffix).Option("missingkey=error").Parse(htmlSrc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", base+htmlSuffix, err)
+	}
+
+	return &messageTemplates{subject: subject, text: text, html: html}, nil
+}
+
+// readTemplate reads a template file from override if present, otherwise from embedded.
+func readTemplate(embedded, override fs.FS, name string) (string, error) {
+	if override != nil {
+		content, err := fs.ReadFile(override, name)
+		if err == nil {
+			return string(content), nil
+		}
+
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("failed to read template override %s: %w", name, err)
+		}
+	}
+
+	content, err := fs.ReadFile(embedded, name)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+
+	return string(content), nil
+}
@@ -0,0 +1,394 @@
+package emailsender_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"custom_auth_api/internal/domain/emailsender"
+)
+
+const (
+	testRecipient = "user@example.com"
+	testOTPCode   = "123456"
+)
+
+// testOTP returns an OTP email for a session created now.
+func testOTP() emailsender.OTP {
+	return emailsender.OTP{Code: testOTPCode, ExpiresAt: time.Now().Add(5 * time.Minute)}
+}
+
+// writeOverride writes a template override file below dir.
+func writeOverride(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+
+	err := os.MkdirAll(filepath.Dir(path), 0o750)
+	if err != nil {
+		t.Fatalf("failed to create override directory: %v", err)
+	}
+
+	err = os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write override: %v", err)
+	}
+}
+
+func TestTemplateRenderer_RenderOTP(t *testing.T) {
+	t.Parallel()
+
+	renderer, err := emailsender.NewTemplateRenderer("", emailsender.LocaleJapanese)
+	if err != nil {
+		t.Fatalf("failed to create renderer: %v", err)
+	}
+
+	testCases := []struct {
+		name        string
+		locale      emailsender.Locale
+		wantSubject string
+		wantText    string
+		wantHTML    string
+	}{
+		{
+			name:        "renders Japanese templates",
+			locale:      emailsender.LocaleJapanese,
+			wantSubject: "認証コードのお知らせ",
+			wantText:    "このコードの有効期限は5分です。",
+			wantHTML:    `<html lang="ja">`,
+		},
+		{
+			name:        "renders English templates",
+			locale:      emailsender.LocaleEnglish,
+			wantSubject: "Your verification code",
+			wantText:    "This code expires in 5 minutes.",
+			wantHTML:    `<html lang="en">`,
+		},
+		{
+			name:        "falls back to the default locale when none is given",
+			locale:      "",
+			wantSubject: "認証コードのお知らせ",
+			wantText:    "このコードの有効期限は5分です。",
+			wantHTML:    `<html lang="ja">`,
+		},
+		{
+			name:        "falls back to the default locale for unsupported locales",
+			locale:      "fr",
+			wantSubject: "認証コードのお知らせ",
+			wantText:    "このコードの有効期限は5分です。",
+			wantHTML:    `<html lang="ja">`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			message, err := renderer.RenderOTP(tc.locale, testRecipient, testOTP())
+
+			// Assert
+			if err != nil {
+				t.Fatalf("RenderOTP() unexpected error: %v", err)
+			}
+
+			if message.To != testRecipient {
+				t.Errorf("expected recipient %s, got %s", testRecipient, message.To)
+			}
+
+			if message.Subject != tc.wantSubject {
+				t.Errorf("expected subject %q, got %q", tc.wantSubject, message.Subject)
+			}
+
+			if !strings.Contains(message.Text, testOTPCode) || !strings.Contains(message.Text, tc.wantText) {
+				t.Errorf("text part missing code or expiry: %q", message.Text)
+			}
+
+			if !strings.Contains(message.HTML, testOTPCode) || !strings.Contains(message.HTML, tc.wantHTML) {
+				t.Errorf("html part missing code or language: %q", message.HTML)
+			}
+		})
+	}
+}
+
+func TestTemplateRenderer_RenderSignInNotice(t *testing.T) {
+	t.Parallel()
+
+	renderer, err := emailsender.NewTemplateRenderer("", emailsender.LocaleJapanese)
+	if err != nil {
+		t.Fatalf("failed to create renderer: %v", err)
+	}
+
+	testCases := []struct {
+		name        string
+		locale      emailsender.Locale
+		wantSubject string
+		wantText    string
+	}{
+		{
+			name:        "japanese",
+			locale:      emailsender.LocaleJapanese,
+			wantSubject: "ログイン試行のお知らせ",
+			wantText:    "登録されたアカウントはありません",
+		},
+		{
+			name:        "english",
+			locale:      emailsender.LocaleEnglish,
+			wantSubject: "Sign-in attempt with your email address",
+			wantText:    "there is no account registered",
+		},
+		{
+			name:        "falls back to default locale",
+			locale:      "",
+			wantSubject: "ログイン試行のお知らせ",
+			wantText:    "登録されたアカウントはありません",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			message, err := renderer.RenderSignInNotice(tc.locale, testRecipient)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("RenderSignInNotice() unexpected error: %v", err)
+			}
+
+			if message.To != testRecipient || message.Subject != tc.wantSubject {
+				t.Errorf("unexpected recipient or subject: %q, %q", message.To, message.Subject)
+			}
+
+			if !strings.Contains(message.Text, testRecipient) || !strings.Contains(message.Text, tc.wantText) {
+				t.Errorf("text part missing address or notice: %q", message.Text)
+			}
+
+			if !strings.Contains(message.HTML, testRecipient) {
+				t.Errorf("html part missing address: %q", message.HTML)
+			}
+		})
+	}
+}
+
+func TestTemplateRenderer_RenderInvitation(t *testing.T) {
+	t.Parallel()
+
+	renderer, err := emailsender.NewTemplateRenderer("", emailsender.LocaleJapanese)
+	if err != nil {
+		t.Fatalf("failed to create renderer: %v", err)
+	}
+
+	invitation := emailsender.Invitation{
+		Inviter:   "admin@example.com",
+		AcceptURL: "https://app.example.com/invite?token=v1.abc.123.sig&x=1",
+		ExpiresAt: time.Date(2030, time.January, 2, 3, 4, 0, 0, time.UTC),
+	}
+
+	testCases := []struct {
+		name        string
+		locale      emailsender.Locale
+		wantSubject string
+		wantText    string
+	}{
+		{
+			name:        "japanese",
+			locale:      emailsender.LocaleJapanese,
+			wantSubject: "アカウント作成のご招待",
+			wantText:    "admin@example.com さんから",
+		},
+		{
+			name:        "english",
+			locale:      emailsender.LocaleEnglish,
+			wantSubject: "You have been invited to create an account",
+			wantText:    "admin@example.com has invited",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			message, err := renderer.RenderInvitation(tc.locale, testRecipient, invitation)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("RenderInvitation() unexpected error: %v", err)
+			}
+
+			if message.To != testRecipient || message.Subject != tc.wantSubject {
+				t.Errorf("unexpected recipient or subject: %q, %q", message.To, message.Subject)
+			}
+
+			if !strings.Contains(message.Text, tc.wantText) || !strings.Contains(message.Text, invitation.AcceptURL) ||
+				!strings.Contains(message.Text, "2030-01-02 03:04 UTC") {
+				t.Errorf("text part missing inviter, link or expiry: %q", message.Text)
+			}
+
+			if !strings.Contains(message.HTML, `href="https://app.example.com/invite?token=v1.abc.123.sig&amp;x=1"`) {
+				t.Errorf("html part missing the escaped link: %q", message.HTML)
+			}
+		})
+	}
+}
+
+func TestTemplateRenderer_RenderOTP_SessionExpiry(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	dir := t.TempDir()
+	writeOverride(t, dir, "en/otp.html", `<p>{{.ExpiresInMinutes}} {{.ExpiresAt.UTC.Format "15:04"}}</p>`)
+
+	renderer, err := emailsender.NewTemplateRenderer(dir, emailsender.LocaleEnglish)
+	if err != nil {
+		t.Fatalf("failed to create renderer: %v", err)
+	}
+
+	// A session created two minutes before the email is rendered
+	expiresAt := time.Now().Add(3 * time.Minute)
+
+	// Act
+	message, err := renderer.RenderOTP(emailsender.LocaleEnglish, testRecipient, emailsender.OTP{
+		Code:      testOTPCode,
+		ExpiresAt: expiresAt,
+	})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("RenderOTP() unexpected error: %v", err)
+	}
+
+	want := "<p>3 " + expiresAt.UTC().Format("15:04") + "</p>"
+	if message.HTML != want {
+		t.Errorf("expected html %q, got %q", want, message.HTML)
+	}
+
+	if !strings.Contains(message.Text, "This code expires in 3 minutes.") {
+		t.Errorf("expected the remaining session time in the text, got %q", message.Text)
+	}
+}
+
+func TestTemplateRenderer_Overrides(t *testing.T) {
+	t.Parallel()
+
+	t.Run("uses override files and keeps embedded defaults for the rest", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		dir := t.TempDir()
+		writeOverride(t, dir, "en/otp_subject.txt", "Sign-in code for {{.Email}}")
+		writeOverride(t, dir, "en/otp.html", "<p>{{.OTP}} ({{.ExpiresInMinutes}} min)</p>")
+
+		renderer, err := emailsender.NewTemplateRenderer(dir, emailsender.LocaleEnglish)
+		if err != nil {
+			t.Fatalf("failed to create renderer: %v", err)
+		}
+
+		// Act
+		message, err := renderer.RenderOTP(emailsender.LocaleEnglish, testRecipient, testOTP())
+
+		// Assert
+		if err != nil {
+			t.Fatalf("RenderOTP() unexpected error: %v", err)
+		}
+
+		if message.Subject != "Sign-in code for user@example.com" {
+			t.Errorf("unexpected subject %q", message.Subject)
+		}
+
+		if message.HTML != "<p>123456 (5 min)</p>" {
+			t.Errorf("unexpected html %q", message.HTML)
+		}
+
+		if !strings.Contains(message.Text, "Your one-time password is: 123456") {
+			t.Errorf("expected embedded text template, got %q", message.Text)
+		}
+	})
+
+	t.Run("escapes values in HTML templates", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		dir := t.TempDir()
+		writeOverride(t, dir, "en/otp.html", "<p>{{.Email}}</p>")
+
+		renderer, err := emailsender.NewTemplateRenderer(dir, emailsender.LocaleEnglish)
+		if err != nil {
+			t.Fatalf("failed to create renderer: %v", err)
+		}
+
+		// Act
+		message, err := renderer.RenderOTP(emailsender.LocaleEnglish, "<b>x</b>@example.com", testOTP())
+
+		// Assert
+		if err != nil {
+			t.Fatalf("RenderOTP() unexpected error: %v", err)
+		}
+
+		if message.HTML != "<p>&lt;b&gt;x&lt;/b&gt;@example.com</p>" {
+			t.Errorf("expected escaped html, got %q", message.HTML)
+		}
+	})
+
+	t.Run("returns error for malformed override", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		dir := t.TempDir()
+		writeOverride(t, dir, "ja/otp.txt", "{{.OTP")
+
+		// Act
+		renderer, err := emailsender.NewTemplateRenderer(dir, emailsender.LocaleJapanese)
+
+		// Assert
+		if err == nil {
+			t.Error("expected parse error, got nil")
+		}
+
+		if renderer != nil {
+			t.Error("expected nil renderer on error")
+		}
+	})
+
+	t.Run("returns render error for unknown fields", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		dir := t.TempDir()
+		writeOverride(t, dir, "ja/otp.txt", "{{.Unknown}}")
+
+		renderer, err := emailsender.NewTemplateRenderer(dir, emailsender.LocaleJapanese)
+		if err != nil {
+			t.Fatalf("failed to create renderer: %v", err)
+		}
+
+		// Act
+		_, err = renderer.RenderOTP(emailsender.LocaleJapanese, testRecipient, testOTP())
+
+		// Assert
+		if !errors.Is(err, emailsender.ErrTemplateRender) {
+			t.Errorf("expected ErrTemplateRender, got %v", err)
+		}
+	})
+}
+
+func TestNewTemplateRenderer_UnsupportedDefaultLocale(t *testing.T) {
+	t.Parallel()
+
+	// Act
+	renderer, err := emailsender.NewTemplateRenderer("", "fr")
+
+	// Assert
+	if !errors.Is(err, emailsender.ErrTemplateNotFound) {
+		t.Errorf("expected ErrTemplateNotFound, got %v", err)
+	}
+
+	if renderer != nil {
+		t.Error("expected nil renderer on error")
+	}
+}
@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"custom_auth_api/internal/domain/vo/ipaddress"
+	"custom_auth_api/internal/domain/vo/useragent"
+)
+
+// BindingPolicy selects which properties of the requesting client a verification
+// must match before the OTP code is even compared.
+type BindingPolicy string
+
+// Supported binding policies.
+const (
+	// BindingNone accepts verification from any client.
+	BindingNone BindingPolicy = "none"
+
+	// BindingUserAgent requires the same browser family (e.g. Chrome) as the request.
+	BindingUserAgent BindingPolicy = "user_agent"
+
+	// BindingIP requires the same client IP as the request.
+	BindingIP BindingPolicy = "ip"
+
+	// BindingStrict requires both the same client IP and the same browser family.
+	BindingStrict BindingPolicy = "strict"
+)
+
+// CheckBinding reports whether a verification from ipAddress with userAgent may proceed under policy.
+// ipHasher verifies ipAddress against the recorded hash; without one a recorded IP never matches.
+// Returns ErrSessionBindingMismatch if a bound property differs from the one recorded at creation.
+//
+// Properties that were not recorded (sessions created without request context) are not enforced,
+// so enabling a policy does not invalidate sessions created before it.
+func (s *OTPSession) CheckBinding(
+	policy BindingPolicy,
+	ipHasher *ipaddress.Hasher,
+	ipAddress string,
+	userAgent string,
+) error {
+	bindIP := policy == BindingIP || policy == BindingStrict
+	bindUserAgent := policy == BindingUserAgent || policy == BindingStrict
+
+	if bindIP && !s.ipAddressHash.IsEmpty() && (ipHasher == nil || !ipHasher.Matches(s.ipAddressHash, ipAddress)) {
+		return ErrSessionBindingMismatch
+	}
+
+	if bindUserAgent && s.userAgent != "" && useragent.Family(userAgent) != useragent.Family(s.userAgent) {
+		return ErrSessionBindingMismatch
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package entity_test
+
+import (
+	"errors"
+	"testing"
+
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/vo/email"
+	"custom_auth_api/internal/domain/vo/otp"
+)
+
+const (
+	chromeUserAgent  = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+	chrome2UserAgent = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36"
+	firefoxUserAgent = "Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0"
+	otherIPAddress   = "198.51.100.7"
+)
+
+func TestCheckBinding(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		policy      entity.BindingPolicy
+		withContext bool
+		ipAddress   string
+		userAgent   string
+		expectErr   error
+	}{
+		{
+			name:        "none accepts any client",
+			policy:      entity.BindingNone,
+			withContext: true,
+			ipAddress:   otherIPAddress,
+			userAgent:   firefoxUserAgent,
+			expectErr:   nil,
+		},
+		{
+			name:        "user_agent accepts a newer version of the same browser",
+			policy:      entity.BindingUserAgent,
+			withContext: true,
+			ipAddress:   otherIPAddress,
+			userAgent:   chrome2UserAgent,
+			expectErr:   nil,
+		},
+		{
+			name:        "user_agent rejects a different browser",
+			policy:      entity.BindingUserAgent,
+			withContext: true,
+			ipAddress:   testIPAddress,
+			userAgent:   firefoxUserAgent,
+			expectErr:   entity.ErrSessionBindingMismatch,
+		},
+		{
+			name:        "ip accepts the same address from another browser",
+			policy:      entity.BindingIP,
+			withContext: true,
+			ipAddress:   testIPAddress,
+			userAgent:   firefoxUserAgent,
+			expectErr:   nil,
+		},
+		{
+			name:        "ip rejects a different address",
+			policy:      entity.BindingIP,
+			withContext: true,
+			ipAddress:   otherIPAddress,
+			userAgent:   chromeUserAgent,
+			expectErr:   entity.ErrSessionBindingMismatch,
+		},
+		{
+			name:        "strict accepts the same address and browser",
+			policy:      entity.BindingStrict,
+			withContext: true,
+			ipAddress:   testIPAddress,
+			userAgent:   chrome2UserAgent,
+			expectErr:   nil,
+		},
+		{
+			name:        "strict rejects a different browser",
+			policy:      entity.BindingStrict,
+			withContext: true,
+			ipAddress:   testIPAddress,
+			userAgent:   firefoxUserAgent,
+			expectErr:   entity.ErrSessionBindingMismatch,
+		},
+		{
+			name:        "strict does not enforce properties that were not recorded",
+			policy:      entity.BindingStrict,
+			withContext: false,
+			ipAddress:   otherIPAddress,
+			userAgent:   firefoxUserAgent,
+			expectErr:   nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			testEmail, _ := email.NewEmail("test@example.com")
+			testOTP, _ := otp.NewOTP()
+			hasher := newTestIPHasher(t)
+
+			session := entity.NewOTPSession(testEmail, testOTP)
+			if tc.withContext {
+				session = entity.NewOTPSessionWithContext(testEmail, testOTP, hasher.Hash(testIPAddress), chromeUserAgent)
+			}
+
+			// Act
+			err := session.CheckBinding(tc.policy, hasher, tc.ipAddress, tc.userAgent)
+
+			// Assert
+			if !errors.Is(err, tc.expectErr) {
+				t.Errorf("expected error %v, got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckBinding_WithoutHasher(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	testEmail, _ := email.NewEmail("test@example.com")
+	testOTP, _ := otp.NewOTP()
+	session := entity.NewOTPSessionWithContext(testEmail, testOTP, newTestIPHasher(t).Hash(testIPAddress), chromeUserAgent)
+
+	// Act
+	err := session.CheckBinding(entity.BindingIP, nil, testIPAddress, chromeUserAgent)
+
+	// Assert
+	if !errors.Is(err, entity.ErrSessionBindingMismatch) {
+		t.Errorf("expected a recorded IP to fail closed without a hasher, got %v", err)
+	}
+}
@@ -14,4 +14,27 @@ var (
 
 	// ErrInvalidOTP is returned when the provided OTP does not match.
 	ErrInvalidOTP = errors.New("invalid otp code")
+
+	// ErrSessionBindingMismatch is returned when verification comes from a different
+	// client than the one that requested the OTP.
+	ErrSessionBindingMismatch = errors.New("otp session is bound to a different client")
+)
+
+// Invitation errors.
+var (
+	// ErrInvitationNotFound is returned when an invitation is not found.
+	ErrInvitationNotFound = errors.New("invitation not found")
+
+	// ErrInvitationExpired is returned when an invitation has expired.
+	ErrInvitationExpired = errors.New("invitation has expired")
+
+	// ErrInvitationAlreadyUsed is returned when an invitation has already been accepted.
+	ErrInvitationAlreadyUsed = errors.New("invitation has already been accepted")
+
+	// ErrInviterRequired is returned when an invitation is created without an inviter.
+	ErrInviterRequired = errors.New("inviter is required")
+
+	// ErrInvalidRole is returned for role names that are not 1-64 characters of
+	// [A-Za-z0-9_.:-], or when an invitation grants too many roles.
+	ErrInvalidRole = errors.New("roles must be at most 20 names of 1-64 characters [A-Za-z0-9_.:-]")
 )
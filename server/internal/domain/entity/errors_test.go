@@ -33,6 +33,26 @@ func TestDomainErrors_AreDefined(t *testing.T) {
 			err:  entity.ErrInvalidOTP,
 			want: "invalid otp code",
 		},
+		{
+			name: "entity.ErrSessionBindingMismatch has correct message",
+			err:  entity.ErrSessionBindingMismatch,
+			want: "otp session is bound to a different client",
+		},
+		{
+			name: "entity.ErrInvitationNotFound has correct message",
+			err:  entity.ErrInvitationNotFound,
+			want: "invitation not found",
+		},
+		{
+			name: "entity.ErrInvitationExpired has correct message",
+			err:  entity.ErrInvitationExpired,
+			want: "invitation has expired",
+		},
+		{
+			name: "entity.ErrInvitationAlreadyUsed has correct message",
+			err:  entity.ErrInvitationAlreadyUsed,
+			want: "invitation has already been accepted",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,194 @@
+package entity
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"time"
+
+	"custom_auth_api/internal/domain/vo/email"
+)
+
+const (
+	// DefaultInvitationExpiration is the default duration for which an invitation can be accepted.
+	DefaultInvitationExpiration = 7 * 24 * time.Hour
+
+	// MaxInvitationRoles is the maximum number of roles an invitation can grant.
+	MaxInvitationRoles = 20
+
+	// MaxRoleLength is the maximum length of a role name.
+	MaxRoleLength = 64
+
+	// invitationIDBytes is the number of random bytes in an invitation ID.
+	invitationIDBytes = 16
+)
+
+// Invitation represents an admin-created invitation to register one email address.
+// This is an Entity because:
+//   - It has identity (a random ID)
+//   - It has mutable state (accepted or not)
+//   - It has lifecycle (created → accepted/expired)
+//
+// Invitations are single use: once accepted they can never be accepted again.
+// Roles are granted to the invited user as custom claims when the invitation is accepted.
+type Invitation struct {
+	id         string
+	email      *email.Email
+	inviter    string // Who created the invitation (admin email, or UID if it has none)
+	roles      []string
+	createdAt  time.Time
+	expiresAt  time.Time
+	acceptedAt time.Time // Zero until accepted
+}
+
+// NewInvitation creates an invitation for userEmail with a random ID.
+// A non-positive ttl selects DefaultInvitationExpiration. Duplicate roles are dropped.
+// Returns ErrInviterRequired if inviter is empty, or an error wrapping ErrInvalidRole
+// if a role name is not acceptable or there are more than MaxInvitationRoles roles.
+func NewInvitation(userEmail *email.Email, inviter string, roles []string, ttl time.Duration) (*Invitation, error) {
+	if userEmail == nil {
+		return nil, ErrEmailRequired
+	}
+	if inviter == "" {
+		return nil, ErrInviterRequired
+	}
+
+	roles, err := NormalizeRoles(roles)
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl <= 0 {
+		ttl = DefaultInvitationExpiration
+	}
+
+	var id [invitationIDBytes]byte
+
+	_, _ = rand.Read(id[:]) // crypto/rand.Read never returns an error
+
+	now := time.Now()
+
+	return &Invitation{
+		id:         hex.EncodeToString(id[:]),
+		email:      userEmail,
+		inviter:    inviter,
+		roles:      roles,
+		createdAt:  now,
+		expiresAt:  now.Add(ttl),
+		acceptedAt: time.Time{},
+	}, nil
+}
+
+// Accept marks the invitation as accepted now.
+// Returns ErrInvitationAlreadyUsed if it has been accepted before,
+// or ErrInvitationExpired if it has expired.
+func (i *Invitation) Accept() error {
+	err := i.CanAccept()
+	if err != nil {
+		return err
+	}
+
+	i.acceptedAt = time.Now()
+
+	return nil
+}
+
+// CanAccept checks if the invitation can still be accepted.
+// Returns ErrInvitationAlreadyUsed if accepted, or ErrInvitationExpired if expired.
+func (i *Invitation) CanAccept() error {
+	if i.IsAccepted() {
+		return ErrInvitationAlreadyUsed
+	}
+
+	if i.IsExpired() {
+		return ErrInvitationExpired
+	}
+
+	return nil
+}
+
+// IsExpired checks if the invitation has expired.
+func (i *Invitation) IsExpired() bool {
+	return time.Now().After(i.expiresAt)
+}
+
+// IsAccepted reports whether the invitation has been accepted.
+func (i *Invitation) IsAccepted() bool {
+	return !i.acceptedAt.IsZero()
+}
+
+// ID returns the invitation ID.
+func (i *Invitation) ID() string {
+	return i.id
+}
+
+// Email returns the invited email address.
+func (i *Invitation) Email() *email.Email {
+	return i.email
+}
+
+// Inviter returns who created the invitation.
+func (i *Invitation) Inviter() string {
+	return i.inviter
+}
+
+// Roles returns a copy of the roles granted by the invitation.
+func (i *Invitation) Roles() []string {
+	return slices.Clone(i.roles)
+}
+
+// CreatedAt returns the invitation creation timestamp.
+func (i *Invitation) CreatedAt() time.Time {
+	return i.createdAt
+}
+
+// ExpiresAt returns the invitation expiration timestamp.
+func (i *Invitation) ExpiresAt() time.Time {
+	return i.expiresAt
+}
+
+// AcceptedAt returns when the invitation was accepted, or the zero time if it has not been.
+func (i *Invitation) AcceptedAt() time.Time {
+	return i.acceptedAt
+}
+
+// NormalizeRoles validates role names and drops duplicates, keeping the first occurrence.
+// Returns an error wrapping ErrInvalidRole for an invalid name or more than MaxInvitationRoles roles.
+func NormalizeRoles(roles []string) ([]string, error) {
+	normalized := make([]string, 0, len(roles))
+
+	for _, role := range roles {
+		if !isValidRole(role) {
+			return nil, fmt.Errorf("%w (got %q)", ErrInvalidRole, role)
+		}
+
+		if !slices.Contains(normalized, role) {
+			normalized = append(normalized, role)
+		}
+	}
+
+	if len(normalized) > MaxInvitationRoles {
+		return nil, fmt.Errorf("%w (got %d roles)", ErrInvalidRole, len(normalized))
+	}
+
+	return normalized, nil
+}
+
+// isValidRole reports whether role is 1-64 ASCII letters, digits, '_', '-', '.' or ':'.
+func isValidRole(role string) bool {
+	if role == "" || len(role) > MaxRoleLength {
+		return false
+	}
+
+	for _, r := range role {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '_', r == '-', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
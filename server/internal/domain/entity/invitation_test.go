@@ -0,0 +1,214 @@
+package entity_test
+
+import "custom_auth_api/internal/domain/entity"
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"custom_auth_api/internal/domain/vo/email"
+)
+
+const testInviter = "admin@example.com"
+
+// newTestInvitation creates an invitation for invitee@example.com with the given roles.
+func newTestInvitation(t *testing.T, roles []string, ttl time.Duration) *entity.Invitation {
+	t.Helper()
+
+	userEmail, _ := email.NewEmail("invitee@example.com")
+
+	invitation, err := entity.NewInvitation(userEmail, testInviter, roles, ttl)
+	if err != nil {
+		t.Fatalf("NewInvitation() returned an error: %v", err)
+	}
+
+	return invitation
+}
+
+// restoreTestInvitation restores an invitation with the given expiry and acceptance time.
+func restoreTestInvitation(t *testing.T, expiresAt, acceptedAt time.Time) *entity.Invitation {
+	t.Helper()
+
+	userEmail, _ := email.NewEmail("invitee@example.com")
+
+	data, err := entity.NewInvitationRestorationData(
+		"0123456789abcdef", userEmail, testInviter, []string{"member"}, expiresAt.Add(-time.Hour), expiresAt, acceptedAt,
+	)
+	if err != nil {
+		t.Fatalf("NewInvitationRestorationData() returned an error: %v", err)
+	}
+
+	return entity.RestoreInvitation(data)
+}
+
+func TestNewInvitation(t *testing.T) {
+	t.Parallel()
+
+	// Act
+	first := newTestInvitation(t, []string{"member", "billing:read", "member"}, time.Hour)
+	second := newTestInvitation(t, nil, 0)
+
+	// Assert
+	if len(first.ID()) != 32 || first.ID() == second.ID() {
+		t.Errorf("IDs = %q, %q, want distinct 32-character hex IDs", first.ID(), second.ID())
+	}
+	if first.Email().Value != "invitee@example.com" || first.Inviter() != testInviter {
+		t.Errorf("Email() = %q, Inviter() = %q", first.Email().Value, first.Inviter())
+	}
+	if !slices.Equal(first.Roles(), []string{"member", "billing:read"}) {
+		t.Errorf("Roles() = %v, want duplicates dropped", first.Roles())
+	}
+	if got := first.ExpiresAt().Sub(first.CreatedAt()); got != time.Hour {
+		t.Errorf("lifetime = %v, want 1h", got)
+	}
+	if got := second.ExpiresAt().Sub(second.CreatedAt()); got != entity.DefaultInvitationExpiration {
+		t.Errorf("default lifetime = %v, want %v", got, entity.DefaultInvitationExpiration)
+	}
+	if first.IsAccepted() || !first.AcceptedAt().IsZero() {
+		t.Error("a new invitation should not be accepted")
+	}
+}
+
+func TestNewInvitation_Invalid(t *testing.T) {
+	t.Parallel()
+
+	userEmail, _ := email.NewEmail("invitee@example.com")
+	tooMany := make([]string, entity.MaxInvitationRoles+1)
+	for i := range tooMany {
+		tooMany[i] = "role" + strings.Repeat("x", i)
+	}
+
+	tests := []struct {
+		name    string
+		email   *email.Email
+		inviter string
+		roles   []string
+		wantErr error
+	}{
+		{name: "nil email", email: nil, inviter: testInviter, roles: nil, wantErr: entity.ErrEmailRequired},
+		{name: "empty inviter", email: userEmail, inviter: "", roles: nil, wantErr: entity.ErrInviterRequired},
+		{name: "empty role", email: userEmail, inviter: testInviter, roles: []string{""}, wantErr: entity.ErrInvalidRole},
+		{name: "role with space", email: userEmail, inviter: testInviter, roles: []string{"a b"}, wantErr: entity.ErrInvalidRole},
+		{
+			name:    "role too long",
+			email:   userEmail,
+			inviter: testInviter,
+			roles:   []string{strings.Repeat("r", entity.MaxRoleLength+1)},
+			wantErr: entity.ErrInvalidRole,
+		},
+		{name: "too many roles", email: userEmail, inviter: testInviter, roles: tooMany, wantErr: entity.ErrInvalidRole},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			_, err := entity.NewInvitation(tt.email, tt.inviter, tt.roles, time.Hour)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewInvitation() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInvitation_Accept(t *testing.T) {
+	t.Parallel()
+
+	t.Run("accepts once", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		invitation := newTestInvitation(t, nil, time.Hour)
+
+		// Act
+		firstErr := invitation.Accept()
+		secondErr := invitation.Accept()
+
+		// Assert
+		if firstErr != nil {
+			t.Fatalf("first Accept() returned an error: %v", firstErr)
+		}
+		if !invitation.IsAccepted() || invitation.AcceptedAt().IsZero() {
+			t.Error("invitation should be accepted")
+		}
+		if !errors.Is(secondErr, entity.ErrInvitationAlreadyUsed) {
+			t.Errorf("second Accept() error = %v, want ErrInvitationAlreadyUsed", secondErr)
+		}
+	})
+
+	t.Run("rejects expired invitations", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		invitation := restoreTestInvitation(t, time.Now().Add(-time.Minute), time.Time{})
+
+		// Act
+		err := invitation.Accept()
+
+		// Assert
+		if !errors.Is(err, entity.ErrInvitationExpired) {
+			t.Errorf("Accept() error = %v, want ErrInvitationExpired", err)
+		}
+		if invitation.IsAccepted() {
+			t.Error("an expired invitation should not become accepted")
+		}
+	})
+
+	t.Run("reports reuse before expiry", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		invitation := restoreTestInvitation(t, time.Now().Add(-time.Minute), time.Now().Add(-time.Hour))
+
+		// Act
+		err := invitation.CanAccept()
+
+		// Assert
+		if !errors.Is(err, entity.ErrInvitationAlreadyUsed) {
+			t.Errorf("CanAccept() error = %v, want ErrInvitationAlreadyUsed", err)
+		}
+	})
+}
+
+func TestNewInvitationRestorationData_Invalid(t *testing.T) {
+	t.Parallel()
+
+	userEmail, _ := email.NewEmail("invitee@example.com")
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		id        string
+		email     *email.Email
+		inviter   string
+		createdAt time.Time
+		expiresAt time.Time
+		wantErr   error
+	}{
+		{name: "empty id", id: "", email: userEmail, inviter: testInviter, createdAt: now, expiresAt: now, wantErr: entity.ErrInvitationIDRequired},
+		{name: "nil email", id: "id", email: nil, inviter: testInviter, createdAt: now, expiresAt: now, wantErr: entity.ErrEmailRequired},
+		{name: "empty inviter", id: "id", email: userEmail, inviter: "", createdAt: now, expiresAt: now, wantErr: entity.ErrInviterRequired},
+		{name: "zero createdAt", id: "id", email: userEmail, inviter: testInviter, expiresAt: now, wantErr: entity.ErrCreatedAtRequired},
+		{name: "zero expiresAt", id: "id", email: userEmail, inviter: testInviter, createdAt: now, wantErr: entity.ErrExpiresAtRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			_, err := entity.NewInvitationRestorationData(tt.id, tt.email, tt.inviter, nil, tt.createdAt, tt.expiresAt, time.Time{})
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewInvitationRestorationData() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package entity
 
 import (
-	"crypto/subtle"
 	"time"
 
 	"custom_auth_api/internal/domain/vo/email"
@@ -30,7 +29,7 @@ type OTPSession struct {
 	code          *otp.OTP
 	createdAt     time.Time
 	expiresAt     time.Time
-	ipAddressHash *ipaddress.Hash // SHA-256 hash of IP address for privacy compliance
+	ipAddressHash *ipaddress.Hash // Keyed hash of IP address for privacy compliance
 	userAgent     string
 	attempts      int // Changes during verification attempts
 }
@@ -52,15 +51,18 @@ func NewOTPSession(userEmail *email.Email, otpCode *otp.OTP) *OTPSession {
 }
 
 // NewOTPSessionWithContext creates a new OTP session with IP and User-Agent for audit trail.
-// IP addresses are hashed using SHA-256 for privacy compliance (GDPR).
+// The IP address is only stored as a keyed hash (see ipaddress.Hasher) for privacy compliance (GDPR).
+// A nil ipHash records no IP address.
 func NewOTPSessionWithContext(
 	userEmail *email.Email,
 	otpCode *otp.OTP,
-	ipAddress string,
+	ipHash *ipaddress.Hash,
 	userAgent string,
 ) *OTPSession {
 	session := NewOTPSession(userEmail, otpCode)
-	session.ipAddressHash = ipaddress.NewHash(ipAddress)
+	if ipHash != nil {
+		session.ipAddressHash = ipHash
+	}
 	session.userAgent = userAgent
 
 	return session
@@ -72,7 +74,8 @@ func NewOTPSessionWithContext(
 // Returns ErrTooManyAttempts if max attempts (3) have been exceeded.
 // Returns ErrInvalidOTP if the code doesn't match.
 //
-// Uses constant-time comparison to prevent timing attacks.
+// Uses constant-time comparison to prevent timing attacks. Sessions restored
+// from storage hold only the keyed digest of the code, which is compared instead.
 // Automatically increments the attempts counter on mismatch.
 func (s *OTPSession) Verify(inputCode string) error {
 	// Check if session is eligible for verification
@@ -81,19 +84,8 @@ func (s *OTPSession) Verify(inputCode string) error {
 		return err
 	}
 
-	// Timing-safe comparison to prevent timing attacks
-	expected := []byte(s.code.String())
-	actual := []byte(inputCode)
-
-	// Check length first (constant-time compare requires same length)
-	if len(expected) != len(actual) {
-		s.attempts++
-
-		return ErrInvalidOTP
-	}
-
-	// Constant-time comparison
-	if subtle.ConstantTimeCompare(expected, actual) != 1 {
+	// Timing-safe comparison (against the keyed digest for restored sessions)
+	if !s.code.Matches(inputCode) {
 		s.attempts++
 
 		return ErrInvalidOTP
@@ -138,6 +130,7 @@ func (s *OTPSession) Email() *email.Email {
 }
 
 // OTP returns the OTP code (for repository serialization).
+// For sessions restored from storage this is a hashed-only OTP.
 func (s *OTPSession) OTP() *otp.OTP {
 	return s.code
 }
@@ -157,7 +150,7 @@ func (s *OTPSession) ExpiresAt() time.Time {
 	return s.expiresAt
 }
 
-// IPAddressHash returns the SHA-256 hash of the IP address.
+// IPAddressHash returns the keyed hash of the IP address.
 func (s *OTPSession) IPAddressHash() *ipaddress.Hash {
 	return s.ipAddressHash
 }
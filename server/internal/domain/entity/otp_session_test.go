@@ -3,11 +3,13 @@ package entity_test
 import "custom_auth_api/internal/domain/entity"
 
 import (
+	"bytes"
 	"errors"
 	"testing"
 	"time"
 
 	"custom_auth_api/internal/domain/vo/email"
+	"custom_auth_api/internal/domain/vo/ipaddress"
 	"custom_auth_api/internal/domain/vo/otp"
 )
 
@@ -16,6 +18,20 @@ const (
 	testUserAgent = "Mozilla/5.0"
 )
 
+// newTestIPHasher creates an IP hasher with a fixed key.
+func newTestIPHasher(t *testing.T) *ipaddress.Hasher {
+	t.Helper()
+
+	hasher, err := ipaddress.NewHasher(
+		"test", map[string][]byte{"test": bytes.Repeat([]byte("i"), ipaddress.MinHashKeyLength)}, ipaddress.HasherOptions{},
+	)
+	if err != nil {
+		t.Fatalf("Failed to create IP hasher: %v", err)
+	}
+
+	return hasher
+}
+
 // TestNewOTPSession tests the creation of a new OTP session with default settings.
 func TestNewOTPSession(t *testing.T) {
 	t.Parallel()
@@ -111,32 +127,25 @@ func TestNewOTPSession(t *testing.T) {
 func TestNewOTPSessionWithContext(t *testing.T) {
 	t.Parallel()
 
-	t.Run("creates session with IP address hashed", func(t *testing.T) {
+	t.Run("records the keyed IP address hash", func(t *testing.T) {
 		t.Parallel()
 
 		// Arrange
 		testEmail, _ := email.NewEmail("test@example.com")
 		testOTP, _ := otp.NewOTP()
-		ipAddress := testIPAddress
-		userAgent := testUserAgent
+		hasher := newTestIPHasher(t)
+		ipHash := hasher.Hash(testIPAddress)
 
 		// Act
-		session := entity.NewOTPSessionWithContext(testEmail, testOTP, ipAddress, userAgent)
+		session := entity.NewOTPSessionWithContext(testEmail, testOTP, ipHash, testUserAgent)
 
 		// Assert
-		if session.IPAddressHash().IsEmpty() {
-			t.Error("expected non-empty IP address hash")
-		}
-
-		// SHA-256 hash should be 64 characters (hex encoded)
-		if len(session.IPAddressHash().String()) != 64 {
-			t.Errorf("expected IP hash length 64, got %d", len(session.IPAddressHash().String()))
+		if session.IPAddressHash().String() != ipHash.String() {
+			t.Errorf("expected IP hash %q, got %q", ipHash.String(), session.IPAddressHash().String())
 		}
 
-		// Should be deterministic - same IP should produce same hash
-		session2 := entity.NewOTPSessionWithContext(testEmail, testOTP, ipAddress, userAgent)
-		if session.IPAddressHash().String() != session2.IPAddressHash().String() {
-			t.Error("same IP should produce same hash")
+		if !hasher.Matches(session.IPAddressHash(), testIPAddress) {
+			t.Error("expected the recorded hash to match the client IP")
 		}
 	})
 
@@ -146,11 +155,12 @@ func TestNewOTPSessionWithContext(t *testing.T) {
 		// Arrange
 		testEmail, _ := email.NewEmail("test@example.com")
 		testOTP, _ := otp.NewOTP()
-		ipAddress := testIPAddress
 		userAgent := "Mozilla/5.0 (Windows NT 10.0; Win64; x64)"
 
 		// Act
-		session := entity.NewOTPSessionWithContext(testEmail, testOTP, ipAddress, userAgent)
+		session := entity.NewOTPSessionWithContext(
+			testEmail, testOTP, newTestIPHasher(t).Hash(testIPAddress), userAgent,
+		)
 
 		// Assert
 		if session.UserAgent() != userAgent {
@@ -158,7 +168,7 @@ func TestNewOTPSessionWithContext(t *testing.T) {
 		}
 	})
 
-	t.Run("handles empty IP address gracefully", func(t *testing.T) {
+	t.Run("records no IP address for a nil hash", func(t *testing.T) {
 		t.Parallel()
 
 		// Arrange
@@ -166,12 +176,11 @@ func TestNewOTPSessionWithContext(t *testing.T) {
 		testOTP, _ := otp.NewOTP()
 
 		// Act
-		session := entity.NewOTPSessionWithContext(testEmail, testOTP, "", testUserAgent)
+		session := entity.NewOTPSessionWithContext(testEmail, testOTP, nil, testUserAgent)
 
 		// Assert
-		// Empty IP should produce hash of empty string
-		if session.IPAddressHash().IsEmpty() {
-			t.Error("expected hash even for empty IP")
+		if !session.IPAddressHash().IsEmpty() {
+			t.Errorf("expected empty IP hash, got %q", session.IPAddressHash().String())
 		}
 	})
 
@@ -183,7 +192,9 @@ func TestNewOTPSessionWithContext(t *testing.T) {
 		testOTP, _ := otp.NewOTP()
 
 		// Act
-		session := entity.NewOTPSessionWithContext(testEmail, testOTP, testIPAddress, "")
+		session := entity.NewOTPSessionWithContext(
+			testEmail, testOTP, newTestIPHasher(t).Hash(testIPAddress), "",
+		)
 
 		// Assert
 		if session.UserAgent() != "" {
@@ -320,6 +331,78 @@ func TestVerify_Failure(t *testing.T) {
 	})
 }
 
+// TestVerify_HashedOnly tests verification of sessions restored with only the OTP digest.
+func TestVerify_HashedOnly(t *testing.T) {
+	t.Parallel()
+
+	restore := func(t *testing.T, attempts int) *entity.OTPSession {
+		t.Helper()
+
+		hasher, err := otp.NewHasher("v1", map[string][]byte{"v1": bytes.Repeat([]byte("k"), otp.MinHashKeyLength)})
+		if err != nil {
+			t.Fatalf("failed to create hasher: %v", err)
+		}
+
+		plain, _ := otp.FromString("123456")
+
+		testEmail, _ := email.NewEmail("test@example.com")
+
+		hashed, err := otp.FromDigest(hasher.Digest(plain, testEmail.Canonical()), hasher, testEmail.Canonical())
+		if err != nil {
+			t.Fatalf("failed to restore hashed otp: %v", err)
+		}
+
+		data, err := entity.NewRestorationData(
+			testEmail,
+			hashed,
+			attempts,
+			time.Now(),
+			time.Now().Add(entity.DefaultOTPExpiration),
+			ipaddress.NewEmptyHash(),
+			"",
+		)
+		if err != nil {
+			t.Fatalf("failed to create restoration data: %v", err)
+		}
+
+		return entity.RestoreOTPSession(data)
+	}
+
+	t.Run("returns nil when code matches the digest", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		session := restore(t, 0)
+
+		// Act
+		err := session.Verify("123456")
+
+		// Assert
+		if err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+	})
+
+	t.Run("returns ErrInvalidOTP and increments attempts on mismatch", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		session := restore(t, 1)
+
+		// Act
+		err := session.Verify("654321")
+
+		// Assert
+		if !errors.Is(err, entity.ErrInvalidOTP) {
+			t.Errorf("expected ErrInvalidOTP, got %v", err)
+		}
+
+		if session.Attempts() != 2 {
+			t.Errorf("expected 2 attempts, got %d", session.Attempts())
+		}
+	})
+}
+
 // TestCanVerify tests the session eligibility check.
 func TestCanVerify(t *testing.T) {
 	t.Parallel()
@@ -479,9 +562,8 @@ func TestGetters(t *testing.T) {
 	// Arrange
 	testEmail, _ := email.NewEmail("test@example.com")
 	testOTP, _ := otp.FromString("123456")
-	ipAddress := testIPAddress
 	userAgent := testUserAgent
-	session := entity.NewOTPSessionWithContext(testEmail, testOTP, ipAddress, userAgent)
+	session := entity.NewOTPSessionWithContext(testEmail, testOTP, newTestIPHasher(t).Hash(testIPAddress), userAgent)
 
 	t.Run("Email returns correct value", func(t *testing.T) {
 		if session.Email() != testEmail {
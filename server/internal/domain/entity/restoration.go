@@ -17,6 +17,7 @@ var (
 	ErrCreatedAtRequired     = errors.New("createdAt is required for restoration")
 	ErrExpiresAtRequired     = errors.New("expiresAt is required for restoration")
 	ErrIPAddressHashRequired = errors.New("ipAddressHash is required for restoration (use NewEmptyHash if no IP)")
+	ErrInvitationIDRequired  = errors.New("invitation id is required for restoration")
 )
 
 // RestorationData contains all fields needed to restore a persisted OTPSession.
@@ -40,7 +41,7 @@ type RestorationData struct {
 //
 // Validation rules:
 //   - Email must not be nil
-//   - OTP code must not be nil
+//   - OTP code must not be nil (a hashed-only code from otp.FromDigest is expected)
 //   - Attempts must not be negative
 //   - CreatedAt must not be zero time
 //   - ExpiresAt must not be zero time
@@ -105,3 +106,83 @@ func RestoreOTPSession(data *RestorationData) *OTPSession {
 		userAgent:     data.UserAgent,
 	}
 }
+
+// InvitationRestorationData contains all fields needed to restore a persisted Invitation.
+// Like RestorationData, it is designed to be used exclusively by repository implementations.
+//
+// Use NewInvitationRestorationData() to create instances with validation.
+type InvitationRestorationData struct {
+	ID         string
+	Email      *email.Email
+	Inviter    string
+	Roles      []string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	AcceptedAt time.Time
+}
+
+// NewInvitationRestorationData creates invitation restoration data with validation.
+//
+// Validation rules:
+//   - ID must not be empty
+//   - Email must not be nil
+//   - Inviter must not be empty
+//   - Roles must be valid role names (see NewInvitation)
+//   - CreatedAt must not be zero time
+//   - ExpiresAt must not be zero time
+//   - AcceptedAt is the zero time for invitations that have not been accepted
+func NewInvitationRestorationData(
+	id string,
+	userEmail *email.Email,
+	inviter string,
+	roles []string,
+	createdAt time.Time,
+	expiresAt time.Time,
+	acceptedAt time.Time,
+) (*InvitationRestorationData, error) {
+	if id == "" {
+		return nil, ErrInvitationIDRequired
+	}
+	if userEmail == nil {
+		return nil, ErrEmailRequired
+	}
+	if inviter == "" {
+		return nil, ErrInviterRequired
+	}
+	if createdAt.IsZero() {
+		return nil, ErrCreatedAtRequired
+	}
+	if expiresAt.IsZero() {
+		return nil, ErrExpiresAtRequired
+	}
+
+	roles, err := NormalizeRoles(roles)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InvitationRestorationData{
+		ID:         id,
+		Email:      userEmail,
+		Inviter:    inviter,
+		Roles:      roles,
+		CreatedAt:  createdAt,
+		ExpiresAt:  expiresAt,
+		AcceptedAt: acceptedAt,
+	}, nil
+}
+
+// RestoreInvitation reconstructs an Invitation from persisted data.
+//
+// REPOSITORY USE ONLY: Application code should use NewInvitation instead.
+func RestoreInvitation(data *InvitationRestorationData) *Invitation {
+	return &Invitation{
+		id:         data.ID,
+		email:      data.Email,
+		inviter:    data.Inviter,
+		roles:      data.Roles,
+		createdAt:  data.CreatedAt,
+		expiresAt:  data.ExpiresAt,
+		acceptedAt: data.AcceptedAt,
+	}
+}
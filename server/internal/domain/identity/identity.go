@@ -0,0 +1,73 @@
+// Package identity defines the identity backend the API signs users in against:
+// a UserDirectory holding the accounts and a TokenIssuer minting and verifying their tokens.
+//
+// Implementations live in the infrastructure layer (e.g. Firebase Authentication), so
+// use cases and handlers never depend on a backend SDK.
+package identity
+
+import (
+	"context"
+	"errors"
+)
+
+// Identity backend errors.
+var (
+	// ErrUserNotFound is returned when no user matches the requested email address or ID.
+	ErrUserNotFound = errors.New("user not found")
+
+	// ErrEmailAlreadyExists is returned when creating a user whose email address is taken.
+	ErrEmailAlreadyExists = errors.New("email address already in use")
+
+	// ErrInvalidToken is returned for tokens that are malformed, forged, expired or revoked.
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+// User is an account in the identity backend.
+type User struct {
+	UID           string
+	Email         string
+	EmailVerified bool
+	DisplayName   string
+	Roles         []string // Roles stored on the account, e.g. granted by an invitation
+}
+
+// UserToCreate describes a new account.
+type UserToCreate struct {
+	Email         string
+	EmailVerified bool
+	DisplayName   string // Optional
+}
+
+// VerifiedToken is what a verified token says about its user.
+type VerifiedToken struct {
+	UID    string
+	Email  string         // Empty for users without an email address
+	Claims map[string]any // All claims of the token, including custom claims
+}
+
+// UserDirectory stores the accounts users sign in to.
+type UserDirectory interface {
+	// GetUserByEmail returns the user registered with email.
+	// Returns an error wrapping ErrUserNotFound if there is none.
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+
+	// CreateUser creates a user with no roles.
+	// Returns an error wrapping ErrEmailAlreadyExists if the address is already registered.
+	CreateUser(ctx context.Context, user UserToCreate) (*User, error)
+
+	// SetRoles replaces the roles of the user with ID uid, keeping its other attributes.
+	// Returns an error wrapping ErrUserNotFound if there is no such user.
+	SetRoles(ctx context.Context, uid string, roles []string) error
+}
+
+// TokenIssuer mints the tokens handed to signed-in users and verifies the tokens they present.
+type TokenIssuer interface {
+	// IssueToken mints a sign-in token for user carrying claims.
+	// Call it only once the user has proven control of their address.
+	IssueToken(ctx context.Context, user *User, claims map[string]any) (string, error)
+
+	// VerifyToken verifies a token presented by a signed-in client. For Firebase this is
+	// the ID token obtained by exchanging the issued custom token.
+	// Returns an error wrapping ErrInvalidToken if the token is not valid.
+	VerifyToken(ctx context.Context, token string) (*VerifiedToken, error)
+}
@@ -0,0 +1,90 @@
+// Package identitytest provides an in-memory identity.TokenIssuer for tests and conformance
+// tests for identity.UserDirectory implementations.
+package identitytest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"maps"
+	"slices"
+	"sync"
+
+	"custom_auth_api/internal/domain/identity"
+)
+
+// IssuedToken is a token minted by an Issuer.
+type IssuedToken struct {
+	User   identity.User
+	Claims map[string]any
+}
+
+// Issuer is an in-memory identity.TokenIssuer. Its tokens are opaque random strings that
+// VerifyToken accepts until they are revoked, reporting the user and claims they were issued with.
+type Issuer struct {
+	mu     sync.Mutex
+	tokens map[string]IssuedToken
+}
+
+// NewIssuer creates an Issuer that has issued no tokens.
+func NewIssuer() *Issuer {
+	return &Issuer{mu: sync.Mutex{}, tokens: make(map[string]IssuedToken)}
+}
+
+// IssueToken returns a new token for user and records its claims.
+func (i *Issuer) IssueToken(_ context.Context, user *identity.User, claims map[string]any) (string, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	token := randomID("token")
+	i.tokens[token] = IssuedToken{User: *copyUser(user), Claims: maps.Clone(claims)}
+
+	return token, nil
+}
+
+// VerifyToken accepts the tokens issued by IssueToken.
+func (i *Issuer) VerifyToken(_ context.Context, token string) (*identity.VerifiedToken, error) {
+	issued, ok := i.Issued(token)
+	if !ok {
+		return nil, identity.ErrInvalidToken
+	}
+
+	return &identity.VerifiedToken{UID: issued.User.UID, Email: issued.User.Email, Claims: issued.Claims}, nil
+}
+
+// Issued returns the user and claims token was issued with.
+func (i *Issuer) Issued(token string) (IssuedToken, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	issued, ok := i.tokens[token]
+
+	return issued, ok
+}
+
+// Revoke makes VerifyToken reject token.
+func (i *Issuer) Revoke(token string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	delete(i.tokens, token)
+}
+
+// copyUser returns a deep copy of user, so callers cannot modify stored users.
+func copyUser(user *identity.User) *identity.User {
+	copied := *user
+	copied.Roles = slices.Clone(user.Roles)
+
+	return &copied
+}
+
+// randomID returns prefix followed by 16 random bytes in hex.
+func randomID(prefix string) string {
+	var id [16]byte
+
+	_, _ = rand.Read(id[:]) // crypto/rand.Read never returns an error
+
+	return prefix + "-" + hex.EncodeToString(id[:])
+}
+
+var _ identity.TokenIssuer = (*Issuer)(nil)
@@ -0,0 +1,43 @@
+package identitytest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"custom_auth_api/internal/domain/identity"
+	"custom_auth_api/internal/domain/identity/identitytest"
+)
+
+func TestIssuer_VerifyToken(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	issuer := identitytest.NewIssuer()
+	user := &identity.User{UID: "uid-1", Email: "user@example.com", EmailVerified: true, DisplayName: "", Roles: nil}
+
+	token, err := issuer.IssueToken(ctx, user, map[string]any{"admin": true})
+	if err != nil {
+		t.Fatalf("IssueToken() returned an error: %v", err)
+	}
+
+	// Act
+	verified, err := issuer.VerifyToken(ctx, token)
+	_, forgedErr := issuer.VerifyToken(ctx, token+"x")
+	issuer.Revoke(token)
+	_, revokedErr := issuer.VerifyToken(ctx, token)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("VerifyToken() returned an error: %v", err)
+	}
+
+	if verified.UID != "uid-1" || verified.Email != "user@example.com" || verified.Claims["admin"] != true {
+		t.Errorf("Unexpected verified token %+v", verified)
+	}
+
+	if !errors.Is(forgedErr, identity.ErrInvalidToken) || !errors.Is(revokedErr, identity.ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for forged and revoked tokens, got %v and %v", forgedErr, revokedErr)
+	}
+}
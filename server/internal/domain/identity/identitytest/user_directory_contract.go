@@ -0,0 +1,151 @@
+package identitytest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"custom_auth_api/internal/domain/identity"
+)
+
+// ContractEmailPrefix starts every address created by TestUserDirectory, so backends
+// that outlive the test run can find and delete the contract users.
+const ContractEmailPrefix = "identity-contract-"
+
+// UserDirectoryFactory returns the directory under test.
+// It may return the same directory for every call; the suite uses a random
+// address per test so tests do not observe each other's users.
+type UserDirectoryFactory func(t *testing.T) identity.UserDirectory
+
+// TestUserDirectory runs the identity.UserDirectory contract against the
+// implementation returned by newDirectory. Every backend must pass it:
+//
+//   - GetUserByEmail reports identity.ErrUserNotFound for an unknown address
+//   - CreateUser stores the address, verification state and display name, with no roles
+//   - CreateUser reports identity.ErrEmailAlreadyExists for a registered address
+//   - SetRoles replaces the roles and keeps the other attributes
+//   - SetRoles reports identity.ErrUserNotFound for an unknown user
+//
+// Created users are not deleted; their addresses start with ContractEmailPrefix.
+func TestUserDirectory(t *testing.T, newDirectory UserDirectoryFactory) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, directory identity.UserDirectory, email string)
+	}{
+		{name: "GetUserByEmail returns ErrUserNotFound", run: testGetUserNotFound},
+		{name: "CreateUser then GetUserByEmail round-trips", run: testCreateUserRoundTrip},
+		{name: "CreateUser rejects a registered address", run: testCreateUserDuplicate},
+		{name: "SetRoles replaces the roles", run: testSetRoles},
+		{name: "SetRoles returns ErrUserNotFound", run: testSetRolesNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newDirectory(t), ContractEmailPrefix+randomID("user")+"@example.com")
+		})
+	}
+}
+
+// createContractUser creates a verified user named "Contract User".
+func createContractUser(t *testing.T, directory identity.UserDirectory, email string) *identity.User {
+	t.Helper()
+
+	user, err := directory.CreateUser(context.Background(), identity.UserToCreate{
+		Email:         email,
+		EmailVerified: true,
+		DisplayName:   "Contract User",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser() returned an error: %v", err)
+	}
+
+	return user
+}
+
+func testGetUserNotFound(t *testing.T, directory identity.UserDirectory, email string) {
+	t.Helper()
+
+	_, err := directory.GetUserByEmail(context.Background(), email)
+	if !errors.Is(err, identity.ErrUserNotFound) {
+		t.Errorf("GetUserByEmail() error = %v, want ErrUserNotFound", err)
+	}
+}
+
+func testCreateUserRoundTrip(t *testing.T, directory identity.UserDirectory, email string) {
+	t.Helper()
+
+	created := createContractUser(t, directory, email)
+	if created.UID == "" {
+		t.Fatal("CreateUser() returned a user without a UID")
+	}
+
+	found, err := directory.GetUserByEmail(context.Background(), email)
+	if err != nil {
+		t.Fatalf("GetUserByEmail() returned an error: %v", err)
+	}
+
+	if found.UID != created.UID || found.Email != email || !found.EmailVerified || found.DisplayName != "Contract User" {
+		t.Errorf("GetUserByEmail() = %+v, want the created user %+v", found, created)
+	}
+
+	if len(found.Roles) != 0 {
+		t.Errorf("Expected a new user without roles, got %v", found.Roles)
+	}
+}
+
+func testCreateUserDuplicate(t *testing.T, directory identity.UserDirectory, email string) {
+	t.Helper()
+
+	createContractUser(t, directory, email)
+
+	_, err := directory.CreateUser(context.Background(), identity.UserToCreate{
+		Email:         email,
+		EmailVerified: false,
+		DisplayName:   "",
+	})
+	if !errors.Is(err, identity.ErrEmailAlreadyExists) {
+		t.Errorf("CreateUser() error = %v, want ErrEmailAlreadyExists", err)
+	}
+}
+
+func testSetRoles(t *testing.T, directory identity.UserDirectory, email string) {
+	t.Helper()
+
+	ctx := context.Background()
+	created := createContractUser(t, directory, email)
+
+	err := directory.SetRoles(ctx, created.UID, []string{"member", "billing"})
+	if err != nil {
+		t.Fatalf("SetRoles() returned an error: %v", err)
+	}
+
+	err = directory.SetRoles(ctx, created.UID, []string{"admin", "member"})
+	if err != nil {
+		t.Fatalf("SetRoles() returned an error: %v", err)
+	}
+
+	found, err := directory.GetUserByEmail(ctx, email)
+	if err != nil {
+		t.Fatalf("GetUserByEmail() returned an error: %v", err)
+	}
+
+	if !slices.Equal(found.Roles, []string{"admin", "member"}) {
+		t.Errorf("Expected roles [admin member], got %v", found.Roles)
+	}
+
+	if found.UID != created.UID || !found.EmailVerified || found.DisplayName != "Contract User" {
+		t.Errorf("Expected SetRoles() to keep the other attributes, got %+v", found)
+	}
+}
+
+func testSetRolesNotFound(t *testing.T, directory identity.UserDirectory, _ string) {
+	t.Helper()
+
+	err := directory.SetRoles(context.Background(), randomID("missing"), []string{"member"})
+	if !errors.Is(err, identity.ErrUserNotFound) {
+		t.Errorf("SetRoles() error = %v, want ErrUserNotFound", err)
+	}
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+
+	"custom_auth_api/internal/domain/entity"
+)
+
+// InvitationRepository defines the interface for Invitation persistence.
+// Like OTPSessionRepository it holds NO business logic: expiry and single-use
+// rules are enforced by the entity.
+type InvitationRepository interface {
+	// Save stores or replaces an invitation, keyed by its ID.
+	Save(ctx context.Context, invitation *entity.Invitation) error
+
+	// FindByID retrieves an invitation by ID.
+	// Returns entity.ErrInvitationNotFound if no invitation exists with the ID.
+	// Does NOT check expiry or acceptance - that's the entity's responsibility.
+	FindByID(ctx context.Context, id string) (*entity.Invitation, error)
+
+	// Delete removes an invitation. Deleting a missing invitation is not an error.
+	Delete(ctx context.Context, id string) error
+
+	// Accept atomically marks the invitation as accepted. In a single transaction it
+	// loads the invitation, delegates to invitation.Accept and persists the acceptance time.
+	//
+	// Implementations MUST serialize concurrent calls for the same ID so that an
+	// invitation is accepted exactly once; later calls get entity.ErrInvitationAlreadyUsed.
+	//
+	// Returns the accepted invitation, entity.ErrInvitationNotFound if no invitation
+	// exists, or the error returned by invitation.Accept.
+	Accept(ctx context.Context, id string) (*entity.Invitation, error)
+}
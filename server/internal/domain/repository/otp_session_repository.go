@@ -21,6 +21,19 @@ type OTPSessionRepository interface {
 	FindByEmail(ctx context.Context, userEmail *email.Email) (*entity.OTPSession, error)
 
 	// Delete removes an OTP session by user email.
-	// Used after successful verification (one-time use) or for cleanup.
+	// Used for cleanup; successful verification consumes the session via VerifyAndConsume.
 	Delete(ctx context.Context, userEmail *email.Email) error
+
+	// VerifyAndConsume atomically verifies inputCode against the stored session.
+	// In a single transaction it loads the session, delegates to session.Verify
+	// (the entity owns expiration, attempt and comparison rules), then either deletes
+	// the session on success or persists the incremented attempts counter on failure.
+	//
+	// Implementations MUST serialize concurrent calls for the same email so that:
+	//   - at most entity.MaxVerificationAttempts wrong codes are ever evaluated
+	//   - a valid code is consumed exactly once (later calls get entity.ErrSessionNotFound)
+	//
+	// Returns nil on success, entity.ErrSessionNotFound if no session exists,
+	// or the error returned by session.Verify.
+	VerifyAndConsume(ctx context.Context, userEmail *email.Email, inputCode string) error
 }
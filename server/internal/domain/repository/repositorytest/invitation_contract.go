@@ -0,0 +1,291 @@
+package repositorytest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/repository"
+)
+
+// contractInviter is the inviter recorded on contract invitations.
+const contractInviter = "contract-admin@example.com"
+
+// InvitationRepositoryFactory returns the repository under test.
+// It may return the same repository for every call; invitations have random IDs,
+// so tests do not observe each other's invitations.
+type InvitationRepositoryFactory func(t *testing.T) repository.InvitationRepository
+
+// TestInvitationRepository runs the repository.InvitationRepository contract against
+// the implementation returned by newRepository. Every backend must pass it:
+//
+//   - FindByID and Accept report entity.ErrInvitationNotFound for a missing invitation
+//   - all invitation fields, including roles and the acceptance time, round-trip
+//   - Save replaces any previous invitation with the same ID
+//   - Delete is idempotent
+//   - Accept persists the acceptance and accepts an invitation exactly once,
+//     even when called concurrently
+//   - an expired invitation is never accepted (entity.ErrInvitationExpired, or
+//     entity.ErrInvitationNotFound for stores that evict on expiry)
+//
+// Invitations created by the suite are deleted on cleanup.
+func TestInvitationRepository(t *testing.T, newRepository InvitationRepositoryFactory) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo repository.InvitationRepository, id string)
+	}{
+		{name: "FindByID returns ErrInvitationNotFound", run: testInvitationFindNotFound},
+		{name: "Save then FindByID round-trips all fields", run: testInvitationRoundTrip},
+		{name: "Save overwrites the previous invitation", run: testInvitationSaveOverwrites},
+		{name: "Delete is idempotent", run: testInvitationDeleteIdempotent},
+		{name: "Accept returns ErrInvitationNotFound", run: testInvitationAcceptNotFound},
+		{name: "Accept is single use", run: testInvitationAcceptOnce},
+		{name: "Accept rejects expired invitations", run: testInvitationAcceptExpired},
+		{name: "concurrent Accept succeeds exactly once", run: testInvitationConcurrentAccept},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepository(t)
+			id := contractInvitationID(t)
+
+			t.Cleanup(func() {
+				err := repo.Delete(context.Background(), id)
+				if err != nil {
+					t.Logf("Failed to clean up invitation %s: %v", id, err)
+				}
+			})
+
+			tt.run(t, repo, id)
+		})
+	}
+}
+
+// contractInvitationID returns a random invitation ID.
+func contractInvitationID(t *testing.T) string {
+	t.Helper()
+
+	var id [16]byte
+
+	_, _ = rand.Read(id[:]) // crypto/rand.Read never returns an error
+
+	return hex.EncodeToString(id[:])
+}
+
+// saveInvitation stores an invitation with the given state for the test's contract email.
+func saveInvitation(
+	t *testing.T,
+	repo repository.InvitationRepository,
+	id string,
+	roles []string,
+	expiresAt time.Time,
+	acceptedAt time.Time,
+) {
+	t.Helper()
+
+	data, err := entity.NewInvitationRestorationData(
+		id, contractEmail(t), contractInviter, roles, expiresAt.Add(-entity.DefaultInvitationExpiration), expiresAt, acceptedAt,
+	)
+	if err != nil {
+		t.Fatalf("Failed to create invitation restoration data: %v", err)
+	}
+
+	err = repo.Save(context.Background(), entity.RestoreInvitation(data))
+	if err != nil {
+		t.Fatalf("Save() returned an error: %v", err)
+	}
+}
+
+// saveFreshInvitation stores a pending invitation granting the "member" role.
+func saveFreshInvitation(t *testing.T, repo repository.InvitationRepository, id string) {
+	t.Helper()
+
+	saveInvitation(t, repo, id, []string{"member"}, contractNow().Add(entity.DefaultInvitationExpiration), time.Time{})
+}
+
+func testInvitationFindNotFound(t *testing.T, repo repository.InvitationRepository, id string) {
+	t.Helper()
+
+	_, err := repo.FindByID(context.Background(), id)
+	if !errors.Is(err, entity.ErrInvitationNotFound) {
+		t.Errorf("FindByID() error = %v, want ErrInvitationNotFound", err)
+	}
+}
+
+func testInvitationRoundTrip(t *testing.T, repo repository.InvitationRepository, id string) {
+	t.Helper()
+
+	expiresAt := contractNow().Add(time.Hour)
+	acceptedAt := contractNow().Add(-time.Minute)
+	saveInvitation(t, repo, id, []string{"member", "billing:admin"}, expiresAt, acceptedAt)
+
+	invitation, err := repo.FindByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("FindByID() returned an error: %v", err)
+	}
+
+	if invitation.ID() != id {
+		t.Errorf("ID = %s, want %s", invitation.ID(), id)
+	}
+
+	if invitation.Email().Value != contractEmail(t).Value {
+		t.Errorf("Email = %s, want %s", invitation.Email().Value, contractEmail(t).Value)
+	}
+
+	if invitation.Inviter() != contractInviter {
+		t.Errorf("Inviter = %q, want %q", invitation.Inviter(), contractInviter)
+	}
+
+	if !slices.Equal(invitation.Roles(), []string{"member", "billing:admin"}) {
+		t.Errorf("Roles = %v, want [member billing:admin]", invitation.Roles())
+	}
+
+	if !invitation.CreatedAt().Equal(expiresAt.Add(-entity.DefaultInvitationExpiration)) {
+		t.Errorf("CreatedAt = %v, want %v", invitation.CreatedAt(), expiresAt.Add(-entity.DefaultInvitationExpiration))
+	}
+
+	if !invitation.ExpiresAt().Equal(expiresAt) {
+		t.Errorf("ExpiresAt = %v, want %v", invitation.ExpiresAt(), expiresAt)
+	}
+
+	if !invitation.AcceptedAt().Equal(acceptedAt) {
+		t.Errorf("AcceptedAt = %v, want %v", invitation.AcceptedAt(), acceptedAt)
+	}
+}
+
+func testInvitationSaveOverwrites(t *testing.T, repo repository.InvitationRepository, id string) {
+	t.Helper()
+
+	saveInvitation(t, repo, id, []string{"member", "owner"}, contractNow().Add(time.Hour), contractNow())
+	saveFreshInvitation(t, repo, id)
+
+	invitation, err := repo.FindByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("FindByID() returned an error: %v", err)
+	}
+
+	if invitation.IsAccepted() {
+		t.Error("expected the acceptance of the previous invitation to be cleared")
+	}
+
+	if !slices.Equal(invitation.Roles(), []string{"member"}) {
+		t.Errorf("Roles = %v, want [member]", invitation.Roles())
+	}
+}
+
+func testInvitationDeleteIdempotent(t *testing.T, repo repository.InvitationRepository, id string) {
+	t.Helper()
+
+	saveFreshInvitation(t, repo, id)
+
+	for i := range 2 {
+		err := repo.Delete(context.Background(), id)
+		if err != nil {
+			t.Fatalf("Delete() call %d returned an error: %v", i+1, err)
+		}
+	}
+
+	_, err := repo.FindByID(context.Background(), id)
+	if !errors.Is(err, entity.ErrInvitationNotFound) {
+		t.Errorf("FindByID() after Delete error = %v, want ErrInvitationNotFound", err)
+	}
+}
+
+func testInvitationAcceptNotFound(t *testing.T, repo repository.InvitationRepository, id string) {
+	t.Helper()
+
+	_, err := repo.Accept(context.Background(), id)
+	if !errors.Is(err, entity.ErrInvitationNotFound) {
+		t.Errorf("Accept() error = %v, want ErrInvitationNotFound", err)
+	}
+}
+
+func testInvitationAcceptOnce(t *testing.T, repo repository.InvitationRepository, id string) {
+	t.Helper()
+
+	saveFreshInvitation(t, repo, id)
+
+	accepted, err := repo.Accept(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Accept() returned an error: %v", err)
+	}
+
+	if !accepted.IsAccepted() || !slices.Equal(accepted.Roles(), []string{"member"}) {
+		t.Errorf("Accept() returned %+v, want the accepted invitation", accepted)
+	}
+
+	stored, err := repo.FindByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("FindByID() returned an error: %v", err)
+	}
+
+	if !stored.IsAccepted() {
+		t.Error("expected the acceptance to be persisted")
+	}
+
+	_, err = repo.Accept(context.Background(), id)
+	if !errors.Is(err, entity.ErrInvitationAlreadyUsed) {
+		t.Errorf("second Accept() error = %v, want ErrInvitationAlreadyUsed", err)
+	}
+}
+
+func testInvitationAcceptExpired(t *testing.T, repo repository.InvitationRepository, id string) {
+	t.Helper()
+
+	saveInvitation(t, repo, id, nil, contractNow().Add(-time.Minute), time.Time{})
+
+	_, err := repo.Accept(context.Background(), id)
+	if !errors.Is(err, entity.ErrInvitationExpired) && !errors.Is(err, entity.ErrInvitationNotFound) {
+		t.Errorf("Accept() error = %v, want ErrInvitationExpired or ErrInvitationNotFound", err)
+	}
+}
+
+func testInvitationConcurrentAccept(t *testing.T, repo repository.InvitationRepository, id string) {
+	t.Helper()
+
+	saveFreshInvitation(t, repo, id)
+
+	results := make([]error, contractConcurrentCalls)
+	start := make(chan struct{})
+
+	var wg sync.WaitGroup
+
+	for i := range contractConcurrentCalls {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			<-start
+
+			_, results[i] = repo.Accept(context.Background(), id)
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	successes := 0
+
+	for _, err := range results {
+		switch {
+		case err == nil:
+			successes++
+		case errors.Is(err, entity.ErrInvitationAlreadyUsed):
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	if successes != 1 {
+		t.Errorf("expected exactly 1 successful acceptance, got %d", successes)
+	}
+}
@@ -0,0 +1,421 @@
+// Package repositorytest provides conformance tests for repository implementations.
+package repositorytest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/repository"
+	"custom_auth_api/internal/domain/vo/email"
+	"custom_auth_api/internal/domain/vo/ipaddress"
+	"custom_auth_api/internal/domain/vo/otp"
+)
+
+const (
+	contractCode            = "123456"
+	contractWrongCode       = "000000"
+	contractConcurrentCalls = 20
+
+	// contractIPHash is a keyed IP hash as produced by ipaddress.Hasher.
+	contractIPHash = "test:4f1c3a0d9e8b7a6f5e4d3c2b1a0f9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f"
+)
+
+// OTPSessionRepositoryFactory returns the repository under test.
+// It may return the same repository for every call; the suite uses a distinct
+// email address per test so tests do not observe each other's sessions.
+type OTPSessionRepositoryFactory func(t *testing.T) repository.OTPSessionRepository
+
+// TestOTPSessionRepository runs the repository.OTPSessionRepository contract against
+// the implementation returned by newRepository. Every backend must pass it:
+//
+//   - FindByEmail and VerifyAndConsume report entity.ErrSessionNotFound for a missing session
+//   - Save replaces any previous session for the same email
+//   - sessions are keyed by the canonical email, so differently cased spellings
+//     share one session while the address as entered is preserved
+//   - all session fields, including IPAddressHash and UserAgent, round-trip
+//   - Delete is idempotent
+//   - VerifyAndConsume consumes the session exactly once and evaluates at most
+//     entity.MaxVerificationAttempts wrong codes, even when called concurrently
+//   - an expired session is never verified (entity.ErrSessionExpired, or
+//     entity.ErrSessionNotFound for stores that evict on expiry)
+//
+// Sessions created by the suite are deleted on cleanup.
+func TestOTPSessionRepository(t *testing.T, newRepository OTPSessionRepositoryFactory) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo repository.OTPSessionRepository, userEmail *email.Email)
+	}{
+		{name: "FindByEmail returns ErrSessionNotFound", run: testFindNotFound},
+		{name: "Save then FindByEmail round-trips all fields", run: testRoundTrip},
+		{name: "Save overwrites the previous session", run: testSaveOverwrites},
+		{name: "sessions are keyed by the canonical email", run: testCanonicalEmailKey},
+		{name: "Delete is idempotent", run: testDeleteIdempotent},
+		{name: "VerifyAndConsume returns ErrSessionNotFound", run: testVerifyNotFound},
+		{name: "VerifyAndConsume consumes the session", run: testVerifyConsumes},
+		{name: "VerifyAndConsume records failed attempts", run: testVerifyRecordsAttempts},
+		{name: "VerifyAndConsume rejects expired sessions", run: testVerifyExpired},
+		{name: "concurrent valid codes consume exactly once", run: testConcurrentValidCode},
+		{name: "concurrent guesses are capped", run: testConcurrentGuesses},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepository(t)
+			userEmail := contractEmail(t)
+
+			t.Cleanup(func() {
+				err := repo.Delete(context.Background(), userEmail)
+				if err != nil {
+					t.Logf("Failed to clean up session for %s: %v", userEmail.Value, err)
+				}
+			})
+
+			tt.run(t, repo, userEmail)
+		})
+	}
+}
+
+// contractEmailNameLength keeps the readable part of contract addresses short enough
+// for the 64-octet local part limit once the prefix and the name hash are added.
+const contractEmailNameLength = 46
+
+// contractEmail derives a unique, valid address from the test name.
+func contractEmail(t *testing.T) *email.Email {
+	t.Helper()
+
+	local := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+
+		return '-'
+	}, strings.ToLower(t.Name()))
+
+	// Long test names are truncated; the hash of the full name keeps addresses unique
+	local = strings.Trim(local, "-")
+	if len(local) > contractEmailNameLength {
+		local = local[len(local)-contractEmailNameLength:]
+	}
+
+	sum := sha256.Sum256([]byte(t.Name()))
+
+	userEmail, err := email.NewEmail("contract-" + strings.Trim(local, "-") + "-" + hex.EncodeToString(sum[:4]) + "@example.com")
+	if err != nil {
+		t.Fatalf("Failed to create contract email: %v", err)
+	}
+
+	return userEmail
+}
+
+// saveSession stores a session for userEmail with the given state.
+func saveSession(
+	t *testing.T,
+	repo repository.OTPSessionRepository,
+	userEmail *email.Email,
+	code string,
+	attempts int,
+	createdAt time.Time,
+	ipHash *ipaddress.Hash,
+	userAgent string,
+) {
+	t.Helper()
+
+	otpCode, err := otp.FromString(code)
+	if err != nil {
+		t.Fatalf("Failed to create OTP: %v", err)
+	}
+
+	data, err := entity.NewRestorationData(
+		userEmail, otpCode, attempts, createdAt, createdAt.Add(entity.DefaultOTPExpiration), ipHash, userAgent,
+	)
+	if err != nil {
+		t.Fatalf("Failed to create restoration data: %v", err)
+	}
+
+	err = repo.Save(context.Background(), entity.RestoreOTPSession(data))
+	if err != nil {
+		t.Fatalf("Save() returned an error: %v", err)
+	}
+}
+
+// saveFreshSession stores a new session for userEmail with contractCode.
+func saveFreshSession(t *testing.T, repo repository.OTPSessionRepository, userEmail *email.Email) {
+	t.Helper()
+
+	saveSession(t, repo, userEmail, contractCode, 0, contractNow(), ipaddress.NewEmptyHash(), "")
+}
+
+// contractNow returns the current time at millisecond precision, which every backend can store exactly.
+func contractNow() time.Time {
+	return time.Now().Truncate(time.Millisecond)
+}
+
+func testFindNotFound(t *testing.T, repo repository.OTPSessionRepository, userEmail *email.Email) {
+	t.Helper()
+
+	_, err := repo.FindByEmail(context.Background(), userEmail)
+	if !errors.Is(err, entity.ErrSessionNotFound) {
+		t.Errorf("FindByEmail() error = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func testRoundTrip(t *testing.T, repo repository.OTPSessionRepository, userEmail *email.Email) {
+	t.Helper()
+
+	createdAt := contractNow()
+	ipHash := ipaddress.FromString(contractIPHash)
+	saveSession(t, repo, userEmail, contractCode, 2, createdAt, ipHash, "contract-agent/1.0")
+
+	session, err := repo.FindByEmail(context.Background(), userEmail)
+	if err != nil {
+		t.Fatalf("FindByEmail() returned an error: %v", err)
+	}
+
+	if session.Email().Value != userEmail.Value {
+		t.Errorf("Email = %s, want %s", session.Email().Value, userEmail.Value)
+	}
+
+	if !session.OTP().Matches(contractCode) || session.OTP().Matches(contractWrongCode) {
+		t.Error("restored OTP does not match the saved code")
+	}
+
+	if session.Attempts() != 2 {
+		t.Errorf("Attempts = %d, want 2", session.Attempts())
+	}
+
+	if !session.CreatedAt().Equal(createdAt) {
+		t.Errorf("CreatedAt = %v, want %v", session.CreatedAt(), createdAt)
+	}
+
+	if !session.ExpiresAt().Equal(createdAt.Add(entity.DefaultOTPExpiration)) {
+		t.Errorf("ExpiresAt = %v, want %v", session.ExpiresAt(), createdAt.Add(entity.DefaultOTPExpiration))
+	}
+
+	if session.IPAddressHash().String() != ipHash.String() {
+		t.Errorf("IPAddressHash = %s, want %s", session.IPAddressHash(), ipHash)
+	}
+
+	if session.UserAgent() != "contract-agent/1.0" {
+		t.Errorf("UserAgent = %q, want %q", session.UserAgent(), "contract-agent/1.0")
+	}
+}
+
+func testCanonicalEmailKey(t *testing.T, repo repository.OTPSessionRepository, userEmail *email.Email) {
+	t.Helper()
+
+	local, domain, _ := strings.Cut(userEmail.Value, "@")
+
+	displayEmail, err := email.NewEmail(strings.ToUpper(local[:1]) + local[1:] + "@" + strings.ToUpper(domain))
+	if err != nil {
+		t.Fatalf("Failed to create email: %v", err)
+	}
+
+	saveSession(t, repo, displayEmail, contractCode, 0, contractNow(), ipaddress.NewEmptyHash(), "")
+
+	session, err := repo.FindByEmail(context.Background(), userEmail)
+	if err != nil {
+		t.Fatalf("FindByEmail() with a differently cased address returned an error: %v", err)
+	}
+
+	if session.Email().Value != displayEmail.Value {
+		t.Errorf("Email = %s, want the address as entered %s", session.Email().Value, displayEmail.Value)
+	}
+
+	err = repo.VerifyAndConsume(context.Background(), userEmail, contractCode)
+	if err != nil {
+		t.Errorf("VerifyAndConsume() with a differently cased address returned an error: %v", err)
+	}
+}
+
+func testSaveOverwrites(t *testing.T, repo repository.OTPSessionRepository, userEmail *email.Email) {
+	t.Helper()
+
+	saveSession(t, repo, userEmail, contractCode, 2, contractNow(), ipaddress.FromString(contractIPHash), "old-agent")
+	saveSession(t, repo, userEmail, "654321", 0, contractNow(), ipaddress.NewEmptyHash(), "")
+
+	session, err := repo.FindByEmail(context.Background(), userEmail)
+	if err != nil {
+		t.Fatalf("FindByEmail() returned an error: %v", err)
+	}
+
+	if !session.OTP().Matches("654321") || session.OTP().Matches(contractCode) {
+		t.Error("expected the new code to replace the old one")
+	}
+
+	if session.Attempts() != 0 {
+		t.Errorf("Attempts = %d, want 0", session.Attempts())
+	}
+
+	if !session.IPAddressHash().IsEmpty() || session.UserAgent() != "" {
+		t.Error("expected audit fields of the previous session to be cleared")
+	}
+}
+
+func testDeleteIdempotent(t *testing.T, repo repository.OTPSessionRepository, userEmail *email.Email) {
+	t.Helper()
+
+	saveFreshSession(t, repo, userEmail)
+
+	for i := range 2 {
+		err := repo.Delete(context.Background(), userEmail)
+		if err != nil {
+			t.Fatalf("Delete() call %d returned an error: %v", i+1, err)
+		}
+	}
+
+	_, err := repo.FindByEmail(context.Background(), userEmail)
+	if !errors.Is(err, entity.ErrSessionNotFound) {
+		t.Errorf("FindByEmail() after Delete error = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func testVerifyNotFound(t *testing.T, repo repository.OTPSessionRepository, userEmail *email.Email) {
+	t.Helper()
+
+	err := repo.VerifyAndConsume(context.Background(), userEmail, contractCode)
+	if !errors.Is(err, entity.ErrSessionNotFound) {
+		t.Errorf("VerifyAndConsume() error = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func testVerifyConsumes(t *testing.T, repo repository.OTPSessionRepository, userEmail *email.Email) {
+	t.Helper()
+
+	saveFreshSession(t, repo, userEmail)
+
+	err := repo.VerifyAndConsume(context.Background(), userEmail, contractCode)
+	if err != nil {
+		t.Fatalf("VerifyAndConsume() returned an error: %v", err)
+	}
+
+	err = repo.VerifyAndConsume(context.Background(), userEmail, contractCode)
+	if !errors.Is(err, entity.ErrSessionNotFound) {
+		t.Errorf("second VerifyAndConsume() error = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func testVerifyRecordsAttempts(t *testing.T, repo repository.OTPSessionRepository, userEmail *email.Email) {
+	t.Helper()
+
+	saveFreshSession(t, repo, userEmail)
+
+	for attempt := 1; attempt <= entity.MaxVerificationAttempts; attempt++ {
+		err := repo.VerifyAndConsume(context.Background(), userEmail, contractWrongCode)
+		if !errors.Is(err, entity.ErrInvalidOTP) {
+			t.Fatalf("attempt %d: VerifyAndConsume() error = %v, want ErrInvalidOTP", attempt, err)
+		}
+
+		session, err := repo.FindByEmail(context.Background(), userEmail)
+		if err != nil {
+			t.Fatalf("FindByEmail() returned an error: %v", err)
+		}
+
+		if session.Attempts() != attempt {
+			t.Errorf("Attempts = %d, want %d", session.Attempts(), attempt)
+		}
+	}
+
+	err := repo.VerifyAndConsume(context.Background(), userEmail, contractCode)
+	if !errors.Is(err, entity.ErrTooManyAttempts) {
+		t.Errorf("VerifyAndConsume() after lockout error = %v, want ErrTooManyAttempts", err)
+	}
+}
+
+func testVerifyExpired(t *testing.T, repo repository.OTPSessionRepository, userEmail *email.Email) {
+	t.Helper()
+
+	createdAt := contractNow().Add(-entity.DefaultOTPExpiration - time.Minute)
+	saveSession(t, repo, userEmail, contractCode, 0, createdAt, ipaddress.NewEmptyHash(), "")
+
+	err := repo.VerifyAndConsume(context.Background(), userEmail, contractCode)
+	if !errors.Is(err, entity.ErrSessionExpired) && !errors.Is(err, entity.ErrSessionNotFound) {
+		t.Errorf("VerifyAndConsume() error = %v, want ErrSessionExpired or ErrSessionNotFound", err)
+	}
+}
+
+func testConcurrentValidCode(t *testing.T, repo repository.OTPSessionRepository, userEmail *email.Email) {
+	t.Helper()
+
+	saveFreshSession(t, repo, userEmail)
+
+	successes := 0
+
+	for _, err := range verifyConcurrently(repo, userEmail, contractCode) {
+		switch {
+		case err == nil:
+			successes++
+		case errors.Is(err, entity.ErrSessionNotFound):
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	if successes != 1 {
+		t.Errorf("expected exactly 1 successful verification, got %d", successes)
+	}
+}
+
+func testConcurrentGuesses(t *testing.T, repo repository.OTPSessionRepository, userEmail *email.Email) {
+	t.Helper()
+
+	saveFreshSession(t, repo, userEmail)
+
+	invalid := 0
+
+	for _, err := range verifyConcurrently(repo, userEmail, contractWrongCode) {
+		switch {
+		case errors.Is(err, entity.ErrInvalidOTP):
+			invalid++
+		case errors.Is(err, entity.ErrTooManyAttempts):
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	if invalid != entity.MaxVerificationAttempts {
+		t.Errorf("expected %d evaluated guesses, got %d", entity.MaxVerificationAttempts, invalid)
+	}
+
+	session, err := repo.FindByEmail(context.Background(), userEmail)
+	if err != nil {
+		t.Fatalf("FindByEmail() returned an error: %v", err)
+	}
+
+	if session.Attempts() != entity.MaxVerificationAttempts {
+		t.Errorf("Attempts = %d, want %d", session.Attempts(), entity.MaxVerificationAttempts)
+	}
+}
+
+// verifyConcurrently calls VerifyAndConsume from contractConcurrentCalls goroutines at once.
+func verifyConcurrently(repo repository.OTPSessionRepository, userEmail *email.Email, code string) []error {
+	results := make([]error, contractConcurrentCalls)
+	start := make(chan struct{})
+
+	var wg sync.WaitGroup
+
+	for i := range contractConcurrentCalls {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			<-start
+
+			results[i] = repo.VerifyAndConsume(context.Background(), userEmail, code)
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,76 @@
+package email
+
+import (
+	"slices"
+	"strings"
+)
+
+// Options configures how NewEmailWithOptions validates an address and derives its canonical form.
+type Options struct {
+	// ProviderRules fold provider-specific aliases of a mailbox onto one canonical form.
+	ProviderRules []ProviderRule
+
+	// AllowQuotedLocalPart accepts quoted local parts such as "john doe"@example.com.
+	// Many mail systems mishandle them, so they are rejected by default.
+	AllowQuotedLocalPart bool
+
+	// AllowUTF8LocalPart accepts non-ASCII local parts (RFC 6531 SMTPUTF8).
+	// Only enable it if the outgoing mail server supports SMTPUTF8.
+	AllowUTF8LocalPart bool
+}
+
+// ProviderRule describes how a mail provider treats aliases of a mailbox.
+type ProviderRule struct {
+	// Domains the rule applies to, in lowercase ASCII form.
+	Domains []string
+
+	// CanonicalDomain replaces any of Domains in the canonical form ("" keeps the domain).
+	CanonicalDomain string
+
+	// IgnoreDots removes dots from the local part ("j.doe" and "jdoe" are one mailbox).
+	IgnoreDots bool
+
+	// StripPlusTag removes a "+tag" suffix from the local part.
+	StripPlusTag bool
+}
+
+// GmailRule returns the provider rule of Gmail, which ignores dots and "+tag"
+// suffixes and treats googlemail.com as an alias of gmail.com.
+func GmailRule() ProviderRule {
+	return ProviderRule{
+		Domains:         []string{"gmail.com", "googlemail.com"},
+		CanonicalDomain: "gmail.com",
+		IgnoreDots:      true,
+		StripPlusTag:    true,
+	}
+}
+
+// canonicalize returns the canonical form of a parsed address. domain must already be in ASCII form.
+//
+// The local part is lowercased for every domain. RFC 5321 lets a server treat it as case-sensitive,
+// but the identity backends treat case variants as one account, so sessions and limits must too.
+func canonicalize(local, domain string, rules []ProviderRule) string {
+	local = strings.ToLower(local)
+
+	for _, rule := range rules {
+		if !slices.Contains(rule.Domains, domain) {
+			continue
+		}
+
+		if rule.StripPlusTag {
+			local, _, _ = strings.Cut(local, "+")
+		}
+
+		if rule.IgnoreDots {
+			local = strings.ReplaceAll(local, ".", "")
+		}
+
+		if rule.CanonicalDomain != "" {
+			domain = rule.CanonicalDomain
+		}
+
+		break
+	}
+
+	return local + "@" + domain
+}
@@ -0,0 +1,257 @@
+package email
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// Length limits from RFC 5321 section 4.5.3.1, in octets.
+const (
+	maxAddressLength     = 254 // Forward-path of 256 octets minus the angle brackets
+	maxLocalPartLength   = 64
+	maxDomainLength      = 253 // 255 octets on the wire minus the length and root label octets
+	maxDomainLabelLength = 63
+)
+
+// ErrInvalidEmailFormat is matched by every *FormatError, so callers that do not care
+// about the reason can keep testing errors.Is(err, ErrInvalidEmailFormat).
+var ErrInvalidEmailFormat = errors.New("invalid email format")
+
+// FormatError reports why an address is malformed.
+// Compare against the Err* reasons below with errors.Is.
+type FormatError struct {
+	Reason string
+}
+
+// Error returns the reason prefixed with "invalid email format".
+func (e *FormatError) Error() string {
+	return ErrInvalidEmailFormat.Error() + ": " + e.Reason
+}
+
+// Is reports whether target is ErrInvalidEmailFormat.
+func (e *FormatError) Is(target error) bool {
+	return target == ErrInvalidEmailFormat
+}
+
+// Reasons an address can be rejected.
+var (
+	ErrEmptyAddress         = &FormatError{Reason: "address is empty"}
+	ErrInvalidEncoding      = &FormatError{Reason: "address is not valid UTF-8"}
+	ErrMissingAtSign        = &FormatError{Reason: "address has no @ sign"}
+	ErrAddressTooLong       = &FormatError{Reason: "address exceeds 254 octets"}
+	ErrLocalPartEmpty       = &FormatError{Reason: "local part is empty"}
+	ErrLocalPartTooLong     = &FormatError{Reason: "local part exceeds 64 octets"}
+	ErrInvalidDotPlacement  = &FormatError{Reason: "local part has a leading, trailing or consecutive dot"}
+	ErrInvalidLocalPartChar = &FormatError{Reason: "local part contains a character that is not allowed"}
+	ErrQuotedLocalPart      = &FormatError{Reason: "quoted local parts are not accepted"}
+	ErrInvalidQuotedString  = &FormatError{Reason: "quoted local part is malformed"}
+	ErrUTF8LocalPart        = &FormatError{Reason: "non-ASCII local parts are not accepted"}
+	ErrDomainEmpty          = &FormatError{Reason: "domain is empty"}
+	ErrAddressLiteral       = &FormatError{Reason: "address literals are not accepted"}
+	ErrInvalidDomain        = &FormatError{Reason: "domain is not a valid host name"}
+	ErrDomainTooLong        = &FormatError{Reason: "domain exceeds 253 octets"}
+	ErrDomainLabelTooLong   = &FormatError{Reason: "domain label exceeds 63 octets"}
+	ErrDomainNotQualified   = &FormatError{Reason: "domain must have at least two labels"}
+)
+
+// idnaProfile converts domains to their ASCII (punycode) form for lookup.
+var idnaProfile = idna.Lookup
+
+// parse validates address (RFC 5321 with the RFC 6531 internationalized extensions)
+// and returns its local part and the domain in lowercase ASCII form.
+func parse(address string, options Options) (string, string, error) {
+	if address == "" {
+		return "", "", ErrEmptyAddress
+	}
+
+	if !utf8.ValidString(address) {
+		return "", "", ErrInvalidEncoding
+	}
+
+	// The domain cannot contain "@", a quoted local part can
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return "", "", ErrMissingAtSign
+	}
+
+	local, domain := address[:at], address[at+1:]
+
+	err := validateLocalPart(local, options)
+	if err != nil {
+		return "", "", err
+	}
+
+	asciiDomain, err := parseDomain(domain)
+	if err != nil {
+		return "", "", err
+	}
+
+	if len(local)+1+len(asciiDomain) > maxAddressLength {
+		return "", "", ErrAddressTooLong
+	}
+
+	return local, asciiDomain, nil
+}
+
+// validateLocalPart checks a dot-atom or, if allowed, a quoted-string local part.
+func validateLocalPart(local string, options Options) error {
+	if local == "" {
+		return ErrLocalPartEmpty
+	}
+
+	if len(local) > maxLocalPartLength {
+		return ErrLocalPartTooLong
+	}
+
+	if strings.HasPrefix(local, `"`) {
+		if !options.AllowQuotedLocalPart {
+			return ErrQuotedLocalPart
+		}
+
+		return validateQuotedString(local, options)
+	}
+
+	for atom := range strings.SplitSeq(local, ".") {
+		if atom == "" {
+			return ErrInvalidDotPlacement
+		}
+
+		for _, r := range atom {
+			err := checkLocalRune(r, isAtext(r), options)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateQuotedString checks a quoted-string local part such as "john doe".
+func validateQuotedString(local string, options Options) error {
+	if len(local) < 2 || !strings.HasSuffix(local, `"`) {
+		return ErrInvalidQuotedString
+	}
+
+	escaped := false
+
+	for _, r := range local[1 : len(local)-1] {
+		if escaped {
+			// quoted-pair: backslash followed by VCHAR or SP
+			if r < ' ' || r == 0x7f {
+				return ErrInvalidQuotedString
+			}
+
+			escaped = false
+
+			continue
+		}
+
+		switch r {
+		case '\\':
+			escaped = true
+		case '"':
+			return ErrInvalidQuotedString
+		default:
+			err := checkLocalRune(r, r >= ' ' && r < 0x7f, options)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if escaped {
+		return ErrInvalidQuotedString
+	}
+
+	return nil
+}
+
+// checkLocalRune accepts allowedASCII characters and, with SMTPUTF8 enabled, any non-ASCII character.
+func checkLocalRune(r rune, allowedASCII bool, options Options) error {
+	if r < utf8.RuneSelf {
+		if !allowedASCII {
+			return ErrInvalidLocalPartChar
+		}
+
+		return nil
+	}
+
+	if !options.AllowUTF8LocalPart {
+		return ErrUTF8LocalPart
+	}
+
+	return nil
+}
+
+// isAtext reports whether r is an RFC 5322 atext character.
+func isAtext(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	default:
+		return strings.ContainsRune("!#$%&'*+-/=?^_`{|}~", r)
+	}
+}
+
+// parseDomain validates a host name, internationalized or not, and returns its
+// lowercase ASCII form. Address literals ("[192.0.2.1]") are rejected.
+func parseDomain(domain string) (string, error) {
+	if domain == "" {
+		return "", ErrDomainEmpty
+	}
+
+	if strings.HasPrefix(domain, "[") {
+		return "", ErrAddressLiteral
+	}
+
+	ascii, err := idnaProfile.ToASCII(strings.ToLower(domain))
+	if err != nil {
+		return "", ErrInvalidDomain
+	}
+
+	if len(ascii) > maxDomainLength {
+		return "", ErrDomainTooLong
+	}
+
+	labels := strings.Split(ascii, ".")
+	if len(labels) < 2 {
+		return "", ErrDomainNotQualified
+	}
+
+	for _, label := range labels {
+		if len(label) > maxDomainLabelLength {
+			return "", ErrDomainLabelTooLong
+		}
+
+		if !isHostLabel(label) {
+			return "", ErrInvalidDomain
+		}
+	}
+
+	if strings.Trim(labels[len(labels)-1], "0123456789") == "" {
+		// An all-numeric top-level label would make the domain look like an IPv4 address
+		return "", ErrInvalidDomain
+	}
+
+	return ascii, nil
+}
+
+// isHostLabel reports whether label is a non-empty letter-digit-hyphen label
+// that neither starts nor ends with a hyphen.
+func isHostLabel(label string) bool {
+	if label == "" || label[0] == '-' || label[len(label)-1] == '-' {
+		return false
+	}
+
+	for _, r := range label {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,279 @@
+package email_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"custom_auth_api/internal/domain/vo/email"
+)
+
+func TestNewEmailWithOptions_Syntax(t *testing.T) {
+	t.Parallel()
+
+	strict := email.Options{ProviderRules: nil, AllowQuotedLocalPart: false, AllowUTF8LocalPart: false}
+	quoted := email.Options{ProviderRules: nil, AllowQuotedLocalPart: true, AllowUTF8LocalPart: false}
+	smtpUTF8 := email.Options{ProviderRules: nil, AllowQuotedLocalPart: false, AllowUTF8LocalPart: true}
+
+	testCases := []struct {
+		name              string
+		address           string
+		options           email.Options
+		expectedErr       error
+		expectedCanonical string
+	}{
+		{
+			name:              "accepts atext special characters",
+			address:           "o'brien!#$%&*/=?^_`{|}~-@example.com",
+			options:           strict,
+			expectedErr:       nil,
+			expectedCanonical: "o'brien!#$%&*/=?^_`{|}~-@example.com",
+		},
+		{
+			name:              "accepts long top-level domains",
+			address:           "user@example.photography",
+			options:           strict,
+			expectedErr:       nil,
+			expectedCanonical: "user@example.photography",
+		},
+		{
+			name:              "converts internationalized domains to punycode",
+			address:           "user@Bücher.example",
+			options:           strict,
+			expectedErr:       nil,
+			expectedCanonical: "user@xn--bcher-kva.example",
+		},
+		{
+			name:              "accepts quoted local parts when allowed",
+			address:           `"john \"jd\" doe"@example.com`,
+			options:           quoted,
+			expectedErr:       nil,
+			expectedCanonical: `"john \"jd\" doe"@example.com`,
+		},
+		{
+			name:              "accepts non-ASCII local parts with SMTPUTF8",
+			address:           "用户@例子.测试",
+			options:           smtpUTF8,
+			expectedErr:       nil,
+			expectedCanonical: "用户@xn--fsqu00a.xn--0zwm56d",
+		},
+		{
+			name:              "rejects an empty address",
+			address:           "   ",
+			options:           strict,
+			expectedErr:       email.ErrEmptyAddress,
+			expectedCanonical: "",
+		},
+		{
+			name:              "rejects invalid UTF-8",
+			address:           "user\xff@example.com",
+			options:           strict,
+			expectedErr:       email.ErrInvalidEncoding,
+			expectedCanonical: "",
+		},
+		{
+			name:              "rejects an address without @",
+			address:           "user.example.com",
+			options:           strict,
+			expectedErr:       email.ErrMissingAtSign,
+			expectedCanonical: "",
+		},
+		{
+			name:              "rejects an empty local part",
+			address:           "@example.com",
+			options:           strict,
+			expectedErr:       email.ErrLocalPartEmpty,
+			expectedCanonical: "",
+		},
+		{
+			name:              "rejects a local part over 64 octets",
+			address:           strings.Repeat("a", 65) + "@example.com",
+			options:           strict,
+			expectedErr:       email.ErrLocalPartTooLong,
+			expectedCanonical: "",
+		},
+		{
+			name:              "rejects a leading dot",
+			address:           ".user@example.com",
+			options:           strict,
+			expectedErr:       email.ErrInvalidDotPlacement,
+			expectedCanonical: "",
+		},
+		{
+			name:              "rejects consecutive dots",
+			address:           "first..last@example.com",
+			options:           strict,
+			expectedErr:       email.ErrInvalidDotPlacement,
+			expectedCanonical: "",
+		},
+		{
+			name:              "rejects characters outside atext",
+			address:           "user(comment)@example.com",
+			options:           strict,
+			expectedErr:       email.ErrInvalidLocalPartChar,
+			expectedCanonical: "",
+		},
+		{
+			name:              "rejects quoted local parts by default",
+			address:           `"john doe"@example.com`,
+			options:           strict,
+			expectedErr:       email.ErrQuotedLocalPart,
+			expectedCanonical: "",
+		},
+		{
+			name:              "rejects an unescaped quote in a quoted local part",
+			address:           `"john"doe"@example.com`,
+			options:           quoted,
+			expectedErr:       email.ErrInvalidQuotedString,
+			expectedCanonical: "",
+		},
+		{
+			name:              "rejects non-ASCII local parts without SMTPUTF8",
+			address:           "用户@example.com",
+			options:           strict,
+			expectedErr:       email.ErrUTF8LocalPart,
+			expectedCanonical: "",
+		},
+		{
+			name:              "rejects an empty domain",
+			address:           "user@",
+			options:           strict,
+			expectedErr:       email.ErrDomainEmpty,
+			expectedCanonical: "",
+		},
+		{
+			name:              "rejects address literals",
+			address:           "user@[192.0.2.1]",
+			options:           strict,
+			expectedErr:       email.ErrAddressLiteral,
+			expectedCanonical: "",
+		},
+		{
+			name:              "rejects single-label domains",
+			address:           "user@localhost",
+			options:           strict,
+			expectedErr:       email.ErrDomainNotQualified,
+			expectedCanonical: "",
+		},
+		{
+			name:              "rejects labels starting with a hyphen",
+			address:           "user@-example.com",
+			options:           strict,
+			expectedErr:       email.ErrInvalidDomain,
+			expectedCanonical: "",
+		},
+		{
+			name:              "rejects underscores in the domain",
+			address:           "user@exa_mple.com",
+			options:           strict,
+			expectedErr:       email.ErrInvalidDomain,
+			expectedCanonical: "",
+		},
+		{
+			name:              "rejects a trailing dot in the domain",
+			address:           "user@example.com.",
+			options:           strict,
+			expectedErr:       email.ErrInvalidDomain,
+			expectedCanonical: "",
+		},
+		{
+			name:              "rejects an all-numeric top-level label",
+			address:           "user@192.0.2.1",
+			options:           strict,
+			expectedErr:       email.ErrInvalidDomain,
+			expectedCanonical: "",
+		},
+		{
+			name:              "rejects domain labels over 63 octets",
+			address:           "user@" + strings.Repeat("a", 64) + ".com",
+			options:           strict,
+			expectedErr:       email.ErrDomainLabelTooLong,
+			expectedCanonical: "",
+		},
+		{
+			name:              "rejects domains over 253 octets",
+			address:           "user@" + strings.Repeat(strings.Repeat("a", 63)+".", 4) + "com",
+			options:           strict,
+			expectedErr:       email.ErrDomainTooLong,
+			expectedCanonical: "",
+		},
+		{
+			name:              "rejects addresses over 254 octets",
+			address:           strings.Repeat("a", 64) + "@" + strings.Repeat(strings.Repeat("a", 62)+".", 3) + "com",
+			options:           strict,
+			expectedErr:       email.ErrAddressTooLong,
+			expectedCanonical: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			address, err := email.NewEmailWithOptions(tc.address, tc.options)
+
+			// Assert
+			if tc.expectedErr != nil {
+				if !errors.Is(err, tc.expectedErr) {
+					t.Fatalf("expected %v, got %v", tc.expectedErr, err)
+				}
+
+				if !errors.Is(err, email.ErrInvalidEmailFormat) {
+					t.Errorf("expected %v to match ErrInvalidEmailFormat", err)
+				}
+
+				if address != nil {
+					t.Error("expected nil email on error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if address.Canonical() != tc.expectedCanonical {
+				t.Errorf("expected canonical %q, got %q", tc.expectedCanonical, address.Canonical())
+			}
+		})
+	}
+}
+
+func TestFormatError_Reason(t *testing.T) {
+	t.Parallel()
+
+	// Act
+	_, err := email.NewEmail("first..last@example.com")
+
+	// Assert
+	var formatErr *email.FormatError
+	if !errors.As(err, &formatErr) {
+		t.Fatalf("expected *FormatError, got %T", err)
+	}
+
+	if formatErr.Reason != email.ErrInvalidDotPlacement.Reason {
+		t.Errorf("expected reason %q, got %q", email.ErrInvalidDotPlacement.Reason, formatErr.Reason)
+	}
+
+	if errors.Is(err, email.ErrLocalPartTooLong) {
+		t.Error("expected error not to match a different reason")
+	}
+}
+
+func TestFromString(t *testing.T) {
+	t.Parallel()
+
+	// Act
+	address, err := email.FromString(`"用户 name"@Example.com`)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("expected stored addresses to be restored regardless of options, got %v", err)
+	}
+
+	if address.Canonical() != `"用户 name"@example.com` {
+		t.Errorf("expected canonical %q, got %q", `"用户 name"@example.com`, address.Canonical())
+	}
+}
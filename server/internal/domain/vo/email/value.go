@@ -1,29 +1,68 @@
 package email
 
 import (
-	"errors"
-	"regexp"
+	"strings"
 )
 
 // Email represents an email address value object.
+//
+// Value is the address as entered (surrounding whitespace removed) and is used
+// for display and sending. Canonical returns the form used to identify the
+// mailbox, e.g. as a storage key or rate limiting key.
 type Email struct {
-	Value string
+	Value     string
+	canonical string
 }
 
-const emailRegex = `^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,4}$`
+// NewEmail creates a new Email value object with the default options: ASCII or
+// internationalized domains, dot-atom ASCII local parts and no provider-specific canonicalization.
+// Returns a *FormatError (matching ErrInvalidEmailFormat) describing why a malformed address was rejected.
+func NewEmail(email string) (*Email, error) {
+	return NewEmailWithOptions(email, Options{
+		ProviderRules:        nil,
+		AllowQuotedLocalPart: false,
+		AllowUTF8LocalPart:   false,
+	})
+}
 
-var (
-	ErrInvalidEmailFormat = errors.New("invalid email format")
-	emailPattern          = regexp.MustCompile(emailRegex)
-)
+// NewEmailWithOptions creates a new Email value object validated and canonicalized according to options.
+func NewEmailWithOptions(email string, options Options) (*Email, error) {
+	email = strings.TrimSpace(email)
 
-// NewEmail creates a new Email value object.
-func NewEmail(email string) (*Email, error) {
-	if !emailPattern.MatchString(email) {
-		return nil, ErrInvalidEmailFormat
+	local, domain, err := parse(email, options)
+	if err != nil {
+		return nil, err
 	}
 
-	return &Email{Value: email}, nil
+	return &Email{Value: email, canonical: canonicalize(local, domain, options.ProviderRules)}, nil
+}
+
+// Domain returns the domain of the canonical form (lowercase IDNA ASCII, provider aliases folded).
+func (e *Email) Domain() string {
+	canonical := e.Canonical()
+
+	return canonical[strings.LastIndex(canonical, "@")+1:]
+}
+
+// FromString reconstructs an Email from a previously validated address.
+// This is used by repository implementations when loading from persistent storage, so it accepts
+// quoted and non-ASCII local parts regardless of the options the address was first accepted with.
+func FromString(email string) (*Email, error) {
+	return NewEmailWithOptions(email, Options{
+		ProviderRules:        nil,
+		AllowQuotedLocalPart: true,
+		AllowUTF8LocalPart:   true,
+	})
 }
 
-// Verify if it has become the general format of an email.
+// Canonical returns the canonical form of the address: lowercased, with the domain
+// in IDNA ASCII (punycode) form and provider aliases folded if configured.
+// Two Email values with the same canonical form refer to the same mailbox.
+func (e *Email) Canonical() string {
+	if e.canonical == "" {
+		// Constructed without NewEmail; fall back to the case-folded address
+		return strings.ToLower(e.Value)
+	}
+
+	return e.canonical
+}
@@ -74,3 +74,95 @@ func TestNewEmail(t *testing.T) {
 		})
 	}
 }
+
+func TestEmail_Canonical(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name              string
+		address           string
+		options           email.Options
+		expectedValue     string
+		expectedCanonical string
+	}{
+		{
+			name:              "trims whitespace and folds case of both parts",
+			address:           "  User@Example.COM ",
+			options:           email.Options{ProviderRules: nil, AllowQuotedLocalPart: false, AllowUTF8LocalPart: false},
+			expectedValue:     "User@Example.COM",
+			expectedCanonical: "user@example.com",
+		},
+		{
+			name:              "keeps dots and tags without provider rules",
+			address:           "j.doe+news@gmail.com",
+			options:           email.Options{ProviderRules: nil, AllowQuotedLocalPart: false, AllowUTF8LocalPart: false},
+			expectedValue:     "j.doe+news@gmail.com",
+			expectedCanonical: "j.doe+news@gmail.com",
+		},
+		{
+			name:              "folds Gmail dots and tags",
+			address:           "J.Doe+news@GoogleMail.com",
+			options:           email.Options{ProviderRules: []email.ProviderRule{email.GmailRule()}, AllowQuotedLocalPart: false, AllowUTF8LocalPart: false},
+			expectedValue:     "J.Doe+news@GoogleMail.com",
+			expectedCanonical: "jdoe@gmail.com",
+		},
+		{
+			name:              "does not apply provider rules to other domains",
+			address:           "j.doe+news@example.com",
+			options:           email.Options{ProviderRules: []email.ProviderRule{email.GmailRule()}, AllowQuotedLocalPart: false, AllowUTF8LocalPart: false},
+			expectedValue:     "j.doe+news@example.com",
+			expectedCanonical: "j.doe+news@example.com",
+		},
+		{
+			name:              "folds local part case for domains without provider rules",
+			address:           "J.Doe@Example.com",
+			options:           email.Options{ProviderRules: []email.ProviderRule{email.GmailRule()}, AllowQuotedLocalPart: false, AllowUTF8LocalPart: false},
+			expectedValue:     "J.Doe@Example.com",
+			expectedCanonical: "j.doe@example.com",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			address, err := email.NewEmailWithOptions(tc.address, tc.options)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if address.Value != tc.expectedValue {
+				t.Errorf("expected value %q, got %q", tc.expectedValue, address.Value)
+			}
+
+			if address.Canonical() != tc.expectedCanonical {
+				t.Errorf("expected canonical %q, got %q", tc.expectedCanonical, address.Canonical())
+			}
+		})
+	}
+}
+
+func TestEmail_Domain(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	address, err := email.NewEmailWithOptions("J.Doe@GoogleMail.com", email.Options{
+		ProviderRules:        []email.ProviderRule{email.GmailRule()},
+		AllowQuotedLocalPart: false,
+		AllowUTF8LocalPart:   false,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Act
+	domain := address.Domain()
+
+	// Assert
+	if domain != "gmail.com" {
+		t.Errorf("expected domain %q, got %q", "gmail.com", domain)
+	}
+}
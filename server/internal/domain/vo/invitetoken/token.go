@@ -0,0 +1,125 @@
+// Package invitetoken signs and verifies the tokens sent in invitation emails.
+package invitetoken
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"custom_auth_api/internal/pkg/keyset"
+)
+
+// MinSigningKeyLength is the minimum HMAC key length in bytes (256 bits).
+const MinSigningKeyLength = keyset.MinKeyLength
+
+// tokenSeparator separates the token fields. Key IDs and invitation IDs must not contain it.
+const tokenSeparator = "."
+
+// Token errors.
+var (
+	ErrInvalidInvitationID = errors.New("invitation id must be non-empty and must not contain '.'")
+
+	// ErrInvalidToken is returned for tokens that are malformed, were not signed with
+	// a key in the key set, or have been tampered with.
+	ErrInvalidToken = errors.New("invalid invitation token")
+
+	// ErrTokenExpired is returned for correctly signed tokens past their expiry.
+	ErrTokenExpired = errors.New("invitation token has expired")
+)
+
+// Claims are the facts carried by an invitation token.
+type Claims struct {
+	InvitationID string
+	ExpiresAt    time.Time
+}
+
+// Signer issues and verifies invitation tokens.
+//
+// A token is "<keyID>.<invitationID>.<expiry Unix seconds>.<base64url HMAC-SHA256>",
+// safe to embed in URLs. New tokens are always signed with the active key; verification
+// looks up the key by the token's key ID, so rotating keys only requires adding the new
+// key, making it active, and removing the old key once its invitations have expired.
+//
+// The token only proves that the server issued it: whether the invitation still exists
+// and has not been accepted is checked against the repository.
+type Signer struct {
+	keys *keyset.Keyset
+}
+
+// NewSigner creates a Signer from a key set and the ID of the key used for new tokens.
+// Returns an error wrapping one of the keyset errors if the key set is not acceptable.
+func NewSigner(activeKeyID string, keys map[string][]byte) (*Signer, error) {
+	signingKeys, err := keyset.New(activeKeyID, keys, tokenSeparator)
+	if err != nil {
+		return nil, fmt.Errorf("invalid invitation token signing keys: %w", err)
+	}
+
+	return &Signer{keys: signingKeys}, nil
+}
+
+// Sign returns a token for the claims, signed with the active key.
+// The expiry is truncated to whole seconds.
+// Returns an error wrapping ErrInvalidInvitationID if the ID cannot be embedded.
+func (s *Signer) Sign(claims Claims) (string, error) {
+	if claims.InvitationID == "" || strings.Contains(claims.InvitationID, tokenSeparator) {
+		return "", fmt.Errorf("%w (got %q)", ErrInvalidInvitationID, claims.InvitationID)
+	}
+
+	payload := strings.Join(
+		[]string{s.keys.ActiveKeyID(), claims.InvitationID, strconv.FormatInt(claims.ExpiresAt.Unix(), 10)},
+		tokenSeparator,
+	)
+
+	return payload + tokenSeparator + base64.RawURLEncoding.EncodeToString(s.keys.Sum(payload)), nil
+}
+
+// Verify checks the token's signature and expiry against now and returns its claims.
+// Returns ErrInvalidToken if the token is malformed or its signature does not match,
+// or ErrTokenExpired if it is correctly signed but expired.
+func (s *Signer) Verify(token string, now time.Time) (Claims, error) {
+	payload, encodedMAC, found := cutLast(token, tokenSeparator)
+	if !found {
+		return Claims{}, ErrInvalidToken
+	}
+
+	fields := strings.Split(payload, tokenSeparator)
+	if len(fields) != 3 || fields[1] == "" {
+		return Claims{}, ErrInvalidToken
+	}
+
+	expectedMAC, ok := s.keys.SumWith(fields[0], payload)
+	if !ok {
+		return Claims{}, ErrInvalidToken
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil || !hmac.Equal(mac, expectedMAC) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	expiresUnix, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+
+	claims := Claims{InvitationID: fields[1], ExpiresAt: time.Unix(expiresUnix, 0)}
+	if now.After(claims.ExpiresAt) {
+		return Claims{}, ErrTokenExpired
+	}
+
+	return claims, nil
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (string, string, bool) {
+	index := strings.LastIndex(s, sep)
+	if index < 0 {
+		return s, "", false
+	}
+
+	return s[:index], s[index+len(sep):], true
+}
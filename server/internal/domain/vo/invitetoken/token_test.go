@@ -0,0 +1,194 @@
+package invitetoken_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"custom_auth_api/internal/domain/vo/invitetoken"
+	"custom_auth_api/internal/pkg/keyset"
+)
+
+var (
+	testKeyV1 = bytes.Repeat([]byte("1"), invitetoken.MinSigningKeyLength)
+	testKeyV2 = bytes.Repeat([]byte("2"), invitetoken.MinSigningKeyLength)
+)
+
+func mustSigner(t *testing.T, activeKeyID string, keys map[string][]byte) *invitetoken.Signer {
+	t.Helper()
+
+	signer, err := invitetoken.NewSigner(activeKeyID, keys)
+	if err != nil {
+		t.Fatalf("NewSigner() returned an error: %v", err)
+	}
+
+	return signer
+}
+
+func mustSign(t *testing.T, signer *invitetoken.Signer, claims invitetoken.Claims) string {
+	t.Helper()
+
+	token, err := signer.Sign(claims)
+	if err != nil {
+		t.Fatalf("Sign() returned an error: %v", err)
+	}
+
+	return token
+}
+
+func TestNewSigner_Validation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		activeKeyID string
+		keys        map[string][]byte
+		wantErr     error
+	}{
+		{name: "no keys", activeKeyID: "v1", keys: nil, wantErr: keyset.ErrKeyRequired},
+		{
+			name:        "key too short",
+			activeKeyID: "v1",
+			keys:        map[string][]byte{"v1": []byte("short")},
+			wantErr:     keyset.ErrKeyTooShort,
+		},
+		{
+			name:        "key id contains separator",
+			activeKeyID: "v.1",
+			keys:        map[string][]byte{"v.1": testKeyV1},
+			wantErr:     keyset.ErrInvalidKeyID,
+		},
+		{
+			name:        "active key missing",
+			activeKeyID: "v2",
+			keys:        map[string][]byte{"v1": testKeyV1},
+			wantErr:     keyset.ErrActiveKeyNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			_, err := invitetoken.NewSigner(tt.activeKeyID, tt.keys)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewSigner() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSigner_SignAndVerify(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	signer := mustSigner(t, "v1", map[string][]byte{"v1": testKeyV1})
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	// Act
+	token := mustSign(t, signer, invitetoken.Claims{InvitationID: "abc123", ExpiresAt: expiresAt})
+	claims, err := signer.Verify(token, time.Now())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Verify() returned an error: %v", err)
+	}
+	if !strings.HasPrefix(token, "v1.abc123.") {
+		t.Errorf("token = %q, want the key ID and invitation ID in clear", token)
+	}
+	if claims.InvitationID != "abc123" || !claims.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("claims = %+v, want abc123 expiring at %v", claims, expiresAt)
+	}
+}
+
+func TestSigner_Sign_InvalidInvitationID(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	signer := mustSigner(t, "v1", map[string][]byte{"v1": testKeyV1})
+
+	for _, id := range []string{"", "a.b"} {
+		// Act
+		_, err := signer.Sign(invitetoken.Claims{InvitationID: id, ExpiresAt: time.Now()})
+
+		// Assert
+		if !errors.Is(err, invitetoken.ErrInvalidInvitationID) {
+			t.Errorf("Sign(%q) error = %v, want ErrInvalidInvitationID", id, err)
+		}
+	}
+}
+
+func TestSigner_Verify_Rejects(t *testing.T) {
+	t.Parallel()
+
+	signer := mustSigner(t, "v1", map[string][]byte{"v1": testKeyV1})
+	otherSigner := mustSigner(t, "v1", map[string][]byte{"v1": testKeyV2})
+	claims := invitetoken.Claims{InvitationID: "abc123", ExpiresAt: time.Now().Add(time.Hour)}
+	token := mustSign(t, signer, claims)
+	signature := token[strings.LastIndex(token, ".")+1:]
+
+	tests := []struct {
+		name    string
+		token   string
+		now     time.Time
+		wantErr error
+	}{
+		{name: "empty", token: "", now: time.Now(), wantErr: invitetoken.ErrInvalidToken},
+		{name: "missing fields", token: "v1.abc123." + signature, now: time.Now(), wantErr: invitetoken.ErrInvalidToken},
+		{name: "other key", token: mustSign(t, otherSigner, claims), now: time.Now(), wantErr: invitetoken.ErrInvalidToken},
+		{
+			name:    "tampered invitation id",
+			token:   strings.Replace(token, "abc123", "abc124", 1),
+			now:     time.Now(),
+			wantErr: invitetoken.ErrInvalidToken,
+		},
+		{
+			name:    "unknown key id",
+			token:   strings.Replace(token, "v1.", "v9.", 1),
+			now:     time.Now(),
+			wantErr: invitetoken.ErrInvalidToken,
+		},
+		{name: "expired", token: token, now: time.Now().Add(2 * time.Hour), wantErr: invitetoken.ErrTokenExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			_, err := signer.Verify(tt.token, tt.now)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSigner_Verify_AfterRotation(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	oldSigner := mustSigner(t, "v1", map[string][]byte{"v1": testKeyV1})
+	token := mustSign(t, oldSigner, invitetoken.Claims{InvitationID: "abc123", ExpiresAt: time.Now().Add(time.Hour)})
+	rotated := mustSigner(t, "v2", map[string][]byte{"v1": testKeyV1, "v2": testKeyV2})
+	retired := mustSigner(t, "v2", map[string][]byte{"v2": testKeyV2})
+
+	// Act
+	_, rotatedErr := rotated.Verify(token, time.Now())
+	_, retiredErr := retired.Verify(token, time.Now())
+
+	// Assert
+	if rotatedErr != nil {
+		t.Errorf("Verify() with the old key still in the set returned an error: %v", rotatedErr)
+	}
+	if !errors.Is(retiredErr, invitetoken.ErrInvalidToken) {
+		t.Errorf("Verify() after removing the old key error = %v, want ErrInvalidToken", retiredErr)
+	}
+}
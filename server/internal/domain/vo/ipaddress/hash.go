@@ -2,24 +2,27 @@ package ipaddress
 
 import (
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
+	"strings"
 )
 
-// Hash represents a SHA-256 hashed IP address for privacy protection.
+// hashSeparator separates the key ID from the MAC in keyed hashes.
+const hashSeparator = ":"
+
+// Hash represents a hashed IP address for privacy protection.
 // This value object ensures IP addresses are stored securely while still
-// allowing fraud detection (same IP → same hash).
+// allowing fraud detection (same IP → same hash under the same key).
+//
+// Two formats exist:
+//   - keyed: "<keyID>:<hex HMAC-SHA256>", produced by Hasher
+//   - legacy: "<hex SHA-256>", an unkeyed hash written by earlier versions.
+//     The IPv4 space is small enough to reverse these by brute force, so they
+//     are only read, never produced.
 type Hash struct {
 	value string
 }
 
-// NewHash creates a hash from a raw IP address string.
-// Uses SHA-256 to one-way hash the IP address for GDPR/privacy compliance.
-func NewHash(ipAddress string) *Hash {
-	hash := sha256.Sum256([]byte(ipAddress))
-
-	return &Hash{value: hex.EncodeToString(hash[:])}
-}
-
 // NewEmptyHash creates an empty hash for sessions without IP tracking.
 func NewEmptyHash() *Hash {
 	return &Hash{value: ""}
@@ -27,12 +30,12 @@ func NewEmptyHash() *Hash {
 
 // FromString reconstructs a Hash from a previously computed hash string.
 // This is used by repository implementations when loading from persistent storage.
-// The input should be a 64-character hex string (SHA-256 hash).
+// Both the keyed and the legacy format are accepted.
 func FromString(hashValue string) *Hash {
 	return &Hash{value: hashValue}
 }
 
-// String returns the hash value (64-character hex string for SHA-256).
+// String returns the hash value in the format it was produced in.
 func (h *Hash) String() string {
 	return h.value
 }
@@ -41,3 +44,41 @@ func (h *Hash) String() string {
 func (h *Hash) IsEmpty() bool {
 	return h.value == ""
 }
+
+// KeyID returns the ID of the key that produced the hash.
+// Returns "" for legacy and empty hashes.
+func (h *Hash) KeyID() string {
+	keyID, _, found := strings.Cut(h.value, hashSeparator)
+	if !found {
+		return ""
+	}
+
+	return keyID
+}
+
+// IsLegacy reports whether the hash is an unkeyed SHA-256 from an earlier version.
+func (h *Hash) IsLegacy() bool {
+	return !h.IsEmpty() && !strings.Contains(h.value, hashSeparator)
+}
+
+// mac returns the hex MAC part of a keyed hash, or the whole value of a legacy hash.
+func (h *Hash) mac() string {
+	_, mac, found := strings.Cut(h.value, hashSeparator)
+	if !found {
+		return h.value
+	}
+
+	return mac
+}
+
+// legacyHash computes the unkeyed SHA-256 format of earlier versions.
+func legacyHash(ipAddress string) string {
+	sum := sha256.Sum256([]byte(ipAddress))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// equalHex compares two hex strings in constant time.
+func equalHex(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
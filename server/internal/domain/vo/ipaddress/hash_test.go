@@ -8,102 +8,10 @@ import (
 
 const (
 	testIP = "192.168.1.1"
-)
-
-func TestNewHash(t *testing.T) {
-	t.Parallel()
-
-	t.Run("creates hash from IP address", func(t *testing.T) {
-		t.Parallel()
-
-		// Arrange
-		ip := testIP
-
-		// Act
-		hash := ipaddress.NewHash(ip)
-
-		// Assert
-		if hash == nil {
-			t.Fatal("expected non-nil hash")
-		}
-		if hash.String() == "" {
-			t.Error("expected non-empty hash value")
-		}
-		// SHA-256 produces 64-character hex string
-		if len(hash.String()) != 64 {
-			t.Errorf("expected hash length 64, got %d", len(hash.String()))
-		}
-	})
 
-	t.Run("same IP produces same hash (deterministic)", func(t *testing.T) {
-		t.Parallel()
-
-		// Arrange
-		ip := testIP
-
-		// Act
-		hash1 := ipaddress.NewHash(ip)
-		hash2 := ipaddress.NewHash(ip)
-
-		// Assert
-		if hash1.String() != hash2.String() {
-			t.Error("same IP should produce same hash")
-		}
-	})
-
-	t.Run("different IPs produce different hashes", func(t *testing.T) {
-		t.Parallel()
-
-		// Arrange
-		ip1 := testIP
-		ip2 := "192.168.1.2"
-
-		// Act
-		hash1 := ipaddress.NewHash(ip1)
-		hash2 := ipaddress.NewHash(ip2)
-
-		// Assert
-		if hash1.String() == hash2.String() {
-			t.Error("different IPs should produce different hashes")
-		}
-	})
-
-	t.Run("handles empty string", func(t *testing.T) {
-		t.Parallel()
-
-		// Act
-		hash := ipaddress.NewHash("")
-
-		// Assert
-		if hash == nil {
-			t.Fatal("expected non-nil hash")
-		}
-		// Empty string still produces a hash
-		if hash.String() == "" {
-			t.Error("expected non-empty hash even for empty input")
-		}
-		if len(hash.String()) != 64 {
-			t.Errorf("expected hash length 64, got %d", len(hash.String()))
-		}
-	})
-
-	t.Run("produces valid hex characters only", func(t *testing.T) {
-		t.Parallel()
-
-		// Arrange
-		ip := "10.0.0.1"
-
-		// Act
-		hash := ipaddress.NewHash(ip)
-
-		// Assert
-		for _, c := range hash.String() {
-			if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
-				t.Errorf("hash contains invalid hex character: %c", c)
-			}
-		}
-	})
-}
+	// legacyTestHash is the unkeyed SHA-256 of testIP written by earlier versions.
+	legacyTestHash = "c5eb5a4cc76a5cdb16e79864b9ccd26c3553f0c396d0a21bafb7be71c1efcd8c"
+)
 
 func TestNewEmptyHash(t *testing.T) {
 	t.Parallel()
@@ -127,23 +35,36 @@ func TestNewEmptyHash(t *testing.T) {
 	})
 }
 
-func TestHash_IsEmpty(t *testing.T) {
+func TestFromString(t *testing.T) {
 	t.Parallel()
 
 	tests := []struct {
-		name     string
-		hash     *ipaddress.Hash
-		expected bool
+		name         string
+		value        string
+		expectKeyID  string
+		expectLegacy bool
+		expectEmpty  bool
 	}{
 		{
-			name:     "empty hash returns true",
-			hash:     ipaddress.NewEmptyHash(),
-			expected: true,
+			name:         "keyed hash",
+			value:        "v1:" + legacyTestHash,
+			expectKeyID:  "v1",
+			expectLegacy: false,
+			expectEmpty:  false,
+		},
+		{
+			name:         "legacy unkeyed hash",
+			value:        legacyTestHash,
+			expectKeyID:  "",
+			expectLegacy: true,
+			expectEmpty:  false,
 		},
 		{
-			name:     "non-empty hash returns false",
-			hash:     ipaddress.NewHash(testIP),
-			expected: false,
+			name:         "empty hash",
+			value:        "",
+			expectKeyID:  "",
+			expectLegacy: false,
+			expectEmpty:  true,
 		},
 	}
 
@@ -152,50 +73,21 @@ func TestHash_IsEmpty(t *testing.T) {
 			t.Parallel()
 
 			// Act
-			result := tt.hash.IsEmpty()
+			hash := ipaddress.FromString(tt.value)
 
 			// Assert
-			if result != tt.expected {
-				t.Errorf("IsEmpty() = %v, want %v", result, tt.expected)
+			if hash.String() != tt.value {
+				t.Errorf("String() = %q, want %q", hash.String(), tt.value)
+			}
+			if hash.KeyID() != tt.expectKeyID {
+				t.Errorf("KeyID() = %q, want %q", hash.KeyID(), tt.expectKeyID)
+			}
+			if hash.IsLegacy() != tt.expectLegacy {
+				t.Errorf("IsLegacy() = %v, want %v", hash.IsLegacy(), tt.expectLegacy)
+			}
+			if hash.IsEmpty() != tt.expectEmpty {
+				t.Errorf("IsEmpty() = %v, want %v", hash.IsEmpty(), tt.expectEmpty)
 			}
 		})
 	}
 }
-
-func TestHash_String(t *testing.T) {
-	t.Parallel()
-
-	t.Run("returns the hash value", func(t *testing.T) {
-		t.Parallel()
-
-		// Arrange
-		ip := "203.0.113.0"
-		hash := ipaddress.NewHash(ip)
-
-		// Act
-		value := hash.String()
-
-		// Assert
-		if value == "" {
-			t.Error("expected non-empty string")
-		}
-		if len(value) != 64 {
-			t.Errorf("expected length 64, got %d", len(value))
-		}
-	})
-
-	t.Run("empty hash returns empty string", func(t *testing.T) {
-		t.Parallel()
-
-		// Arrange
-		hash := ipaddress.NewEmptyHash()
-
-		// Act
-		value := hash.String()
-
-		// Assert
-		if value != "" {
-			t.Errorf("expected empty string, got %q", value)
-		}
-	})
-}
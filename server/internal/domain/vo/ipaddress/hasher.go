@@ -0,0 +1,137 @@
+package ipaddress
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"custom_auth_api/internal/pkg/keyset"
+)
+
+// MinHashKeyLength is the minimum HMAC key length in bytes (256 bits).
+const MinHashKeyLength = keyset.MinKeyLength
+
+// ErrInvalidPrefixLength is returned for a truncation prefix outside /8 to the address length.
+var ErrInvalidPrefixLength = errors.New("ip hash prefix length is out of range")
+
+// HasherOptions configures address normalization before hashing.
+type HasherOptions struct {
+	// IPv4PrefixBits truncates IPv4 addresses to this prefix before hashing
+	// (e.g. 24 hashes 192.0.2.0/24 as one network). 0 keeps the full address.
+	IPv4PrefixBits int
+
+	// IPv6PrefixBits truncates IPv6 addresses to this prefix before hashing
+	// (e.g. 64, a typical subscriber allocation). 0 keeps the full address.
+	IPv6PrefixBits int
+}
+
+// Hasher computes and verifies keyed IP address hashes.
+//
+// Addresses are normalized first: IPv4-mapped IPv6 addresses are unmapped,
+// zones are dropped, IPv6 is written in its canonical form, and the address
+// is optionally truncated to a network prefix, so equivalent spellings of an
+// address hash identically.
+//
+// New hashes are always produced with the active key. Verification looks up
+// the key by the hash's key ID, so rotating keys only requires adding the new
+// key, making it active, and removing the old key once no stored hash uses it.
+type Hasher struct {
+	keys     *keyset.Keyset
+	ipv4Bits int
+	ipv6Bits int
+}
+
+// NewHasher creates a Hasher from a key set and the ID of the key used for new hashes.
+// Returns an error wrapping one of the keyset errors if the key set is not acceptable,
+// or ErrInvalidPrefixLength for an out-of-range prefix.
+func NewHasher(activeKeyID string, keys map[string][]byte, options HasherOptions) (*Hasher, error) {
+	hashKeys, err := keyset.New(activeKeyID, keys, hashSeparator)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ip hash keys: %w", err)
+	}
+
+	ipv4Bits, err := prefixBits(options.IPv4PrefixBits, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: IPv4 /%d", err, options.IPv4PrefixBits)
+	}
+
+	ipv6Bits, err := prefixBits(options.IPv6PrefixBits, 128)
+	if err != nil {
+		return nil, fmt.Errorf("%w: IPv6 /%d", err, options.IPv6PrefixBits)
+	}
+
+	return &Hasher{keys: hashKeys, ipv4Bits: ipv4Bits, ipv6Bits: ipv6Bits}, nil
+}
+
+// ActiveKeyID returns the ID of the key used for new hashes.
+func (h *Hasher) ActiveKeyID() string {
+	return h.keys.ActiveKeyID()
+}
+
+// Hash returns the keyed hash of ipAddress under the active key.
+func (h *Hasher) Hash(ipAddress string) *Hash {
+	mac := hex.EncodeToString(h.keys.Sum(h.normalize(ipAddress)))
+
+	return &Hash{value: h.keys.ActiveKeyID() + hashSeparator + mac}
+}
+
+// Matches reports whether ipAddress hashes to hash, using a constant-time comparison.
+// Legacy hashes are compared against the unkeyed SHA-256 of the raw address.
+// Returns false for empty hashes and for hashes produced with a key that is no
+// longer in the key set.
+func (h *Hasher) Matches(hash *Hash, ipAddress string) bool {
+	if hash.IsEmpty() {
+		return false
+	}
+
+	if hash.IsLegacy() {
+		return equalHex(legacyHash(ipAddress), hash.mac())
+	}
+
+	mac, ok := h.keys.SumWith(hash.KeyID(), h.normalize(ipAddress))
+	if !ok {
+		return false
+	}
+
+	return equalHex(hex.EncodeToString(mac), hash.mac())
+}
+
+// normalize returns the canonical, optionally truncated form of ipAddress.
+// Values that are not IP addresses are hashed as given (trimmed and lowercased).
+func (h *Hasher) normalize(ipAddress string) string {
+	value := strings.ToLower(strings.TrimSpace(ipAddress))
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return value
+	}
+
+	addr = addr.WithZone("").Unmap()
+
+	bits := h.ipv6Bits
+	if addr.Is4() {
+		bits = h.ipv4Bits
+	}
+
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return addr.String()
+	}
+
+	return prefix.Addr().String()
+}
+
+// prefixBits validates a prefix length, mapping 0 to the full address length.
+func prefixBits(bits, addrBits int) (int, error) {
+	if bits == 0 {
+		return addrBits, nil
+	}
+
+	if bits < 8 || bits > addrBits {
+		return 0, ErrInvalidPrefixLength
+	}
+
+	return bits, nil
+}
@@ -0,0 +1,245 @@
+package ipaddress_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"custom_auth_api/internal/domain/vo/ipaddress"
+	"custom_auth_api/internal/pkg/keyset"
+)
+
+var (
+	testKeyV1 = bytes.Repeat([]byte("1"), ipaddress.MinHashKeyLength)
+	testKeyV2 = bytes.Repeat([]byte("2"), ipaddress.MinHashKeyLength)
+)
+
+func mustHasher(
+	t *testing.T,
+	activeKeyID string,
+	keys map[string][]byte,
+	options ipaddress.HasherOptions,
+) *ipaddress.Hasher {
+	t.Helper()
+
+	hasher, err := ipaddress.NewHasher(activeKeyID, keys, options)
+	if err != nil {
+		t.Fatalf("NewHasher() returned an error: %v", err)
+	}
+
+	return hasher
+}
+
+func TestNewHasher_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		activeKeyID string
+		keys        map[string][]byte
+		options     ipaddress.HasherOptions
+		wantErr     error
+	}{
+		{
+			name:        "no keys",
+			activeKeyID: "v1",
+			keys:        nil,
+			options:     ipaddress.HasherOptions{},
+			wantErr:     keyset.ErrKeyRequired,
+		},
+		{
+			name:        "short key",
+			activeKeyID: "v1",
+			keys:        map[string][]byte{"v1": []byte("short")},
+			options:     ipaddress.HasherOptions{},
+			wantErr:     keyset.ErrKeyTooShort,
+		},
+		{
+			name:        "key id with separator",
+			activeKeyID: "v:1",
+			keys:        map[string][]byte{"v:1": testKeyV1},
+			options:     ipaddress.HasherOptions{},
+			wantErr:     keyset.ErrInvalidKeyID,
+		},
+		{
+			name:        "unknown active key",
+			activeKeyID: "v2",
+			keys:        map[string][]byte{"v1": testKeyV1},
+			options:     ipaddress.HasherOptions{},
+			wantErr:     keyset.ErrActiveKeyNotFound,
+		},
+		{
+			name:        "IPv4 prefix too long",
+			activeKeyID: "v1",
+			keys:        map[string][]byte{"v1": testKeyV1},
+			options:     ipaddress.HasherOptions{IPv4PrefixBits: 33, IPv6PrefixBits: 0},
+			wantErr:     ipaddress.ErrInvalidPrefixLength,
+		},
+		{
+			name:        "IPv6 prefix too short",
+			activeKeyID: "v1",
+			keys:        map[string][]byte{"v1": testKeyV1},
+			options:     ipaddress.HasherOptions{IPv4PrefixBits: 0, IPv6PrefixBits: 4},
+			wantErr:     ipaddress.ErrInvalidPrefixLength,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			_, err := ipaddress.NewHasher(tt.activeKeyID, tt.keys, tt.options)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewHasher() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHasher_Hash(t *testing.T) {
+	t.Parallel()
+
+	t.Run("produces a keyed hash that does not contain the address", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		hasher := mustHasher(t, "v1", map[string][]byte{"v1": testKeyV1}, ipaddress.HasherOptions{})
+
+		// Act
+		hash := hasher.Hash(testIP)
+
+		// Assert
+		if hash.KeyID() != "v1" {
+			t.Errorf("KeyID() = %q, want v1", hash.KeyID())
+		}
+		if hash.IsLegacy() {
+			t.Error("expected a keyed hash")
+		}
+		if strings.Contains(hash.String(), testIP) || strings.HasSuffix(hash.String(), legacyTestHash) {
+			t.Error("keyed hash must not reveal the address or its unkeyed hash")
+		}
+	})
+
+	t.Run("depends on the key", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		hasherV1 := mustHasher(t, "k", map[string][]byte{"k": testKeyV1}, ipaddress.HasherOptions{})
+		hasherV2 := mustHasher(t, "k", map[string][]byte{"k": testKeyV2}, ipaddress.HasherOptions{})
+
+		// Act & Assert
+		if hasherV1.Hash(testIP).String() == hasherV2.Hash(testIP).String() {
+			t.Error("different keys should produce different hashes")
+		}
+	})
+
+	tests := []struct {
+		name      string
+		options   ipaddress.HasherOptions
+		a         string
+		b         string
+		wantEqual bool
+	}{
+		{
+			name:      "IPv6 spellings are normalized",
+			options:   ipaddress.HasherOptions{},
+			a:         "2001:DB8:0:0::1",
+			b:         "2001:db8::1",
+			wantEqual: true,
+		},
+		{
+			name:      "IPv4-mapped IPv6 is unmapped",
+			options:   ipaddress.HasherOptions{},
+			a:         "::ffff:192.0.2.1",
+			b:         "192.0.2.1",
+			wantEqual: true,
+		},
+		{
+			name:      "different addresses differ without truncation",
+			options:   ipaddress.HasherOptions{},
+			a:         "192.0.2.1",
+			b:         "192.0.2.2",
+			wantEqual: false,
+		},
+		{
+			name:      "IPv4 truncated to /24",
+			options:   ipaddress.HasherOptions{IPv4PrefixBits: 24, IPv6PrefixBits: 0},
+			a:         "192.0.2.1",
+			b:         "192.0.2.200",
+			wantEqual: true,
+		},
+		{
+			name:      "IPv6 truncated to /64",
+			options:   ipaddress.HasherOptions{IPv4PrefixBits: 0, IPv6PrefixBits: 64},
+			a:         "2001:db8:1:2::1",
+			b:         "2001:db8:1:2:ffff::9",
+			wantEqual: true,
+		},
+		{
+			name:      "IPv6 /64 keeps different subnets apart",
+			options:   ipaddress.HasherOptions{IPv4PrefixBits: 0, IPv6PrefixBits: 64},
+			a:         "2001:db8:1:2::1",
+			b:         "2001:db8:1:3::1",
+			wantEqual: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			hasher := mustHasher(t, "v1", map[string][]byte{"v1": testKeyV1}, tt.options)
+
+			// Act
+			equal := hasher.Hash(tt.a).String() == hasher.Hash(tt.b).String()
+
+			// Assert
+			if equal != tt.wantEqual {
+				t.Errorf("hash(%s) == hash(%s) is %v, want %v", tt.a, tt.b, equal, tt.wantEqual)
+			}
+		})
+	}
+}
+
+func TestHasher_Matches(t *testing.T) {
+	t.Parallel()
+
+	oldHasher := mustHasher(t, "v1", map[string][]byte{"v1": testKeyV1}, ipaddress.HasherOptions{})
+	rotated := mustHasher(t, "v2", map[string][]byte{"v1": testKeyV1, "v2": testKeyV2}, ipaddress.HasherOptions{})
+	retired := mustHasher(t, "v2", map[string][]byte{"v2": testKeyV2}, ipaddress.HasherOptions{})
+
+	tests := []struct {
+		name   string
+		hasher *ipaddress.Hasher
+		hash   *ipaddress.Hash
+		ip     string
+		want   bool
+	}{
+		{name: "same key and address", hasher: oldHasher, hash: oldHasher.Hash(testIP), ip: testIP, want: true},
+		{name: "different address", hasher: oldHasher, hash: oldHasher.Hash(testIP), ip: "192.168.1.2", want: false},
+		{name: "old key after rotation", hasher: rotated, hash: oldHasher.Hash(testIP), ip: testIP, want: true},
+		{name: "retired key", hasher: retired, hash: oldHasher.Hash(testIP), ip: testIP, want: false},
+		{name: "legacy hash", hasher: retired, hash: ipaddress.FromString(legacyTestHash), ip: testIP, want: true},
+		{name: "legacy hash of another address", hasher: retired, hash: ipaddress.FromString(legacyTestHash), ip: "10.0.0.1", want: false},
+		{name: "empty hash", hasher: oldHasher, hash: ipaddress.NewEmptyHash(), ip: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			got := tt.hasher.Matches(tt.hash, tt.ip)
+
+			// Assert
+			if got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
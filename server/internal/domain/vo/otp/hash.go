@@ -0,0 +1,115 @@
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"custom_auth_api/internal/pkg/keyset"
+)
+
+// MinHashKeyLength is the minimum HMAC key length in bytes (256 bits).
+const MinHashKeyLength = keyset.MinKeyLength
+
+// digestSeparator separates the key ID from the MAC in the encoded digest.
+const digestSeparator = ":"
+
+// macSeparator separates the canonical email from the code in the MAC input.
+// Parsed addresses never contain it, so no two (email, code) pairs share an input.
+const macSeparator = "\x00"
+
+// Hashing errors.
+var (
+	ErrInvalidDigestFormat = errors.New("otp digest must be formatted as <keyID>:<hex HMAC-SHA256>")
+	ErrHasherRequired      = errors.New("a hasher is required to restore a hashed otp")
+)
+
+// Digest is a keyed HMAC-SHA256 of an OTP code and the canonical email of its session.
+// Binding the email means equal codes in two sessions have unrelated digests, so reading
+// the store does not reveal code reuse. It carries the ID of the key that produced it so codes hashed before a
+// key rotation can still be verified while the old key is kept in the key set.
+type Digest struct {
+	keyID string
+	mac   []byte
+}
+
+// ParseDigest reconstructs a Digest from its String() form.
+// This is used by repository implementations when loading from persistent storage.
+func ParseDigest(encoded string) (*Digest, error) {
+	keyID, macHex, found := strings.Cut(encoded, digestSeparator)
+	if !found || keyID == "" || len(macHex) != hex.EncodedLen(sha256.Size) {
+		return nil, ErrInvalidDigestFormat
+	}
+
+	mac, err := hex.DecodeString(macHex)
+	if err != nil {
+		return nil, ErrInvalidDigestFormat
+	}
+
+	return &Digest{keyID: keyID, mac: mac}, nil
+}
+
+// KeyID returns the ID of the key that produced this digest.
+func (d *Digest) KeyID() string {
+	return d.keyID
+}
+
+// String returns the digest encoded as "<keyID>:<hex HMAC-SHA256>".
+func (d *Digest) String() string {
+	return d.keyID + digestSeparator + hex.EncodeToString(d.mac)
+}
+
+// Hasher computes and verifies keyed OTP digests.
+//
+// New digests are always produced with the active key. Verification looks up
+// the key by the digest's key ID, so rotating keys only requires adding the new
+// key, making it active, and removing the old key once all sessions hashed with
+// it have expired.
+type Hasher struct {
+	keys *keyset.Keyset
+}
+
+// NewHasher creates a Hasher from a key set and the ID of the key used for new digests.
+// Returns an error wrapping one of the keyset errors if the key set is not acceptable.
+func NewHasher(activeKeyID string, keys map[string][]byte) (*Hasher, error) {
+	hashKeys, err := keyset.New(activeKeyID, keys, digestSeparator)
+	if err != nil {
+		return nil, fmt.Errorf("invalid otp hash keys: %w", err)
+	}
+
+	return &Hasher{keys: hashKeys}, nil
+}
+
+// ActiveKeyID returns the ID of the key used for new digests.
+func (h *Hasher) ActiveKeyID() string {
+	return h.keys.ActiveKeyID()
+}
+
+// Digest returns the keyed digest of the OTP sent to canonicalEmail (email.Email.Canonical()).
+// A hashed-only OTP already carries its digest, which is returned unchanged.
+func (h *Hasher) Digest(code *OTP, canonicalEmail string) *Digest {
+	if code.digest != nil {
+		return code.digest
+	}
+
+	return &Digest{keyID: h.keys.ActiveKeyID(), mac: h.keys.Sum(macInput(canonicalEmail, code.value))}
+}
+
+// Matches reports whether code sent to canonicalEmail hashes to digest, using a constant-time
+// comparison. Returns false if the digest was produced with a key that is no longer in the key set.
+func (h *Hasher) Matches(digest *Digest, canonicalEmail, code string) bool {
+	mac, ok := h.keys.SumWith(digest.keyID, macInput(canonicalEmail, code))
+	if !ok {
+		return false
+	}
+
+	return hmac.Equal(mac, digest.mac)
+}
+
+// macInput returns the message authenticated for code sent to canonicalEmail.
+func macInput(canonicalEmail, code string) string {
+	return canonicalEmail + macSeparator + code
+}
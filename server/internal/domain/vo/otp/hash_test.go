@@ -0,0 +1,295 @@
+package otp_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"custom_auth_api/internal/domain/vo/otp"
+	"custom_auth_api/internal/pkg/keyset"
+)
+
+var (
+	testKeyV1 = bytes.Repeat([]byte("1"), otp.MinHashKeyLength)
+	testKeyV2 = bytes.Repeat([]byte("2"), otp.MinHashKeyLength)
+)
+
+// testEmail is the canonical email digests in these tests are bound to.
+const testEmail = "user@example.com"
+
+func mustHasher(t *testing.T, activeKeyID string, keys map[string][]byte) *otp.Hasher {
+	t.Helper()
+
+	hasher, err := otp.NewHasher(activeKeyID, keys)
+	if err != nil {
+		t.Fatalf("NewHasher() returned an error: %v", err)
+	}
+
+	return hasher
+}
+
+func TestNewHasher_Validation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		activeKeyID string
+		keys        map[string][]byte
+		wantErr     error
+	}{
+		{
+			name:        "no keys",
+			activeKeyID: "v1",
+			keys:        nil,
+			wantErr:     keyset.ErrKeyRequired,
+		},
+		{
+			name:        "key too short",
+			activeKeyID: "v1",
+			keys:        map[string][]byte{"v1": []byte("short")},
+			wantErr:     keyset.ErrKeyTooShort,
+		},
+		{
+			name:        "key id contains separator",
+			activeKeyID: "v:1",
+			keys:        map[string][]byte{"v:1": testKeyV1},
+			wantErr:     keyset.ErrInvalidKeyID,
+		},
+		{
+			name:        "active key missing",
+			activeKeyID: "v2",
+			keys:        map[string][]byte{"v1": testKeyV1},
+			wantErr:     keyset.ErrActiveKeyNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			hasher, err := otp.NewHasher(tt.activeKeyID, tt.keys)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewHasher() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if hasher != nil {
+				t.Error("NewHasher() should return nil hasher on error")
+			}
+		})
+	}
+}
+
+func TestHasher_Digest(t *testing.T) {
+	t.Parallel()
+
+	t.Run("digest does not contain the plaintext code", func(t *testing.T) {
+		t.Parallel()
+
+		hasher := mustHasher(t, "v1", map[string][]byte{"v1": testKeyV1})
+		code, _ := otp.FromString("123456")
+
+		digest := hasher.Digest(code, testEmail)
+
+		if strings.Contains(digest.String(), "123456") {
+			t.Errorf("digest %s leaks the plaintext code", digest)
+		}
+
+		if digest.KeyID() != "v1" {
+			t.Errorf("expected key id v1, got %s", digest.KeyID())
+		}
+
+		if !strings.HasPrefix(digest.String(), "v1:") || len(digest.String()) != len("v1:")+64 {
+			t.Errorf("unexpected digest encoding %s", digest)
+		}
+	})
+
+	t.Run("different keys produce different digests", func(t *testing.T) {
+		t.Parallel()
+
+		code, _ := otp.FromString("123456")
+		h1 := mustHasher(t, "v1", map[string][]byte{"v1": testKeyV1})
+		h2 := mustHasher(t, "v1", map[string][]byte{"v1": testKeyV2})
+
+		if h1.Digest(code, testEmail).String() == h2.Digest(code, testEmail).String() {
+			t.Error("expected different digests for different keys")
+		}
+	})
+
+	t.Run("equal codes of different sessions produce different digests", func(t *testing.T) {
+		t.Parallel()
+
+		hasher := mustHasher(t, "v1", map[string][]byte{"v1": testKeyV1})
+		code, _ := otp.FromString("123456")
+
+		if hasher.Digest(code, testEmail).String() == hasher.Digest(code, "other@example.com").String() {
+			t.Error("expected the digest to depend on the session email")
+		}
+	})
+
+	t.Run("hashed-only OTP keeps its original digest", func(t *testing.T) {
+		t.Parallel()
+
+		oldHasher := mustHasher(t, "v1", map[string][]byte{"v1": testKeyV1})
+		rotated := mustHasher(t, "v2", map[string][]byte{"v1": testKeyV1, "v2": testKeyV2})
+		code, _ := otp.FromString("123456")
+		digest := oldHasher.Digest(code, testEmail)
+
+		restored, err := otp.FromDigest(digest, rotated, testEmail)
+		if err != nil {
+			t.Fatalf("FromDigest() returned an error: %v", err)
+		}
+
+		if rotated.Digest(restored, testEmail).String() != digest.String() {
+			t.Error("expected hashed-only OTP to keep its digest across rotation")
+		}
+	})
+}
+
+func TestHasher_Matches(t *testing.T) {
+	t.Parallel()
+
+	code, _ := otp.FromString("123456")
+	oldHasher := mustHasher(t, "v1", map[string][]byte{"v1": testKeyV1})
+	oldDigest := oldHasher.Digest(code, testEmail)
+
+	tests := []struct {
+		name   string
+		hasher *otp.Hasher
+		email  string
+		input  string
+		want   bool
+	}{
+		{
+			name:   "matching code",
+			hasher: oldHasher,
+			email:  testEmail,
+			input:  "123456",
+			want:   true,
+		},
+		{
+			name:   "wrong code",
+			hasher: oldHasher,
+			email:  testEmail,
+			input:  "654321",
+			want:   false,
+		},
+		{
+			name:   "code of another session",
+			hasher: oldHasher,
+			email:  "other@example.com",
+			input:  "123456",
+			want:   false,
+		},
+		{
+			name:   "old key still verifies after rotation",
+			hasher: mustHasher(t, "v2", map[string][]byte{"v1": testKeyV1, "v2": testKeyV2}),
+			email:  testEmail,
+			input:  "123456",
+			want:   true,
+		},
+		{
+			name:   "retired key no longer verifies",
+			hasher: mustHasher(t, "v2", map[string][]byte{"v2": testKeyV2}),
+			email:  testEmail,
+			input:  "123456",
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.hasher.Matches(oldDigest, tt.email, tt.input); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDigest(t *testing.T) {
+	t.Parallel()
+
+	t.Run("round-trips String()", func(t *testing.T) {
+		t.Parallel()
+
+		hasher := mustHasher(t, "v1", map[string][]byte{"v1": testKeyV1})
+		code, _ := otp.FromString("123456")
+		digest := hasher.Digest(code, testEmail)
+
+		parsed, err := otp.ParseDigest(digest.String())
+		if err != nil {
+			t.Fatalf("ParseDigest() returned an error: %v", err)
+		}
+
+		if !hasher.Matches(parsed, testEmail, "123456") {
+			t.Error("parsed digest should match the original code")
+		}
+	})
+
+	for _, input := range []string{"", "v1", ":abcd", "v1:zz", "v1:" + strings.Repeat("g", 64), "v1:abcd"} {
+		t.Run("rejects "+input, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := otp.ParseDigest(input)
+			if !errors.Is(err, otp.ErrInvalidDigestFormat) {
+				t.Errorf("ParseDigest(%q) error = %v, want ErrInvalidDigestFormat", input, err)
+			}
+		})
+	}
+}
+
+func TestOTP_Matches(t *testing.T) {
+	t.Parallel()
+
+	t.Run("plaintext OTP", func(t *testing.T) {
+		t.Parallel()
+
+		code, _ := otp.FromString("123456")
+
+		if code.IsHashedOnly() {
+			t.Error("plaintext OTP should not be hashed-only")
+		}
+
+		if !code.Matches("123456") || code.Matches("123457") || code.Matches("12345") {
+			t.Error("plaintext OTP matched incorrectly")
+		}
+	})
+
+	t.Run("hashed-only OTP", func(t *testing.T) {
+		t.Parallel()
+
+		hasher := mustHasher(t, "v1", map[string][]byte{"v1": testKeyV1})
+		plain, _ := otp.FromString("123456")
+
+		code, err := otp.FromDigest(hasher.Digest(plain, testEmail), hasher, testEmail)
+		if err != nil {
+			t.Fatalf("FromDigest() returned an error: %v", err)
+		}
+
+		if !code.IsHashedOnly() {
+			t.Error("restored OTP should be hashed-only")
+		}
+
+		if code.String() != "" {
+			t.Errorf("hashed-only OTP should not expose a plaintext value, got %q", code.String())
+		}
+
+		if !code.Matches("123456") || code.Matches("654321") {
+			t.Error("hashed-only OTP matched incorrectly")
+		}
+	})
+
+	t.Run("FromDigest requires a hasher", func(t *testing.T) {
+		t.Parallel()
+
+		hasher := mustHasher(t, "v1", map[string][]byte{"v1": testKeyV1})
+		plain, _ := otp.FromString("123456")
+
+		_, err := otp.FromDigest(hasher.Digest(plain, testEmail), nil, testEmail)
+		if !errors.Is(err, otp.ErrHasherRequired) {
+			t.Errorf("FromDigest() error = %v, want ErrHasherRequired", err)
+		}
+	})
+}
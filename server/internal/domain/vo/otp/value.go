@@ -2,6 +2,7 @@ package otp
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
 	"errors"
 	"fmt"
 	"math/big"
@@ -9,8 +10,16 @@ import (
 )
 
 // OTP represents a one-time password value object.
+//
+// An OTP is either plaintext (freshly generated or parsed, so it can be sent
+// to the user) or hashed-only (restored from storage, where only the keyed
+// digest is persisted). A hashed-only OTP can be matched against user input
+// but its plaintext value is unknown.
 type OTP struct {
-	value string
+	value  string
+	digest *Digest
+	hasher *Hasher
+	email  string // Canonical email the digest is bound to
 }
 
 var (
@@ -26,7 +35,7 @@ func NewOTP() (*OTP, error) {
 		return nil, err
 	}
 
-	return &OTP{value: otp}, nil
+	return &OTP{value: otp, digest: nil, hasher: nil, email: ""}, nil
 }
 
 // FromString creates an OTP from a string value.
@@ -36,14 +45,49 @@ func FromString(code string) (*OTP, error) {
 		return nil, ErrInvalidOTPFormat
 	}
 
-	return &OTP{value: code}, nil
+	return &OTP{value: code, digest: nil, hasher: nil, email: ""}, nil
+}
+
+// FromDigest restores a hashed-only OTP from a persisted digest of a code sent to canonicalEmail.
+// The hasher must hold the key the digest was produced with for Matches to succeed.
+func FromDigest(digest *Digest, hasher *Hasher, canonicalEmail string) (*OTP, error) {
+	if digest == nil {
+		return nil, ErrInvalidDigestFormat
+	}
+
+	if hasher == nil {
+		return nil, ErrHasherRequired
+	}
+
+	return &OTP{value: "", digest: digest, hasher: hasher, email: canonicalEmail}, nil
 }
 
 // String returns the string representation of the OTP.
+// Returns an empty string for a hashed-only OTP.
 func (o *OTP) String() string {
 	return o.value
 }
 
+// IsHashedOnly reports whether only the digest of this OTP is known.
+func (o *OTP) IsHashedOnly() bool {
+	return o.digest != nil
+}
+
+// Matches reports whether input equals this OTP using a constant-time comparison.
+// For a hashed-only OTP the input is hashed with the digest's key and compared to the digest.
+func (o *OTP) Matches(input string) bool {
+	if o.IsHashedOnly() {
+		return o.hasher.Matches(o.digest, o.email, input)
+	}
+
+	// Check length first (constant-time compare requires same length)
+	if len(o.value) != len(input) {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(o.value), []byte(input)) == 1
+}
+
 // generate6DigitCode generates a random 6-digit string without modulo bias.
 // Uses crypto/rand.Int for unbiased random number generation.
 func generate6DigitCode() (string, error) {
@@ -0,0 +1,52 @@
+package useragent
+
+import "strings"
+
+// Family names returned by Family.
+const (
+	FamilyEdge    = "Edge"
+	FamilyOpera   = "Opera"
+	FamilyFirefox = "Firefox"
+	FamilyChrome  = "Chrome"
+	FamilySafari  = "Safari"
+	FamilyOther   = "Other"
+)
+
+// familyTokens maps product tokens to families, in match order. Order matters because
+// browsers include the tokens of the engines they derive from (Edge also sends "Chrome/"
+// and "Safari/", Chrome also sends "Safari/").
+var familyTokens = []struct {
+	token  string
+	family string
+}{
+	{token: "Edg/", family: FamilyEdge},
+	{token: "Edge/", family: FamilyEdge},
+	{token: "EdgA/", family: FamilyEdge},
+	{token: "EdgiOS/", family: FamilyEdge},
+	{token: "OPR/", family: FamilyOpera},
+	{token: "Opera/", family: FamilyOpera},
+	{token: "Firefox/", family: FamilyFirefox},
+	{token: "FxiOS/", family: FamilyFirefox},
+	{token: "Chrome/", family: FamilyChrome},
+	{token: "CriOS/", family: FamilyChrome},
+	{token: "Safari/", family: FamilySafari},
+}
+
+// Family returns the browser family of a User-Agent string, e.g. "Chrome" or "Firefox".
+// Version numbers are ignored so that a browser update between requesting and verifying
+// an OTP does not change the family. Non-browser clients are identified by their first
+// product token ("curl/8.5.0" → "curl"); an empty User-Agent yields FamilyOther.
+func Family(userAgent string) string {
+	for _, candidate := range familyTokens {
+		if strings.Contains(userAgent, candidate.token) {
+			return candidate.family
+		}
+	}
+
+	product, _, _ := strings.Cut(strings.TrimSpace(userAgent), "/")
+	if product == "" || product == "Mozilla" {
+		return FamilyOther
+	}
+
+	return product
+}
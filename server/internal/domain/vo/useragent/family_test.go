@@ -0,0 +1,77 @@
+package useragent_test
+
+import (
+	"testing"
+
+	"custom_auth_api/internal/domain/vo/useragent"
+)
+
+func TestFamily(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		userAgent string
+		expected  string
+	}{
+		{
+			name:      "Chrome on Windows",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			expected:  useragent.FamilyChrome,
+		},
+		{
+			name:      "Edge also advertises Chrome and Safari",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.2478.67",
+			expected:  useragent.FamilyEdge,
+		},
+		{
+			name:      "Opera",
+			userAgent: "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 OPR/110.0.0.0",
+			expected:  useragent.FamilyOpera,
+		},
+		{
+			name:      "Firefox",
+			userAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0",
+			expected:  useragent.FamilyFirefox,
+		},
+		{
+			name:      "Safari on iOS",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			expected:  useragent.FamilySafari,
+		},
+		{
+			name:      "Chrome on iOS",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/124.0.6367.88 Mobile/15E148 Safari/604.1",
+			expected:  useragent.FamilyChrome,
+		},
+		{
+			name:      "non-browser client uses its product token",
+			userAgent: "curl/8.5.0",
+			expected:  "curl",
+		},
+		{
+			name:      "unknown Mozilla-compatible client",
+			userAgent: "Mozilla/5.0 (compatible; SomeBot)",
+			expected:  useragent.FamilyOther,
+		},
+		{
+			name:      "empty user agent",
+			userAgent: "",
+			expected:  useragent.FamilyOther,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			family := useragent.Family(tc.userAgent)
+
+			// Assert
+			if family != tc.expected {
+				t.Errorf("expected family %q, got %q", tc.expected, family)
+			}
+		})
+	}
+}
@@ -17,11 +17,26 @@ func NewDummyEmailSender() *DummyEmailSender {
 
 // SendOTP simulates sending an OTP email.
 // In development, check the Firestore Emulator UI to see the OTP.
-func (s *DummyEmailSender) SendOTP(ctx context.Context, toEmail, otp string) error {
+func (s *DummyEmailSender) SendOTP(ctx context.Context, toEmail string, otp emailsender.OTP) error {
 	log.Printf("Dummy Email Sent to: %s (check Firestore Emulator UI for OTP)", toEmail)
 
 	return nil
 }
 
+// SendSignInNotice simulates sending a sign-in attempt notice to an unregistered address.
+func (s *DummyEmailSender) SendSignInNotice(ctx context.Context, toEmail string) error {
+	log.Printf("Dummy Email Sent to: %s (sign-in attempt notice)", toEmail)
+
+	return nil
+}
+
+// SendInvitation simulates sending an invitation email.
+// The accept link is logged so invitations can be accepted during development.
+func (s *DummyEmailSender) SendInvitation(ctx context.Context, toEmail string, invitation emailsender.Invitation) error {
+	log.Printf("Dummy Email Sent to: %s (invitation from %s, accept at %s)", toEmail, invitation.Inviter, invitation.AcceptURL)
+
+	return nil
+}
+
 // Ensure DummyEmailSender implements the EmailSender interface.
 var _ emailsender.EmailSender = (*DummyEmailSender)(nil)
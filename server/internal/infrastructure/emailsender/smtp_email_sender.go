@@ -0,0 +1,493 @@
+package emailsender
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"custom_auth_api/internal/domain/emailsender"
+)
+
+// TLSMode selects how the SMTP connection is secured.
+type TLSMode string
+
+// Supported TLS modes.
+const (
+	// TLSModeNone sends mail over a plaintext connection (local relays only).
+	TLSModeNone TLSMode = "none"
+
+	// TLSModeSTARTTLS upgrades a plaintext connection with STARTTLS (usually port 587).
+	TLSModeSTARTTLS TLSMode = "starttls"
+
+	// TLSModeImplicit speaks TLS from the first byte (SMTPS, usually port 465).
+	TLSModeImplicit TLSMode = "implicit"
+)
+
+// AuthMechanism selects the SASL mechanism used to authenticate with the SMTP server.
+type AuthMechanism string
+
+// Supported authentication mechanisms.
+const (
+	AuthMechanismPlain AuthMechanism = "plain"
+	AuthMechanismLogin AuthMechanism = "login"
+)
+
+const (
+	defaultSMTPTimeout = 10 * time.Second
+	messageIDBytes     = 16
+)
+
+// SMTP configuration errors.
+var (
+	ErrSMTPHostRequired          = errors.New("smtp host is required")
+	ErrSMTPRendererRequired      = errors.New("smtp email sender requires a template renderer")
+	ErrSMTPInvalidPort           = errors.New("smtp port must be between 1 and 65535")
+	ErrSMTPInvalidFrom           = errors.New("smtp from address is invalid")
+	ErrSMTPInvalidReplyTo        = errors.New("smtp reply-to address is invalid")
+	ErrSMTPUnsupportedTLSMode    = errors.New("unsupported smtp tls mode")
+	ErrSMTPUnsupportedAuth       = errors.New("unsupported smtp auth mechanism")
+	ErrSMTPSTARTTLSNotSupported  = errors.New("smtp server does not support STARTTLS")
+	ErrSMTPUnencryptedConnection = errors.New("refusing to send smtp credentials over an unencrypted connection")
+	ErrSMTPUnexpectedChallenge   = errors.New("unexpected smtp LOGIN challenge")
+)
+
+// SMTPConfig holds the settings for SMTPEmailSender.
+type SMTPConfig struct {
+	Host string
+	Port int
+
+	// Username and Password are optional. Authentication is skipped when Username is empty.
+	Username      string
+	Password      string
+	AuthMechanism AuthMechanism
+
+	// From is the sender address, optionally with a display name ("Example <no-reply@example.com>").
+	From string
+	// ReplyTo is optional.
+	ReplyTo string
+
+	TLSMode TLSMode
+	// TLSConfig overrides the TLS client configuration (e.g. custom root CAs). Optional.
+	TLSConfig *tls.Config
+
+	// Timeout bounds the whole SMTP conversation, including dialing. Defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+// SMTPEmailSender delivers emails through an SMTP server.
+//
+// Supports:
+// - Plaintext, STARTTLS and implicit TLS connections
+// - PLAIN and LOGIN authentication
+// - Configurable From/Reply-To headers
+// - Localized multipart (plain text + HTML) bodies rendered from templates
+// - Connection timeouts (the earlier of Timeout and the context deadline wins).
+type SMTPEmailSender struct {
+	config   SMTPConfig
+	renderer *emailsender.TemplateRenderer
+	from     *mail.Address
+	replyTo  *mail.Address
+}
+
+// NewSMTPEmailSender creates a new SMTPEmailSender that renders messages with renderer.
+// Returns an error if the configuration is incomplete or invalid.
+func NewSMTPEmailSender(config SMTPConfig, renderer *emailsender.TemplateRenderer) (*SMTPEmailSender, error) {
+	if config.Host == "" {
+		return nil, ErrSMTPHostRequired
+	}
+
+	if renderer == nil {
+		return nil, ErrSMTPRendererRequired
+	}
+
+	if config.Port < 1 || config.Port > 65535 {
+		return nil, ErrSMTPInvalidPort
+	}
+
+	switch config.TLSMode {
+	case TLSModeNone, TLSModeSTARTTLS, TLSModeImplicit:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrSMTPUnsupportedTLSMode, config.TLSMode)
+	}
+
+	if config.Username != "" {
+		switch config.AuthMechanism {
+		case AuthMechanismPlain, AuthMechanismLogin:
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrSMTPUnsupportedAuth, config.AuthMechanism)
+		}
+	}
+
+	from, err := mail.ParseAddress(config.From)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSMTPInvalidFrom, err)
+	}
+
+	var replyTo *mail.Address
+	if config.ReplyTo != "" {
+		replyTo, err = mail.ParseAddress(config.ReplyTo)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrSMTPInvalidReplyTo, err)
+		}
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultSMTPTimeout
+	}
+
+	return &SMTPEmailSender{
+		config:   config,
+		renderer: renderer,
+		from:     from,
+		replyTo:  replyTo,
+	}, nil
+}
+
+// SendOTP renders the OTP email in the recipient's locale (see emailsender.ContextWithLocale)
+// and sends it to the given address.
+func (s *SMTPEmailSender) SendOTP(ctx context.Context, toEmail string, otp emailsender.OTP) error {
+	locale, _ := emailsender.LocaleFromContext(ctx)
+
+	message, err := s.renderer.RenderOTP(locale, toEmail, otp)
+	if err != nil {
+		return fmt.Errorf("failed to render otp email: %w", err)
+	}
+
+	return s.Send(ctx, message)
+}
+
+// SendSignInNotice renders the sign-in attempt notice in the recipient's locale and sends it.
+func (s *SMTPEmailSender) SendSignInNotice(ctx context.Context, toEmail string) error {
+	locale, _ := emailsender.LocaleFromContext(ctx)
+
+	message, err := s.renderer.RenderSignInNotice(locale, toEmail)
+	if err != nil {
+		return fmt.Errorf("failed to render sign-in notice email: %w", err)
+	}
+
+	return s.Send(ctx, message)
+}
+
+// SendInvitation renders the invitation email in the recipient's locale and sends it.
+func (s *SMTPEmailSender) SendInvitation(ctx context.Context, toEmail string, invitation emailsender.Invitation) error {
+	locale, _ := emailsender.LocaleFromContext(ctx)
+
+	message, err := s.renderer.RenderInvitation(locale, toEmail, invitation)
+	if err != nil {
+		return fmt.Errorf("failed to render invitation email: %w", err)
+	}
+
+	return s.Send(ctx, message)
+}
+
+// Send delivers a rendered message as a multipart (plain text + HTML) email.
+func (s *SMTPEmailSender) Send(ctx context.Context, message *emailsender.Message) error {
+	msg, err := s.buildMessage(message)
+	if err != nil {
+		return err
+	}
+
+	return s.send(ctx, message.To, msg)
+}
+
+// send delivers a fully built message to a single recipient.
+func (s *SMTPEmailSender) send(ctx context.Context, toEmail string, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Abort any blocking I/O when the context is done
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	deadline, _ := ctx.Deadline()
+
+	err = conn.SetDeadline(deadline)
+	if err != nil {
+		_ = conn.Close()
+
+		return fmt.Errorf("failed to set smtp deadline: %w", err)
+	}
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		_ = conn.Close()
+
+		return fmt.Errorf("failed to start smtp session: %w", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	err = s.converse(client, toEmail, msg)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("smtp send aborted: %w", ctx.Err())
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// converse runs the SMTP transaction on an established session.
+func (s *SMTPEmailSender) converse(client *smtp.Client, toEmail string, msg []byte) error {
+	if s.config.TLSMode == TLSModeSTARTTLS {
+		ok, _ := client.Extension("STARTTLS")
+		if !ok {
+			return ErrSMTPSTARTTLSNotSupported
+		}
+
+		err := client.StartTLS(s.tlsConfig())
+		if err != nil {
+			return fmt.Errorf("failed to start tls: %w", err)
+		}
+	}
+
+	if s.config.Username != "" {
+		err := client.Auth(s.auth())
+		if err != nil {
+			return fmt.Errorf("smtp authentication failed: %w", err)
+		}
+	}
+
+	err := client.Mail(s.from.Address)
+	if err != nil {
+		return fmt.Errorf("smtp MAIL FROM failed: %w", err)
+	}
+
+	err = client.Rcpt(toEmail)
+	if err != nil {
+		return fmt.Errorf("smtp RCPT TO failed: %w", err)
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA failed: %w", err)
+	}
+
+	_, err = writer.Write(msg)
+	if err != nil {
+		return fmt.Errorf("failed to write smtp message: %w", err)
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return fmt.Errorf("smtp server rejected message: %w", err)
+	}
+
+	err = client.Quit()
+	if err != nil {
+		return fmt.Errorf("smtp QUIT failed: %w", err)
+	}
+
+	return nil
+}
+
+// dial opens the TCP (or implicit TLS) connection to the SMTP server.
+func (s *SMTPEmailSender) dial(ctx context.Context) (net.Conn, error) {
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+
+	if s.config.TLSMode == TLSModeImplicit {
+		dialer := &tls.Dialer{NetDialer: &net.Dialer{}, Config: s.tlsConfig()}
+
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to smtp server: %w", err)
+		}
+
+		return conn, nil
+	}
+
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+
+	return conn, nil
+}
+
+// tlsConfig returns the TLS client configuration for the server.
+func (s *SMTPEmailSender) tlsConfig() *tls.Config {
+	if s.config.TLSConfig != nil {
+		config := s.config.TLSConfig.Clone()
+		if config.ServerName == "" {
+			config.ServerName = s.config.Host
+		}
+
+		return config
+	}
+
+	return &tls.Config{
+		ServerName: s.config.Host,
+		MinVersion: tls.VersionTLS12,
+	}
+}
+
+// auth returns the configured SASL mechanism.
+func (s *SMTPEmailSender) auth() smtp.Auth {
+	if s.config.AuthMechanism == AuthMechanismLogin {
+		return &loginAuth{
+			username: s.config.Username,
+			password: s.config.Password,
+		}
+	}
+
+	return smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+}
+
+// buildMessage renders an RFC 5322 multipart/alternative message with
+// quoted-printable UTF-8 text and HTML parts.
+func (s *SMTPEmailSender) buildMessage(message *emailsender.Message) ([]byte, error) {
+	messageID, err := s.newMessageID()
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+
+	parts := multipart.NewWriter(&body)
+
+	err = writePart(parts, "text/plain", message.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writePart(parts, "text/html", message.HTML)
+	if err != nil {
+		return nil, err
+	}
+
+	err = parts.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to finish multipart body: %w", err)
+	}
+
+	var buf bytes.Buffer
+
+	writeHeader(&buf, "From", s.from.String())
+	writeHeader(&buf, "To", (&mail.Address{Name: "", Address: message.To}).String())
+
+	if s.replyTo != nil {
+		writeHeader(&buf, "Reply-To", s.replyTo.String())
+	}
+
+	writeHeader(&buf, "Subject", mime.QEncoding.Encode("utf-8", message.Subject))
+	writeHeader(&buf, "Date", time.Now().Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", messageID)
+	writeHeader(&buf, "MIME-Version", "1.0")
+	writeHeader(&buf, "Content-Type", mime.FormatMediaType(
+		"multipart/alternative",
+		map[string]string{"boundary": parts.Boundary()},
+	))
+	buf.WriteString("\r\n")
+	buf.Write(body.Bytes())
+
+	return buf.Bytes(), nil
+}
+
+// writePart appends a quoted-printable UTF-8 part to a multipart body.
+func writePart(parts *multipart.Writer, contentType, content string) error {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType+`; charset="utf-8"`)
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+
+	partWriter, err := parts.CreatePart(header)
+	if err != nil {
+		return fmt.Errorf("failed to create %s part: %w", contentType, err)
+	}
+
+	qp := quotedprintable.NewWriter(partWriter)
+
+	_, err = qp.Write([]byte(content))
+	if err != nil {
+		return fmt.Errorf("failed to encode %s part: %w", contentType, err)
+	}
+
+	err = qp.Close()
+	if err != nil {
+		return fmt.Errorf("failed to encode %s part: %w", contentType, err)
+	}
+
+	return nil
+}
+
+// newMessageID generates a unique Message-ID using the sender's domain.
+func (s *SMTPEmailSender) newMessageID() (string, error) {
+	random := make([]byte, messageIDBytes)
+
+	_, err := rand.Read(random)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate message id: %w", err)
+	}
+
+	_, domain, _ := strings.Cut(s.from.Address, "@")
+
+	return "<" + hex.EncodeToString(random) + "@" + domain + ">", nil
+}
+
+func writeHeader(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	buf.WriteString(": ")
+	buf.WriteString(value)
+	buf.WriteString("\r\n")
+}
+
+// loginAuth implements the (non-standard but widely deployed) LOGIN SASL mechanism.
+// Like smtp.PlainAuth, it refuses to send credentials over an unencrypted
+// connection unless the server is on localhost.
+type loginAuth struct {
+	username string
+	password string
+}
+
+// Start begins the LOGIN exchange.
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, ErrSMTPUnencryptedConnection
+	}
+
+	return "LOGIN", nil, nil
+}
+
+// Next answers the server's "Username:" and "Password:" challenges.
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrSMTPUnexpectedChallenge, fromServer)
+	}
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
+
+// Ensure SMTPEmailSender implements the EmailSender interface.
+var _ emailsender.EmailSender = (*SMTPEmailSender)(nil)
@@ -0,0 +1,553 @@
+package emailsender_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	domainemailsender "custom_auth_api/internal/domain/emailsender"
+	"custom_auth_api/internal/infrastructure/emailsender"
+	"custom_auth_api/internal/infrastructure/emailsender/smtptest"
+)
+
+const (
+	testUsername  = "mailer"
+	testPassword  = "s3cret"
+	testRecipient = "user@example.com"
+	testOTPCode   = "123456"
+)
+
+// testOTP returns an OTP email for a session created now.
+func testOTP() domainemailsender.OTP {
+	return domainemailsender.OTP{Code: testOTPCode, ExpiresAt: time.Now().Add(5 * time.Minute)}
+}
+
+// startServer starts an in-process SMTP server and stops it when the test ends.
+func startServer(t *testing.T, config smtptest.Config) *smtptest.Server {
+	t.Helper()
+
+	server, err := smtptest.NewServer(config)
+	if err != nil {
+		t.Fatalf("failed to start smtp server: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = server.Close()
+	})
+
+	return server
+}
+
+// newSender creates an SMTPEmailSender pointed at the test server.
+func newSender(t *testing.T, server *smtptest.Server, config emailsender.SMTPConfig) *emailsender.SMTPEmailSender {
+	t.Helper()
+
+	config.Host = server.Host()
+	config.Port = server.Port()
+	config.TLSConfig = server.ClientTLSConfig()
+
+	if config.From == "" {
+		config.From = "Custom Auth <no-reply@example.com>"
+	}
+
+	renderer, err := domainemailsender.NewTemplateRenderer("", domainemailsender.LocaleJapanese)
+	if err != nil {
+		t.Fatalf("failed to create template renderer: %v", err)
+	}
+
+	sender, err := emailsender.NewSMTPEmailSender(config, renderer)
+	if err != nil {
+		t.Fatalf("failed to create smtp sender: %v", err)
+	}
+
+	return sender
+}
+
+// readParts decodes the parts of a multipart/alternative message keyed by media type.
+func readParts(t *testing.T, msg *mail.Message) map[string]string {
+	t.Helper()
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("expected multipart/alternative, got %q (%v)", msg.Header.Get("Content-Type"), err)
+	}
+
+	parts := make(map[string]string)
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+
+	for {
+		part, err := reader.NextRawPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read part: %v", err)
+		}
+
+		if part.Header.Get("Content-Transfer-Encoding") != "quoted-printable" {
+			t.Errorf("expected quoted-printable part, got %q", part.Header.Get("Content-Transfer-Encoding"))
+		}
+
+		partType, partParams, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if partParams["charset"] != "utf-8" {
+			t.Errorf("expected utf-8 charset for %s, got %q", partType, partParams["charset"])
+		}
+
+		content, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatalf("failed to decode %s part: %v", partType, err)
+		}
+
+		parts[partType] = string(content)
+	}
+
+	return parts
+}
+
+// singleMessage returns the only message received by the server.
+func singleMessage(t *testing.T, server *smtptest.Server) (smtptest.Message, *mail.Message) {
+	t.Helper()
+
+	messages := server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(messages))
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(messages[0].Data))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+
+	return messages[0], parsed
+}
+
+func TestSMTPEmailSender_SendOTP(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		server        smtptest.Config
+		tlsMode       emailsender.TLSMode
+		authMechanism emailsender.AuthMechanism
+		wantTLS       bool
+		wantAuth      string
+	}{
+		{
+			name:          "plaintext without authentication",
+			server:        smtptest.Config{},
+			tlsMode:       emailsender.TLSModeNone,
+			authMechanism: "",
+			wantTLS:       false,
+			wantAuth:      "",
+		},
+		{
+			name:          "STARTTLS with PLAIN authentication",
+			server:        smtptest.Config{STARTTLS: true, Username: testUsername, Password: testPassword},
+			tlsMode:       emailsender.TLSModeSTARTTLS,
+			authMechanism: emailsender.AuthMechanismPlain,
+			wantTLS:       true,
+			wantAuth:      "PLAIN",
+		},
+		{
+			name:          "STARTTLS with LOGIN authentication",
+			server:        smtptest.Config{STARTTLS: true, Username: testUsername, Password: testPassword},
+			tlsMode:       emailsender.TLSModeSTARTTLS,
+			authMechanism: emailsender.AuthMechanismLogin,
+			wantTLS:       true,
+			wantAuth:      "LOGIN",
+		},
+		{
+			name:          "implicit TLS with PLAIN authentication",
+			server:        smtptest.Config{ImplicitTLS: true, Username: testUsername, Password: testPassword},
+			tlsMode:       emailsender.TLSModeImplicit,
+			authMechanism: emailsender.AuthMechanismPlain,
+			wantTLS:       true,
+			wantAuth:      "PLAIN",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			server := startServer(t, tc.server)
+
+			config := emailsender.SMTPConfig{TLSMode: tc.tlsMode, AuthMechanism: tc.authMechanism}
+			if tc.wantAuth != "" {
+				config.Username = testUsername
+				config.Password = testPassword
+			}
+
+			sender := newSender(t, server, config)
+
+			// Act
+			err := sender.SendOTP(context.Background(), testRecipient, testOTP())
+
+			// Assert
+			if err != nil {
+				t.Fatalf("SendOTP() unexpected error: %v", err)
+			}
+
+			received, _ := singleMessage(t, server)
+
+			if received.TLS != tc.wantTLS {
+				t.Errorf("expected TLS %v, got %v", tc.wantTLS, received.TLS)
+			}
+
+			if received.AuthMechanism != tc.wantAuth {
+				t.Errorf("expected auth mechanism %q, got %q", tc.wantAuth, received.AuthMechanism)
+			}
+
+			if received.From != "no-reply@example.com" {
+				t.Errorf("expected envelope sender no-reply@example.com, got %q", received.From)
+			}
+
+			if len(received.To) != 1 || received.To[0] != testRecipient {
+				t.Errorf("expected envelope recipient %s, got %v", testRecipient, received.To)
+			}
+		})
+	}
+}
+
+func TestSMTPEmailSender_SendOTP_MessageContent(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	server := startServer(t, smtptest.Config{})
+	sender := newSender(t, server, emailsender.SMTPConfig{
+		TLSMode: emailsender.TLSModeNone,
+		From:    "Custom Auth <no-reply@example.com>",
+		ReplyTo: "support@example.com",
+	})
+
+	ctx := domainemailsender.ContextWithLocale(context.Background(), domainemailsender.LocaleEnglish)
+
+	// Act
+	err := sender.SendOTP(ctx, testRecipient, testOTP())
+	if err != nil {
+		t.Fatalf("SendOTP() unexpected error: %v", err)
+	}
+
+	// Assert
+	_, msg := singleMessage(t, server)
+
+	expectedHeaders := map[string]string{
+		"From":         `"Custom Auth" <no-reply@example.com>`,
+		"To":           "<user@example.com>",
+		"Reply-To":     "<support@example.com>",
+		"Mime-Version": "1.0",
+	}
+
+	for name, want := range expectedHeaders {
+		if got := msg.Header.Get(name); got != want {
+			t.Errorf("expected header %s = %q, got %q", name, want, got)
+		}
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("failed to decode subject: %v", err)
+	}
+
+	if subject != "Your verification code" {
+		t.Errorf("unexpected subject %q", subject)
+	}
+
+	if !strings.HasSuffix(msg.Header.Get("Message-Id"), "@example.com>") {
+		t.Errorf("expected Message-ID on sender domain, got %q", msg.Header.Get("Message-Id"))
+	}
+
+	if _, err := msg.Header.Date(); err != nil {
+		t.Errorf("expected valid Date header: %v", err)
+	}
+
+	parts := readParts(t, msg)
+
+	// Quoted-printable encoding normalizes line breaks to CRLF
+	expectedText := "Your one-time password is: 123456\r\n\r\n" +
+		"This code expires in 5 minutes.\r\n" +
+		"If you did not request this code, you can safely ignore this email.\r\n"
+	if parts["text/plain"] != expectedText {
+		t.Errorf("expected text part %q, got %q", expectedText, parts["text/plain"])
+	}
+
+	if !strings.Contains(parts["text/html"], `<html lang="en">`) || !strings.Contains(parts["text/html"], ">123456</p>") {
+		t.Errorf("unexpected html part %q", parts["text/html"])
+	}
+}
+
+func TestSMTPEmailSender_SendSignInNotice(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	server := startServer(t, smtptest.Config{})
+	sender := newSender(t, server, emailsender.SMTPConfig{TLSMode: emailsender.TLSModeNone})
+
+	ctx := domainemailsender.ContextWithLocale(context.Background(), domainemailsender.LocaleEnglish)
+
+	// Act
+	err := sender.SendSignInNotice(ctx, testRecipient)
+	if err != nil {
+		t.Fatalf("SendSignInNotice() unexpected error: %v", err)
+	}
+
+	// Assert
+	_, msg := singleMessage(t, server)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("failed to decode subject: %v", err)
+	}
+
+	if subject != "Sign-in attempt with your email address" {
+		t.Errorf("unexpected subject %q", subject)
+	}
+
+	parts := readParts(t, msg)
+
+	if !strings.Contains(parts["text/plain"], "there is no account registered") {
+		t.Errorf("unexpected text part %q", parts["text/plain"])
+	}
+}
+
+func TestSMTPEmailSender_SendInvitation(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	server := startServer(t, smtptest.Config{})
+	sender := newSender(t, server, emailsender.SMTPConfig{TLSMode: emailsender.TLSModeNone})
+
+	ctx := domainemailsender.ContextWithLocale(context.Background(), domainemailsender.LocaleEnglish)
+	invitation := domainemailsender.Invitation{
+		Inviter:   "admin@example.com",
+		AcceptURL: "https://app.example.com/invite?token=v1.abc.123.sig",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	// Act
+	err := sender.SendInvitation(ctx, testRecipient, invitation)
+	if err != nil {
+		t.Fatalf("SendInvitation() unexpected error: %v", err)
+	}
+
+	// Assert
+	_, msg := singleMessage(t, server)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("failed to decode subject: %v", err)
+	}
+
+	if subject != "You have been invited to create an account" {
+		t.Errorf("unexpected subject %q", subject)
+	}
+
+	parts := readParts(t, msg)
+
+	if !strings.Contains(parts["text/plain"], invitation.AcceptURL) {
+		t.Errorf("text part missing the accept link: %q", parts["text/plain"])
+	}
+}
+
+func TestSMTPEmailSender_SendOTP_DefaultLocale(t *testing.T) {
+	t.Parallel()
+
+	// Arrange: no locale in the context, renderer default is Japanese
+	server := startServer(t, smtptest.Config{})
+	sender := newSender(t, server, emailsender.SMTPConfig{TLSMode: emailsender.TLSModeNone})
+
+	// Act
+	err := sender.SendOTP(context.Background(), testRecipient, testOTP())
+	if err != nil {
+		t.Fatalf("SendOTP() unexpected error: %v", err)
+	}
+
+	// Assert
+	_, msg := singleMessage(t, server)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("failed to decode subject: %v", err)
+	}
+
+	if subject != "認証コードのお知らせ" {
+		t.Errorf("expected Japanese subject, got %q", subject)
+	}
+
+	parts := readParts(t, msg)
+	if !strings.Contains(parts["text/plain"], "このコードの有効期限は5分です。") {
+		t.Errorf("expected Japanese text part, got %q", parts["text/plain"])
+	}
+}
+
+func TestSMTPEmailSender_SendOTP_Errors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("returns error on invalid credentials", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		server := startServer(t, smtptest.Config{STARTTLS: true, Username: testUsername, Password: testPassword})
+		sender := newSender(t, server, emailsender.SMTPConfig{
+			TLSMode:       emailsender.TLSModeSTARTTLS,
+			Username:      testUsername,
+			Password:      "wrong",
+			AuthMechanism: emailsender.AuthMechanismPlain,
+		})
+
+		// Act
+		err := sender.SendOTP(context.Background(), testRecipient, testOTP())
+
+		// Assert
+		if err == nil {
+			t.Fatal("expected authentication error, got nil")
+		}
+
+		if len(server.Messages()) != 0 {
+			t.Error("expected no message to be accepted")
+		}
+	})
+
+	t.Run("returns error when STARTTLS is required but not offered", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		server := startServer(t, smtptest.Config{})
+		sender := newSender(t, server, emailsender.SMTPConfig{TLSMode: emailsender.TLSModeSTARTTLS})
+
+		// Act
+		err := sender.SendOTP(context.Background(), testRecipient, testOTP())
+
+		// Assert
+		if !errors.Is(err, emailsender.ErrSMTPSTARTTLSNotSupported) {
+			t.Errorf("expected ErrSMTPSTARTTLSNotSupported, got %v", err)
+		}
+	})
+
+	t.Run("returns error when the context is already canceled", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		server := startServer(t, smtptest.Config{})
+		sender := newSender(t, server, emailsender.SMTPConfig{TLSMode: emailsender.TLSModeNone})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// Act
+		err := sender.SendOTP(ctx, testRecipient, testOTP())
+
+		// Assert
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("times out when the server is unreachable", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange: start and immediately stop a server to obtain a closed port
+		server := startServer(t, smtptest.Config{})
+		_ = server.Close()
+
+		sender := newSender(t, server, emailsender.SMTPConfig{
+			TLSMode: emailsender.TLSModeNone,
+			Timeout: 500 * time.Millisecond,
+		})
+
+		// Act
+		err := sender.SendOTP(context.Background(), testRecipient, testOTP())
+
+		// Assert
+		if err == nil {
+			t.Error("expected connection error, got nil")
+		}
+	})
+}
+
+func TestNewSMTPEmailSender_Validation(t *testing.T) {
+	t.Parallel()
+
+	valid := emailsender.SMTPConfig{
+		Host:    "smtp.example.com",
+		Port:    587,
+		From:    "no-reply@example.com",
+		TLSMode: emailsender.TLSModeSTARTTLS,
+	}
+
+	testCases := []struct {
+		name        string
+		modify      func(c *emailsender.SMTPConfig)
+		expectedErr error
+	}{
+		{
+			name:        "missing host",
+			modify:      func(c *emailsender.SMTPConfig) { c.Host = "" },
+			expectedErr: emailsender.ErrSMTPHostRequired,
+		},
+		{
+			name:        "invalid port",
+			modify:      func(c *emailsender.SMTPConfig) { c.Port = 0 },
+			expectedErr: emailsender.ErrSMTPInvalidPort,
+		},
+		{
+			name:        "invalid from address",
+			modify:      func(c *emailsender.SMTPConfig) { c.From = "not an address" },
+			expectedErr: emailsender.ErrSMTPInvalidFrom,
+		},
+		{
+			name:        "invalid reply-to address",
+			modify:      func(c *emailsender.SMTPConfig) { c.ReplyTo = "nope" },
+			expectedErr: emailsender.ErrSMTPInvalidReplyTo,
+		},
+		{
+			name:        "unsupported tls mode",
+			modify:      func(c *emailsender.SMTPConfig) { c.TLSMode = "ssl3" },
+			expectedErr: emailsender.ErrSMTPUnsupportedTLSMode,
+		},
+		{
+			name: "unsupported auth mechanism",
+			modify: func(c *emailsender.SMTPConfig) {
+				c.Username = testUsername
+				c.AuthMechanism = "cram-md5"
+			},
+			expectedErr: emailsender.ErrSMTPUnsupportedAuth,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			config := valid
+			tc.modify(&config)
+
+			renderer, err := domainemailsender.NewTemplateRenderer("", domainemailsender.LocaleJapanese)
+			if err != nil {
+				t.Fatalf("failed to create template renderer: %v", err)
+			}
+
+			// Act
+			sender, err := emailsender.NewSMTPEmailSender(config, renderer)
+
+			// Assert
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected error %v, got %v", tc.expectedErr, err)
+			}
+
+			if sender != nil {
+				t.Error("expected nil sender on error")
+			}
+		})
+	}
+}
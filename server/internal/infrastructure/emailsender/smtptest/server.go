@@ -0,0 +1,546 @@
+// Package smtptest provides an in-process SMTP server for testing EmailSender
+// implementations without a real mail relay.
+//
+// The server understands the subset of SMTP needed by net/smtp clients:
+// EHLO/HELO, STARTTLS, AUTH PLAIN/LOGIN, MAIL, RCPT, DATA, RSET, NOOP and QUIT.
+// Every accepted message is recorded so tests can assert exactly what was sent.
+package smtptest
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errMalformedPlainResponse is returned when an AUTH PLAIN response is not "authzid\0authcid\0password".
+var errMalformedPlainResponse = errors.New("malformed PLAIN response")
+
+const (
+	serverName      = "localhost"
+	certificateTTL  = time.Hour
+	serialNumberMax = 1 << 62
+)
+
+// Config controls the behavior of the test server.
+type Config struct {
+	// ImplicitTLS makes the server speak TLS from the first byte (SMTPS).
+	ImplicitTLS bool
+
+	// STARTTLS advertises and accepts the STARTTLS extension on a plaintext connection.
+	STARTTLS bool
+
+	// Username and Password enable AUTH PLAIN/LOGIN. When set, MAIL is rejected until
+	// the client has authenticated with these credentials.
+	Username string
+	Password string
+}
+
+// Message is a single message accepted by the server.
+type Message struct {
+	From          string
+	To            []string
+	Data          []byte
+	AuthMechanism string
+	Username      string
+	TLS           bool
+}
+
+// Server is an in-process SMTP server listening on a loopback address.
+type Server struct {
+	config    Config
+	listener  net.Listener
+	tlsConfig *tls.Config
+	certPool  *x509.CertPool
+
+	mu       sync.Mutex
+	messages []Message
+
+	wg sync.WaitGroup
+}
+
+// NewServer starts a new SMTP server on 127.0.0.1 with a random port.
+// A self-signed certificate is generated for TLS; use ClientTLSConfig to trust it.
+func NewServer(config Config) (*Server, error) {
+	tlsConfig, certPool, err := newSelfSignedTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+
+	if config.ImplicitTLS {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	server := &Server{
+		config:    config,
+		listener:  listener,
+		tlsConfig: tlsConfig,
+		certPool:  certPool,
+		mu:        sync.Mutex{},
+		messages:  nil,
+		wg:        sync.WaitGroup{},
+	}
+
+	server.wg.Add(1)
+
+	go server.serve()
+
+	return server, nil
+}
+
+// Host returns the host the server listens on.
+func (s *Server) Host() string {
+	host, _, _ := net.SplitHostPort(s.listener.Addr().String())
+
+	return host
+}
+
+// Port returns the port the server listens on.
+func (s *Server) Port() int {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	n, _ := strconv.Atoi(port)
+
+	return n
+}
+
+// ClientTLSConfig returns a TLS configuration that trusts the server certificate.
+func (s *Server) ClientTLSConfig() *tls.Config {
+	return &tls.Config{
+		RootCAs:    s.certPool,
+		ServerName: s.Host(),
+		MinVersion: tls.VersionTLS12,
+	}
+}
+
+// Messages returns a copy of all messages accepted so far.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Message(nil), s.messages...)
+}
+
+// Close stops the server and waits for open sessions to finish.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.wg.Add(1)
+
+		go func() {
+			defer s.wg.Done()
+
+			newSession(s, conn).run()
+		}()
+	}
+}
+
+func (s *Server) record(msg Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages = append(s.messages, msg)
+}
+
+// session holds the state of a single SMTP connection.
+type session struct {
+	server *Server
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+
+	tls           bool
+	authMechanism string
+	username      string
+	from          string
+	to            []string
+}
+
+func newSession(server *Server, conn net.Conn) *session {
+	_, isTLS := conn.(*tls.Conn)
+
+	return &session{
+		server:        server,
+		conn:          conn,
+		reader:        bufio.NewReader(conn),
+		writer:        bufio.NewWriter(conn),
+		tls:           isTLS,
+		authMechanism: "",
+		username:      "",
+		from:          "",
+		to:            nil,
+	}
+}
+
+func (s *session) run() {
+	defer func() { _ = s.conn.Close() }()
+
+	s.reply("220 " + serverName + " ESMTP smtptest")
+
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+
+		if !s.handle(strings.ToUpper(verb), arg) {
+			return
+		}
+	}
+}
+
+// handle processes a single command and reports whether the session should continue.
+func (s *session) handle(verb, arg string) bool {
+	switch verb {
+	case "EHLO":
+		s.replyEHLO()
+	case "HELO":
+		s.reply("250 " + serverName)
+	case "STARTTLS":
+		return s.handleSTARTTLS()
+	case "AUTH":
+		s.handleAuth(arg)
+	case "MAIL":
+		s.handleMail(arg)
+	case "RCPT":
+		s.handleRcpt(arg)
+	case "DATA":
+		return s.handleData()
+	case "RSET":
+		s.from, s.to = "", nil
+		s.reply("250 2.0.0 OK")
+	case "NOOP":
+		s.reply("250 2.0.0 OK")
+	case "QUIT":
+		s.reply("221 2.0.0 Bye")
+
+		return false
+	default:
+		s.reply("502 5.5.2 Command not recognized")
+	}
+
+	return true
+}
+
+func (s *session) replyEHLO() {
+	lines := []string{serverName, "8BITMIME", "SMTPUTF8"}
+	if s.server.config.STARTTLS && !s.tls {
+		lines = append(lines, "STARTTLS")
+	}
+
+	if s.server.config.Username != "" {
+		lines = append(lines, "AUTH PLAIN LOGIN")
+	}
+
+	for i, line := range lines {
+		sep := "-"
+		if i == len(lines)-1 {
+			sep = " "
+		}
+
+		s.reply("250" + sep + line)
+	}
+}
+
+func (s *session) handleSTARTTLS() bool {
+	if !s.server.config.STARTTLS || s.tls {
+		s.reply("502 5.5.1 STARTTLS not available")
+
+		return true
+	}
+
+	s.reply("220 2.0.0 Ready to start TLS")
+
+	tlsConn := tls.Server(s.conn, s.server.tlsConfig)
+
+	err := tlsConn.Handshake()
+	if err != nil {
+		return false
+	}
+
+	s.conn = tlsConn
+	s.reader = bufio.NewReader(tlsConn)
+	s.writer = bufio.NewWriter(tlsConn)
+	s.tls = true
+	s.authMechanism, s.username, s.from, s.to = "", "", "", nil
+
+	return true
+}
+
+func (s *session) handleAuth(arg string) {
+	mechanism, initial, _ := strings.Cut(arg, " ")
+
+	var username, password string
+
+	var err error
+
+	switch strings.ToUpper(mechanism) {
+	case "PLAIN":
+		username, password, err = s.authPlain(initial)
+	case "LOGIN":
+		username, password, err = s.authLogin(initial)
+	default:
+		s.reply("504 5.5.4 Unrecognized authentication type")
+
+		return
+	}
+
+	if err != nil || username != s.server.config.Username || password != s.server.config.Password {
+		s.reply("535 5.7.8 Authentication credentials invalid")
+
+		return
+	}
+
+	s.authMechanism = strings.ToUpper(mechanism)
+	s.username = username
+	s.reply("235 2.7.0 Authentication successful")
+}
+
+func (s *session) authPlain(initial string) (string, string, error) {
+	if initial == "" {
+		s.reply("334 ")
+
+		line, err := s.readLine()
+		if err != nil {
+			return "", "", err
+		}
+
+		initial = line
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(initial)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid base64: %w", err)
+	}
+
+	parts := strings.Split(string(decoded), "\x00")
+	if len(parts) != 3 { //nolint:mnd // authzid, authcid, password
+		return "", "", errMalformedPlainResponse
+	}
+
+	return parts[1], parts[2], nil
+}
+
+func (s *session) authLogin(initial string) (string, string, error) {
+	username, err := s.challenge(initial, "Username:")
+	if err != nil {
+		return "", "", err
+	}
+
+	password, err := s.challenge("", "Password:")
+	if err != nil {
+		return "", "", err
+	}
+
+	return username, password, nil
+}
+
+// challenge sends a base64-encoded prompt (unless an initial response was given)
+// and returns the decoded client response.
+func (s *session) challenge(initial, prompt string) (string, error) {
+	response := initial
+	if response == "" {
+		s.reply("334 " + base64.StdEncoding.EncodeToString([]byte(prompt)))
+
+		line, err := s.readLine()
+		if err != nil {
+			return "", err
+		}
+
+		response = line
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(response)
+	if err != nil {
+		return "", fmt.Errorf("invalid base64: %w", err)
+	}
+
+	return string(decoded), nil
+}
+
+func (s *session) handleMail(arg string) {
+	if s.server.config.Username != "" && s.authMechanism == "" {
+		s.reply("530 5.7.0 Authentication required")
+
+		return
+	}
+
+	address, ok := parsePath(arg, "FROM:")
+	if !ok {
+		s.reply("501 5.5.4 Syntax error in MAIL")
+
+		return
+	}
+
+	s.from, s.to = address, nil
+	s.reply("250 2.1.0 OK")
+}
+
+func (s *session) handleRcpt(arg string) {
+	if s.from == "" {
+		s.reply("503 5.5.1 MAIL first")
+
+		return
+	}
+
+	address, ok := parsePath(arg, "TO:")
+	if !ok || address == "" {
+		s.reply("501 5.5.4 Syntax error in RCPT")
+
+		return
+	}
+
+	s.to = append(s.to, address)
+	s.reply("250 2.1.5 OK")
+}
+
+func (s *session) handleData() bool {
+	if len(s.to) == 0 {
+		s.reply("503 5.5.1 RCPT first")
+
+		return true
+	}
+
+	s.reply("354 End data with <CR><LF>.<CR><LF>")
+
+	var data strings.Builder
+
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return false
+		}
+
+		if line == "." {
+			break
+		}
+
+		// Undo dot-stuffing (RFC 5321 section 4.5.2)
+		data.WriteString(strings.TrimPrefix(line, "."))
+		data.WriteString("\r\n")
+	}
+
+	s.server.record(Message{
+		From:          s.from,
+		To:            s.to,
+		Data:          []byte(data.String()),
+		AuthMechanism: s.authMechanism,
+		Username:      s.username,
+		TLS:           s.tls,
+	})
+
+	s.from, s.to = "", nil
+	s.reply("250 2.0.0 OK: queued")
+
+	return true
+}
+
+func (s *session) readLine() (string, error) {
+	line, err := s.reader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read line: %w", err)
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (s *session) reply(line string) {
+	_, _ = s.writer.WriteString(line + "\r\n")
+	_ = s.writer.Flush()
+}
+
+// parsePath extracts the address from "FROM:<addr> PARAMS" or "TO:<addr>".
+func parsePath(arg, prefix string) (string, bool) {
+	if !strings.HasPrefix(strings.ToUpper(arg), prefix) {
+		return "", false
+	}
+
+	rest := strings.TrimSpace(arg[len(prefix):])
+
+	start := strings.Index(rest, "<")
+	end := strings.Index(rest, ">")
+
+	if start != 0 || end < start {
+		return "", false
+	}
+
+	return rest[start+1 : end], true
+}
+
+// newSelfSignedTLSConfig generates an ephemeral certificate valid for the loopback address.
+func newSelfSignedTLSConfig() (*tls.Config, *x509.CertPool, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(serialNumberMax))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: serverName},
+		DNSNames:              []string{serverName},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(certificateTTL),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse certificate: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	return tlsConfig, pool, nil
+}
@@ -9,24 +9,34 @@ import (
 	"firebase.google.com/go/v4/auth"
 )
 
-// NewClient initializes a new Firebase app and returns a Firestore client.
-func NewClient(ctx context.Context) (*firestore.Client, *auth.Client, error) {
+// NewApp initializes a new Firebase app.
+func NewApp(ctx context.Context) (*firebase.App, error) {
 	// The `FIRESTORE_EMULATOR_HOST` environment variable is automatically used by the
 	// library to connect to the emulator.
 	app, err := firebase.NewApp(ctx, nil)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create new firebase app: %w", err)
+		return nil, fmt.Errorf("failed to create new firebase app: %w", err)
 	}
 
-	firestoreClient, err := app.Firestore(ctx)
+	return app, nil
+}
+
+// NewAuthClient returns a Firebase Auth client for the app.
+func NewAuthClient(ctx context.Context, app *firebase.App) (*auth.Client, error) {
+	authClient, err := app.Auth(ctx)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create firestore client: %w", err)
+		return nil, fmt.Errorf("failed to create auth client: %w", err)
 	}
 
-	authClient, err := app.Auth(ctx)
+	return authClient, nil
+}
+
+// NewFirestoreClient returns a Firestore client for the app.
+func NewFirestoreClient(ctx context.Context, app *firebase.App) (*firestore.Client, error) {
+	firestoreClient, err := app.Firestore(ctx)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create auth client: %w", err)
+		return nil, fmt.Errorf("failed to create firestore client: %w", err)
 	}
 
-	return firestoreClient, authClient, nil
+	return firestoreClient, nil
 }
@@ -0,0 +1,113 @@
+package firebase
+
+import (
+	"context"
+	"fmt"
+
+	"firebase.google.com/go/v4/auth"
+
+	"custom_auth_api/internal/domain/claims"
+	"custom_auth_api/internal/domain/identity"
+)
+
+// UserDirectory is an identity.UserDirectory backed by Firebase Authentication.
+// Roles are stored in the claims.RolesClaim custom claim of the user.
+type UserDirectory struct {
+	client *auth.Client
+}
+
+// NewUserDirectory creates a UserDirectory using the Firebase Auth client.
+func NewUserDirectory(client *auth.Client) *UserDirectory {
+	return &UserDirectory{client: client}
+}
+
+// GetUserByEmail returns the Firebase user registered with email.
+func (d *UserDirectory) GetUserByEmail(ctx context.Context, email string) (*identity.User, error) {
+	record, err := d.client.GetUserByEmail(ctx, email)
+	if err != nil {
+		if auth.IsUserNotFound(err) {
+			return nil, fmt.Errorf("%w: %w", identity.ErrUserNotFound, err)
+		}
+
+		return nil, fmt.Errorf("failed to get user by email: %w", err)
+	}
+
+	return userFromRecord(record), nil
+}
+
+// CreateUser creates a Firebase user.
+func (d *UserDirectory) CreateUser(ctx context.Context, user identity.UserToCreate) (*identity.User, error) {
+	params := (&auth.UserToCreate{}).Email(user.Email).EmailVerified(user.EmailVerified)
+	if user.DisplayName != "" {
+		params = params.DisplayName(user.DisplayName)
+	}
+
+	record, err := d.client.CreateUser(ctx, params)
+	if err != nil {
+		if auth.IsEmailAlreadyExists(err) {
+			return nil, fmt.Errorf("%w: %w", identity.ErrEmailAlreadyExists, err)
+		}
+
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return userFromRecord(record), nil
+}
+
+// SetRoles replaces the roles custom claim of the user, keeping its other custom claims.
+// The new claims reach the user's ID token on its next refresh.
+func (d *UserDirectory) SetRoles(ctx context.Context, uid string, roles []string) error {
+	record, err := d.client.GetUser(ctx, uid)
+	if err != nil {
+		if auth.IsUserNotFound(err) {
+			return fmt.Errorf("%w: %w", identity.ErrUserNotFound, err)
+		}
+
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	customClaims := make(map[string]any, len(record.CustomClaims)+1)
+	for name, value := range record.CustomClaims {
+		customClaims[name] = value
+	}
+
+	customClaims[claims.RolesClaim] = roles
+
+	err = d.client.SetCustomUserClaims(ctx, uid, customClaims)
+	if err != nil {
+		return fmt.Errorf("failed to set custom claims: %w", err)
+	}
+
+	return nil
+}
+
+// userFromRecord converts a Firebase user record.
+func userFromRecord(record *auth.UserRecord) *identity.User {
+	return &identity.User{
+		UID:           record.UID,
+		Email:         record.Email,
+		EmailVerified: record.EmailVerified,
+		DisplayName:   record.DisplayName,
+		Roles:         claimStrings(record.CustomClaims[claims.RolesClaim]),
+	}
+}
+
+// claimStrings returns the strings in a decoded list claim, ignoring other values.
+func claimStrings(value any) []string {
+	list, ok := value.([]any)
+	if !ok {
+		return nil
+	}
+
+	values := make([]string, 0, len(list))
+
+	for _, item := range list {
+		if text, ok := item.(string); ok {
+			values = append(values, text)
+		}
+	}
+
+	return values
+}
+
+var _ identity.UserDirectory = (*UserDirectory)(nil)
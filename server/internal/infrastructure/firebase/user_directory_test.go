@@ -0,0 +1,81 @@
+package firebase_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	firebaseapp "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+
+	"custom_auth_api/internal/domain/identity"
+	"custom_auth_api/internal/domain/identity/identitytest"
+	"custom_auth_api/internal/infrastructure/firebase"
+)
+
+// setupAuthClient returns an Auth client for the emulator, skipping the test without one.
+func setupAuthClient(t *testing.T) *auth.Client {
+	t.Helper()
+
+	if os.Getenv("FIREBASE_AUTH_EMULATOR_HOST") == "" {
+		t.Skip("Skipping integration test: FIREBASE_AUTH_EMULATOR_HOST is not set.")
+	}
+
+	ctx := context.Background()
+
+	app, err := firebaseapp.NewApp(ctx, &firebaseapp.Config{ProjectID: "demo-project"}, option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("Failed to initialize Firebase app: %v", err)
+	}
+
+	client, err := app.Auth(ctx)
+	if err != nil {
+		t.Fatalf("Failed to create Auth client: %v", err)
+	}
+
+	return client
+}
+
+// deleteContractUsers removes the users created by identitytest.TestUserDirectory.
+func deleteContractUsers(t *testing.T, client *auth.Client) {
+	t.Helper()
+
+	ctx := context.Background()
+	users := client.Users(ctx, "")
+
+	for {
+		user, err := users.Next()
+		if errors.Is(err, iterator.Done) {
+			return
+		}
+
+		if err != nil {
+			t.Logf("Failed to list contract users: %v", err)
+
+			return
+		}
+
+		if strings.HasPrefix(user.Email, identitytest.ContractEmailPrefix) {
+			err := client.DeleteUser(ctx, user.UID)
+			if err != nil {
+				t.Logf("Failed to clean up user %s: %v", user.Email, err)
+			}
+		}
+	}
+}
+
+func TestUserDirectory_Contract(t *testing.T) {
+	client := setupAuthClient(t)
+
+	t.Cleanup(func() {
+		deleteContractUsers(t, client)
+	})
+
+	identitytest.TestUserDirectory(t, func(*testing.T) identity.UserDirectory {
+		return firebase.NewUserDirectory(client)
+	})
+}
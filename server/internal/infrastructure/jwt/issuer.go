@@ -0,0 +1,333 @@
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"custom_auth_api/internal/domain/claims"
+	"custom_auth_api/internal/domain/identity"
+)
+
+// AdminRole is the role that sets claims.AdminClaim on issued tokens. The standalone
+// backend has no console to set the claim on users, so administrators are granted this role.
+const AdminRole = "admin"
+
+// clockSkew is how far token times may be off the verifier's clock.
+const clockSkew = 30 * time.Second
+
+// tokenIDBytes is the size of the random "jti" claim.
+const tokenIDBytes = 16
+
+// Issuer errors.
+var (
+	ErrSigningKeyRequired       = errors.New("at least one token signing key is required")
+	ErrDuplicateSigningKeyID    = errors.New("token signing key ids must be unique")
+	ErrActiveSigningKeyNotFound = errors.New("active token key id is not in the key set")
+	ErrIssuerRequired           = errors.New("token issuer and audience are required")
+	ErrInvalidTTL               = errors.New("token ttl must be positive")
+
+	// ErrReservedClaim is returned when IssueToken is given a claim the issuer sets itself.
+	ErrReservedClaim = errors.New("claims must not set registered token claims")
+)
+
+// registeredClaims are set by the issuer and cannot be passed to IssueToken.
+var registeredClaims = []string{
+	"iss", "sub", "aud", "exp", "nbf", "iat", "jti", "email", "email_verified", "name", "amr", "auth_time",
+}
+
+// Options configures an Issuer.
+type Options struct {
+	Issuer   string        // "iss" claim, e.g. the public URL of the API
+	Audience string        // "aud" claim, the services the tokens are meant for
+	TTL      time.Duration // Lifetime of issued tokens
+
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Issuer is an identity.TokenIssuer minting short-lived access tokens signed with local keys.
+//
+// Tokens are compact JWS (RFC 7515) with the standard claims (iss, sub, aud, iat, exp, jti),
+// the user's email and email_verified, and the claims passed by the caller. The
+// authentication claims are written under their standard names: claims.AuthMethodsClaim
+// as "amr" (RFC 8176) and claims.AuthTimeClaim as "auth_time".
+//
+// New tokens are signed with the active key and verified with the key named by their "kid"
+// header. To rotate keys, add the new key and publish it through JWKS, make it active once
+// verifiers have fetched it, and remove the old key once its last tokens have expired.
+type Issuer struct {
+	activeKey *SigningKey
+	keys      map[string]*SigningKey
+	options   Options
+}
+
+// NewIssuer creates an Issuer from a key set and the ID of the key used for new tokens.
+func NewIssuer(activeKeyID string, keys []*SigningKey, options Options) (*Issuer, error) {
+	if len(keys) == 0 {
+		return nil, ErrSigningKeyRequired
+	}
+
+	if options.Issuer == "" || options.Audience == "" {
+		return nil, ErrIssuerRequired
+	}
+
+	if options.TTL <= 0 {
+		return nil, fmt.Errorf("%w (got %s)", ErrInvalidTTL, options.TTL)
+	}
+
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+
+	keysByID := make(map[string]*SigningKey, len(keys))
+
+	for _, key := range keys {
+		if _, ok := keysByID[key.id]; ok {
+			return nil, fmt.Errorf("%w (got %q twice)", ErrDuplicateSigningKeyID, key.id)
+		}
+
+		keysByID[key.id] = key
+	}
+
+	activeKey, ok := keysByID[activeKeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w (got %q)", ErrActiveSigningKeyNotFound, activeKeyID)
+	}
+
+	return &Issuer{activeKey: activeKey, keys: keysByID, options: options}, nil
+}
+
+// header is the JOSE header of issued tokens.
+type header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Type      string `json:"typ"`
+}
+
+// IssueToken returns an access token for user carrying custom. The roles in
+// claims.RolesClaim are combined with the user's roles; users with AdminRole get
+// claims.AdminClaim. Returns an error wrapping ErrReservedClaim if custom sets a
+// claim the issuer sets itself.
+func (i *Issuer) IssueToken(_ context.Context, user *identity.User, custom map[string]any) (string, error) {
+	now := i.options.Now()
+	payload := make(map[string]any, len(custom)+len(registeredClaims))
+
+	for name, value := range custom {
+		switch {
+		case name == claims.AuthMethodsClaim:
+			payload["amr"] = value
+		case name == claims.AuthTimeClaim:
+			payload["auth_time"] = value
+		case slices.Contains(registeredClaims, name):
+			return "", fmt.Errorf("%w (got %q)", ErrReservedClaim, name)
+		default:
+			payload[name] = value
+		}
+	}
+
+	roles := slices.Clone(user.Roles)
+	for _, role := range stringList(custom[claims.RolesClaim]) {
+		if !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+
+	delete(payload, claims.RolesClaim)
+
+	if len(roles) > 0 {
+		payload[claims.RolesClaim] = roles
+	}
+
+	if slices.Contains(roles, AdminRole) {
+		payload[claims.AdminClaim] = true
+	}
+
+	var tokenID [tokenIDBytes]byte
+
+	_, _ = rand.Read(tokenID[:]) // crypto/rand.Read never returns an error
+
+	payload["iss"] = i.options.Issuer
+	payload["sub"] = user.UID
+	payload["aud"] = i.options.Audience
+	payload["iat"] = now.Unix()
+	payload["exp"] = now.Add(i.options.TTL).Unix()
+	payload["jti"] = hex.EncodeToString(tokenID[:])
+
+	if user.Email != "" {
+		payload["email"] = user.Email
+		payload["email_verified"] = user.EmailVerified
+	}
+
+	if user.DisplayName != "" {
+		payload["name"] = user.DisplayName
+	}
+
+	encodedHeader, err := encodeSegment(header{Algorithm: i.activeKey.algorithm, KeyID: i.activeKey.id, Type: "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	encodedPayload, err := encodeSegment(payload)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := encodedHeader + "." + encodedPayload
+
+	signature, err := i.activeKey.sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// registeredClaimValues are the claims checked by VerifyToken.
+type registeredClaimValues struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	Audience  string `json:"aud"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	Email     string `json:"email"`
+}
+
+// VerifyToken verifies a token issued by an Issuer with the same keys, issuer and audience.
+// The token must be signed by a key in the key set with the algorithm of that key, and must
+// not be expired, allowing 30 seconds of clock skew. Returns an error wrapping
+// identity.ErrInvalidToken otherwise.
+func (i *Issuer) VerifyToken(_ context.Context, token string) (*identity.VerifiedToken, error) {
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return nil, invalidToken("malformed token")
+	}
+
+	var tokenHeader header
+
+	err := decodeSegment(segments[0], &tokenHeader)
+	if err != nil {
+		return nil, invalidToken("malformed header")
+	}
+
+	key, ok := i.keys[tokenHeader.KeyID]
+	if !ok || tokenHeader.Algorithm != key.algorithm {
+		return nil, invalidToken(fmt.Sprintf("unknown key %q or algorithm %q", tokenHeader.KeyID, tokenHeader.Algorithm))
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(segments[2])
+	if err != nil || !key.verify([]byte(segments[0]+"."+segments[1]), signature) {
+		return nil, invalidToken("signature mismatch")
+	}
+
+	var registered registeredClaimValues
+
+	err = decodeSegment(segments[1], &registered)
+	if err != nil {
+		return nil, invalidToken("malformed payload")
+	}
+
+	now := i.options.Now()
+
+	switch {
+	case registered.Issuer != i.options.Issuer || registered.Audience != i.options.Audience:
+		return nil, invalidToken(fmt.Sprintf("issued by %q for %q", registered.Issuer, registered.Audience))
+	case registered.Subject == "":
+		return nil, invalidToken("missing subject")
+	case now.After(time.Unix(registered.ExpiresAt, 0).Add(clockSkew)):
+		return nil, invalidToken("token has expired")
+	case now.Add(clockSkew).Before(time.Unix(registered.IssuedAt, 0)):
+		return nil, invalidToken("token is issued in the future")
+	}
+
+	var tokenClaims map[string]any
+
+	err = decodeSegment(segments[1], &tokenClaims)
+	if err != nil {
+		return nil, invalidToken("malformed payload")
+	}
+
+	return &identity.VerifiedToken{UID: registered.Subject, Email: registered.Email, Claims: tokenClaims}, nil
+}
+
+// jsonWebKeySet is a JWK Set (RFC 7517 section 5).
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// JWKS returns the public keys of the key set as a JSON Web Key Set, ordered by key ID.
+// It includes inactive keys, so tokens signed before a rotation can still be verified.
+func (i *Issuer) JWKS() ([]byte, error) {
+	keySet := jsonWebKeySet{Keys: make([]jsonWebKey, 0, len(i.keys))}
+
+	for _, keyID := range slices.Sorted(maps.Keys(i.keys)) {
+		jwk, err := i.keys[keyID].publicJWK()
+		if err != nil {
+			return nil, err
+		}
+
+		keySet.Keys = append(keySet.Keys, jwk)
+	}
+
+	encoded, err := json.Marshal(keySet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode key set: %w", err)
+	}
+
+	return encoded, nil
+}
+
+// encodeSegment returns value as base64url-encoded JSON.
+func encodeSegment(value any) (string, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(encoded), nil
+}
+
+// decodeSegment decodes base64url-encoded JSON into value.
+func decodeSegment(segment string, value any) error {
+	decoded, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(decoded, value)
+}
+
+// invalidToken returns an error wrapping identity.ErrInvalidToken.
+func invalidToken(reason string) error {
+	return fmt.Errorf("%w: %s", identity.ErrInvalidToken, reason)
+}
+
+// stringList returns the strings in a list claim, ignoring other values.
+func stringList(value any) []string {
+	switch list := value.(type) {
+	case []string:
+		return list
+	case []any:
+		values := make([]string, 0, len(list))
+
+		for _, item := range list {
+			if text, ok := item.(string); ok {
+				values = append(values, text)
+			}
+		}
+
+		return values
+	default:
+		return nil
+	}
+}
+
+var _ identity.TokenIssuer = (*Issuer)(nil)
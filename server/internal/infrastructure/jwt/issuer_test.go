@@ -0,0 +1,404 @@
+package jwt_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"custom_auth_api/internal/domain/claims"
+	"custom_auth_api/internal/domain/identity"
+	"custom_auth_api/internal/infrastructure/jwt"
+)
+
+const (
+	testIssuer   = "https://auth.example.com"
+	testAudience = "internal-tools"
+)
+
+var testUser = &identity.User{
+	UID:           "uid-1",
+	Email:         "user@example.com",
+	EmailVerified: true,
+	DisplayName:   "Test User",
+	Roles:         []string{"member"},
+}
+
+func mustGenerateKey(t *testing.T, keyID string) *jwt.SigningKey {
+	t.Helper()
+
+	key, err := jwt.GenerateSigningKey(keyID)
+	if err != nil {
+		t.Fatalf("GenerateSigningKey() returned an error: %v", err)
+	}
+
+	return key
+}
+
+func mustEd25519Key(t *testing.T, keyID string) *jwt.SigningKey {
+	t.Helper()
+
+	_, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	key, err := jwt.ParseSigningKey(keyID, mustPKCS8(t, privateKey))
+	if err != nil {
+		t.Fatalf("ParseSigningKey() returned an error: %v", err)
+	}
+
+	return key
+}
+
+func mustIssuer(t *testing.T, activeKeyID string, keys []*jwt.SigningKey, now func() time.Time) *jwt.Issuer {
+	t.Helper()
+
+	issuer, err := jwt.NewIssuer(activeKeyID, keys, jwt.Options{
+		Issuer:   testIssuer,
+		Audience: testAudience,
+		TTL:      15 * time.Minute,
+		Now:      now,
+	})
+	if err != nil {
+		t.Fatalf("NewIssuer() returned an error: %v", err)
+	}
+
+	return issuer
+}
+
+func mustIssueToken(t *testing.T, issuer *jwt.Issuer, custom map[string]any) string {
+	t.Helper()
+
+	token, err := issuer.IssueToken(context.Background(), testUser, custom)
+	if err != nil {
+		t.Fatalf("IssueToken() returned an error: %v", err)
+	}
+
+	return token
+}
+
+// decodeJSONSegment decodes one base64url JSON segment of token.
+func decodeJSONSegment(t *testing.T, token string, index int) map[string]any {
+	t.Helper()
+
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[index])
+	if err != nil {
+		t.Fatalf("Token segment %d is not base64url: %v", index, err)
+	}
+
+	var segment map[string]any
+
+	err = json.Unmarshal(decoded, &segment)
+	if err != nil {
+		t.Fatalf("Token segment %d is not JSON: %v", index, err)
+	}
+
+	return segment
+}
+
+func TestNewIssuer_Validation(t *testing.T) {
+	t.Parallel()
+
+	key := mustGenerateKey(t, "k1")
+	options := jwt.Options{Issuer: testIssuer, Audience: testAudience, TTL: time.Minute, Now: nil}
+
+	tests := []struct {
+		name        string
+		activeKeyID string
+		keys        []*jwt.SigningKey
+		options     jwt.Options
+		wantErr     error
+	}{
+		{name: "no keys", activeKeyID: "k1", keys: nil, options: options, wantErr: jwt.ErrSigningKeyRequired},
+		{
+			name:        "unknown active key",
+			activeKeyID: "k2",
+			keys:        []*jwt.SigningKey{key},
+			options:     options,
+			wantErr:     jwt.ErrActiveSigningKeyNotFound,
+		},
+		{
+			name:        "duplicate key id",
+			activeKeyID: "k1",
+			keys:        []*jwt.SigningKey{key, mustEd25519Key(t, "k1")},
+			options:     options,
+			wantErr:     jwt.ErrDuplicateSigningKeyID,
+		},
+		{
+			name:        "missing audience",
+			activeKeyID: "k1",
+			keys:        []*jwt.SigningKey{key},
+			options:     jwt.Options{Issuer: testIssuer, Audience: "", TTL: time.Minute, Now: nil},
+			wantErr:     jwt.ErrIssuerRequired,
+		},
+		{
+			name:        "zero ttl",
+			activeKeyID: "k1",
+			keys:        []*jwt.SigningKey{key},
+			options:     jwt.Options{Issuer: testIssuer, Audience: testAudience, TTL: 0, Now: nil},
+			wantErr:     jwt.ErrInvalidTTL,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			_, err := jwt.NewIssuer(tt.activeKeyID, tt.keys, tt.options)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestIssuer_IssueToken_Claims(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	now := time.Unix(1_800_000_000, 0)
+	issuer := mustIssuer(t, "k1", []*jwt.SigningKey{mustGenerateKey(t, "k1")}, func() time.Time { return now })
+
+	// Act
+	token := mustIssueToken(t, issuer, map[string]any{
+		claims.RolesClaim:       []string{"admin", "member"},
+		claims.AuthMethodsClaim: []string{"otp", "email"},
+		claims.AuthTimeClaim:    now.Unix(),
+		"tenant":                "acme",
+	})
+
+	// Assert
+	tokenHeader := decodeJSONSegment(t, token, 0)
+	if tokenHeader["alg"] != jwt.AlgorithmES256 || tokenHeader["kid"] != "k1" || tokenHeader["typ"] != "JWT" {
+		t.Errorf("Unexpected header %v", tokenHeader)
+	}
+
+	payload := decodeJSONSegment(t, token, 1)
+	expected := map[string]any{
+		"iss":            testIssuer,
+		"sub":            "uid-1",
+		"aud":            testAudience,
+		"iat":            float64(now.Unix()),
+		"exp":            float64(now.Add(15 * time.Minute).Unix()),
+		"email":          "user@example.com",
+		"email_verified": true,
+		"name":           "Test User",
+		"auth_time":      float64(now.Unix()),
+		"tenant":         "acme",
+		"admin":          true,
+	}
+
+	for name, value := range expected {
+		if payload[name] != value {
+			t.Errorf("Expected claim %q to be %v, got %v", name, value, payload[name])
+		}
+	}
+
+	if roles, _ := json.Marshal(payload[claims.RolesClaim]); string(roles) != `["member","admin"]` {
+		t.Errorf("Expected the user's roles followed by the added roles, got %s", roles)
+	}
+
+	if amr, _ := json.Marshal(payload["amr"]); string(amr) != `["otp","email"]` {
+		t.Errorf("Expected amr [otp email], got %s", amr)
+	}
+
+	for _, name := range []string{claims.AuthMethodsClaim, claims.AuthTimeClaim} {
+		if _, ok := payload[name]; ok {
+			t.Errorf("Expected %q to be written under its standard name", name)
+		}
+	}
+
+	if jti, _ := payload["jti"].(string); len(jti) != 32 {
+		t.Errorf("Expected a random token ID, got %v", payload["jti"])
+	}
+}
+
+func TestIssuer_IssueToken_RejectsRegisteredClaims(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	issuer := mustIssuer(t, "k1", []*jwt.SigningKey{mustGenerateKey(t, "k1")}, nil)
+
+	for _, name := range []string{"sub", "exp", "email", "amr"} {
+		// Act
+		token, err := issuer.IssueToken(context.Background(), testUser, map[string]any{name: "forged"})
+
+		// Assert
+		if !errors.Is(err, jwt.ErrReservedClaim) || token != "" {
+			t.Errorf("Expected ErrReservedClaim for %q, got %q and %v", name, token, err)
+		}
+	}
+}
+
+func TestIssuer_VerifyToken(t *testing.T) {
+	t.Parallel()
+
+	for _, key := range []*jwt.SigningKey{mustGenerateKey(t, "es256"), mustEd25519Key(t, "eddsa")} {
+		t.Run(key.Algorithm(), func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			issuer := mustIssuer(t, key.ID(), []*jwt.SigningKey{key}, nil)
+			token := mustIssueToken(t, issuer, map[string]any{claims.RolesClaim: []string{"admin"}})
+
+			// Act
+			verified, err := issuer.VerifyToken(context.Background(), token)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("VerifyToken() returned an error: %v", err)
+			}
+
+			if verified.UID != "uid-1" || verified.Email != "user@example.com" || verified.Claims[claims.AdminClaim] != true {
+				t.Errorf("Unexpected verified token %+v", verified)
+			}
+		})
+	}
+}
+
+func TestIssuer_VerifyToken_Rejects(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_800_000_000, 0)
+	key := mustGenerateKey(t, "k1")
+	issuer := mustIssuer(t, "k1", []*jwt.SigningKey{key}, func() time.Time { return now })
+	token := mustIssueToken(t, issuer, nil)
+	segments := strings.Split(token, ".")
+
+	forgedPayload := base64.RawURLEncoding.EncodeToString(
+		[]byte(`{"iss":"` + testIssuer + `","aud":"` + testAudience + `","sub":"uid-admin","exp":1900000000,"admin":true}`),
+	)
+	noneHeader := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"k1","typ":"JWT"}`))
+
+	otherAudience, _ := jwt.NewIssuer("k1", []*jwt.SigningKey{key}, jwt.Options{
+		Issuer:   testIssuer,
+		Audience: "other-service",
+		TTL:      15 * time.Minute,
+		Now:      func() time.Time { return now },
+	})
+	later := mustIssuer(t, "k1", []*jwt.SigningKey{key}, func() time.Time { return now.Add(16 * time.Minute) })
+	otherKey := mustIssuer(t, "k1", []*jwt.SigningKey{mustGenerateKey(t, "k1")}, func() time.Time { return now })
+
+	tests := []struct {
+		name     string
+		verifier *jwt.Issuer
+		token    string
+	}{
+		{name: "malformed", verifier: issuer, token: "not-a-token"},
+		{name: "forged payload", verifier: issuer, token: segments[0] + "." + forgedPayload + "." + segments[2]},
+		{name: "alg none", verifier: issuer, token: noneHeader + "." + segments[1] + "."},
+		{name: "other audience", verifier: otherAudience, token: token},
+		{name: "expired", verifier: later, token: token},
+		{name: "unknown key", verifier: otherKey, token: token},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			verified, err := tt.verifier.VerifyToken(context.Background(), tt.token)
+
+			// Assert
+			if !errors.Is(err, identity.ErrInvalidToken) || verified != nil {
+				t.Errorf("Expected ErrInvalidToken, got %+v and %v", verified, err)
+			}
+		})
+	}
+}
+
+func TestIssuer_KeyRotation(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	oldKey := mustGenerateKey(t, "2026-09")
+	newKey := mustEd25519Key(t, "2026-10")
+	beforeRotation := mustIssuer(t, "2026-09", []*jwt.SigningKey{oldKey}, nil)
+	oldToken := mustIssueToken(t, beforeRotation, nil)
+
+	// Act
+	afterRotation := mustIssuer(t, "2026-10", []*jwt.SigningKey{oldKey, newKey}, nil)
+	newToken := mustIssueToken(t, afterRotation, nil)
+	oldKeyRemoved := mustIssuer(t, "2026-10", []*jwt.SigningKey{newKey}, nil)
+
+	// Assert
+	if kid := decodeJSONSegment(t, newToken, 0)["kid"]; kid != "2026-10" {
+		t.Errorf("Expected new tokens to be signed with the active key, got kid %v", kid)
+	}
+
+	_, err := afterRotation.VerifyToken(context.Background(), oldToken)
+	if err != nil {
+		t.Errorf("Expected tokens signed with the previous key to stay valid, got %v", err)
+	}
+
+	_, err = beforeRotation.VerifyToken(context.Background(), newToken)
+	if !errors.Is(err, identity.ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken from a verifier without the new key, got %v", err)
+	}
+
+	_, err = oldKeyRemoved.VerifyToken(context.Background(), oldToken)
+	if !errors.Is(err, identity.ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken once the old key is removed, got %v", err)
+	}
+}
+
+func TestIssuer_JWKS(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	issuer := mustIssuer(t, "es256", []*jwt.SigningKey{mustGenerateKey(t, "es256"), mustEd25519Key(t, "eddsa")}, nil)
+	token := mustIssueToken(t, issuer, nil)
+
+	// Act
+	encoded, err := issuer.JWKS()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("JWKS() returned an error: %v", err)
+	}
+
+	var keySet struct {
+		Keys []map[string]string `json:"keys"`
+	}
+
+	err = json.Unmarshal(encoded, &keySet)
+	if err != nil {
+		t.Fatalf("JWKS() returned invalid JSON: %v", err)
+	}
+
+	if len(keySet.Keys) != 2 || keySet.Keys[0]["kid"] != "eddsa" || keySet.Keys[1]["kid"] != "es256" {
+		t.Fatalf("Expected both keys ordered by key ID, got %s", encoded)
+	}
+
+	if jwk := keySet.Keys[0]; jwk["kty"] != "OKP" || jwk["crv"] != "Ed25519" || jwk["alg"] != jwt.AlgorithmEdDSA {
+		t.Errorf("Unexpected Ed25519 key %v", jwk)
+	}
+
+	// A verifier holding only the published key accepts the token
+	jwk := keySet.Keys[1]
+	if jwk["kty"] != "EC" || jwk["crv"] != "P-256" || jwk["alg"] != jwt.AlgorithmES256 || jwk["use"] != "sig" {
+		t.Fatalf("Unexpected P-256 key %v", jwk)
+	}
+
+	x, _ := base64.RawURLEncoding.DecodeString(jwk["x"])
+	y, _ := base64.RawURLEncoding.DecodeString(jwk["y"])
+	publicKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+
+	segments := strings.Split(token, ".")
+	signature, _ := base64.RawURLEncoding.DecodeString(segments[2])
+	digest := sha256.Sum256([]byte(segments[0] + "." + segments[1]))
+
+	if !ecdsa.Verify(publicKey, digest[:], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])) {
+		t.Error("Expected the published key to verify the token signature")
+	}
+}
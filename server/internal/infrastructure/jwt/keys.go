@@ -0,0 +1,209 @@
+// Package jwt issues and verifies the access tokens of the standalone identity backend:
+// JSON Web Tokens signed with ES256 or EdDSA keys loaded from PEM files, and the
+// JSON Web Key Set that lets other services verify them.
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// Signing algorithms, as written in the "alg" header and JWK.
+const (
+	AlgorithmES256 = "ES256" // ECDSA P-256 with SHA-256
+	AlgorithmEdDSA = "EdDSA" // Ed25519
+)
+
+// es256CoordinateSize is the size of P-256 coordinates and of the r and s signature halves.
+const es256CoordinateSize = 32
+
+// Signing key errors.
+var (
+	ErrInvalidKeyID   = errors.New("signing key id must be non-empty")
+	ErrInvalidKeyPEM  = errors.New("signing key must be a PEM-encoded private key")
+	ErrUnsupportedKey = errors.New("signing key must be an ECDSA P-256 (ES256) or Ed25519 (EdDSA) private key")
+)
+
+// SigningKey is a private key tokens are signed with, identified by the "kid" header.
+type SigningKey struct {
+	id        string
+	algorithm string
+	signer    crypto.Signer
+}
+
+// ParseSigningKey parses a PEM-encoded ECDSA P-256 or Ed25519 private key
+// ("PRIVATE KEY" in PKCS #8, or "EC PRIVATE KEY" in SEC 1 form, as written by openssl).
+func ParseSigningKey(keyID string, pemData []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, fmt.Errorf("%w (key %q)", ErrInvalidKeyPEM, keyID)
+	}
+
+	var (
+		key any
+		err error
+	)
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w (key %q has PEM type %q)", ErrInvalidKeyPEM, keyID, block.Type)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w (key %q): %w", ErrInvalidKeyPEM, keyID, err)
+	}
+
+	return newSigningKey(keyID, key)
+}
+
+// LoadSigningKey reads a signing key from a PEM file. See ParseSigningKey.
+func LoadSigningKey(keyID, path string) (*SigningKey, error) {
+	pemData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key %q: %w", keyID, err)
+	}
+
+	return ParseSigningKey(keyID, pemData)
+}
+
+// GenerateSigningKey creates a random ES256 key, e.g. for development without key files.
+// Tokens signed with it cannot be verified after a restart.
+func GenerateSigningKey(keyID string) (*SigningKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	return newSigningKey(keyID, key)
+}
+
+// newSigningKey wraps a parsed private key, selecting the algorithm from its type.
+func newSigningKey(keyID string, key any) (*SigningKey, error) {
+	if keyID == "" {
+		return nil, ErrInvalidKeyID
+	}
+
+	switch key := key.(type) {
+	case *ecdsa.PrivateKey:
+		if key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("%w (key %q uses curve %s)", ErrUnsupportedKey, keyID, key.Curve.Params().Name)
+		}
+
+		return &SigningKey{id: keyID, algorithm: AlgorithmES256, signer: key}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{id: keyID, algorithm: AlgorithmEdDSA, signer: key}, nil
+	default:
+		return nil, fmt.Errorf("%w (key %q is %T)", ErrUnsupportedKey, keyID, key)
+	}
+}
+
+// ID returns the key ID written in the "kid" header of the tokens the key signs.
+func (k *SigningKey) ID() string {
+	return k.id
+}
+
+// Algorithm returns AlgorithmES256 or AlgorithmEdDSA.
+func (k *SigningKey) Algorithm() string {
+	return k.algorithm
+}
+
+// sign returns the JWS signature of signingInput. ES256 signatures are r and s
+// as 32-byte big-endian integers, as required by RFC 7518 (not ASN.1).
+func (k *SigningKey) sign(signingInput []byte) ([]byte, error) {
+	switch key := k.signer.(type) {
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256(signingInput)
+
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign token: %w", err)
+		}
+
+		signature := make([]byte, 2*es256CoordinateSize)
+		r.FillBytes(signature[:es256CoordinateSize])
+		s.FillBytes(signature[es256CoordinateSize:])
+
+		return signature, nil
+	case ed25519.PrivateKey:
+		return ed25519.Sign(key, signingInput), nil
+	default:
+		return nil, fmt.Errorf("%w (key %q)", ErrUnsupportedKey, k.id)
+	}
+}
+
+// verify reports whether signature is a valid JWS signature of signingInput.
+func (k *SigningKey) verify(signingInput, signature []byte) bool {
+	switch key := k.signer.(type) {
+	case *ecdsa.PrivateKey:
+		if len(signature) != 2*es256CoordinateSize {
+			return false
+		}
+
+		digest := sha256.Sum256(signingInput)
+		r := new(big.Int).SetBytes(signature[:es256CoordinateSize])
+		s := new(big.Int).SetBytes(signature[es256CoordinateSize:])
+
+		return ecdsa.Verify(&key.PublicKey, digest[:], r, s)
+	case ed25519.PrivateKey:
+		publicKey, _ := key.Public().(ed25519.PublicKey)
+
+		return ed25519.Verify(publicKey, signingInput, signature)
+	default:
+		return false
+	}
+}
+
+// jsonWebKey is the public half of a signing key as a JWK (RFC 7517, RFC 8037).
+type jsonWebKey struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y,omitempty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+}
+
+// publicJWK returns the public key as a JWK.
+func (k *SigningKey) publicJWK() (jsonWebKey, error) {
+	jwk := jsonWebKey{KeyType: "", Curve: "", X: "", Y: "", KeyID: k.id, Algorithm: k.algorithm, Use: "sig"}
+
+	switch key := k.signer.(type) {
+	case *ecdsa.PrivateKey:
+		publicKey, err := key.PublicKey.ECDH()
+		if err != nil {
+			return jsonWebKey{}, fmt.Errorf("failed to encode signing key %q: %w", k.id, err)
+		}
+
+		// Uncompressed point: 0x04 || X || Y
+		point := publicKey.Bytes()
+		jwk.KeyType = "EC"
+		jwk.Curve = "P-256"
+		jwk.X = base64.RawURLEncoding.EncodeToString(point[1 : 1+es256CoordinateSize])
+		jwk.Y = base64.RawURLEncoding.EncodeToString(point[1+es256CoordinateSize:])
+	case ed25519.PrivateKey:
+		publicKey, _ := key.Public().(ed25519.PublicKey)
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	default:
+		return jsonWebKey{}, fmt.Errorf("%w (key %q)", ErrUnsupportedKey, k.id)
+	}
+
+	return jwk, nil
+}
@@ -0,0 +1,110 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"custom_auth_api/internal/infrastructure/jwt"
+)
+
+// encodePEM returns key as a PEM block of type blockType.
+func encodePEM(blockType string, der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Headers: nil, Bytes: der})
+}
+
+// mustPKCS8 returns key in PKCS #8 form.
+func mustPKCS8(t *testing.T, key any) []byte {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey() returned an error: %v", err)
+	}
+
+	return encodePEM("PRIVATE KEY", der)
+}
+
+func TestParseSigningKey(t *testing.T) {
+	t.Parallel()
+
+	p256Key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384Key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	_, ed25519Key, _ := ed25519.GenerateKey(rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	sec1DER, _ := x509.MarshalECPrivateKey(p256Key)
+
+	tests := []struct {
+		name          string
+		keyID         string
+		pemData       []byte
+		wantAlgorithm string
+		wantErr       error
+	}{
+		{name: "ES256 PKCS8", keyID: "k1", pemData: mustPKCS8(t, p256Key), wantAlgorithm: jwt.AlgorithmES256, wantErr: nil},
+		{name: "ES256 SEC1", keyID: "k1", pemData: encodePEM("EC PRIVATE KEY", sec1DER), wantAlgorithm: jwt.AlgorithmES256, wantErr: nil},
+		{name: "Ed25519", keyID: "k1", pemData: mustPKCS8(t, ed25519Key), wantAlgorithm: jwt.AlgorithmEdDSA, wantErr: nil},
+		{name: "P-384", keyID: "k1", pemData: mustPKCS8(t, p384Key), wantAlgorithm: "", wantErr: jwt.ErrUnsupportedKey},
+		{name: "RSA", keyID: "k1", pemData: mustPKCS8(t, rsaKey), wantAlgorithm: "", wantErr: jwt.ErrUnsupportedKey},
+		{name: "not PEM", keyID: "k1", pemData: []byte("not a key"), wantAlgorithm: "", wantErr: jwt.ErrInvalidKeyPEM},
+		{name: "public key", keyID: "k1", pemData: encodePEM("PUBLIC KEY", []byte{1}), wantAlgorithm: "", wantErr: jwt.ErrInvalidKeyPEM},
+		{name: "corrupt DER", keyID: "k1", pemData: encodePEM("PRIVATE KEY", []byte{1}), wantAlgorithm: "", wantErr: jwt.ErrInvalidKeyPEM},
+		{name: "empty key id", keyID: "", pemData: mustPKCS8(t, p256Key), wantAlgorithm: "", wantErr: jwt.ErrInvalidKeyID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			key, err := jwt.ParseSigningKey(tt.keyID, tt.pemData)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if err == nil && (key.ID() != tt.keyID || key.Algorithm() != tt.wantAlgorithm) {
+				t.Errorf("Expected key %q with %s, got %q with %s", tt.keyID, tt.wantAlgorithm, key.ID(), key.Algorithm())
+			}
+		})
+	}
+}
+
+func TestLoadSigningKey(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	_, ed25519Key, _ := ed25519.GenerateKey(rand.Reader)
+	path := filepath.Join(t.TempDir(), "signing.pem")
+
+	err := os.WriteFile(path, mustPKCS8(t, ed25519Key), 0o600)
+	if err != nil {
+		t.Fatalf("WriteFile() returned an error: %v", err)
+	}
+
+	// Act
+	key, err := jwt.LoadSigningKey("2026-10", path)
+	_, missingErr := jwt.LoadSigningKey("missing", filepath.Join(t.TempDir(), "missing.pem"))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("LoadSigningKey() returned an error: %v", err)
+	}
+
+	if key.ID() != "2026-10" || key.Algorithm() != jwt.AlgorithmEdDSA {
+		t.Errorf("Unexpected key %q with %s", key.ID(), key.Algorithm())
+	}
+
+	if !errors.Is(missingErr, os.ErrNotExist) {
+		t.Errorf("Expected os.ErrNotExist for a missing file, got %v", missingErr)
+	}
+}
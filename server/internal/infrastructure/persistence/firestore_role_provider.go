@@ -0,0 +1,73 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"custom_auth_api/internal/domain/claims"
+	"custom_auth_api/internal/domain/entity"
+)
+
+// DefaultRoleCollection is the Firestore collection read by FirestoreRoleProvider by default.
+const DefaultRoleCollection = "roles"
+
+// roleDocument represents the Firestore document schema of a user's roles.
+// The user's UID is the document ID.
+type roleDocument struct {
+	Roles []string `firestore:"roles"`
+}
+
+// FirestoreRoleProvider is a claims.Provider reading each user's roles from a Firestore
+// document named by the UID, e.g. roles/{uid} = {roles: ["admin"]}.
+// Users without a document get no claims.
+type FirestoreRoleProvider struct {
+	client     *firestore.Client
+	collection string
+}
+
+// NewFirestoreRoleProvider creates a FirestoreRoleProvider reading the collection.
+// An empty collection selects DefaultRoleCollection.
+func NewFirestoreRoleProvider(client *firestore.Client, collection string) *FirestoreRoleProvider {
+	if collection == "" {
+		collection = DefaultRoleCollection
+	}
+
+	return &FirestoreRoleProvider{client: client, collection: collection}
+}
+
+// Claims returns the subject's roles as the claims.RolesClaim.
+// Returns an error wrapping entity.ErrInvalidRole if the stored roles are invalid.
+func (p *FirestoreRoleProvider) Claims(ctx context.Context, subject claims.Subject) (map[string]any, error) {
+	docSnap, err := p.client.Collection(p.collection).Doc(subject.UID).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return map[string]any{}, nil
+		}
+
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+
+	var doc roleDocument
+
+	err = docSnap.DataTo(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse roles: %w", err)
+	}
+
+	roles, err := entity.NormalizeRoles(doc.Roles)
+	if err != nil {
+		return nil, fmt.Errorf("invalid roles for %s: %w", subject.UID, err)
+	}
+
+	if len(roles) == 0 {
+		return map[string]any{}, nil
+	}
+
+	return map[string]any{claims.RolesClaim: roles}, nil
+}
+
+var _ claims.Provider = (*FirestoreRoleProvider)(nil)
@@ -0,0 +1,70 @@
+package persistence_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"custom_auth_api/internal/domain/claims"
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/infrastructure/persistence"
+)
+
+func TestFirestoreRoleProvider_Claims(t *testing.T) {
+	client := setupFirestoreClient(t)
+	ctx := context.Background()
+	collection := "roles-" + t.Name()
+
+	docs := map[string]map[string]any{
+		"with-roles":    {"roles": []string{"admin", "member", "admin"}},
+		"invalid-roles": {"roles": []string{"has space"}},
+	}
+
+	for uid, data := range docs {
+		_, err := client.Collection(collection).Doc(uid).Set(ctx, data)
+		if err != nil {
+			t.Fatalf("Failed to write roles for %s: %v", uid, err)
+		}
+
+		t.Cleanup(func() {
+			_, _ = client.Collection(collection).Doc(uid).Delete(context.Background())
+		})
+	}
+
+	provider := persistence.NewFirestoreRoleProvider(client, collection)
+
+	t.Run("returns stored roles without duplicates", func(t *testing.T) {
+		// Act
+		granted, err := provider.Claims(ctx, claims.Subject{UID: "with-roles", Email: ""})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("Claims() error = %v", err)
+		}
+
+		if roles, _ := granted[claims.RolesClaim].([]string); !slices.Equal(roles, []string{"admin", "member"}) {
+			t.Errorf("expected roles [admin member], got %v", granted)
+		}
+	})
+
+	t.Run("returns no claims without a document", func(t *testing.T) {
+		// Act
+		granted, err := provider.Claims(ctx, claims.Subject{UID: "missing", Email: ""})
+
+		// Assert
+		if err != nil || len(granted) != 0 {
+			t.Errorf("expected no claims, got %v, %v", granted, err)
+		}
+	})
+
+	t.Run("rejects invalid roles", func(t *testing.T) {
+		// Act
+		_, err := provider.Claims(ctx, claims.Subject{UID: "invalid-roles", Email: ""})
+
+		// Assert
+		if !errors.Is(err, entity.ErrInvalidRole) {
+			t.Errorf("expected ErrInvalidRole, got %v", err)
+		}
+	})
+}
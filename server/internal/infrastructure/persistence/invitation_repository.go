@@ -0,0 +1,171 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/repository"
+	"custom_auth_api/internal/domain/vo/email"
+)
+
+const (
+	invitationCollection = "invitations"
+)
+
+// invitationDocument represents the Firestore document schema for invitations.
+// This is the persistence model, separate from the domain entity.
+// The invitation ID is the document ID.
+type invitationDocument struct {
+	Email      string    `firestore:"email"`
+	Inviter    string    `firestore:"inviter"`
+	Roles      []string  `firestore:"roles"`
+	CreatedAt  time.Time `firestore:"createdAt"`
+	ExpiresAt  time.Time `firestore:"expiresAt"`
+	AcceptedAt time.Time `firestore:"acceptedAt,omitempty"`
+}
+
+// InvitationRepository handles Invitation persistence in Firestore.
+// Invitations are kept after they expire or are accepted, so the outcome of an
+// invitation can be audited; expired invitations are reported as entity.ErrInvitationExpired.
+type InvitationRepository struct {
+	client *firestore.Client
+}
+
+// NewInvitationRepository creates a new InvitationRepository.
+func NewInvitationRepository(client *firestore.Client) *InvitationRepository {
+	return &InvitationRepository{client: client}
+}
+
+// Save stores or replaces an invitation in Firestore.
+func (r *InvitationRepository) Save(ctx context.Context, invitation *entity.Invitation) error {
+	_, err := r.client.Collection(invitationCollection).Doc(invitation.ID()).Set(ctx, newInvitationDocument(invitation))
+	if err != nil {
+		return fmt.Errorf("failed to save invitation: %w", err)
+	}
+
+	return nil
+}
+
+// FindByID retrieves an invitation from Firestore by ID.
+// Returns entity.ErrInvitationNotFound if the document doesn't exist.
+func (r *InvitationRepository) FindByID(ctx context.Context, id string) (*entity.Invitation, error) {
+	docSnap, err := r.client.Collection(invitationCollection).Doc(id).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, entity.ErrInvitationNotFound
+		}
+
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+
+	return invitationFromSnapshot(docSnap)
+}
+
+// Delete removes an invitation from Firestore.
+func (r *InvitationRepository) Delete(ctx context.Context, id string) error {
+	_, err := r.client.Collection(invitationCollection).Doc(id).Delete(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete invitation: %w", err)
+	}
+
+	return nil
+}
+
+// Accept marks the invitation as accepted inside a Firestore transaction.
+// Firestore retries the transaction when another one modifies the document
+// concurrently, so only one acceptance can succeed.
+func (r *InvitationRepository) Accept(ctx context.Context, id string) (*entity.Invitation, error) {
+	docRef := r.client.Collection(invitationCollection).Doc(id)
+
+	var (
+		invitation *entity.Invitation
+		acceptErr  error
+	)
+
+	err := r.client.RunTransaction(ctx, func(_ context.Context, tx *firestore.Transaction) error {
+		// Reset on every run: the function is retried on contention
+		invitation, acceptErr = nil, nil
+
+		docSnap, err := tx.Get(docRef)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				acceptErr = entity.ErrInvitationNotFound
+
+				return nil
+			}
+
+			return fmt.Errorf("failed to get invitation: %w", err)
+		}
+
+		invitation, err = invitationFromSnapshot(docSnap)
+		if err != nil {
+			return err
+		}
+
+		acceptErr = invitation.Accept()
+		if acceptErr != nil {
+			return nil
+		}
+
+		return tx.Update(docRef, []firestore.Update{{Path: "acceptedAt", Value: invitation.AcceptedAt()}})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invitation acceptance transaction failed: %w", err)
+	}
+
+	if acceptErr != nil {
+		return nil, acceptErr
+	}
+
+	return invitation, nil
+}
+
+// invitationFromSnapshot converts a Firestore snapshot into a domain entity.
+func invitationFromSnapshot(docSnap *firestore.DocumentSnapshot) (*entity.Invitation, error) {
+	var doc invitationDocument
+
+	err := docSnap.DataTo(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal invitation: %w", err)
+	}
+
+	return reconstructInvitationFromDocument(docSnap.Ref.ID, doc)
+}
+
+// newInvitationDocument converts an invitation into its persistence model.
+func newInvitationDocument(invitation *entity.Invitation) invitationDocument {
+	return invitationDocument{
+		Email:      invitation.Email().Value,
+		Inviter:    invitation.Inviter(),
+		Roles:      invitation.Roles(),
+		CreatedAt:  invitation.CreatedAt(),
+		ExpiresAt:  invitation.ExpiresAt(),
+		AcceptedAt: invitation.AcceptedAt(),
+	}
+}
+
+// reconstructInvitationFromDocument creates a domain entity from a persisted invitation.
+// It is shared by all backends, which map their records onto invitationDocument.
+func reconstructInvitationFromDocument(id string, doc invitationDocument) (*entity.Invitation, error) {
+	userEmail, err := email.FromString(doc.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconstruct email: %w", err)
+	}
+
+	data, err := entity.NewInvitationRestorationData(
+		id, userEmail, doc.Inviter, doc.Roles, doc.CreatedAt, doc.ExpiresAt, doc.AcceptedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invitation restoration data: %w", err)
+	}
+
+	return entity.RestoreInvitation(data), nil
+}
+
+var _ repository.InvitationRepository = (*InvitationRepository)(nil)
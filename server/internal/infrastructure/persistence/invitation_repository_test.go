@@ -0,0 +1,49 @@
+package persistence_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/repository"
+	"custom_auth_api/internal/domain/repository/repositorytest"
+	"custom_auth_api/internal/domain/vo/email"
+	"custom_auth_api/internal/infrastructure/persistence"
+)
+
+// saveTestInvitation stores an invitation for <id>@example.com that expires at expiresAt.
+func saveTestInvitation(
+	t *testing.T,
+	repo repository.InvitationRepository,
+	id string,
+	expiresAt time.Time,
+) *entity.Invitation {
+	t.Helper()
+
+	userEmail, _ := email.NewEmail(id + "@example.com")
+
+	data, err := entity.NewInvitationRestorationData(
+		id, userEmail, "admin@example.com", []string{"member"}, expiresAt.Add(-time.Hour), expiresAt, time.Time{},
+	)
+	if err != nil {
+		t.Fatalf("Failed to create invitation restoration data: %v", err)
+	}
+
+	invitation := entity.RestoreInvitation(data)
+
+	err = repo.Save(context.Background(), invitation)
+	if err != nil {
+		t.Fatalf("Failed to save invitation: %v", err)
+	}
+
+	return invitation
+}
+
+func TestInvitationRepository_Contract(t *testing.T) {
+	repositorytest.TestInvitationRepository(t, func(t *testing.T) repository.InvitationRepository {
+		t.Helper()
+
+		return persistence.NewInvitationRepository(setupFirestoreClient(t))
+	})
+}
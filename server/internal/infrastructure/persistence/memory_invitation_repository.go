@@ -0,0 +1,130 @@
+package persistence
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/repository"
+)
+
+// MemoryInvitationRepository stores invitations in process memory.
+// It is safe for concurrent use and intended for single-node deployments,
+// local development and tests. Invitations are lost on restart.
+//
+// Expired invitations are kept (so acceptance reports entity.ErrInvitationExpired)
+// until the next eviction sweep run by RunEviction.
+type MemoryInvitationRepository struct {
+	mu          sync.Mutex
+	invitations map[string]invitationDocument
+}
+
+// NewMemoryInvitationRepository creates a new MemoryInvitationRepository.
+func NewMemoryInvitationRepository() *MemoryInvitationRepository {
+	return &MemoryInvitationRepository{
+		mu:          sync.Mutex{},
+		invitations: make(map[string]invitationDocument),
+	}
+}
+
+// Save stores or replaces the invitation with the invitation's ID.
+func (r *MemoryInvitationRepository) Save(_ context.Context, invitation *entity.Invitation) error {
+	doc := newInvitationDocument(invitation)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.invitations[invitation.ID()] = doc
+
+	return nil
+}
+
+// FindByID retrieves the invitation with the ID.
+// Returns entity.ErrInvitationNotFound if no invitation exists.
+func (r *MemoryInvitationRepository) FindByID(_ context.Context, id string) (*entity.Invitation, error) {
+	r.mu.Lock()
+	doc, ok := r.invitations[id]
+	r.mu.Unlock()
+
+	if !ok {
+		return nil, entity.ErrInvitationNotFound
+	}
+
+	return reconstructInvitationFromDocument(id, doc)
+}
+
+// Delete removes the invitation with the ID. Deleting a missing invitation is not an error.
+func (r *MemoryInvitationRepository) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.invitations, id)
+
+	return nil
+}
+
+// Accept marks the invitation as accepted while holding the repository lock,
+// which serializes concurrent acceptances of the same invitation.
+func (r *MemoryInvitationRepository) Accept(_ context.Context, id string) (*entity.Invitation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	doc, ok := r.invitations[id]
+	if !ok {
+		return nil, entity.ErrInvitationNotFound
+	}
+
+	invitation, err := reconstructInvitationFromDocument(id, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = invitation.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	doc.AcceptedAt = invitation.AcceptedAt()
+	r.invitations[id] = doc
+
+	return invitation, nil
+}
+
+// RunEviction removes expired invitations every interval until ctx is done.
+// It blocks, so run it in its own goroutine.
+func (r *MemoryInvitationRepository) RunEviction(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.EvictExpired()
+		}
+	}
+}
+
+// EvictExpired removes all invitations whose expiration time has passed
+// and returns the number of invitations removed.
+func (r *MemoryInvitationRepository) EvictExpired() int {
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	evicted := 0
+
+	for id, doc := range r.invitations {
+		if now.After(doc.ExpiresAt) {
+			delete(r.invitations, id)
+			evicted++
+		}
+	}
+
+	return evicted
+}
+
+var _ repository.InvitationRepository = (*MemoryInvitationRepository)(nil)
@@ -0,0 +1,50 @@
+package persistence_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/repository"
+	"custom_auth_api/internal/domain/repository/repositorytest"
+	"custom_auth_api/internal/infrastructure/persistence"
+)
+
+func TestMemoryInvitationRepository_Contract(t *testing.T) {
+	t.Parallel()
+
+	repo := persistence.NewMemoryInvitationRepository()
+
+	repositorytest.TestInvitationRepository(t, func(*testing.T) repository.InvitationRepository {
+		return repo
+	})
+}
+
+func TestMemoryInvitationRepository_EvictExpired(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	repo := persistence.NewMemoryInvitationRepository()
+	saveTestInvitation(t, repo, "memory-active", time.Now().Add(time.Hour))
+	saveTestInvitation(t, repo, "memory-expired", time.Now().Add(-time.Minute))
+
+	// Act
+	evicted := repo.EvictExpired()
+
+	// Assert
+	if evicted != 1 {
+		t.Errorf("expected 1 evicted invitation, got %d", evicted)
+	}
+
+	_, err := repo.FindByID(context.Background(), "memory-expired")
+	if !errors.Is(err, entity.ErrInvitationNotFound) {
+		t.Errorf("expected expired invitation to be evicted, got %v", err)
+	}
+
+	_, err = repo.FindByID(context.Background(), "memory-active")
+	if err != nil {
+		t.Errorf("expected active invitation to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/repository"
+	"custom_auth_api/internal/domain/vo/email"
+	"custom_auth_api/internal/domain/vo/ipaddress"
+	"custom_auth_api/internal/domain/vo/otp"
+)
+
+// memorySessionRecord is the in-memory persistence model for OTP sessions.
+// Like the Firestore document, it holds only the keyed digest of the OTP.
+type memorySessionRecord struct {
+	email         string
+	otpHash       string
+	attempts      int
+	createdAt     time.Time
+	expiresAt     time.Time
+	ipAddressHash string
+	userAgent     string
+}
+
+// MemoryOTPSessionRepository stores OTP sessions in process memory.
+// It is safe for concurrent use and intended for single-node deployments,
+// local development and tests. Sessions are lost on restart.
+//
+// Expired sessions are kept (so verification still reports entity.ErrSessionExpired)
+// until the next eviction sweep run by RunEviction.
+type MemoryOTPSessionRepository struct {
+	mu       sync.Mutex
+	sessions map[string]memorySessionRecord
+	hasher   *otp.Hasher
+}
+
+// NewMemoryOTPSessionRepository creates a new MemoryOTPSessionRepository.
+func NewMemoryOTPSessionRepository(hasher *otp.Hasher) *MemoryOTPSessionRepository {
+	return &MemoryOTPSessionRepository{
+		mu:       sync.Mutex{},
+		sessions: make(map[string]memorySessionRecord),
+		hasher:   hasher,
+	}
+}
+
+// Save stores or replaces the OTP session for the session's email.
+func (r *MemoryOTPSessionRepository) Save(_ context.Context, session *entity.OTPSession) error {
+	record := memorySessionRecord{
+		email:         session.Email().Value,
+		otpHash:       r.hasher.Digest(session.OTP(), session.Email().Canonical()).String(),
+		attempts:      session.Attempts(),
+		createdAt:     session.CreatedAt(),
+		expiresAt:     session.ExpiresAt(),
+		ipAddressHash: session.IPAddressHash().String(),
+		userAgent:     session.UserAgent(),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sessions[session.Email().Canonical()] = record
+
+	return nil
+}
+
+// FindByEmail retrieves the OTP session for the email.
+// Returns entity.ErrSessionNotFound if no session exists.
+func (r *MemoryOTPSessionRepository) FindByEmail(_ context.Context, userEmail *email.Email) (*entity.OTPSession, error) {
+	r.mu.Lock()
+	record, ok := r.sessions[userEmail.Canonical()]
+	r.mu.Unlock()
+
+	if !ok {
+		return nil, entity.ErrSessionNotFound
+	}
+
+	return r.restore(userEmail.Canonical(), record)
+}
+
+// Delete removes the OTP session for the email. Deleting a missing session is not an error.
+func (r *MemoryOTPSessionRepository) Delete(_ context.Context, userEmail *email.Email) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.sessions, userEmail.Canonical())
+
+	return nil
+}
+
+// VerifyAndConsume verifies the code while holding the repository lock,
+// which serializes concurrent verifications of the same session.
+func (r *MemoryOTPSessionRepository) VerifyAndConsume(_ context.Context, userEmail *email.Email, inputCode string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.sessions[userEmail.Canonical()]
+	if !ok {
+		return entity.ErrSessionNotFound
+	}
+
+	session, err := r.restore(userEmail.Canonical(), record)
+	if err != nil {
+		return err
+	}
+
+	err = session.Verify(inputCode)
+	if err == nil {
+		// One-time use: consume the session
+		delete(r.sessions, userEmail.Canonical())
+
+		return nil
+	}
+
+	record.attempts = session.Attempts()
+	r.sessions[userEmail.Canonical()] = record
+
+	return err
+}
+
+// RunEviction removes expired sessions every interval until ctx is done.
+// It blocks, so run it in its own goroutine.
+func (r *MemoryOTPSessionRepository) RunEviction(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.EvictExpired()
+		}
+	}
+}
+
+// EvictExpired removes all sessions whose expiration time has passed
+// and returns the number of sessions removed.
+func (r *MemoryOTPSessionRepository) EvictExpired() int {
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	evicted := 0
+
+	for key, record := range r.sessions {
+		if now.After(record.expiresAt) {
+			delete(r.sessions, key)
+			evicted++
+		}
+	}
+
+	return evicted
+}
+
+// Len returns the number of stored sessions, including expired ones not yet evicted.
+func (r *MemoryOTPSessionRepository) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.sessions)
+}
+
+// restore reconstructs the domain entity from the record stored for canonicalEmail using RestorationData.
+func (r *MemoryOTPSessionRepository) restore(canonicalEmail string, record memorySessionRecord) (*entity.OTPSession, error) {
+	digest, err := otp.ParseDigest(record.otpHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconstruct otp code: %w", err)
+	}
+
+	otpCode, err := otp.FromDigest(digest, r.hasher, canonicalEmail)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconstruct otp code: %w", err)
+	}
+
+	userEmail, err := email.FromString(record.email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconstruct email: %w", err)
+	}
+
+	ipHash := ipaddress.NewEmptyHash()
+	if record.ipAddressHash != "" {
+		ipHash = ipaddress.FromString(record.ipAddressHash)
+	}
+
+	restorationData, err := entity.NewRestorationData(
+		userEmail,
+		otpCode,
+		record.attempts,
+		record.createdAt,
+		record.expiresAt,
+		ipHash,
+		record.userAgent,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create restoration data: %w", err)
+	}
+
+	return entity.RestoreOTPSession(restorationData), nil
+}
+
+var _ repository.OTPSessionRepository = (*MemoryOTPSessionRepository)(nil)
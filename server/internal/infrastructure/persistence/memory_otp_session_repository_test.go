@@ -0,0 +1,174 @@
+package persistence_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/repository"
+	"custom_auth_api/internal/domain/repository/repositorytest"
+	"custom_auth_api/internal/domain/vo/email"
+	"custom_auth_api/internal/domain/vo/ipaddress"
+	"custom_auth_api/internal/domain/vo/otp"
+	"custom_auth_api/internal/infrastructure/persistence"
+)
+
+// saveMemorySession stores a fresh session with testCode in the memory repository.
+func saveMemorySession(t *testing.T, repo *persistence.MemoryOTPSessionRepository, addr string) *email.Email {
+	t.Helper()
+
+	userEmail, _ := email.NewEmail(addr)
+	code, _ := otp.FromString(testCode)
+
+	err := repo.Save(context.Background(), entity.NewOTPSessionWithContext(userEmail, code, ipaddress.FromString(testIPHash), "test-agent"))
+	if err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+
+	return userEmail
+}
+
+// saveExpiredMemorySession stores a session that expired a minute ago.
+func saveExpiredMemorySession(t *testing.T, repo *persistence.MemoryOTPSessionRepository, addr string) *email.Email {
+	t.Helper()
+
+	userEmail, _ := email.NewEmail(addr)
+	code, _ := otp.FromString(testCode)
+	createdAt := time.Now().Add(-entity.DefaultOTPExpiration - time.Minute)
+
+	data, err := entity.NewRestorationData(
+		userEmail, code, 0, createdAt, createdAt.Add(entity.DefaultOTPExpiration), ipaddress.NewEmptyHash(), "",
+	)
+	if err != nil {
+		t.Fatalf("Failed to create restoration data: %v", err)
+	}
+
+	err = repo.Save(context.Background(), entity.RestoreOTPSession(data))
+	if err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+
+	return userEmail
+}
+
+func TestMemoryOTPSessionRepository_SaveAndFind(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	repo := persistence.NewMemoryOTPSessionRepository(newTestHasher(t))
+	userEmail := saveMemorySession(t, repo, "memory-find@example.com")
+
+	// Act
+	session, err := repo.FindByEmail(context.Background(), userEmail)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("FindByEmail() returned an error: %v", err)
+	}
+
+	if !session.OTP().IsHashedOnly() {
+		t.Error("expected the stored OTP to be hashed-only")
+	}
+
+	if !session.OTP().Matches(testCode) {
+		t.Error("expected the restored OTP to match the saved code")
+	}
+
+	if session.UserAgent() != "test-agent" || session.IPAddressHash().IsEmpty() {
+		t.Error("expected audit fields to be restored")
+	}
+}
+
+func TestMemoryOTPSessionRepository_Contract(t *testing.T) {
+	t.Parallel()
+
+	repo := persistence.NewMemoryOTPSessionRepository(newTestHasher(t))
+
+	repositorytest.TestOTPSessionRepository(t, func(*testing.T) repository.OTPSessionRepository {
+		return repo
+	})
+}
+
+func TestMemoryOTPSessionRepository_VerifyAndConsume_ExpiredUntilEvicted(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	repo := persistence.NewMemoryOTPSessionRepository(newTestHasher(t))
+	userEmail := saveExpiredMemorySession(t, repo, "memory-expired@example.com")
+
+	// Act
+	err := repo.VerifyAndConsume(context.Background(), userEmail, testCode)
+
+	// Assert
+	if !errors.Is(err, entity.ErrSessionExpired) {
+		t.Errorf("expected ErrSessionExpired, got %v", err)
+	}
+}
+
+func TestMemoryOTPSessionRepository_EvictExpired(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	repo := persistence.NewMemoryOTPSessionRepository(newTestHasher(t))
+	activeEmail := saveMemorySession(t, repo, "memory-active@example.com")
+	expiredEmail := saveExpiredMemorySession(t, repo, "memory-evicted@example.com")
+
+	// Act
+	evicted := repo.EvictExpired()
+
+	// Assert
+	if evicted != 1 {
+		t.Errorf("expected 1 evicted session, got %d", evicted)
+	}
+
+	if repo.Len() != 1 {
+		t.Errorf("expected 1 remaining session, got %d", repo.Len())
+	}
+
+	_, err := repo.FindByEmail(context.Background(), expiredEmail)
+	if !errors.Is(err, entity.ErrSessionNotFound) {
+		t.Errorf("expected expired session to be evicted, got %v", err)
+	}
+
+	_, err = repo.FindByEmail(context.Background(), activeEmail)
+	if err != nil {
+		t.Errorf("expected active session to be kept, got %v", err)
+	}
+}
+
+func TestMemoryOTPSessionRepository_RunEviction(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	repo := persistence.NewMemoryOTPSessionRepository(newTestHasher(t))
+	saveExpiredMemorySession(t, repo, "memory-run-eviction@example.com")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	// Act
+	go func() {
+		repo.RunEviction(ctx, time.Millisecond)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for repo.Len() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+
+	// Assert
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RunEviction did not return after context cancellation")
+	}
+
+	if repo.Len() != 0 {
+		t.Errorf("expected the expired session to be evicted, got %d sessions", repo.Len())
+	}
+}
@@ -0,0 +1,124 @@
+package persistence
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"custom_auth_api/internal/domain/identity"
+	"custom_auth_api/internal/domain/vo/email"
+)
+
+// MemoryUserDirectory stores users in process memory.
+// It is safe for concurrent use and intended for single-node deployments of the
+// standalone identity backend, local development and tests. Users are lost on restart.
+//
+// Addresses are stored and matched in canonical form without provider rules (see userEmailKey).
+type MemoryUserDirectory struct {
+	mu      sync.Mutex
+	users   map[string]*identity.User // By UID
+	byEmail map[string]string         // Canonical email to UID
+}
+
+// NewMemoryUserDirectory creates an empty MemoryUserDirectory.
+func NewMemoryUserDirectory() *MemoryUserDirectory {
+	return &MemoryUserDirectory{
+		mu:      sync.Mutex{},
+		users:   make(map[string]*identity.User),
+		byEmail: make(map[string]string),
+	}
+}
+
+// GetUserByEmail returns the user registered with email.
+// Returns an error wrapping identity.ErrUserNotFound if there is none.
+func (d *MemoryUserDirectory) GetUserByEmail(_ context.Context, email string) (*identity.User, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	uid, ok := d.byEmail[userEmailKey(email)]
+	if !ok {
+		return nil, fmt.Errorf("%w (got %q)", identity.ErrUserNotFound, email)
+	}
+
+	return copyUser(d.users[uid]), nil
+}
+
+// CreateUser creates a user with a random UID and no roles.
+// Returns an error wrapping identity.ErrEmailAlreadyExists if the address is registered.
+func (d *MemoryUserDirectory) CreateUser(_ context.Context, user identity.UserToCreate) (*identity.User, error) {
+	key := userEmailKey(user.Email)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.byEmail[key]; ok {
+		return nil, fmt.Errorf("%w (got %q)", identity.ErrEmailAlreadyExists, user.Email)
+	}
+
+	created := &identity.User{
+		UID:           newUserID(),
+		Email:         key,
+		EmailVerified: user.EmailVerified,
+		DisplayName:   user.DisplayName,
+		Roles:         nil,
+	}
+
+	d.users[created.UID] = created
+	d.byEmail[key] = created.UID
+
+	return copyUser(created), nil
+}
+
+// SetRoles replaces the roles of the user with ID uid.
+// Returns an error wrapping identity.ErrUserNotFound if there is no such user.
+func (d *MemoryUserDirectory) SetRoles(_ context.Context, uid string, roles []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	user, ok := d.users[uid]
+	if !ok {
+		return fmt.Errorf("%w (got UID %q)", identity.ErrUserNotFound, uid)
+	}
+
+	user.Roles = slices.Clone(roles)
+
+	return nil
+}
+
+// userEmailKey returns the form addresses are stored and matched in: email.Email.Canonical()
+// without provider rules, so case variants are one user as in Firebase Authentication.
+// Provider aliases (Gmail dots and "+tags") stay separate users, as in Firebase: they share an
+// OTP session and email limits but not an account, and toggling EMAIL_FOLD_PROVIDER_ALIASES
+// must not move existing users to another address.
+func userEmailKey(address string) string {
+	userEmail, err := email.FromString(address)
+	if err != nil {
+		// Not a valid address, so no user can be registered with it either
+		return strings.ToLower(strings.TrimSpace(address))
+	}
+
+	return userEmail.Canonical()
+}
+
+// newUserID returns a random user ID (32 hex characters).
+func newUserID() string {
+	var id [16]byte
+
+	_, _ = rand.Read(id[:]) // crypto/rand.Read never returns an error
+
+	return hex.EncodeToString(id[:])
+}
+
+// copyUser returns a deep copy of user, so callers cannot modify stored users.
+func copyUser(user *identity.User) *identity.User {
+	copied := *user
+	copied.Roles = slices.Clone(user.Roles)
+
+	return &copied
+}
+
+var _ identity.UserDirectory = (*MemoryUserDirectory)(nil)
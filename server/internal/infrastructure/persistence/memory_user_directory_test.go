@@ -0,0 +1,74 @@
+package persistence_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"custom_auth_api/internal/domain/identity"
+	"custom_auth_api/internal/domain/identity/identitytest"
+	"custom_auth_api/internal/infrastructure/persistence"
+)
+
+func TestMemoryUserDirectory_Contract(t *testing.T) {
+	t.Parallel()
+
+	directory := persistence.NewMemoryUserDirectory()
+
+	identitytest.TestUserDirectory(t, func(*testing.T) identity.UserDirectory {
+		return directory
+	})
+}
+
+func TestMemoryUserDirectory_GetUserByEmail_CanonicalForm(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		registered  string
+		lookup      string
+		expectFound bool
+	}{
+		{name: "case variants are one user", registered: "Alice@Example.com", lookup: "alice@EXAMPLE.com", expectFound: true},
+		{name: "IDNA spellings are one user", registered: "user@bücher.example", lookup: "user@xn--bcher-kva.example", expectFound: true},
+		{name: "provider aliases are separate users", registered: "j.doe@gmail.com", lookup: "jdoe+otp@gmail.com", expectFound: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			directory := persistence.NewMemoryUserDirectory()
+
+			created, err := directory.CreateUser(context.Background(), identity.UserToCreate{
+				Email:         tc.registered,
+				EmailVerified: true,
+				DisplayName:   "",
+			})
+			if err != nil {
+				t.Fatalf("CreateUser() returned an error: %v", err)
+			}
+
+			// Act
+			found, err := directory.GetUserByEmail(context.Background(), tc.lookup)
+
+			// Assert
+			if !tc.expectFound {
+				if !errors.Is(err, identity.ErrUserNotFound) {
+					t.Errorf("GetUserByEmail(%q) error = %v, want ErrUserNotFound", tc.lookup, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("GetUserByEmail(%q) returned an error: %v", tc.lookup, err)
+			}
+
+			if found.UID != created.UID {
+				t.Errorf("GetUserByEmail(%q) UID = %q, want %q", tc.lookup, found.UID, created.UID)
+			}
+		})
+	}
+}
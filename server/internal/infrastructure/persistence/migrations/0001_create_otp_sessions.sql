@@ -0,0 +1,13 @@
+-- OTP sessions keyed by email. Timestamps are Unix microseconds so the schema
+-- is portable between SQLite and PostgreSQL.
+CREATE TABLE otp_sessions (
+    email           TEXT    NOT NULL PRIMARY KEY,
+    otp_hash        TEXT    NOT NULL,
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    created_at      BIGINT  NOT NULL,
+    expires_at      BIGINT  NOT NULL,
+    ip_address_hash TEXT    NOT NULL DEFAULT '',
+    user_agent      TEXT    NOT NULL DEFAULT ''
+);
+
+CREATE INDEX otp_sessions_expires_at_idx ON otp_sessions (expires_at);
@@ -0,0 +1,4 @@
+-- The email column holds the canonical address (the session key) and
+-- display_email keeps the address as entered. Rows written before this migration
+-- have an empty display_email and are read back with their email column.
+ALTER TABLE otp_sessions ADD COLUMN display_email TEXT NOT NULL DEFAULT '';
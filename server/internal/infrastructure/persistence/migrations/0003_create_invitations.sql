@@ -0,0 +1,13 @@
+-- Invitations keyed by their random ID. Roles are comma-separated (role names
+-- cannot contain commas) and accepted_at is 0 until the invitation is accepted.
+CREATE TABLE invitations (
+    id          TEXT   NOT NULL PRIMARY KEY,
+    email       TEXT   NOT NULL,
+    inviter     TEXT   NOT NULL,
+    roles       TEXT   NOT NULL DEFAULT '',
+    created_at  BIGINT NOT NULL,
+    expires_at  BIGINT NOT NULL,
+    accepted_at BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX invitations_expires_at_idx ON invitations (expires_at);
@@ -0,0 +1,13 @@
+-- Users of the standalone identity backend keyed by their random UID. Emails are
+-- stored in canonical form (lowercased, IDNA ASCII domain) and are unique. Provider
+-- rules are not applied, so Gmail aliases stay separate users as in Firebase and
+-- toggling EMAIL_FOLD_PROVIDER_ALIASES never changes a stored address.
+-- Roles are comma-separated like invitation roles.
+CREATE TABLE users (
+    uid            TEXT    NOT NULL PRIMARY KEY,
+    email          TEXT    NOT NULL UNIQUE,
+    email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+    display_name   TEXT    NOT NULL DEFAULT '',
+    roles          TEXT    NOT NULL DEFAULT '',
+    created_at     BIGINT  NOT NULL
+);
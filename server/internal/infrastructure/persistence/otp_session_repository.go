@@ -21,9 +21,14 @@ const (
 
 // otpSessionDocument represents the Firestore document schema for OTP sessions.
 // This is the persistence model, separate from the domain entity.
+//
+// Only the keyed HMAC digest of the OTP is stored (OTPHash). The plaintext OTP
+// field is never written; it is only read from documents created before hashing
+// was introduced, which expire within entity.DefaultOTPExpiration.
 type otpSessionDocument struct {
 	Email         string    `firestore:"email"`
-	OTP           string    `firestore:"otp"`
+	OTPHash       string    `firestore:"otpHash,omitempty"`
+	OTP           string    `firestore:"otp,omitempty"` // Legacy plaintext, read-only
 	Attempts      int       `firestore:"attempts"`
 	CreatedAt     time.Time `firestore:"createdAt"`
 	ExpiresAt     time.Time `firestore:"expiresAt"`
@@ -35,19 +40,22 @@ type otpSessionDocument struct {
 // This implementation contains NO business logic - it's purely for data access.
 type OTPSessionRepository struct {
 	client *firestore.Client
+	hasher *otp.Hasher
 }
 
 // NewOTPSessionRepository creates a new OTPSessionRepository.
-func NewOTPSessionRepository(client *firestore.Client) *OTPSessionRepository {
-	return &OTPSessionRepository{client: client}
+// The hasher is used to store OTP codes as keyed digests instead of plaintext.
+func NewOTPSessionRepository(client *firestore.Client, hasher *otp.Hasher) *OTPSessionRepository {
+	return &OTPSessionRepository{client: client, hasher: hasher}
 }
 
 // Save stores or updates an OTP session in Firestore.
-// Uses the email as the document ID for deterministic lookups.
+// Uses the canonical email as the document ID for deterministic, case-insensitive lookups.
 func (r *OTPSessionRepository) Save(ctx context.Context, session *entity.OTPSession) error {
 	doc := otpSessionDocument{
 		Email:         session.Email().Value,
-		OTP:           session.OTP().String(),
+		OTPHash:       r.hasher.Digest(session.OTP(), session.Email().Canonical()).String(),
+		OTP:           "",
 		Attempts:      session.Attempts(),
 		CreatedAt:     session.CreatedAt(),
 		ExpiresAt:     session.ExpiresAt(),
@@ -55,7 +63,7 @@ func (r *OTPSessionRepository) Save(ctx context.Context, session *entity.OTPSess
 		UserAgent:     session.UserAgent(),
 	}
 
-	_, err := r.client.Collection(otpSessionCollection).Doc(session.Email().Value).Set(ctx, doc)
+	_, err := r.client.Collection(otpSessionCollection).Doc(session.Email().Canonical()).Set(ctx, doc)
 	if err != nil {
 		return fmt.Errorf("failed to save otp session: %w", err)
 	}
@@ -67,7 +75,7 @@ func (r *OTPSessionRepository) Save(ctx context.Context, session *entity.OTPSess
 // Returns entity.ErrSessionNotFound if the document doesn't exist.
 // Does NOT check expiration or attempt limits - that's the entity's responsibility.
 func (r *OTPSessionRepository) FindByEmail(ctx context.Context, userEmail *email.Email) (*entity.OTPSession, error) {
-	docSnap, err := r.client.Collection(otpSessionCollection).Doc(userEmail.Value).Get(ctx)
+	docSnap, err := r.client.Collection(otpSessionCollection).Doc(userEmail.Canonical()).Get(ctx)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
 			return nil, entity.ErrSessionNotFound
@@ -76,20 +84,89 @@ func (r *OTPSessionRepository) FindByEmail(ctx context.Context, userEmail *email
 		return nil, fmt.Errorf("failed to get otp session: %w", err)
 	}
 
+	return r.sessionFromSnapshot(docSnap, userEmail)
+}
+
+// Delete removes an OTP session from Firestore.
+func (r *OTPSessionRepository) Delete(ctx context.Context, userEmail *email.Email) error {
+	_, err := r.client.Collection(otpSessionCollection).Doc(userEmail.Canonical()).Delete(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete otp session: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyAndConsume verifies the code inside a Firestore transaction.
+// Firestore retries the transaction when another one modifies the document
+// concurrently, so every attempt observes the latest attempts counter and a
+// session can only be deleted (consumed) by one successful verification.
+func (r *OTPSessionRepository) VerifyAndConsume(ctx context.Context, userEmail *email.Email, inputCode string) error {
+	docRef := r.client.Collection(otpSessionCollection).Doc(userEmail.Canonical())
+
+	var verifyErr error
+
+	err := r.client.RunTransaction(ctx, func(_ context.Context, tx *firestore.Transaction) error {
+		// Reset on every run: the function is retried on contention
+		verifyErr = nil
+
+		docSnap, err := tx.Get(docRef)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				verifyErr = entity.ErrSessionNotFound
+
+				return nil
+			}
+
+			return fmt.Errorf("failed to get otp session: %w", err)
+		}
+
+		session, err := r.sessionFromSnapshot(docSnap, userEmail)
+		if err != nil {
+			return err
+		}
+
+		attemptsBefore := session.Attempts()
+		verifyErr = session.Verify(inputCode)
+
+		if verifyErr == nil {
+			// One-time use: consume the session
+			return tx.Delete(docRef)
+		}
+
+		if session.Attempts() != attemptsBefore {
+			return tx.Update(docRef, []firestore.Update{{Path: "attempts", Value: session.Attempts()}})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("otp verification transaction failed: %w", err)
+	}
+
+	return verifyErr
+}
+
+// sessionFromSnapshot converts the Firestore snapshot of the email's session into a domain entity.
+func (r *OTPSessionRepository) sessionFromSnapshot(
+	docSnap *firestore.DocumentSnapshot,
+	userEmail *email.Email,
+) (*entity.OTPSession, error) {
 	var doc otpSessionDocument
-	err = docSnap.DataTo(&doc)
+
+	err := docSnap.DataTo(&doc)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal otp session: %w", err)
 	}
 
 	// Reconstruct domain entity from persistence model
-	otpCode, err := otp.FromString(doc.OTP)
+	otpCode, err := r.restoreOTP(doc, userEmail)
 	if err != nil {
 		return nil, fmt.Errorf("failed to reconstruct otp code: %w", err)
 	}
 
 	// Reconstruct email value object
-	reconstructedEmail, err := email.NewEmail(doc.Email)
+	reconstructedEmail, err := email.FromString(doc.Email)
 	if err != nil {
 		return nil, fmt.Errorf("failed to reconstruct email: %w", err)
 	}
@@ -98,14 +175,19 @@ func (r *OTPSessionRepository) FindByEmail(ctx context.Context, userEmail *email
 	return reconstructSessionFromDocument(doc, reconstructedEmail, otpCode)
 }
 
-// Delete removes an OTP session from Firestore.
-func (r *OTPSessionRepository) Delete(ctx context.Context, userEmail *email.Email) error {
-	_, err := r.client.Collection(otpSessionCollection).Doc(userEmail.Value).Delete(ctx)
+// restoreOTP reconstructs a hashed-only OTP from the stored digest.
+// Falls back to the legacy plaintext field for documents written before hashing.
+func (r *OTPSessionRepository) restoreOTP(doc otpSessionDocument, userEmail *email.Email) (*otp.OTP, error) {
+	if doc.OTPHash == "" {
+		return otp.FromString(doc.OTP)
+	}
+
+	digest, err := otp.ParseDigest(doc.OTPHash)
 	if err != nil {
-		return fmt.Errorf("failed to delete otp session: %w", err)
+		return nil, err
 	}
 
-	return nil
+	return otp.FromDigest(digest, r.hasher, userEmail.Canonical())
 }
 
 // reconstructSessionFromDocument creates a domain entity from a Firestore document.
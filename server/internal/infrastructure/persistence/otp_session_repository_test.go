@@ -0,0 +1,72 @@
+package persistence_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+
+	"custom_auth_api/internal/domain/repository"
+	"custom_auth_api/internal/domain/repository/repositorytest"
+	"custom_auth_api/internal/domain/vo/otp"
+	"custom_auth_api/internal/infrastructure/persistence"
+)
+
+const (
+	testCode = "123456"
+
+	// testIPHash is a keyed IP hash as produced by ipaddress.Hasher.
+	testIPHash = "test:4f1c3a0d9e8b7a6f5e4d3c2b1a0f9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f"
+)
+
+// newTestHasher creates an OTP hasher with a fixed key.
+func newTestHasher(t *testing.T) *otp.Hasher {
+	t.Helper()
+
+	hasher, err := otp.NewHasher("test", map[string][]byte{"test": bytes.Repeat([]byte("k"), otp.MinHashKeyLength)})
+	if err != nil {
+		t.Fatalf("Failed to create OTP hasher: %v", err)
+	}
+
+	return hasher
+}
+
+// setupRepository creates a repository backed by the Firestore emulator.
+func setupRepository(t *testing.T) *persistence.OTPSessionRepository {
+	t.Helper()
+
+	return persistence.NewOTPSessionRepository(setupFirestoreClient(t), newTestHasher(t))
+}
+
+// setupFirestoreClient connects to the Firestore emulator, skipping the test without one.
+func setupFirestoreClient(t *testing.T) *firestore.Client {
+	t.Helper()
+
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		t.Skip("Skipping integration test: FIRESTORE_EMULATOR_HOST is not set.")
+	}
+
+	client, err := firestore.NewClient(context.Background(), "demo-project")
+	if err != nil {
+		t.Fatalf("Failed to create Firestore client for emulator: %v", err)
+	}
+
+	t.Cleanup(func() {
+		err := client.Close()
+		if err != nil {
+			t.Logf("Failed to close Firestore client: %v", err)
+		}
+	})
+
+	return client
+}
+
+func TestOTPSessionRepository_Contract(t *testing.T) {
+	repositorytest.TestOTPSessionRepository(t, func(t *testing.T) repository.OTPSessionRepository {
+		t.Helper()
+
+		return setupRepository(t)
+	})
+}
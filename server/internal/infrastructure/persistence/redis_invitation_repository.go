@@ -0,0 +1,214 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/repository"
+)
+
+const (
+	// redisInvitationKeyPrefix namespaces invitation keys.
+	redisInvitationKeyPrefix = "invitation:"
+
+	// redisInvitationTxRetries bounds optimistic transaction retries in Accept.
+	// An invitation is modified at most once after it is saved, so a retried
+	// acceptance always observes the winning one.
+	redisInvitationTxRetries = 3
+
+	// redisRolesSeparator joins roles in a single hash field; role names cannot contain it.
+	redisRolesSeparator = ","
+)
+
+// Hash field names of the Redis invitation record.
+const (
+	redisFieldInviter    = "inviter"
+	redisFieldRoles      = "roles"
+	redisFieldAcceptedAt = "acceptedAt"
+)
+
+// ErrInvitationContention is returned when an invitation keeps being modified concurrently
+// and the optimistic transaction could not be committed.
+var ErrInvitationContention = errors.New("invitation was modified concurrently, retries exhausted")
+
+// RedisInvitationRepository handles Invitation persistence in Redis.
+//
+// Each invitation is a hash whose key expires at the invitation's ExpiresAt, so an
+// expired invitation is reported as entity.ErrInvitationNotFound rather than
+// entity.ErrInvitationExpired.
+type RedisInvitationRepository struct {
+	client redis.UniversalClient
+}
+
+// NewRedisInvitationRepository creates a new RedisInvitationRepository.
+func NewRedisInvitationRepository(client redis.UniversalClient) *RedisInvitationRepository {
+	return &RedisInvitationRepository{client: client}
+}
+
+// Save stores or replaces an invitation and sets the key to expire at ExpiresAt.
+func (r *RedisInvitationRepository) Save(ctx context.Context, invitation *entity.Invitation) error {
+	key := redisInvitationKeyPrefix + invitation.ID()
+
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		// Replace rather than merge with a previous invitation
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key,
+			redisFieldEmail, invitation.Email().Value,
+			redisFieldInviter, invitation.Inviter(),
+			redisFieldRoles, strings.Join(invitation.Roles(), redisRolesSeparator),
+			redisFieldCreatedAt, invitation.CreatedAt().UTC().Format(time.RFC3339Nano),
+			redisFieldExpiresAt, invitation.ExpiresAt().UTC().Format(time.RFC3339Nano),
+			redisFieldAcceptedAt, formatOptionalTime(invitation.AcceptedAt()),
+		)
+		pipe.PExpireAt(ctx, key, invitation.ExpiresAt())
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save invitation: %w", err)
+	}
+
+	return nil
+}
+
+// FindByID retrieves an invitation from Redis by ID.
+// Returns entity.ErrInvitationNotFound if the key doesn't exist or has expired.
+func (r *RedisInvitationRepository) FindByID(ctx context.Context, id string) (*entity.Invitation, error) {
+	return r.load(ctx, r.client, id)
+}
+
+// Delete removes an invitation from Redis.
+func (r *RedisInvitationRepository) Delete(ctx context.Context, id string) error {
+	err := r.client.Del(ctx, redisInvitationKeyPrefix+id).Err()
+	if err != nil {
+		return fmt.Errorf("failed to delete invitation: %w", err)
+	}
+
+	return nil
+}
+
+// Accept marks the invitation as accepted in an optimistic WATCH/MULTI/EXEC transaction.
+// If another client accepts the invitation between the read and the write, EXEC aborts
+// and the retry reports entity.ErrInvitationAlreadyUsed.
+func (r *RedisInvitationRepository) Accept(ctx context.Context, id string) (*entity.Invitation, error) {
+	key := redisInvitationKeyPrefix + id
+
+	var (
+		invitation *entity.Invitation
+		acceptErr  error
+	)
+
+	txf := func(tx *redis.Tx) error {
+		// Reset on every run: the function is retried on contention
+		invitation, acceptErr = nil, nil
+
+		loaded, err := r.load(ctx, tx, id)
+		if errors.Is(err, entity.ErrInvitationNotFound) {
+			acceptErr = err
+
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		acceptErr = loaded.Accept()
+		if acceptErr != nil {
+			return nil
+		}
+
+		invitation = loaded
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, key, redisFieldAcceptedAt, formatOptionalTime(loaded.AcceptedAt()))
+
+			return nil
+		})
+
+		return err
+	}
+
+	for range redisInvitationTxRetries {
+		err := r.client.Watch(ctx, txf, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invitation acceptance transaction failed: %w", err)
+		}
+
+		if acceptErr != nil {
+			return nil, acceptErr
+		}
+
+		return invitation, nil
+	}
+
+	return nil, ErrInvitationContention
+}
+
+// load reads and reconstructs the invitation with the ID.
+func (r *RedisInvitationRepository) load(ctx context.Context, cmd redis.Cmdable, id string) (*entity.Invitation, error) {
+	fields, err := cmd.HGetAll(ctx, redisInvitationKeyPrefix+id).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+
+	if len(fields) == 0 {
+		return nil, entity.ErrInvitationNotFound
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, fields[redisFieldCreatedAt])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse invitation createdAt: %w", err)
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339Nano, fields[redisFieldExpiresAt])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse invitation expiresAt: %w", err)
+	}
+
+	acceptedAt, err := parseOptionalTime(fields[redisFieldAcceptedAt])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse invitation acceptedAt: %w", err)
+	}
+
+	var roles []string
+	if fields[redisFieldRoles] != "" {
+		roles = strings.Split(fields[redisFieldRoles], redisRolesSeparator)
+	}
+
+	return reconstructInvitationFromDocument(id, invitationDocument{
+		Email:      fields[redisFieldEmail],
+		Inviter:    fields[redisFieldInviter],
+		Roles:      roles,
+		CreatedAt:  createdAt,
+		ExpiresAt:  expiresAt,
+		AcceptedAt: acceptedAt,
+	})
+}
+
+// formatOptionalTime formats t as RFC 3339, or "" for the zero time.
+func formatOptionalTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// parseOptionalTime parses a time formatted by formatOptionalTime.
+func parseOptionalTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+var _ repository.InvitationRepository = (*RedisInvitationRepository)(nil)
@@ -0,0 +1,65 @@
+package persistence_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/repository"
+	"custom_auth_api/internal/domain/repository/repositorytest"
+	"custom_auth_api/internal/infrastructure/persistence"
+)
+
+// setupRedisInvitationRepository creates a repository backed by an in-process miniredis server.
+func setupRedisInvitationRepository(t *testing.T) (*persistence.RedisInvitationRepository, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+
+	t.Cleanup(func() {
+		err := client.Close()
+		if err != nil {
+			t.Logf("Failed to close Redis client: %v", err)
+		}
+	})
+
+	return persistence.NewRedisInvitationRepository(client), server
+}
+
+func TestRedisInvitationRepository_Contract(t *testing.T) {
+	t.Parallel()
+
+	repo, _ := setupRedisInvitationRepository(t)
+
+	repositorytest.TestInvitationRepository(t, func(*testing.T) repository.InvitationRepository {
+		return repo
+	})
+}
+
+func TestRedisInvitationRepository_KeyExpiresAtExpiresAt(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	repo, server := setupRedisInvitationRepository(t)
+	saveTestInvitation(t, repo, "redis-ttl", time.Now().Add(time.Hour))
+
+	ttl := server.TTL("invitation:redis-ttl")
+	if ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("expected TTL close to 1h, got %v", ttl)
+	}
+
+	// Act
+	server.FastForward(time.Hour + time.Second)
+
+	// Assert
+	_, err := repo.Accept(context.Background(), "redis-ttl")
+	if !errors.Is(err, entity.ErrInvitationNotFound) {
+		t.Errorf("expected ErrInvitationNotFound after expiry, got %v", err)
+	}
+}
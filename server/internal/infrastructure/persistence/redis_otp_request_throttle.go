@@ -0,0 +1,148 @@
+package persistence
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"custom_auth_api/internal/domain/vo/email"
+	"custom_auth_api/internal/usecase"
+)
+
+// redisThrottleKeyPrefix namespaces OTP throttle keys. The hash tag keeps the address
+// keys and the global key in one Redis Cluster slot, so one script can update them together.
+const redisThrottleKeyPrefix = "{otp:throttle}:"
+
+// Decisions returned by otpThrottleScript.
+const (
+	redisThrottleAllowed int64 = iota
+	redisThrottleCooldown
+	redisThrottleDailyCap
+	redisThrottleGlobal
+)
+
+// otpThrottleScript applies OTPRequestPolicy atomically on the server.
+// Redis' clock is used so replicas agree on time. Times are in microseconds.
+//
+// KEYS[1]: address key, a hash of the last send, the daily window start and the count
+// KEYS[2]: global key, the GCRA theoretical arrival time (as in the rate limiter)
+// ARGV[1]: cooldown
+// ARGV[2]: daily limit (0 disables)
+// ARGV[3]: daily window
+// ARGV[4]: global emission interval (0 disables)
+// ARGV[5]: global burst
+// Returns {decision, retry after}.
+var otpThrottleScript = redis.NewScript(`
+local cooldown = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local window = tonumber(ARGV[3])
+local interval = tonumber(ARGV[4])
+local burst = tonumber(ARGV[5])
+
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+local record = redis.call("HMGET", KEYS[1], "last", "start", "count")
+local last = tonumber(record[1])
+local start = tonumber(record[2]) or now
+local count = tonumber(record[3]) or 0
+
+if now - start >= window then
+	-- The daily window has passed; keep only the cooldown
+	start = now
+	count = 0
+end
+
+if last then
+	if now < last + cooldown then
+		return {1, last + cooldown - now}
+	end
+
+	if limit > 0 and count >= limit then
+		return {2, start + window - now}
+	end
+end
+
+if interval > 0 then
+	local tat = tonumber(redis.call("GET", KEYS[2])) or now
+	if tat < now then
+		tat = now
+	end
+
+	local new_tat = tat + interval
+	local allow_at = new_tat - burst * interval
+	if now < allow_at then
+		return {3, allow_at - now}
+	end
+
+	redis.call("SET", KEYS[2], string.format("%.0f", new_tat), "PX", string.format("%.0f", math.ceil((new_tat - now) / 1000)))
+end
+
+-- Format explicitly: Lua would print microsecond timestamps in exponent notation
+redis.call("HSET", KEYS[1], "last", string.format("%.0f", now), "start", string.format("%.0f", start), "count", count + 1)
+
+-- Keep the record until both the cooldown and the daily window have passed
+local expire_at = math.max(now + cooldown, start + window)
+redis.call("PEXPIRE", KEYS[1], string.format("%.0f", math.ceil((expire_at - now) / 1000)))
+
+return {0, 0}
+`)
+
+// RedisOTPRequestThrottle is a usecase.OTPThrottle whose state lives in Redis, so the
+// per-address and global limits hold across all API replicas sharing the Redis instance.
+//
+// Address records expire once their cooldown and daily window have passed, so no
+// cleanup routine is needed. Keys contain the SHA-256 of the address instead of the raw address.
+type RedisOTPRequestThrottle struct {
+	client redis.UniversalClient
+	policy usecase.OTPRequestPolicy
+}
+
+// NewRedisOTPRequestThrottle creates a new RedisOTPRequestThrottle enforcing the policy.
+func NewRedisOTPRequestThrottle(client redis.UniversalClient, policy usecase.OTPRequestPolicy) *RedisOTPRequestThrottle {
+	return &RedisOTPRequestThrottle{client: client, policy: policy}
+}
+
+// Allow records an email to userEmail if the policy permits it.
+// Returns an *usecase.OTPThrottleError otherwise; refused requests are not counted.
+func (t *RedisOTPRequestThrottle) Allow(ctx context.Context, userEmail *email.Email) error {
+	var globalInterval time.Duration
+	if t.policy.GlobalPerMinute > 0 {
+		globalInterval = time.Minute / time.Duration(t.policy.GlobalPerMinute)
+	}
+
+	addressKey := sha256.Sum256([]byte(userEmail.Canonical()))
+
+	values, err := otpThrottleScript.Run(ctx, t.client,
+		[]string{redisThrottleKeyPrefix + "email:" + hex.EncodeToString(addressKey[:]), redisThrottleKeyPrefix + "global"},
+		t.policy.Cooldown.Microseconds(),
+		t.policy.DailyLimit,
+		usecase.OTPThrottleWindow.Microseconds(),
+		globalInterval.Microseconds(),
+		t.policy.GlobalPerMinute,
+	).Int64Slice()
+	if err != nil {
+		return fmt.Errorf("failed to evaluate otp throttle: %w", err)
+	}
+
+	retryAfter := time.Duration(values[1]) * time.Microsecond
+
+	switch values[0] {
+	case redisThrottleAllowed:
+		return nil
+	case redisThrottleCooldown:
+		return &usecase.OTPThrottleError{Reason: usecase.OTPThrottleReasonCooldown, RetryAfter: retryAfter}
+	case redisThrottleDailyCap:
+		return &usecase.OTPThrottleError{Reason: usecase.OTPThrottleReasonDailyCap, RetryAfter: retryAfter}
+	case redisThrottleGlobal:
+		return &usecase.OTPThrottleError{Reason: usecase.OTPThrottleReasonGlobal, RetryAfter: retryAfter}
+	default:
+		return fmt.Errorf("failed to evaluate otp throttle: unexpected decision %d", values[0])
+	}
+}
+
+var _ usecase.OTPThrottle = (*RedisOTPRequestThrottle)(nil)
@@ -0,0 +1,206 @@
+package persistence_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"custom_auth_api/internal/domain/vo/email"
+	"custom_auth_api/internal/infrastructure/persistence"
+	"custom_auth_api/internal/usecase"
+)
+
+// setupRedisOTPRequestThrottle creates two throttles sharing one in-process miniredis server,
+// standing in for two API replicas.
+func setupRedisOTPRequestThrottle(
+	t *testing.T,
+	policy usecase.OTPRequestPolicy,
+) (*persistence.RedisOTPRequestThrottle, *persistence.RedisOTPRequestThrottle, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+
+	newThrottle := func() *persistence.RedisOTPRequestThrottle {
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() {
+			err := client.Close()
+			if err != nil {
+				t.Logf("Failed to close Redis client: %v", err)
+			}
+		})
+
+		return persistence.NewRedisOTPRequestThrottle(client, policy)
+	}
+
+	return newThrottle(), newThrottle(), server
+}
+
+// throttleEmail parses address with the Gmail rule, so aliases share one canonical form.
+func throttleEmail(t *testing.T, address string) *email.Email {
+	t.Helper()
+
+	userEmail, err := email.NewEmailWithOptions(address, email.Options{
+		ProviderRules:        []email.ProviderRule{email.GmailRule()},
+		AllowQuotedLocalPart: false,
+		AllowUTF8LocalPart:   false,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create email: %v", err)
+	}
+
+	return userEmail
+}
+
+func TestRedisOTPRequestThrottle_Allow(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		policy       usecase.OTPRequestPolicy
+		requests     []string
+		expectReason string
+		minRetry     time.Duration
+	}{
+		{
+			name:         "allows a first request",
+			policy:       usecase.OTPRequestPolicy{Cooldown: time.Minute, DailyLimit: 1, GlobalPerMinute: 1},
+			requests:     []string{"user@example.com"},
+			expectReason: "",
+			minRetry:     0,
+		},
+		{
+			name:         "refuses a resend during the cooldown",
+			policy:       usecase.OTPRequestPolicy{Cooldown: time.Minute, DailyLimit: 0, GlobalPerMinute: 0},
+			requests:     []string{"user@example.com", "User@Example.COM"},
+			expectReason: usecase.OTPThrottleReasonCooldown,
+			minRetry:     59 * time.Second,
+		},
+		{
+			name:         "treats provider aliases as the same address",
+			policy:       usecase.OTPRequestPolicy{Cooldown: time.Minute, DailyLimit: 0, GlobalPerMinute: 0},
+			requests:     []string{"j.doe@gmail.com", "JDoe+otp@googlemail.com"},
+			expectReason: usecase.OTPThrottleReasonCooldown,
+			minRetry:     59 * time.Second,
+		},
+		{
+			name:         "refuses requests beyond the daily cap",
+			policy:       usecase.OTPRequestPolicy{Cooldown: 0, DailyLimit: 2, GlobalPerMinute: 0},
+			requests:     []string{"user@example.com", "user@example.com", "user@example.com"},
+			expectReason: usecase.OTPThrottleReasonDailyCap,
+			minRetry:     23 * time.Hour,
+		},
+		{
+			name:         "refuses requests beyond the global limit",
+			policy:       usecase.OTPRequestPolicy{Cooldown: time.Minute, DailyLimit: 0, GlobalPerMinute: 1},
+			requests:     []string{"first@example.com", "second@example.com"},
+			expectReason: usecase.OTPThrottleReasonGlobal,
+			minRetry:     59 * time.Second,
+		},
+		{
+			name:         "allows other addresses during a cooldown",
+			policy:       usecase.OTPRequestPolicy{Cooldown: time.Minute, DailyLimit: 1, GlobalPerMinute: 0},
+			requests:     []string{"first@example.com", "second@example.com"},
+			expectReason: "",
+			minRetry:     0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			throttle, _, _ := setupRedisOTPRequestThrottle(t, tc.policy)
+
+			var err error
+
+			// Act
+			for _, emailAddr := range tc.requests {
+				err = throttle.Allow(context.Background(), throttleEmail(t, emailAddr))
+			}
+
+			// Assert
+			if tc.expectReason == "" {
+				if err != nil {
+					t.Fatalf("expected the last request to be allowed, got %v", err)
+				}
+
+				return
+			}
+
+			var throttleErr *usecase.OTPThrottleError
+			if !errors.As(err, &throttleErr) {
+				t.Fatalf("expected *OTPThrottleError, got %v", err)
+			}
+
+			if throttleErr.Reason != tc.expectReason {
+				t.Errorf("expected reason %q, got %q", tc.expectReason, throttleErr.Reason)
+			}
+
+			if throttleErr.RetryAfter < tc.minRetry {
+				t.Errorf("expected RetryAfter of at least %v, got %v", tc.minRetry, throttleErr.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestRedisOTPRequestThrottle_SharedAcrossInstances(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	first, second, _ := setupRedisOTPRequestThrottle(t, usecase.OTPRequestPolicy{
+		Cooldown:        0,
+		DailyLimit:      2,
+		GlobalPerMinute: 0,
+	})
+	ctx := context.Background()
+	userEmail := throttleEmail(t, "user@example.com")
+
+	// Act
+	err1 := first.Allow(ctx, userEmail)
+	err2 := second.Allow(ctx, userEmail)
+	err3 := first.Allow(ctx, userEmail)
+
+	// Assert
+	if err1 != nil || err2 != nil {
+		t.Fatalf("expected the first two requests to be allowed, got %v and %v", err1, err2)
+	}
+
+	if !errors.Is(err3, usecase.ErrOTPRequestThrottled) {
+		t.Errorf("expected the daily cap to be shared by both instances, got %v", err3)
+	}
+}
+
+func TestRedisOTPRequestThrottle_RecordExpiresWithDailyWindow(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	throttle, _, server := setupRedisOTPRequestThrottle(t, usecase.OTPRequestPolicy{
+		Cooldown:        time.Minute,
+		DailyLimit:      1,
+		GlobalPerMinute: 0,
+	})
+
+	// Act
+	err := throttle.Allow(context.Background(), throttleEmail(t, "user@example.com"))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("expected the first request to be allowed, got %v", err)
+	}
+
+	keys := server.Keys()
+	if len(keys) != 1 || !strings.HasPrefix(keys[0], "{otp:throttle}:email:") || strings.Contains(keys[0], "user@example.com") {
+		t.Fatalf("expected one hashed address key, got %v", keys)
+	}
+
+	ttl := server.TTL(keys[0])
+	if ttl <= usecase.OTPThrottleWindow-time.Minute || ttl > usecase.OTPThrottleWindow {
+		t.Errorf("expected TTL close to the daily window, got %v", ttl)
+	}
+}
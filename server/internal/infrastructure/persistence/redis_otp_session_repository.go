@@ -0,0 +1,233 @@
+package persistence
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/repository"
+	"custom_auth_api/internal/domain/vo/email"
+	"custom_auth_api/internal/domain/vo/otp"
+)
+
+const (
+	// redisSessionKeyPrefix namespaces OTP session keys.
+	redisSessionKeyPrefix = "otp:session:"
+
+	// redisMaxTxRetries bounds optimistic transaction retries in VerifyAndConsume.
+	// A session is written at most MaxVerificationAttempts+1 times, so a verification
+	// cannot lose more races than that.
+	redisMaxTxRetries = 2 * (entity.MaxVerificationAttempts + 1)
+)
+
+// Hash field names of the Redis session record.
+const (
+	redisFieldEmail         = "email"
+	redisFieldOTPHash       = "otpHash"
+	redisFieldAttempts      = "attempts"
+	redisFieldCreatedAt     = "createdAt"
+	redisFieldExpiresAt     = "expiresAt"
+	redisFieldIPAddressHash = "ipAddressHash"
+	redisFieldUserAgent     = "userAgent"
+)
+
+// ErrSessionContention is returned when a session keeps being modified concurrently
+// and the optimistic transaction could not be committed.
+var ErrSessionContention = errors.New("otp session was modified concurrently, retries exhausted")
+
+// RedisOTPSessionRepository handles OTPSession persistence in Redis.
+//
+// Each session is a hash whose key expires at the session's ExpiresAt, so Redis
+// evicts expired sessions by itself; an expired session is therefore reported as
+// entity.ErrSessionNotFound rather than entity.ErrSessionExpired. Keys contain the
+// SHA-256 of the canonical email instead of the raw address.
+type RedisOTPSessionRepository struct {
+	client redis.UniversalClient
+	hasher *otp.Hasher
+}
+
+// NewRedisOTPSessionRepository creates a new RedisOTPSessionRepository.
+// The hasher is used to store OTP codes as keyed digests instead of plaintext.
+func NewRedisOTPSessionRepository(client redis.UniversalClient, hasher *otp.Hasher) *RedisOTPSessionRepository {
+	return &RedisOTPSessionRepository{client: client, hasher: hasher}
+}
+
+// Save stores or replaces an OTP session and sets the key to expire at ExpiresAt.
+func (r *RedisOTPSessionRepository) Save(ctx context.Context, session *entity.OTPSession) error {
+	key := redisSessionKey(session.Email())
+
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		// Replace rather than merge with a previous session
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key,
+			redisFieldEmail, session.Email().Value,
+			redisFieldOTPHash, r.hasher.Digest(session.OTP(), session.Email().Canonical()).String(),
+			redisFieldAttempts, session.Attempts(),
+			redisFieldCreatedAt, session.CreatedAt().UTC().Format(time.RFC3339Nano),
+			redisFieldExpiresAt, session.ExpiresAt().UTC().Format(time.RFC3339Nano),
+			redisFieldIPAddressHash, session.IPAddressHash().String(),
+			redisFieldUserAgent, session.UserAgent(),
+		)
+		pipe.PExpireAt(ctx, key, session.ExpiresAt())
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save otp session: %w", err)
+	}
+
+	return nil
+}
+
+// FindByEmail retrieves an OTP session from Redis by email.
+// Returns entity.ErrSessionNotFound if the key doesn't exist or has expired.
+func (r *RedisOTPSessionRepository) FindByEmail(ctx context.Context, userEmail *email.Email) (*entity.OTPSession, error) {
+	return r.load(ctx, r.client, userEmail)
+}
+
+// Delete removes an OTP session from Redis.
+func (r *RedisOTPSessionRepository) Delete(ctx context.Context, userEmail *email.Email) error {
+	err := r.client.Del(ctx, redisSessionKey(userEmail)).Err()
+	if err != nil {
+		return fmt.Errorf("failed to delete otp session: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyAndConsume verifies the code in an optimistic WATCH/MULTI/EXEC transaction.
+// If another client modifies the session between the read and the write, EXEC
+// aborts and the verification is retried against the latest attempts counter,
+// so a session can only be consumed by one successful verification.
+func (r *RedisOTPSessionRepository) VerifyAndConsume(ctx context.Context, userEmail *email.Email, inputCode string) error {
+	key := redisSessionKey(userEmail)
+
+	var verifyErr error
+
+	txf := func(tx *redis.Tx) error {
+		// Reset on every run: the function is retried on contention
+		verifyErr = nil
+
+		session, err := r.load(ctx, tx, userEmail)
+		if errors.Is(err, entity.ErrSessionNotFound) {
+			verifyErr = err
+
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		attemptsBefore := session.Attempts()
+		verifyErr = session.Verify(inputCode)
+
+		switch {
+		case verifyErr == nil:
+			// One-time use: consume the session
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Del(ctx, key)
+
+				return nil
+			})
+		case session.Attempts() != attemptsBefore:
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.HSet(ctx, key, redisFieldAttempts, session.Attempts())
+
+				return nil
+			})
+		}
+
+		return err
+	}
+
+	for range redisMaxTxRetries {
+		err := r.client.Watch(ctx, txf, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("otp verification transaction failed: %w", err)
+		}
+
+		return verifyErr
+	}
+
+	return ErrSessionContention
+}
+
+// load reads and reconstructs the session stored for the email.
+func (r *RedisOTPSessionRepository) load(ctx context.Context, cmd redis.Cmdable, userEmail *email.Email) (*entity.OTPSession, error) {
+	fields, err := cmd.HGetAll(ctx, redisSessionKey(userEmail)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get otp session: %w", err)
+	}
+
+	if len(fields) == 0 {
+		return nil, entity.ErrSessionNotFound
+	}
+
+	return r.sessionFromFields(fields, userEmail)
+}
+
+// sessionFromFields converts the Redis hash of the email's session into a domain entity.
+func (r *RedisOTPSessionRepository) sessionFromFields(fields map[string]string, userEmail *email.Email) (*entity.OTPSession, error) {
+	attempts, err := strconv.Atoi(fields[redisFieldAttempts])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse otp session attempts: %w", err)
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, fields[redisFieldCreatedAt])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse otp session createdAt: %w", err)
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339Nano, fields[redisFieldExpiresAt])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse otp session expiresAt: %w", err)
+	}
+
+	doc := otpSessionDocument{
+		Email:         fields[redisFieldEmail],
+		OTPHash:       fields[redisFieldOTPHash],
+		OTP:           "",
+		Attempts:      attempts,
+		CreatedAt:     createdAt,
+		ExpiresAt:     expiresAt,
+		IPAddressHash: fields[redisFieldIPAddressHash],
+		UserAgent:     fields[redisFieldUserAgent],
+	}
+
+	digest, err := otp.ParseDigest(doc.OTPHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconstruct otp code: %w", err)
+	}
+
+	otpCode, err := otp.FromDigest(digest, r.hasher, userEmail.Canonical())
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconstruct otp code: %w", err)
+	}
+
+	reconstructedEmail, err := email.FromString(doc.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconstruct email: %w", err)
+	}
+
+	return reconstructSessionFromDocument(doc, reconstructedEmail, otpCode)
+}
+
+// redisSessionKey returns the key of the session for the email, derived from its canonical form.
+// The email is hashed so addresses never appear in key names (e.g. in SCAN or MONITOR output).
+func redisSessionKey(userEmail *email.Email) string {
+	sum := sha256.Sum256([]byte(userEmail.Canonical()))
+
+	return redisSessionKeyPrefix + hex.EncodeToString(sum[:])
+}
+
+var _ repository.OTPSessionRepository = (*RedisOTPSessionRepository)(nil)
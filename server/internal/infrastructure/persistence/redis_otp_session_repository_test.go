@@ -0,0 +1,141 @@
+package persistence_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/repository"
+	"custom_auth_api/internal/domain/repository/repositorytest"
+	"custom_auth_api/internal/domain/vo/email"
+	"custom_auth_api/internal/domain/vo/ipaddress"
+	"custom_auth_api/internal/domain/vo/otp"
+	"custom_auth_api/internal/infrastructure/persistence"
+)
+
+// setupRedisRepository creates a repository backed by an in-process miniredis server.
+func setupRedisRepository(t *testing.T) (*persistence.RedisOTPSessionRepository, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+
+	t.Cleanup(func() {
+		err := client.Close()
+		if err != nil {
+			t.Logf("Failed to close Redis client: %v", err)
+		}
+	})
+
+	return persistence.NewRedisOTPSessionRepository(client, newTestHasher(t)), server
+}
+
+// saveRedisSession stores a fresh session with testCode in the Redis repository.
+func saveRedisSession(t *testing.T, repo *persistence.RedisOTPSessionRepository, addr string) *email.Email {
+	t.Helper()
+
+	userEmail, _ := email.NewEmail(addr)
+	code, _ := otp.FromString(testCode)
+
+	err := repo.Save(context.Background(), entity.NewOTPSessionWithContext(userEmail, code, ipaddress.FromString(testIPHash), "test-agent"))
+	if err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+
+	return userEmail
+}
+
+func TestRedisOTPSessionRepository_SaveAndFind(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	repo, server := setupRedisRepository(t)
+	userEmail := saveRedisSession(t, repo, "redis-find@example.com")
+
+	// Act
+	session, err := repo.FindByEmail(context.Background(), userEmail)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("FindByEmail() returned an error: %v", err)
+	}
+
+	if !session.OTP().IsHashedOnly() || !session.OTP().Matches(testCode) {
+		t.Error("expected a hashed-only OTP matching the saved code")
+	}
+
+	if session.UserAgent() != "test-agent" || session.IPAddressHash().IsEmpty() {
+		t.Error("expected audit fields to be restored")
+	}
+
+	keys := server.Keys()
+	if len(keys) != 1 {
+		t.Fatalf("expected 1 key, got %v", keys)
+	}
+
+	if strings.Contains(keys[0], "redis-find") || !strings.HasPrefix(keys[0], "otp:session:") {
+		t.Errorf("key %q must be prefixed and must not contain the email", keys[0])
+	}
+
+	if server.HGet(keys[0], "otp") != "" || server.HGet(keys[0], "otpHash") == "" {
+		t.Error("expected only the OTP digest to be stored")
+	}
+}
+
+func TestRedisOTPSessionRepository_KeyExpiresAtExpiresAt(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	repo, server := setupRedisRepository(t)
+	userEmail := saveRedisSession(t, repo, "redis-ttl@example.com")
+
+	ttl := server.TTL(server.Keys()[0])
+	if ttl <= entity.DefaultOTPExpiration-time.Minute || ttl > entity.DefaultOTPExpiration {
+		t.Errorf("expected TTL close to %v, got %v", entity.DefaultOTPExpiration, ttl)
+	}
+
+	// Act
+	server.FastForward(entity.DefaultOTPExpiration + time.Second)
+
+	// Assert
+	_, err := repo.FindByEmail(context.Background(), userEmail)
+	if !errors.Is(err, entity.ErrSessionNotFound) {
+		t.Errorf("expected ErrSessionNotFound after expiry, got %v", err)
+	}
+}
+
+func TestRedisOTPSessionRepository_Contract(t *testing.T) {
+	t.Parallel()
+
+	repo, _ := setupRedisRepository(t)
+
+	repositorytest.TestOTPSessionRepository(t, func(*testing.T) repository.OTPSessionRepository {
+		return repo
+	})
+}
+
+func TestRedisOTPSessionRepository_VerifyAndConsume_KeepsTTL(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	repo, server := setupRedisRepository(t)
+	userEmail := saveRedisSession(t, repo, "redis-wrong@example.com")
+
+	// Act
+	err := repo.VerifyAndConsume(context.Background(), userEmail, "000000")
+
+	// Assert
+	if !errors.Is(err, entity.ErrInvalidOTP) {
+		t.Fatalf("expected ErrInvalidOTP, got %v", err)
+	}
+
+	if server.TTL(server.Keys()[0]) <= 0 {
+		t.Error("expected the key to keep its expiry after updating attempts")
+	}
+}
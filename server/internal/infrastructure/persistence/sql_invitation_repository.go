@@ -0,0 +1,199 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/repository"
+)
+
+// sqlRolesSeparator joins roles in the roles column; role names cannot contain it.
+const sqlRolesSeparator = ","
+
+// SQLInvitationRepository handles Invitation persistence in a relational database
+// through database/sql. The schema is created by MigrateSQL.
+//
+// Like the Firestore implementation, expired invitations are kept until they are
+// purged, so acceptance reports entity.ErrInvitationExpired for them.
+type SQLInvitationRepository struct {
+	db      *sql.DB
+	dialect SQLDialect
+}
+
+// NewSQLInvitationRepository creates a new SQLInvitationRepository.
+func NewSQLInvitationRepository(db *sql.DB, dialect SQLDialect) (*SQLInvitationRepository, error) {
+	if dialect != SQLDialectSQLite && dialect != SQLDialectPostgres {
+		return nil, fmt.Errorf("%w (got %q)", ErrUnsupportedSQLDialect, dialect)
+	}
+
+	return &SQLInvitationRepository{db: db, dialect: dialect}, nil
+}
+
+// Save stores or replaces the invitation with the invitation's ID.
+func (r *SQLInvitationRepository) Save(ctx context.Context, invitation *entity.Invitation) error {
+	_, err := r.db.ExecContext(ctx, r.dialect.rebind(`
+		INSERT INTO invitations (id, email, inviter, roles, created_at, expires_at, accepted_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			email = excluded.email,
+			inviter = excluded.inviter,
+			roles = excluded.roles,
+			created_at = excluded.created_at,
+			expires_at = excluded.expires_at,
+			accepted_at = excluded.accepted_at`),
+		invitation.ID(),
+		invitation.Email().Value,
+		invitation.Inviter(),
+		strings.Join(invitation.Roles(), sqlRolesSeparator),
+		invitation.CreatedAt().UnixMicro(),
+		invitation.ExpiresAt().UnixMicro(),
+		unixMicroOrZero(invitation.AcceptedAt()),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save invitation: %w", err)
+	}
+
+	return nil
+}
+
+// FindByID retrieves an invitation by ID.
+// Returns entity.ErrInvitationNotFound if no row exists.
+func (r *SQLInvitationRepository) FindByID(ctx context.Context, id string) (*entity.Invitation, error) {
+	return r.load(ctx, r.db, id, "")
+}
+
+// Delete removes an invitation. Deleting a missing invitation is not an error.
+func (r *SQLInvitationRepository) Delete(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, r.dialect.rebind(`DELETE FROM invitations WHERE id = ?`), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete invitation: %w", err)
+	}
+
+	return nil
+}
+
+// Accept marks the invitation as accepted inside a transaction that locks the row
+// (SELECT ... FOR UPDATE on PostgreSQL, the database write lock on SQLite),
+// so only one concurrent acceptance can succeed.
+func (r *SQLInvitationRepository) Accept(ctx context.Context, id string) (*entity.Invitation, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invitation acceptance transaction failed: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	invitation, err := r.load(ctx, tx, id, r.dialect.forUpdate())
+	if err != nil {
+		return nil, err
+	}
+
+	err = invitation.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, r.dialect.rebind(`UPDATE invitations SET accepted_at = ? WHERE id = ?`),
+		invitation.AcceptedAt().UnixMicro(), id)
+	if err != nil {
+		return nil, fmt.Errorf("invitation acceptance transaction failed: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("invitation acceptance transaction failed: %w", err)
+	}
+
+	return invitation, nil
+}
+
+// PurgeExpired deletes all invitations whose expiration time has passed
+// and returns the number of rows removed.
+func (r *SQLInvitationRepository) PurgeExpired(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, r.dialect.rebind(`DELETE FROM invitations WHERE expires_at < ?`),
+		time.Now().UnixMicro())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge expired invitations: %w", err)
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge expired invitations: %w", err)
+	}
+
+	return purged, nil
+}
+
+// RunPurge purges expired invitations every interval until ctx is done.
+// It blocks, so run it in its own goroutine. Failures are reported to onError, which may be nil.
+func (r *SQLInvitationRepository) RunPurge(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := r.PurgeExpired(ctx)
+			if err != nil && onError != nil && ctx.Err() == nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// load reads and reconstructs the invitation row with the ID.
+func (r *SQLInvitationRepository) load(
+	ctx context.Context,
+	queryer sqlQueryer,
+	id string,
+	lockClause string,
+) (*entity.Invitation, error) {
+	var (
+		doc        invitationDocument
+		roles      string
+		createdAt  int64
+		expiresAt  int64
+		acceptedAt int64
+	)
+
+	err := queryer.QueryRowContext(ctx, r.dialect.rebind(`
+		SELECT email, inviter, roles, created_at, expires_at, accepted_at
+		FROM invitations WHERE id = ?`+lockClause), id).
+		Scan(&doc.Email, &doc.Inviter, &roles, &createdAt, &expiresAt, &acceptedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, entity.ErrInvitationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+
+	if roles != "" {
+		doc.Roles = strings.Split(roles, sqlRolesSeparator)
+	}
+
+	doc.CreatedAt = time.UnixMicro(createdAt)
+	doc.ExpiresAt = time.UnixMicro(expiresAt)
+
+	if acceptedAt != 0 {
+		doc.AcceptedAt = time.UnixMicro(acceptedAt)
+	}
+
+	return reconstructInvitationFromDocument(id, doc)
+}
+
+// unixMicroOrZero returns t in Unix microseconds, or 0 for the zero time.
+func unixMicroOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixMicro()
+}
+
+var _ repository.InvitationRepository = (*SQLInvitationRepository)(nil)
@@ -0,0 +1,66 @@
+package persistence_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/repository"
+	"custom_auth_api/internal/domain/repository/repositorytest"
+	"custom_auth_api/internal/infrastructure/persistence"
+)
+
+// setupSQLInvitationRepository creates a repository backed by a fresh SQLite database.
+func setupSQLInvitationRepository(t *testing.T) *persistence.SQLInvitationRepository {
+	t.Helper()
+
+	repo, err := persistence.NewSQLInvitationRepository(openSQLiteDB(t), persistence.SQLDialectSQLite)
+	if err != nil {
+		t.Fatalf("NewSQLInvitationRepository() returned an error: %v", err)
+	}
+
+	return repo
+}
+
+func TestSQLInvitationRepository_Contract(t *testing.T) {
+	t.Parallel()
+
+	repo := setupSQLInvitationRepository(t)
+
+	repositorytest.TestInvitationRepository(t, func(*testing.T) repository.InvitationRepository {
+		return repo
+	})
+}
+
+func TestSQLInvitationRepository_PurgeExpired(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	repo := setupSQLInvitationRepository(t)
+	saveTestInvitation(t, repo, "sql-active", time.Now().Add(time.Hour))
+	saveTestInvitation(t, repo, "sql-purged", time.Now().Add(-time.Minute))
+
+	// Act
+	purged, err := repo.PurgeExpired(context.Background())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("PurgeExpired() returned an error: %v", err)
+	}
+
+	if purged != 1 {
+		t.Errorf("expected 1 purged invitation, got %d", purged)
+	}
+
+	_, err = repo.FindByID(context.Background(), "sql-purged")
+	if !errors.Is(err, entity.ErrInvitationNotFound) {
+		t.Errorf("expected expired invitation to be purged, got %v", err)
+	}
+
+	_, err = repo.FindByID(context.Background(), "sql-active")
+	if err != nil {
+		t.Errorf("expected active invitation to be kept, got %v", err)
+	}
+}
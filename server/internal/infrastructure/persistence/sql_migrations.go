@@ -0,0 +1,167 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// ErrInvalidMigrationName is returned when an embedded migration file is not named <version>_<name>.sql.
+var ErrInvalidMigrationName = errors.New("migration file must be named <version>_<name>.sql")
+
+// sqlMigration is a single versioned schema change.
+type sqlMigration struct {
+	version int
+	name    string
+	script  string
+}
+
+// MigrateSQL applies all embedded migrations that have not been applied yet, in version order.
+// Applied versions are recorded in the schema_migrations table, and each migration runs
+// in its own transaction so a failed migration leaves the schema at the previous version.
+func MigrateSQL(ctx context.Context, db *sql.DB, dialect SQLDialect) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx,
+		`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER NOT NULL PRIMARY KEY, applied_at BIGINT NOT NULL)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	applied, err := appliedMigrationVersions(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range migrations {
+		if applied[migration.version] {
+			continue
+		}
+
+		err = applyMigration(ctx, db, dialect, migration)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadMigrations reads the embedded migration files sorted by version.
+func loadMigrations() ([]sqlMigration, error) {
+	entries, err := fs.ReadDir(migrationFS, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	migrations := make([]sqlMigration, 0, len(entries))
+
+	for _, entry := range entries {
+		versionText, name, found := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), "_")
+
+		version, err := strconv.Atoi(versionText)
+		if !found || err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMigrationName, entry.Name())
+		}
+
+		script, err := migrationFS.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migrations = append(migrations, sqlMigration{version: version, name: name, script: string(script)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+
+	return migrations, nil
+}
+
+// appliedMigrationVersions returns the set of versions recorded in schema_migrations.
+func appliedMigrationVersions(ctx context.Context, db *sql.DB) (map[int]bool, error) {
+	rows, err := db.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+
+	for rows.Next() {
+		var version int
+
+		err = rows.Scan(&version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+
+		applied[version] = true
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	return applied, nil
+}
+
+// applyMigration runs one migration and records its version in a single transaction.
+func applyMigration(ctx context.Context, db *sql.DB, dialect SQLDialect, migration sqlMigration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %d: %w", migration.version, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for statement := range strings.SplitSeq(migration.script, ";") {
+		if strings.TrimSpace(stripSQLComments(statement)) == "" {
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, statement)
+		if err != nil {
+			return fmt.Errorf("failed to apply migration %d_%s: %w", migration.version, migration.name, err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		dialect.rebind(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`),
+		migration.version, time.Now().UnixMicro())
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", migration.version, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", migration.version, err)
+	}
+
+	return nil
+}
+
+// stripSQLComments removes "--" line comments so comment-only fragments are skipped.
+func stripSQLComments(statement string) string {
+	var builder strings.Builder
+
+	for line := range strings.Lines(statement) {
+		code, _, _ := strings.Cut(line, "--")
+		builder.WriteString(code)
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,271 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/repository"
+	"custom_auth_api/internal/domain/vo/email"
+	"custom_auth_api/internal/domain/vo/otp"
+)
+
+// SQLDialect identifies the SQL flavour spoken by the database.
+type SQLDialect string
+
+// Supported SQL dialects.
+const (
+	// SQLDialectSQLite is SQLite. Row locks do not exist, so the database should be
+	// opened with "_txlock=immediate" to make every transaction take the write lock
+	// up front, and with a busy timeout so concurrent transactions wait instead of failing.
+	SQLDialectSQLite SQLDialect = "sqlite"
+
+	// SQLDialectPostgres is PostgreSQL. Verification locks the session row with SELECT ... FOR UPDATE.
+	SQLDialectPostgres SQLDialect = "postgres"
+)
+
+// ErrUnsupportedSQLDialect is returned for dialects other than sqlite and postgres.
+var ErrUnsupportedSQLDialect = errors.New("sql dialect must be one of: sqlite, postgres")
+
+// rebind rewrites "?" placeholders into the dialect's placeholder syntax.
+func (d SQLDialect) rebind(query string) string {
+	if d != SQLDialectPostgres {
+		return query
+	}
+
+	var builder strings.Builder
+
+	position := 0
+
+	for _, char := range query {
+		if char != '?' {
+			builder.WriteRune(char)
+
+			continue
+		}
+
+		position++
+		builder.WriteString("$" + strconv.Itoa(position))
+	}
+
+	return builder.String()
+}
+
+// forUpdate returns the row-locking clause for SELECTs inside VerifyAndConsume.
+func (d SQLDialect) forUpdate() string {
+	if d == SQLDialectPostgres {
+		return " FOR UPDATE"
+	}
+
+	return ""
+}
+
+// SQLOTPSessionRepository handles OTPSession persistence in a relational database
+// through database/sql. The schema is created by MigrateSQL.
+//
+// Like the Firestore implementation, expired sessions are kept until they are
+// purged, so verification reports entity.ErrSessionExpired for them.
+type SQLOTPSessionRepository struct {
+	db      *sql.DB
+	dialect SQLDialect
+	hasher  *otp.Hasher
+}
+
+// NewSQLOTPSessionRepository creates a new SQLOTPSessionRepository.
+// The hasher is used to store OTP codes as keyed digests instead of plaintext.
+func NewSQLOTPSessionRepository(db *sql.DB, dialect SQLDialect, hasher *otp.Hasher) (*SQLOTPSessionRepository, error) {
+	if dialect != SQLDialectSQLite && dialect != SQLDialectPostgres {
+		return nil, fmt.Errorf("%w (got %q)", ErrUnsupportedSQLDialect, dialect)
+	}
+
+	return &SQLOTPSessionRepository{db: db, dialect: dialect, hasher: hasher}, nil
+}
+
+// Save stores or replaces the OTP session for the session's email.
+func (r *SQLOTPSessionRepository) Save(ctx context.Context, session *entity.OTPSession) error {
+	_, err := r.db.ExecContext(ctx, r.dialect.rebind(`
+		INSERT INTO otp_sessions
+			(email, display_email, otp_hash, attempts, created_at, expires_at, ip_address_hash, user_agent)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (email) DO UPDATE SET
+			display_email = excluded.display_email,
+			otp_hash = excluded.otp_hash,
+			attempts = excluded.attempts,
+			created_at = excluded.created_at,
+			expires_at = excluded.expires_at,
+			ip_address_hash = excluded.ip_address_hash,
+			user_agent = excluded.user_agent`),
+		session.Email().Canonical(),
+		session.Email().Value,
+		r.hasher.Digest(session.OTP(), session.Email().Canonical()).String(),
+		session.Attempts(),
+		session.CreatedAt().UnixMicro(),
+		session.ExpiresAt().UnixMicro(),
+		session.IPAddressHash().String(),
+		session.UserAgent(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save otp session: %w", err)
+	}
+
+	return nil
+}
+
+// FindByEmail retrieves an OTP session by email.
+// Returns entity.ErrSessionNotFound if no row exists.
+func (r *SQLOTPSessionRepository) FindByEmail(ctx context.Context, userEmail *email.Email) (*entity.OTPSession, error) {
+	return r.load(ctx, r.db, userEmail, "")
+}
+
+// Delete removes an OTP session. Deleting a missing session is not an error.
+func (r *SQLOTPSessionRepository) Delete(ctx context.Context, userEmail *email.Email) error {
+	_, err := r.db.ExecContext(ctx, r.dialect.rebind(`DELETE FROM otp_sessions WHERE email = ?`), userEmail.Canonical())
+	if err != nil {
+		return fmt.Errorf("failed to delete otp session: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyAndConsume verifies the code inside a transaction that locks the session row
+// (SELECT ... FOR UPDATE on PostgreSQL, the database write lock on SQLite), so
+// concurrent verifications observe each other's attempts and only one can consume the session.
+func (r *SQLOTPSessionRepository) VerifyAndConsume(ctx context.Context, userEmail *email.Email, inputCode string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("otp verification transaction failed: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	session, err := r.load(ctx, tx, userEmail, r.dialect.forUpdate())
+	if err != nil {
+		return err
+	}
+
+	attemptsBefore := session.Attempts()
+	verifyErr := session.Verify(inputCode)
+
+	switch {
+	case verifyErr == nil:
+		// One-time use: consume the session
+		_, err = tx.ExecContext(ctx, r.dialect.rebind(`DELETE FROM otp_sessions WHERE email = ?`), userEmail.Canonical())
+	case session.Attempts() != attemptsBefore:
+		_, err = tx.ExecContext(ctx, r.dialect.rebind(`UPDATE otp_sessions SET attempts = ? WHERE email = ?`),
+			session.Attempts(), userEmail.Canonical())
+	}
+
+	if err != nil {
+		return fmt.Errorf("otp verification transaction failed: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("otp verification transaction failed: %w", err)
+	}
+
+	return verifyErr
+}
+
+// PurgeExpired deletes all sessions whose expiration time has passed
+// and returns the number of rows removed.
+func (r *SQLOTPSessionRepository) PurgeExpired(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, r.dialect.rebind(`DELETE FROM otp_sessions WHERE expires_at < ?`),
+		time.Now().UnixMicro())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge expired otp sessions: %w", err)
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge expired otp sessions: %w", err)
+	}
+
+	return purged, nil
+}
+
+// RunPurge purges expired sessions every interval until ctx is done.
+// It blocks, so run it in its own goroutine. Failures are reported to onError, which may be nil.
+func (r *SQLOTPSessionRepository) RunPurge(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := r.PurgeExpired(ctx)
+			if err != nil && onError != nil && ctx.Err() == nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// sqlQueryer is implemented by both *sql.DB and *sql.Tx.
+type sqlQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// load reads and reconstructs the session row for the email.
+func (r *SQLOTPSessionRepository) load(
+	ctx context.Context,
+	queryer sqlQueryer,
+	userEmail *email.Email,
+	lockClause string,
+) (*entity.OTPSession, error) {
+	var (
+		doc          otpSessionDocument
+		displayEmail string
+		createdAt    int64
+		expiresAt    int64
+	)
+
+	err := queryer.QueryRowContext(ctx, r.dialect.rebind(`
+		SELECT email, display_email, otp_hash, attempts, created_at, expires_at, ip_address_hash, user_agent
+		FROM otp_sessions WHERE email = ?`+lockClause), userEmail.Canonical()).
+		Scan(&doc.Email, &displayEmail, &doc.OTPHash, &doc.Attempts, &createdAt, &expiresAt,
+			&doc.IPAddressHash, &doc.UserAgent)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, entity.ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get otp session: %w", err)
+	}
+
+	if displayEmail != "" {
+		doc.Email = displayEmail
+	}
+
+	doc.CreatedAt = time.UnixMicro(createdAt)
+	doc.ExpiresAt = time.UnixMicro(expiresAt)
+
+	otpCode, err := r.restoreOTP(doc.OTPHash, userEmail)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconstruct otp code: %w", err)
+	}
+
+	reconstructedEmail, err := email.FromString(doc.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconstruct email: %w", err)
+	}
+
+	return reconstructSessionFromDocument(doc, reconstructedEmail, otpCode)
+}
+
+// restoreOTP reconstructs a hashed-only OTP from the digest stored for userEmail.
+func (r *SQLOTPSessionRepository) restoreOTP(encoded string, userEmail *email.Email) (*otp.OTP, error) {
+	digest, err := otp.ParseDigest(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	return otp.FromDigest(digest, r.hasher, userEmail.Canonical())
+}
+
+var _ repository.OTPSessionRepository = (*SQLOTPSessionRepository)(nil)
@@ -0,0 +1,213 @@
+package persistence_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/repository"
+	"custom_auth_api/internal/domain/repository/repositorytest"
+	"custom_auth_api/internal/domain/vo/email"
+	"custom_auth_api/internal/domain/vo/ipaddress"
+	"custom_auth_api/internal/domain/vo/otp"
+	"custom_auth_api/internal/infrastructure/persistence"
+)
+
+// openSQLiteDB opens a migrated SQLite database in a temporary directory.
+// Transactions take the write lock immediately and wait for each other (busy timeout).
+func openSQLiteDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "sessions.db") + "?_pragma=busy_timeout(10000)&_txlock=immediate"
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatalf("Failed to open SQLite database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		err := db.Close()
+		if err != nil {
+			t.Logf("Failed to close SQLite database: %v", err)
+		}
+	})
+
+	err = persistence.MigrateSQL(context.Background(), db, persistence.SQLDialectSQLite)
+	if err != nil {
+		t.Fatalf("MigrateSQL() returned an error: %v", err)
+	}
+
+	return db
+}
+
+// setupSQLRepository creates a repository backed by a fresh SQLite database.
+func setupSQLRepository(t *testing.T) *persistence.SQLOTPSessionRepository {
+	t.Helper()
+
+	repo, err := persistence.NewSQLOTPSessionRepository(openSQLiteDB(t), persistence.SQLDialectSQLite, newTestHasher(t))
+	if err != nil {
+		t.Fatalf("NewSQLOTPSessionRepository() returned an error: %v", err)
+	}
+
+	return repo
+}
+
+// saveSQLSession stores a session with testCode created at createdAt.
+func saveSQLSession(t *testing.T, repo *persistence.SQLOTPSessionRepository, addr string, createdAt time.Time) *email.Email {
+	t.Helper()
+
+	userEmail, _ := email.NewEmail(addr)
+	code, _ := otp.FromString(testCode)
+
+	data, err := entity.NewRestorationData(
+		userEmail,
+		code,
+		0,
+		createdAt,
+		createdAt.Add(entity.DefaultOTPExpiration),
+		ipaddress.FromString(testIPHash),
+		"test-agent",
+	)
+	if err != nil {
+		t.Fatalf("Failed to create restoration data: %v", err)
+	}
+
+	err = repo.Save(context.Background(), entity.RestoreOTPSession(data))
+	if err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+
+	return userEmail
+}
+
+func TestMigrateSQL_IsIdempotent(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	db := openSQLiteDB(t)
+
+	// Act
+	err := persistence.MigrateSQL(context.Background(), db, persistence.SQLDialectSQLite)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("second MigrateSQL() returned an error: %v", err)
+	}
+
+	var applied int
+
+	err = db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied)
+	if err != nil {
+		t.Fatalf("Failed to count applied migrations: %v", err)
+	}
+
+	if applied != 4 {
+		t.Errorf("expected 4 applied migrations, got %d", applied)
+	}
+}
+
+func TestNewSQLOTPSessionRepository_UnsupportedDialect(t *testing.T) {
+	t.Parallel()
+
+	repo, err := persistence.NewSQLOTPSessionRepository(nil, "oracle", newTestHasher(t))
+
+	if !errors.Is(err, persistence.ErrUnsupportedSQLDialect) {
+		t.Errorf("expected ErrUnsupportedSQLDialect, got %v", err)
+	}
+
+	if repo != nil {
+		t.Error("expected nil repository on error")
+	}
+}
+
+func TestSQLOTPSessionRepository_SaveAndFind(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	repo := setupSQLRepository(t)
+	createdAt := time.Now().Truncate(time.Microsecond)
+	userEmail := saveSQLSession(t, repo, "sql-find@example.com", createdAt)
+
+	// Act
+	session, err := repo.FindByEmail(context.Background(), userEmail)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("FindByEmail() returned an error: %v", err)
+	}
+
+	if !session.OTP().IsHashedOnly() || !session.OTP().Matches(testCode) {
+		t.Error("expected a hashed-only OTP matching the saved code")
+	}
+
+	if !session.CreatedAt().Equal(createdAt) || !session.ExpiresAt().Equal(createdAt.Add(entity.DefaultOTPExpiration)) {
+		t.Errorf("timestamps were not round-tripped: created %v, expires %v", session.CreatedAt(), session.ExpiresAt())
+	}
+
+	if session.UserAgent() != "test-agent" || session.IPAddressHash().String() != ipaddress.FromString(testIPHash).String() {
+		t.Error("expected audit fields to be restored")
+	}
+}
+
+func TestSQLOTPSessionRepository_Contract(t *testing.T) {
+	t.Parallel()
+
+	repo := setupSQLRepository(t)
+
+	repositorytest.TestOTPSessionRepository(t, func(*testing.T) repository.OTPSessionRepository {
+		return repo
+	})
+}
+
+func TestSQLOTPSessionRepository_VerifyAndConsume_Expired(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	repo := setupSQLRepository(t)
+	userEmail := saveSQLSession(t, repo, "sql-expired@example.com", time.Now().Add(-time.Hour))
+
+	// Act
+	err := repo.VerifyAndConsume(context.Background(), userEmail, testCode)
+
+	// Assert
+	if !errors.Is(err, entity.ErrSessionExpired) {
+		t.Errorf("expected ErrSessionExpired, got %v", err)
+	}
+}
+
+func TestSQLOTPSessionRepository_PurgeExpired(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	repo := setupSQLRepository(t)
+	activeEmail := saveSQLSession(t, repo, "sql-active@example.com", time.Now())
+	expiredEmail := saveSQLSession(t, repo, "sql-purged@example.com", time.Now().Add(-time.Hour))
+
+	// Act
+	purged, err := repo.PurgeExpired(context.Background())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("PurgeExpired() returned an error: %v", err)
+	}
+
+	if purged != 1 {
+		t.Errorf("expected 1 purged session, got %d", purged)
+	}
+
+	_, err = repo.FindByEmail(context.Background(), expiredEmail)
+	if !errors.Is(err, entity.ErrSessionNotFound) {
+		t.Errorf("expected expired session to be purged, got %v", err)
+	}
+
+	_, err = repo.FindByEmail(context.Background(), activeEmail)
+	if err != nil {
+		t.Errorf("expected active session to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"custom_auth_api/internal/domain/identity"
+)
+
+// SQLUserDirectory stores the users of the standalone identity backend in a relational
+// database through database/sql. The schema is created by MigrateSQL.
+//
+// Addresses are stored and matched in canonical form without provider rules (see userEmailKey).
+type SQLUserDirectory struct {
+	db      *sql.DB
+	dialect SQLDialect
+}
+
+// NewSQLUserDirectory creates a new SQLUserDirectory.
+func NewSQLUserDirectory(db *sql.DB, dialect SQLDialect) (*SQLUserDirectory, error) {
+	if dialect != SQLDialectSQLite && dialect != SQLDialectPostgres {
+		return nil, fmt.Errorf("%w (got %q)", ErrUnsupportedSQLDialect, dialect)
+	}
+
+	return &SQLUserDirectory{db: db, dialect: dialect}, nil
+}
+
+// GetUserByEmail returns the user registered with email.
+// Returns an error wrapping identity.ErrUserNotFound if there is none.
+func (d *SQLUserDirectory) GetUserByEmail(ctx context.Context, email string) (*identity.User, error) {
+	user := &identity.User{UID: "", Email: "", EmailVerified: false, DisplayName: "", Roles: nil}
+
+	var roles string
+
+	err := d.db.QueryRowContext(ctx, d.dialect.rebind(`
+		SELECT uid, email, email_verified, display_name, roles
+		FROM users WHERE email = ?`), userEmailKey(email)).
+		Scan(&user.UID, &user.Email, &user.EmailVerified, &user.DisplayName, &roles)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w (got %q)", identity.ErrUserNotFound, email)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by email: %w", err)
+	}
+
+	if roles != "" {
+		user.Roles = strings.Split(roles, sqlRolesSeparator)
+	}
+
+	return user, nil
+}
+
+// CreateUser creates a user with a random UID and no roles. The unique email column
+// makes concurrent registrations of one address create a single user.
+// Returns an error wrapping identity.ErrEmailAlreadyExists if the address is registered.
+func (d *SQLUserDirectory) CreateUser(ctx context.Context, user identity.UserToCreate) (*identity.User, error) {
+	created := &identity.User{
+		UID:           newUserID(),
+		Email:         userEmailKey(user.Email),
+		EmailVerified: user.EmailVerified,
+		DisplayName:   user.DisplayName,
+		Roles:         nil,
+	}
+
+	result, err := d.db.ExecContext(ctx, d.dialect.rebind(`
+		INSERT INTO users (uid, email, email_verified, display_name, roles, created_at)
+		VALUES (?, ?, ?, ?, '', ?)
+		ON CONFLICT (email) DO NOTHING`),
+		created.UID, created.Email, created.EmailVerified, created.DisplayName, time.Now().UnixMicro(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	if inserted == 0 {
+		return nil, fmt.Errorf("%w (got %q)", identity.ErrEmailAlreadyExists, user.Email)
+	}
+
+	return created, nil
+}
+
+// SetRoles replaces the roles of the user with ID uid.
+// Returns an error wrapping identity.ErrUserNotFound if there is no such user.
+func (d *SQLUserDirectory) SetRoles(ctx context.Context, uid string, roles []string) error {
+	result, err := d.db.ExecContext(ctx, d.dialect.rebind(`UPDATE users SET roles = ? WHERE uid = ?`),
+		strings.Join(roles, sqlRolesSeparator), uid)
+	if err != nil {
+		return fmt.Errorf("failed to set roles: %w", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to set roles: %w", err)
+	}
+
+	if updated == 0 {
+		return fmt.Errorf("%w (got UID %q)", identity.ErrUserNotFound, uid)
+	}
+
+	return nil
+}
+
+var _ identity.UserDirectory = (*SQLUserDirectory)(nil)
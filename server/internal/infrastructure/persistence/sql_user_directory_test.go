@@ -0,0 +1,22 @@
+package persistence_test
+
+import (
+	"testing"
+
+	"custom_auth_api/internal/domain/identity"
+	"custom_auth_api/internal/domain/identity/identitytest"
+	"custom_auth_api/internal/infrastructure/persistence"
+)
+
+func TestSQLUserDirectory_Contract(t *testing.T) {
+	t.Parallel()
+
+	directory, err := persistence.NewSQLUserDirectory(openSQLiteDB(t), persistence.SQLDialectSQLite)
+	if err != nil {
+		t.Fatalf("NewSQLUserDirectory() returned an error: %v", err)
+	}
+
+	identitytest.TestUserDirectory(t, func(*testing.T) identity.UserDirectory {
+		return directory
+	})
+}
@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"custom_auth_api/internal/domain/emailpolicy"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rejectedEmailMessage is returned for every domain policy rejection, so clients cannot
+// tell a denylisted domain from a disposable one or probe the allowlist.
+const rejectedEmailMessage = "This email address cannot be used"
+
+// respondInvalidEmail answers a failed OTPService.ParseEmail.
+// Format errors describe what is wrong with the address (400). Domain policy rejections
+// get a fixed message (403); the matching rule is only logged.
+func respondInvalidEmail(c *gin.Context, err error) {
+	if errors.Is(err, emailpolicy.ErrDomainRejected) {
+		logf(requestContext(c), "Email rejected by domain policy: %v", err)
+		c.JSON(http.StatusForbidden, gin.H{"error": rejectedEmailMessage})
+
+		return
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
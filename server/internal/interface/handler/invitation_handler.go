@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/vo/invitetoken"
+	"custom_auth_api/internal/interface/middleware"
+	"custom_auth_api/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InvitationHandler handles invitation-based registration.
+//
+// Responsibilities:
+// - Handle POST /admin/invitations for administrators (authenticated by middleware.AdminAuthMiddleware)
+// - Handle POST /auth/invitations/otp and POST /auth/invitations/accept for invitees
+// - Require both the invitation token and an OTP sent to the invited address
+// - Create the user (email verified) with the invited roles on acceptance
+// - Issue a sign-in token (e.g. a Firebase custom token) for the new or existing user.
+type InvitationHandler struct {
+	invitationService *usecase.InvitationService
+	otpService        *usecase.OTPService
+	authService       *usecase.AuthService
+	mode              RegistrationMode
+}
+
+// NewInvitationHandler creates a new InvitationHandler.
+// Invitations are accepted in RegistrationModeInvite and RegistrationModeOpen;
+// an empty mode behaves as RegistrationModeClosed.
+func NewInvitationHandler(
+	invitationService *usecase.InvitationService,
+	otpService *usecase.OTPService,
+	authService *usecase.AuthService,
+	mode RegistrationMode,
+) *InvitationHandler {
+	if mode == "" {
+		mode = RegistrationModeClosed
+	}
+
+	return &InvitationHandler{
+		invitationService: invitationService,
+		otpService:        otpService,
+		authService:       authService,
+		mode:              mode,
+	}
+}
+
+// CreateInvitation is a handler for inviting an email address and emailing the invitation.
+func (h *InvitationHandler) CreateInvitation(c *gin.Context) {
+	var req struct {
+		Email  string   `json:"email"`
+		Roles  []string `json:"roles"`  // Optional roles granted on acceptance
+		Locale string   `json:"locale"` // Optional language of the invitation email (e.g. "ja", "en")
+	}
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+
+		return
+	}
+
+	if !h.invitationsEnabled(c) {
+		return
+	}
+
+	userEmail, err := h.otpService.ParseEmail(req.Email)
+	if err != nil {
+		respondInvalidEmail(c, err)
+
+		return
+	}
+
+	ctx := emailContext(c, req.Locale)
+	inviter := middleware.AdminIdentity(c)
+
+	invitation, err := h.invitationService.Create(ctx, userEmail, inviter, req.Roles)
+	if errors.Is(err, entity.ErrInvalidRole) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	if err != nil {
+		logf(ctx, "Error creating invitation for %s by %s: %v", userEmail.Value, inviter, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+
+		return
+	}
+
+	logf(ctx, "Invitation %s created for %s by %s", invitation.ID(), userEmail.Value, inviter)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"id":        invitation.ID(),
+		"email":     userEmail.Value,
+		"roles":     invitation.Roles(),
+		"expiresAt": invitation.ExpiresAt().UTC().Format(time.RFC3339),
+	})
+}
+
+// RequestInvitationOTP is a handler for sending an OTP to the address of an invitation.
+// The response names the address so the accept page can show where the code was sent.
+func (h *InvitationHandler) RequestInvitationOTP(c *gin.Context) {
+	var req struct {
+		Token  string `json:"token"`
+		Locale string `json:"locale"` // Optional per-user language preference (e.g. "ja", "en")
+	}
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+
+		return
+	}
+
+	if !h.invitationsEnabled(c) {
+		return
+	}
+
+	ctx := emailContext(c, req.Locale)
+
+	invitation, err := h.invitationService.Resolve(ctx, req.Token)
+	if err != nil {
+		respondInvitationError(c, err)
+
+		return
+	}
+
+	invitedEmail := invitation.Email().Value
+
+	_, err = h.otpService.GenerateAndSendOTP(ctx, invitedEmail)
+	if respondThrottled(c, err) {
+		return
+	}
+
+	if err != nil {
+		logf(ctx, "Error generating and saving invitation OTP for %s: %v", invitedEmail, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate and save OTP"})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "OTP sent successfully.", "email": invitedEmail})
+}
+
+// AcceptInvitation is a handler for accepting an invitation with the OTP sent to its address.
+// It creates the user if needed, grants the invited roles and signs the user in.
+func (h *InvitationHandler) AcceptInvitation(c *gin.Context) {
+	var req struct {
+		Token       string `json:"token"`
+		OTP         string `json:"otp"`
+		DisplayName string `json:"displayName"` // Optional, only used when the user is created
+	}
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+
+		return
+	}
+
+	if !h.invitationsEnabled(c) {
+		return
+	}
+
+	// Reject a bad display name before the OTP is consumed, so the user can retry
+	err = usecase.ValidateDisplayName(req.DisplayName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	ctx := requestContext(c)
+
+	invitation, err := h.invitationService.Resolve(ctx, req.Token)
+	if err != nil {
+		respondInvitationError(c, err)
+
+		return
+	}
+
+	invitedEmail := invitation.Email().Value
+
+	isValid, err := h.otpService.VerifyOTP(ctx, invitedEmail, req.OTP)
+	if err != nil || !isValid {
+		logf(ctx, "Invitation OTP verification failed for %s: %v", invitedEmail, err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired OTP"})
+
+		return
+	}
+
+	// Provision before claiming the invitation, so a failure leaves it pending for a retry.
+	// Provisioning is idempotent: an existing user is reused and roles are merged.
+	user, created, err := h.authService.ProvisionUser(ctx, invitedEmail, req.DisplayName, invitation.Roles())
+	if err != nil {
+		logf(ctx, "Error provisioning invited user %s (invitation %s): %v", invitedEmail, invitation.ID(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register user"})
+
+		return
+	}
+
+	invitation, err = h.invitationService.Accept(ctx, invitation)
+	if err != nil {
+		respondInvitationError(c, err)
+
+		return
+	}
+
+	logf(ctx, "Invitation %s accepted by user %s (new user: %v)", invitation.ID(), user.UID, created)
+
+	customToken, err := h.authService.GenerateCustomToken(ctx, user)
+	if err != nil {
+		logf(ctx, "Error generating custom token for %s: %v", invitedEmail, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate authentication token"})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"token": customToken, "isNewUser": created})
+}
+
+// invitationsEnabled answers 403 when registration is closed, and reports whether
+// invitations can be created and accepted.
+func (h *InvitationHandler) invitationsEnabled(c *gin.Context) bool {
+	if h.mode == RegistrationModeInvite || h.mode == RegistrationModeOpen {
+		return true
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{"error": "Registration is closed"})
+
+	return false
+}
+
+// respondInvitationError answers a failed InvitationService.Resolve or Accept.
+// Unknown and forged tokens get the same response, so invitation IDs cannot be probed.
+func respondInvitationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, invitetoken.ErrInvalidToken), errors.Is(err, entity.ErrInvitationNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation"})
+	case errors.Is(err, invitetoken.ErrTokenExpired), errors.Is(err, entity.ErrInvitationExpired):
+		c.JSON(http.StatusGone, gin.H{"error": "Invitation has expired"})
+	case errors.Is(err, entity.ErrInvitationAlreadyUsed):
+		c.JSON(http.StatusConflict, gin.H{"error": "Invitation has already been accepted"})
+	default:
+		logf(requestContext(c), "Error resolving invitation: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process invitation"})
+	}
+}
@@ -0,0 +1,452 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"custom_auth_api/internal/domain/identity/identitytest"
+	"custom_auth_api/internal/domain/vo/invitetoken"
+	"custom_auth_api/internal/infrastructure/persistence"
+	"custom_auth_api/internal/interface/handler"
+	"custom_auth_api/internal/interface/middleware"
+	"custom_auth_api/internal/usecase"
+)
+
+const testAdminToken = "admin-token"
+
+var errInvalidIDToken = errors.New("invalid id token")
+
+// staticAdminVerifier accepts testAdminToken as admin@example.com.
+type staticAdminVerifier struct{}
+
+func (staticAdminVerifier) VerifyAdmin(_ context.Context, idToken string) (string, bool, error) {
+	if idToken != testAdminToken {
+		return "", false, errInvalidIDToken
+	}
+
+	return "admin@example.com", true, nil
+}
+
+// invitationTestEnv wires an InvitationHandler to in-memory stores and a recording sender.
+type invitationTestEnv struct {
+	router     *gin.Engine
+	otpService *usecase.OTPService
+	sender     *recordingEmailSender
+}
+
+// newInvitationTestEnv serves the invitation endpoints like the router does.
+// authService may be backed by a nil client for tests that never provision users.
+func newInvitationTestEnv(
+	t *testing.T,
+	mode handler.RegistrationMode,
+	authService *usecase.AuthService,
+) *invitationTestEnv {
+	t.Helper()
+
+	signer, err := invitetoken.NewSigner("test", map[string][]byte{
+		"test": bytes.Repeat([]byte("i"), invitetoken.MinSigningKeyLength),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create invitation token signer: %v", err)
+	}
+
+	sender := &recordingEmailSender{mu: sync.Mutex{}, otps: nil, notices: nil, invitations: nil}
+	otpService := usecase.NewOTPService(persistence.NewMemoryOTPSessionRepository(newTestHasher(t)), sender)
+
+	invitationService, err := usecase.NewInvitationService(
+		persistence.NewMemoryInvitationRepository(),
+		signer,
+		sender,
+		usecase.InvitationServiceOptions{TTL: 0, AcceptURL: "https://app.example.com/invite"},
+	)
+	if err != nil {
+		t.Fatalf("Failed to create invitation service: %v", err)
+	}
+
+	invitationHandler := handler.NewInvitationHandler(invitationService, otpService, authService, mode)
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/admin/invitations", middleware.AdminAuthMiddleware(staticAdminVerifier{}), invitationHandler.CreateInvitation)
+	router.POST("/auth/invitations/otp", invitationHandler.RequestInvitationOTP)
+	router.POST("/auth/invitations/accept", invitationHandler.AcceptInvitation)
+
+	return &invitationTestEnv{router: router, otpService: otpService, sender: sender}
+}
+
+// post sends a JSON body to path, authenticated as the admin if admin is set.
+func (e *invitationTestEnv) post(t *testing.T, path string, body any, admin bool) *httptest.ResponseRecorder {
+	t.Helper()
+
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("Failed to marshal request body: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	if admin {
+		req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	}
+
+	w := httptest.NewRecorder()
+	e.router.ServeHTTP(w, req)
+
+	return w
+}
+
+// invite creates an invitation through the admin endpoint and returns the emailed token.
+func (e *invitationTestEnv) invite(t *testing.T, emailAddr string, roles []string) string {
+	t.Helper()
+
+	w := e.post(t, "/admin/invitations", map[string]any{"email": emailAddr, "roles": roles}, true)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d creating the invitation, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	e.sender.mu.Lock()
+	defer e.sender.mu.Unlock()
+
+	link, err := url.Parse(e.sender.invitations[len(e.sender.invitations)-1].AcceptURL)
+	if err != nil {
+		t.Fatalf("Failed to parse accept link: %v", err)
+	}
+
+	return link.Query().Get("token")
+}
+
+func TestInvitationHandler_CreateInvitation(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		body           map[string]any
+		admin          bool
+		expectedStatus int
+	}{
+		{
+			name:           "creates an invitation",
+			body:           map[string]any{"email": "invitee@example.com", "roles": []string{"member", "billing"}},
+			admin:          true,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "requires an admin",
+			body:           map[string]any{"email": "invitee@example.com"},
+			admin:          false,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "rejects an invalid email",
+			body:           map[string]any{"email": "not-an-email"},
+			admin:          true,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "rejects an invalid role",
+			body:           map[string]any{"email": "invitee@example.com", "roles": []string{"has space"}},
+			admin:          true,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			env := newInvitationTestEnv(t, handler.RegistrationModeInvite, usecase.NewAuthService(persistence.NewMemoryUserDirectory(), identitytest.NewIssuer()))
+
+			// Act
+			w := env.post(t, "/admin/invitations", tc.body, tc.admin)
+
+			// Assert
+			if w.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatus, w.Code, w.Body.String())
+			}
+
+			if tc.expectedStatus != http.StatusCreated {
+				if len(env.sender.invitations) != 0 {
+					t.Errorf("Expected no invitation email, got %d", len(env.sender.invitations))
+				}
+
+				return
+			}
+
+			var response struct {
+				ID    string   `json:"id"`
+				Email string   `json:"email"`
+				Roles []string `json:"roles"`
+			}
+
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			if err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+
+			if response.ID == "" || response.Email != "invitee@example.com" || !slices.Equal(response.Roles, []string{"member", "billing"}) {
+				t.Errorf("Unexpected response %+v", response)
+			}
+
+			if len(env.sender.invitations) != 1 || env.sender.invitations[0].Inviter != "admin@example.com" {
+				t.Errorf("Expected one invitation email from admin@example.com, got %+v", env.sender.invitations)
+			}
+		})
+	}
+}
+
+func TestInvitationHandler_RegistrationClosed(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	env := newInvitationTestEnv(t, handler.RegistrationModeClosed, usecase.NewAuthService(persistence.NewMemoryUserDirectory(), identitytest.NewIssuer()))
+
+	for _, path := range []string{"/admin/invitations", "/auth/invitations/otp", "/auth/invitations/accept"} {
+		// Act
+		w := env.post(t, path, map[string]any{"email": "invitee@example.com", "token": "x"}, true)
+
+		// Assert
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status code %d for %s, got %d", http.StatusForbidden, path, w.Code)
+		}
+	}
+}
+
+func TestInvitationHandler_RequestInvitationOTP(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	env := newInvitationTestEnv(t, handler.RegistrationModeInvite, usecase.NewAuthService(persistence.NewMemoryUserDirectory(), identitytest.NewIssuer()))
+	token := env.invite(t, "invitee@example.com", nil)
+
+	// Act
+	w := env.post(t, "/auth/invitations/otp", map[string]string{"token": token}, false)
+	forged := env.post(t, "/auth/invitations/otp", map[string]string{"token": token + "x"}, false)
+
+	// Assert
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	if !strings.Contains(w.Body.String(), "invitee@example.com") {
+		t.Errorf("Expected the response to name the invited address, got %s", w.Body.String())
+	}
+
+	otps, _ := env.sender.sent()
+	if !slices.Equal(otps, []string{"invitee@example.com"}) {
+		t.Errorf("Expected one OTP sent to the invited address, got %v", otps)
+	}
+
+	if forged.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for a forged token, got %d", http.StatusBadRequest, forged.Code)
+	}
+}
+
+func TestInvitationHandler_AcceptInvitation_Rejects(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	env := newInvitationTestEnv(t, handler.RegistrationModeInvite, usecase.NewAuthService(persistence.NewMemoryUserDirectory(), identitytest.NewIssuer()))
+	token := env.invite(t, "invitee@example.com", []string{"member"})
+
+	code, err := env.otpService.GenerateAndSendOTP(context.Background(), "invitee@example.com")
+	if err != nil {
+		t.Fatalf("Failed to generate OTP: %v", err)
+	}
+
+	// Act
+	badName := env.post(t, "/auth/invitations/accept", map[string]string{
+		"token":       token,
+		"otp":         code,
+		"displayName": strings.Repeat("a", usecase.MaxDisplayNameLength+1),
+	}, false)
+	forged := env.post(t, "/auth/invitations/accept", map[string]string{"token": "test.abc.1.sig", "otp": code}, false)
+	wrongOTP := env.post(t, "/auth/invitations/accept", map[string]string{"token": token, "otp": "000000"}, false)
+
+	// Assert
+	if badName.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for an invalid display name, got %d", http.StatusBadRequest, badName.Code)
+	}
+
+	if forged.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for a forged token, got %d", http.StatusBadRequest, forged.Code)
+	}
+
+	if wrongOTP.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d for a wrong OTP, got %d", http.StatusUnauthorized, wrongOTP.Code)
+	}
+
+	// None of the rejections used up the invitation
+	again := env.post(t, "/auth/invitations/otp", map[string]string{"token": token}, false)
+	if again.Code != http.StatusOK {
+		t.Errorf("Expected the invitation to remain pending, got status code %d: %s", again.Code, again.Body.String())
+	}
+}
+
+func TestInvitationHandler_AcceptInvitation_ProvisionsUser(t *testing.T) {
+	t.Parallel()
+
+	const invitee = "invitation-new-user@example.com"
+
+	// Arrange
+	ctx := context.Background()
+	authService, directory, issuer := newFakeAuthService()
+	env := newInvitationTestEnv(t, handler.RegistrationModeInvite, authService)
+	token := env.invite(t, invitee, []string{"member"})
+
+	code, err := env.otpService.GenerateAndSendOTP(ctx, invitee)
+	if err != nil {
+		t.Fatalf("Failed to generate OTP: %v", err)
+	}
+
+	// Act
+	w := env.post(t, "/auth/invitations/accept", map[string]string{"token": token, "otp": code, "displayName": "Invitee"}, false)
+	reused := env.post(t, "/auth/invitations/otp", map[string]string{"token": token}, false)
+
+	// Assert
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	if reused.Code != http.StatusConflict {
+		t.Errorf("Expected status code %d for an accepted invitation, got %d", http.StatusConflict, reused.Code)
+	}
+
+	user, err := directory.GetUserByEmail(ctx, invitee)
+	if err != nil {
+		t.Fatalf("Expected the user to be created: %v", err)
+	}
+
+	if !user.EmailVerified || !slices.Equal(user.Roles, []string{"member"}) {
+		t.Errorf("Expected a verified user with role member, got %+v", user)
+	}
+
+	var response struct {
+		Token string `json:"token"`
+	}
+
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if issued, ok := issuer.Issued(response.Token); !ok || issued.User.UID != user.UID {
+		t.Errorf("Expected a token issued for user %s, got %+v", user.UID, issued)
+	}
+}
+
+// failingRolesDirectory fails SetRoles while fail is set.
+type failingRolesDirectory struct {
+	*persistence.MemoryUserDirectory
+
+	fail atomic.Bool
+}
+
+var errSetRolesUnavailable = errors.New("set roles unavailable")
+
+func (d *failingRolesDirectory) SetRoles(ctx context.Context, uid string, roles []string) error {
+	if d.fail.Load() {
+		return errSetRolesUnavailable
+	}
+
+	return d.MemoryUserDirectory.SetRoles(ctx, uid, roles)
+}
+
+func TestInvitationHandler_AcceptInvitation_RetryAfterProvisioningFailure(t *testing.T) {
+	t.Parallel()
+
+	const invitee = "invitation-retry@example.com"
+
+	// Arrange
+	ctx := context.Background()
+	directory := &failingRolesDirectory{MemoryUserDirectory: persistence.NewMemoryUserDirectory()}
+	directory.fail.Store(true)
+
+	env := newInvitationTestEnv(t, handler.RegistrationModeInvite, usecase.NewAuthService(directory, identitytest.NewIssuer()))
+	token := env.invite(t, invitee, []string{"member"})
+
+	accept := func() *httptest.ResponseRecorder {
+		code, err := env.otpService.GenerateAndSendOTP(ctx, invitee)
+		if err != nil {
+			t.Fatalf("Failed to generate OTP: %v", err)
+		}
+
+		return env.post(t, "/auth/invitations/accept", map[string]string{"token": token, "otp": code}, false)
+	}
+
+	// Act
+	failed := accept()
+
+	directory.fail.Store(false)
+
+	retried := accept()
+
+	// Assert
+	if failed.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status code %d while provisioning fails, got %d: %s", http.StatusInternalServerError, failed.Code, failed.Body.String())
+	}
+
+	if retried.Code != http.StatusOK {
+		t.Fatalf("Expected the invitation to be accepted on retry, got %d: %s", retried.Code, retried.Body.String())
+	}
+
+	user, err := directory.GetUserByEmail(ctx, invitee)
+	if err != nil {
+		t.Fatalf("Expected the user to exist: %v", err)
+	}
+
+	if !slices.Equal(user.Roles, []string{"member"}) {
+		t.Errorf("Expected role member after the retry, got %v", user.Roles)
+	}
+}
+
+func TestInvitationHandler_AcceptInvitation_ProvisionsFirebaseUser(t *testing.T) {
+	_, authClient, _, _, ctx := setupTestEnvironment(t) //nolint:dogsled // Only need authClient and ctx
+
+	const invitee = "invitation-firebase-user@example.com"
+
+	t.Cleanup(func() {
+		cleanupUser(ctx, t, authClient, invitee)
+	})
+
+	// Arrange
+	env := newInvitationTestEnv(t, handler.RegistrationModeInvite, newFirebaseAuthService(authClient))
+	token := env.invite(t, invitee, []string{"member"})
+
+	code, err := env.otpService.GenerateAndSendOTP(ctx, invitee)
+	if err != nil {
+		t.Fatalf("Failed to generate OTP: %v", err)
+	}
+
+	// Act
+	w := env.post(t, "/auth/invitations/accept", map[string]string{"token": token, "otp": code, "displayName": "Invitee"}, false)
+
+	// Assert
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	user, err := authClient.GetUserByEmail(ctx, invitee)
+	if err != nil {
+		t.Fatalf("Expected the user to be created: %v", err)
+	}
+
+	roles, _ := user.CustomClaims[usecase.RolesClaim].([]any)
+	if !user.EmailVerified || len(roles) != 1 || roles[0] != "member" {
+		t.Errorf("Expected a verified user with role member, got verified=%v claims=%v", user.EmailVerified, user.CustomClaims)
+	}
+}
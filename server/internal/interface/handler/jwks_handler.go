@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jwksCacheControl lets verifiers cache the key set for five minutes. A new signing key must
+// be published at least this long before it becomes active.
+const jwksCacheControl = "public, max-age=300"
+
+// KeySetSource provides the public keys tokens are signed with as a JSON Web Key Set,
+// e.g. a jwt.Issuer.
+type KeySetSource interface {
+	JWKS() ([]byte, error)
+}
+
+// JWKSHandler publishes the keys that verify the access tokens of the standalone identity backend.
+//
+// Responsibilities:
+// - Handle GET /.well-known/jwks.json endpoint
+// - Let verifiers cache the key set.
+type JWKSHandler struct {
+	keys KeySetSource
+}
+
+// NewJWKSHandler creates a new JWKSHandler.
+func NewJWKSHandler(keys KeySetSource) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// GetJWKS is a handler returning the JSON Web Key Set.
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	keySet, err := h.keys.JWKS()
+	if err != nil {
+		logf(requestContext(c), "Failed to encode key set: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load signing keys"})
+
+		return
+	}
+
+	c.Header("Cache-Control", jwksCacheControl)
+	c.Data(http.StatusOK, "application/json", keySet)
+}
@@ -0,0 +1,73 @@
+package handler_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"custom_auth_api/internal/interface/handler"
+)
+
+var errKeySetUnavailable = errors.New("key set unavailable")
+
+// keySetFunc adapts a function to a handler.KeySetSource.
+type keySetFunc func() ([]byte, error)
+
+func (f keySetFunc) JWKS() ([]byte, error) {
+	return f()
+}
+
+func TestJWKSHandler_GetJWKS(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name                 string
+		keys                 keySetFunc
+		expectedStatus       int
+		expectedBody         string
+		expectedCacheControl string
+	}{
+		{
+			name:                 "publishes the key set",
+			keys:                 func() ([]byte, error) { return []byte(`{"keys":[]}`), nil },
+			expectedStatus:       http.StatusOK,
+			expectedBody:         `{"keys":[]}`,
+			expectedCacheControl: "public, max-age=300",
+		},
+		{
+			name:                 "reports a failure without caching",
+			keys:                 func() ([]byte, error) { return nil, errKeySetUnavailable },
+			expectedStatus:       http.StatusInternalServerError,
+			expectedBody:         `{"error":"Failed to load signing keys"}`,
+			expectedCacheControl: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			gin.SetMode(gin.TestMode)
+
+			router := gin.New()
+			router.GET("/.well-known/jwks.json", handler.NewJWKSHandler(tc.keys).GetJWKS)
+
+			// Act
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+			// Assert
+			if w.Code != tc.expectedStatus || w.Body.String() != tc.expectedBody {
+				t.Errorf("expected %d %s, got %d %s", tc.expectedStatus, tc.expectedBody, w.Code, w.Body.String())
+			}
+
+			if cacheControl := w.Header().Get("Cache-Control"); cacheControl != tc.expectedCacheControl {
+				t.Errorf("expected Cache-Control %q, got %q", tc.expectedCacheControl, cacheControl)
+			}
+		})
+	}
+}
@@ -1,39 +1,119 @@
 package handler
 
 import (
-	"log"
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
-	"custom_auth_api/internal/domain/vo/email"
+	"custom_auth_api/internal/domain/emailsender"
 	"custom_auth_api/internal/usecase"
 
 	"github.com/gin-gonic/gin"
 )
 
+// OTPRequestMode selects how POST /auth/otp answers for addresses without an account.
+type OTPRequestMode string
+
+// Supported OTP request modes.
+const (
+	// OTPRequestModeDirect answers 401 for unknown addresses. Registered addresses
+	// can be told apart by status code and latency.
+	OTPRequestModeDirect OTPRequestMode = "direct"
+
+	// OTPRequestModePadded processes the request in the background and always answers
+	// the same 200 response exactly MinDuration after the request started, whether or
+	// not the work has finished.
+	OTPRequestModePadded OTPRequestMode = "padded"
+
+	// OTPRequestModeAsync answers the same 200 response immediately and processes
+	// the request in the background.
+	OTPRequestModeAsync OTPRequestMode = "async"
+)
+
+// uniformOTPResponse is returned for every well-formed request in the padded and async modes.
+const uniformOTPResponse = "If the email address is registered, an OTP has been sent."
+
+// DefaultOTPRequestMaxBackground is the default limit of concurrently processed
+// background OTP requests (padded and async modes).
+const DefaultOTPRequestMaxBackground = 100
+
+// DefaultOTPRequestBackgroundTimeout is the default bound of background OTP request processing.
+const DefaultOTPRequestBackgroundTimeout = 30 * time.Second
+
+// OTPRequestOptions configures enumeration protection for POST /auth/otp.
+type OTPRequestOptions struct {
+	Mode OTPRequestMode
+
+	// MinDuration is the minimum response time in the padded mode.
+	MinDuration time.Duration
+
+	// BackgroundTimeout bounds the lookup and email delivery in the padded and async modes
+	// (DefaultOTPRequestBackgroundTimeout when zero).
+	BackgroundTimeout time.Duration
+
+	// MaxBackground limits concurrently processed background requests
+	// (DefaultOTPRequestMaxBackground when zero). Requests arriving while all slots
+	// are busy get the same response but are not processed.
+	MaxBackground int
+
+	// NotifyUnknownEmails sends a "someone tried to sign in" notice to addresses
+	// without an account (padded and async modes only).
+	NotifyUnknownEmails bool
+}
+
 // OTPRequestHandler handles OTP request related operations.
 //
 // Responsibilities:
 // - Handle POST /auth/otp endpoint
-// - Validate email format
+// - Validate email format and the email domain policy
 // - Check user existence before generating OTP
-// - Generate and send OTP to registered users.
+// - Select the email locale from the request
+// - Generate and send OTP to registered users
+// - Hide whether an address is registered (padded and async modes).
 type OTPRequestHandler struct {
 	otpService  *usecase.OTPService
 	authService *usecase.AuthService
+	options     OTPRequestOptions
+	background  sync.WaitGroup
+	slots       chan struct{} // Semaphore bounding background requests
 }
 
 // NewOTPRequestHandler creates a new OTPRequestHandler.
-func NewOTPRequestHandler(otpService *usecase.OTPService, authService *usecase.AuthService) *OTPRequestHandler {
+// An empty options.Mode behaves as OTPRequestModeDirect.
+func NewOTPRequestHandler(
+	otpService *usecase.OTPService,
+	authService *usecase.AuthService,
+	options OTPRequestOptions,
+) *OTPRequestHandler {
+	if options.Mode == "" {
+		options.Mode = OTPRequestModeDirect
+	}
+
+	if options.BackgroundTimeout <= 0 {
+		options.BackgroundTimeout = DefaultOTPRequestBackgroundTimeout
+	}
+
+	if options.MaxBackground <= 0 {
+		options.MaxBackground = DefaultOTPRequestMaxBackground
+	}
+
 	return &OTPRequestHandler{
 		otpService:  otpService,
 		authService: authService,
+		options:     options,
+		background:  sync.WaitGroup{},
+		slots:       make(chan struct{}, options.MaxBackground),
 	}
 }
 
 // RequestOTP is a handler for generating an OTP.
 func (h *OTPRequestHandler) RequestOTP(c *gin.Context) {
 	var req struct {
-		Email string `json:"email"`
+		Email  string `json:"email"`
+		Locale string `json:"locale"` // Optional per-user language preference (e.g. "ja", "en")
 	}
 
 	err := c.ShouldBindJSON(&req)
@@ -43,28 +123,53 @@ func (h *OTPRequestHandler) RequestOTP(c *gin.Context) {
 		return
 	}
 
-	// Validate email format using the value object
-	_, err = email.NewEmail(req.Email)
+	// Validate email format and domain policy with the same options the service applies
+	_, err = h.otpService.ParseEmail(req.Email)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondInvalidEmail(c, err)
 
 		return
 	}
 
-	// Check if user exists in Firebase Auth before generating OTP
-	_, err = h.authService.GetUserByEmail(c.Request.Context(), req.Email)
+	ctx := emailContext(c, req.Locale)
+
+	switch h.options.Mode {
+	case OTPRequestModePadded:
+		h.requestOTPPadded(ctx, c, req.Email)
+	case OTPRequestModeAsync:
+		h.requestOTPAsync(ctx, c, req.Email)
+	default:
+		h.requestOTPDirect(ctx, c, req.Email)
+	}
+}
+
+// Wait blocks until all background OTP requests (padded and async modes) have finished.
+// Call it after the HTTP server has shut down so pending emails are not dropped.
+func (h *OTPRequestHandler) Wait() {
+	h.background.Wait()
+}
+
+// requestOTPDirect reports unknown addresses with 401.
+func (h *OTPRequestHandler) requestOTPDirect(ctx context.Context, c *gin.Context, emailAddr string) {
+	// Check if user exists in the user directory before generating OTP
+	_, err := h.authService.GetUserByEmail(ctx, emailAddr)
 	if err != nil {
 		// Use generic error message to prevent email enumeration attacks
-		log.Printf("Authentication failed for OTP request: %v", err)
+		logf(ctx, "Authentication failed for OTP request: %v", err)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication failed"})
 
 		return
 	}
 
 	// Generate and save OTP using the service
-	_, err = h.otpService.GenerateAndSendOTP(c.Request.Context(), req.Email)
+	_, err = h.otpService.GenerateAndSendOTP(ctx, emailAddr)
+
+	if respondThrottled(c, err) {
+		return
+	}
+
 	if err != nil {
-		log.Printf("Error generating and saving OTP for %s: %v", req.Email, err)
+		logf(ctx, "Error generating and saving OTP for %s: %v", emailAddr, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate and save OTP"})
 
 		return
@@ -73,3 +178,104 @@ func (h *OTPRequestHandler) RequestOTP(c *gin.Context) {
 	// Return success message without exposing OTP
 	c.JSON(http.StatusOK, gin.H{"message": "OTP sent successfully."})
 }
+
+// requestOTPPadded processes the request in the background and answers once MinDuration
+// has elapsed, so the response time never depends on whether the address is registered.
+func (h *OTPRequestHandler) requestOTPPadded(ctx context.Context, c *gin.Context, emailAddr string) {
+	timer := time.NewTimer(h.options.MinDuration)
+	defer timer.Stop()
+
+	h.goProcess(ctx, emailAddr)
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		// Client went away; nobody observes the timing
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": uniformOTPResponse})
+}
+
+// requestOTPAsync answers immediately and processes the request in the background.
+func (h *OTPRequestHandler) requestOTPAsync(ctx context.Context, c *gin.Context, emailAddr string) {
+	h.goProcess(ctx, emailAddr)
+
+	c.JSON(http.StatusOK, gin.H{"message": uniformOTPResponse})
+}
+
+// goProcess runs process in the background if a slot is free, and drops the request otherwise.
+// The background work is detached from the request so it is not cancelled when the response is sent.
+func (h *OTPRequestHandler) goProcess(ctx context.Context, emailAddr string) {
+	select {
+	case h.slots <- struct{}{}:
+	default:
+		logf(ctx, "Dropping OTP request for %s: %d background requests in progress", emailAddr, cap(h.slots))
+
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), h.options.BackgroundTimeout)
+
+	h.background.Go(func() {
+		defer func() { <-h.slots }()
+		defer cancel()
+
+		h.process(ctx, emailAddr)
+	})
+}
+
+// process sends an OTP to registered addresses and, if enabled, a sign-in notice to
+// unregistered ones. Failures are only logged: the caller's response must not depend on them.
+func (h *OTPRequestHandler) process(ctx context.Context, emailAddr string) {
+	_, err := h.authService.GetUserByEmail(ctx, emailAddr)
+
+	switch {
+	case err == nil:
+		_, err = h.otpService.GenerateAndSendOTP(ctx, emailAddr)
+		if err != nil {
+			logf(ctx, "Error generating and saving OTP for %s: %v", emailAddr, err)
+		}
+	case errors.Is(err, usecase.ErrUserNotFound):
+		if !h.options.NotifyUnknownEmails {
+			return
+		}
+
+		err = h.otpService.SendSignInNotice(ctx, emailAddr)
+		if err != nil {
+			logf(ctx, "Error sending sign-in notice to %s: %v", emailAddr, err)
+		}
+	default:
+		logf(ctx, "Error looking up user for OTP request: %v", err)
+	}
+}
+
+// emailContext returns the request context carrying the email language: the explicit
+// preference first, then Accept-Language.
+func emailContext(c *gin.Context, preferredLocale string) context.Context {
+	ctx := requestContext(c)
+	if locale, ok := emailsender.NegotiateLocale(preferredLocale, c.GetHeader("Accept-Language")); ok {
+		ctx = emailsender.ContextWithLocale(ctx, locale)
+	}
+
+	return ctx
+}
+
+// respondThrottled answers 429 with Retry-After if err is an *usecase.OTPThrottleError
+// and reports whether it did.
+func respondThrottled(c *gin.Context, err error) bool {
+	var throttleErr *usecase.OTPThrottleError
+	if !errors.As(err, &throttleErr) {
+		return false
+	}
+
+	c.Header("Retry-After", strconv.Itoa(retryAfterSeconds(throttleErr.RetryAfter)))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many OTP requests. Please try again later."})
+
+	return true
+}
+
+// retryAfterSeconds converts a wait into a Retry-After value, rounding up to whole seconds.
+func retryAfterSeconds(wait time.Duration) int {
+	return int((wait + time.Second - 1) / time.Second)
+}
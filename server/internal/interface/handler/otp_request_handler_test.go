@@ -7,7 +7,10 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"slices"
+	"sync"
 	"testing"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	firebase "firebase.google.com/go/v4"
@@ -15,7 +18,14 @@ import (
 	"github.com/gin-gonic/gin"
 	"google.golang.org/api/option"
 
+	"custom_auth_api/internal/domain/emailpolicy"
+	domainemailsender "custom_auth_api/internal/domain/emailsender"
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/identity/identitytest"
+	"custom_auth_api/internal/domain/vo/email"
+	"custom_auth_api/internal/domain/vo/otp"
 	"custom_auth_api/internal/infrastructure/emailsender"
+	infrafirebase "custom_auth_api/internal/infrastructure/firebase"
 	"custom_auth_api/internal/infrastructure/persistence"
 	"custom_auth_api/internal/interface/handler"
 	"custom_auth_api/internal/usecase"
@@ -59,16 +69,43 @@ func setupTestEnvironment(t *testing.T) (*firestore.Client, *auth.Client, *handl
 	}
 
 	// Initialize services and handlers
-	otpRepo := persistence.NewOTPSessionRepository(firestoreClient)
+	otpRepo := persistence.NewOTPSessionRepository(firestoreClient, newTestHasher(t))
 	emailSender := emailsender.NewDummyEmailSender()
 	otpService := usecase.NewOTPService(otpRepo, emailSender)
-	authService := usecase.NewAuthService(authClient)
-	otpRequestHandler := handler.NewOTPRequestHandler(otpService, authService)
+	authService := newFirebaseAuthService(authClient)
+	otpRequestHandler := handler.NewOTPRequestHandler(otpService, authService, handler.OTPRequestOptions{})
 	otpVerifyHandler := handler.NewOTPVerifyHandler(otpService, authService)
 
 	return firestoreClient, authClient, otpRequestHandler, otpVerifyHandler, ctx
 }
 
+// newFirebaseAuthService creates an AuthService backed by the Firebase Auth emulator.
+func newFirebaseAuthService(authClient *auth.Client) *usecase.AuthService {
+	return usecase.NewAuthService(infrafirebase.NewUserDirectory(authClient), infrafirebase.NewTokenIssuer(authClient))
+}
+
+// newFakeAuthService creates an AuthService backed by in-memory fakes,
+// for tests that do not need the Firebase Auth emulator.
+func newFakeAuthService() (*usecase.AuthService, *persistence.MemoryUserDirectory, *identitytest.Issuer) {
+	directory := persistence.NewMemoryUserDirectory()
+	issuer := identitytest.NewIssuer()
+
+	return usecase.NewAuthService(directory, issuer), directory, issuer
+}
+
+// newTestHasher creates an OTP hasher with a fixed key so that separately
+// constructed repositories in the same test can verify each other's digests.
+func newTestHasher(t *testing.T) *otp.Hasher {
+	t.Helper()
+
+	hasher, err := otp.NewHasher("test", map[string][]byte{"test": bytes.Repeat([]byte("k"), otp.MinHashKeyLength)})
+	if err != nil {
+		t.Fatalf("Failed to create OTP hasher: %v", err)
+	}
+
+	return hasher
+}
+
 // cleanupOTP deletes the OTP document for the given email.
 func cleanupOTP(ctx context.Context, t *testing.T, client *firestore.Client, email string) {
 	t.Helper()
@@ -175,9 +212,13 @@ func TestOTPRequestHandler_RequestOTP_Success(t *testing.T) {
 	}
 
 	if doc != nil {
-		otp, ok := doc.Data()["otp"].(string)
-		if !ok || len(otp) != 6 {
-			t.Error("Expected 6-digit OTP to be saved")
+		otpHash, ok := doc.Data()["otpHash"].(string)
+		if !ok || otpHash == "" {
+			t.Error("Expected OTP digest to be saved")
+		}
+
+		if _, exists := doc.Data()["otp"]; exists {
+			t.Error("Expected plaintext OTP not to be saved")
 		}
 	}
 }
@@ -293,3 +334,406 @@ func TestOTPRequestHandler_RequestOTP_UserNotFound(t *testing.T) {
 		t.Errorf("Expected 'Authentication failed' error, got %v", response["error"])
 	}
 }
+
+// recordingEmailSender records the recipients of each kind of email.
+type recordingEmailSender struct {
+	mu          sync.Mutex
+	otps        []string
+	notices     []string
+	invitations []domainemailsender.Invitation
+}
+
+func (s *recordingEmailSender) SendOTP(_ context.Context, toEmail string, _ domainemailsender.OTP) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.otps = append(s.otps, toEmail)
+
+	return nil
+}
+
+func (s *recordingEmailSender) SendSignInNotice(_ context.Context, toEmail string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.notices = append(s.notices, toEmail)
+
+	return nil
+}
+
+func (s *recordingEmailSender) SendInvitation(_ context.Context, _ string, invitation domainemailsender.Invitation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.invitations = append(s.invitations, invitation)
+
+	return nil
+}
+
+func (s *recordingEmailSender) sent() ([]string, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.otps), slices.Clone(s.notices)
+}
+
+// newUniformHandler creates an OTPRequestHandler in an enumeration-safe mode,
+// storing sessions in memory and recording sent emails.
+func newUniformHandler(
+	t *testing.T,
+	authClient *auth.Client,
+	options handler.OTPRequestOptions,
+) (*handler.OTPRequestHandler, *recordingEmailSender) {
+	t.Helper()
+
+	sender := &recordingEmailSender{mu: sync.Mutex{}, otps: nil, notices: nil}
+	otpService := usecase.NewOTPService(persistence.NewMemoryOTPSessionRepository(newTestHasher(t)), sender)
+
+	return handler.NewOTPRequestHandler(otpService, newFirebaseAuthService(authClient), options), sender
+}
+
+// requestOTP sends POST /auth/otp for emailAddr to the handler and returns the recorder.
+func requestOTP(t *testing.T, otpRequestHandler *handler.OTPRequestHandler, emailAddr string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	jsonBody, err := json.Marshal(map[string]string{"email": emailAddr})
+	if err != nil {
+		t.Fatalf("Failed to marshal request body: %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/auth/otp", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+
+	gin.SetMode(gin.TestMode)
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	otpRequestHandler.RequestOTP(c)
+
+	return w
+}
+
+// blockingEmailSender records sent OTPs like recordingEmailSender, but holds every
+// send until release is closed.
+type blockingEmailSender struct {
+	recordingEmailSender
+
+	release chan struct{}
+}
+
+func (s *blockingEmailSender) SendOTP(ctx context.Context, toEmail string, otp domainemailsender.OTP) error {
+	<-s.release
+
+	return s.recordingEmailSender.SendOTP(ctx, toEmail, otp)
+}
+
+// newBlockingHandler creates an OTPRequestHandler with in-memory users and sessions
+// whose OTP emails are held until the returned sender is released.
+func newBlockingHandler(
+	t *testing.T,
+	options handler.OTPRequestOptions,
+	registered ...string,
+) (*handler.OTPRequestHandler, *blockingEmailSender) {
+	t.Helper()
+
+	authService, _, _ := newFakeAuthService()
+	for _, emailAddr := range registered {
+		_, _, err := authService.RegisterUser(context.Background(), emailAddr, "")
+		if err != nil {
+			t.Fatalf("Failed to register %s: %v", emailAddr, err)
+		}
+	}
+
+	sender := &blockingEmailSender{release: make(chan struct{})}
+	otpService := usecase.NewOTPService(persistence.NewMemoryOTPSessionRepository(newTestHasher(t)), sender)
+
+	return handler.NewOTPRequestHandler(otpService, authService, options), sender
+}
+
+func TestOTPRequestHandler_RequestOTP_PaddedDoesNotWaitForDelivery(t *testing.T) {
+	t.Parallel()
+
+	const registered = "padded-slow@example.com"
+
+	// Arrange
+	otpRequestHandler, sender := newBlockingHandler(t, handler.OTPRequestOptions{
+		Mode:                handler.OTPRequestModePadded,
+		MinDuration:         50 * time.Millisecond,
+		BackgroundTimeout:   10 * time.Second,
+		NotifyUnknownEmails: false,
+	}, registered)
+
+	// Act
+	start := time.Now()
+	w := requestOTP(t, otpRequestHandler, registered)
+	elapsed := time.Since(start)
+
+	close(sender.release)
+	otpRequestHandler.Wait()
+
+	// Assert
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	if elapsed < 50*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("Expected the response at the minimum duration regardless of delivery, took %v", elapsed)
+	}
+
+	otps, _ := sender.sent()
+	if !slices.Equal(otps, []string{registered}) {
+		t.Errorf("Expected the OTP to be delivered in the background, got %v", otps)
+	}
+}
+
+func TestOTPRequestHandler_RequestOTP_BoundsBackgroundWork(t *testing.T) {
+	t.Parallel()
+
+	const (
+		first  = "bounded-first@example.com"
+		second = "bounded-second@example.com"
+	)
+
+	// Arrange
+	otpRequestHandler, sender := newBlockingHandler(t, handler.OTPRequestOptions{
+		Mode:                handler.OTPRequestModeAsync,
+		MinDuration:         0,
+		BackgroundTimeout:   10 * time.Second,
+		MaxBackground:       1,
+		NotifyUnknownEmails: false,
+	}, first, second)
+
+	// Act
+	firstResponse := requestOTP(t, otpRequestHandler, first)
+	secondResponse := requestOTP(t, otpRequestHandler, second)
+
+	close(sender.release)
+	otpRequestHandler.Wait()
+
+	// Assert
+	if firstResponse.Code != http.StatusOK || secondResponse.Code != http.StatusOK {
+		t.Errorf("Expected 200 for both, got %d and %d", firstResponse.Code, secondResponse.Code)
+	}
+
+	otps, _ := sender.sent()
+	if !slices.Equal(otps, []string{first}) {
+		t.Errorf("Expected only the request holding the single slot to be processed, got %v", otps)
+	}
+}
+
+func TestOTPRequestHandler_RequestOTP_UniformResponse(t *testing.T) {
+	firestoreClient, authClient, _, _, ctx := setupTestEnvironment(t)
+
+	defer func() {
+		err := firestoreClient.Close()
+		if err != nil {
+			t.Logf("Failed to close Firestore client: %v", err)
+		}
+	}()
+
+	registered := "uniform-registered@example.com"
+	unregistered := "uniform-unregistered@example.com"
+
+	createTestUser(t, authClient, registered)
+	t.Cleanup(func() { cleanupUser(ctx, t, authClient, registered) })
+
+	testCases := []struct {
+		name    string
+		options handler.OTPRequestOptions
+	}{
+		{
+			name: "padded",
+			options: handler.OTPRequestOptions{
+				Mode:                handler.OTPRequestModePadded,
+				MinDuration:         200 * time.Millisecond,
+				BackgroundTimeout:   0,
+				NotifyUnknownEmails: true,
+			},
+		},
+		{
+			name: "async",
+			options: handler.OTPRequestOptions{
+				Mode:                handler.OTPRequestModeAsync,
+				MinDuration:         0,
+				BackgroundTimeout:   10 * time.Second,
+				NotifyUnknownEmails: true,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			otpRequestHandler, sender := newUniformHandler(t, authClient, tc.options)
+
+			// Act
+			start := time.Now()
+			registeredResponse := requestOTP(t, otpRequestHandler, registered)
+			unregisteredResponse := requestOTP(t, otpRequestHandler, unregistered)
+			elapsed := time.Since(start)
+
+			otpRequestHandler.Wait()
+
+			// Assert: both addresses get the same answer
+			if registeredResponse.Code != http.StatusOK || unregisteredResponse.Code != http.StatusOK {
+				t.Errorf("expected 200 for both, got %d and %d", registeredResponse.Code, unregisteredResponse.Code)
+			}
+
+			if registeredResponse.Body.String() != unregisteredResponse.Body.String() {
+				t.Errorf("expected identical bodies, got %q and %q",
+					registeredResponse.Body.String(), unregisteredResponse.Body.String())
+			}
+
+			if tc.options.Mode == handler.OTPRequestModePadded && elapsed < 2*tc.options.MinDuration {
+				t.Errorf("expected each response to take at least %v, both took %v", tc.options.MinDuration, elapsed)
+			}
+
+			otps, notices := sender.sent()
+			if !slices.Equal(otps, []string{registered}) {
+				t.Errorf("expected an OTP only for the registered address, got %v", otps)
+			}
+
+			if !slices.Equal(notices, []string{unregistered}) {
+				t.Errorf("expected a notice only for the unregistered address, got %v", notices)
+			}
+		})
+	}
+}
+
+func TestOTPRequestHandler_RequestOTP_UniformResponseWithoutNotice(t *testing.T) {
+	firestoreClient, authClient, _, _, _ := setupTestEnvironment(t) //nolint:dogsled // Only need the auth client
+
+	defer func() {
+		err := firestoreClient.Close()
+		if err != nil {
+			t.Logf("Failed to close Firestore client: %v", err)
+		}
+	}()
+
+	// Arrange
+	otpRequestHandler, sender := newUniformHandler(t, authClient, handler.OTPRequestOptions{
+		Mode:                handler.OTPRequestModeAsync,
+		MinDuration:         0,
+		BackgroundTimeout:   10 * time.Second,
+		NotifyUnknownEmails: false,
+	})
+
+	// Act
+	w := requestOTP(t, otpRequestHandler, "uniform-silent@example.com")
+	otpRequestHandler.Wait()
+
+	// Assert
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	otps, notices := sender.sent()
+	if len(otps) != 0 || len(notices) != 0 {
+		t.Errorf("expected no emails, got otps=%v notices=%v", otps, notices)
+	}
+}
+
+func TestOTPRequestHandler_RequestOTP_Throttled(t *testing.T) {
+	firestoreClient, authClient, _, _, ctx := setupTestEnvironment(t)
+
+	defer func() {
+		err := firestoreClient.Close()
+		if err != nil {
+			t.Logf("Failed to close Firestore client: %v", err)
+		}
+	}()
+
+	registered := "throttled-registered@example.com"
+
+	t.Cleanup(func() {
+		cleanupUser(ctx, t, authClient, registered)
+	})
+
+	// Arrange
+	createTestUser(t, authClient, registered)
+
+	throttle := usecase.NewOTPRequestThrottle(usecase.OTPRequestPolicy{
+		Cooldown:        time.Minute,
+		DailyLimit:      0,
+		GlobalPerMinute: 0,
+	})
+	otpService := usecase.NewOTPServiceWithOptions(
+		persistence.NewMemoryOTPSessionRepository(newTestHasher(t)),
+		emailsender.NewDummyEmailSender(),
+		usecase.OTPServiceOptions{
+			Throttle:     throttle,
+			Binding:      entity.BindingNone,
+			IPHasher:     nil,
+			Email:        email.Options{ProviderRules: nil, AllowQuotedLocalPart: false, AllowUTF8LocalPart: false},
+			DomainPolicy: nil,
+		},
+	)
+	otpRequestHandler := handler.NewOTPRequestHandler(
+		otpService, newFirebaseAuthService(authClient), handler.OTPRequestOptions{},
+	)
+
+	// Act
+	first := requestOTP(t, otpRequestHandler, registered)
+	second := requestOTP(t, otpRequestHandler, registered)
+
+	// Assert
+	if first.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d for the first request, got %d", http.StatusOK, first.Code)
+	}
+
+	if second.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status code %d during the cooldown, got %d", http.StatusTooManyRequests, second.Code)
+	}
+
+	retryAfter := second.Header().Get("Retry-After")
+	if retryAfter != "60" {
+		t.Errorf("Expected Retry-After of 60 seconds, got %q", retryAfter)
+	}
+}
+
+func TestOTPRequestHandler_RequestOTP_DomainRejected(t *testing.T) {
+	// Arrange
+	deny, err := emailpolicy.NewDomainSet([]string{"blocked.example"})
+	if err != nil {
+		t.Fatalf("Failed to create domain set: %v", err)
+	}
+
+	otpService := usecase.NewOTPServiceWithOptions(
+		persistence.NewMemoryOTPSessionRepository(newTestHasher(t)),
+		emailsender.NewDummyEmailSender(),
+		usecase.OTPServiceOptions{
+			Throttle: nil,
+			Binding:  entity.BindingNone,
+			IPHasher: nil,
+			Email:    email.Options{ProviderRules: nil, AllowQuotedLocalPart: false, AllowUTF8LocalPart: false},
+			DomainPolicy: emailpolicy.NewPolicy(emailpolicy.PolicyOptions{
+				Allow:         nil,
+				Deny:          deny,
+				Disposable:    emailpolicy.BundledDisposableDomains(),
+				AllowlistOnly: false,
+			}),
+		},
+	)
+	// The policy rejects before the user lookup, so no Auth client is needed
+	otpRequestHandler := handler.NewOTPRequestHandler(
+		otpService, usecase.NewAuthService(persistence.NewMemoryUserDirectory(), identitytest.NewIssuer()), handler.OTPRequestOptions{},
+	)
+
+	// Act
+	denied := requestOTP(t, otpRequestHandler, "user@blocked.example")
+	disposable := requestOTP(t, otpRequestHandler, "user@mailinator.com")
+
+	// Assert
+	for _, w := range []*httptest.ResponseRecorder{denied, disposable} {
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status code %d, got %d", http.StatusForbidden, w.Code)
+		}
+	}
+
+	if denied.Body.String() != disposable.Body.String() {
+		t.Errorf("Expected identical responses regardless of the rule, got %q and %q", denied.Body.String(), disposable.Body.String())
+	}
+}
@@ -1,10 +1,8 @@
 package handler
 
 import (
-	"log"
 	"net/http"
 
-	"custom_auth_api/internal/domain/vo/email"
 	"custom_auth_api/internal/usecase"
 
 	"github.com/gin-gonic/gin"
@@ -14,9 +12,9 @@ import (
 //
 // Responsibilities:
 // - Handle POST /auth/verify endpoint
-// - Validate email format
+// - Validate email format and the email domain policy
 // - Verify OTP against stored value
-// - Generate Firebase custom token for authenticated users.
+// - Issue a sign-in token (e.g. a Firebase custom token) for authenticated users.
 type OTPVerifyHandler struct {
 	otpService  *usecase.OTPService
 	authService *usecase.AuthService
@@ -44,38 +42,40 @@ func (h *OTPVerifyHandler) VerifyOTP(c *gin.Context) {
 		return
 	}
 
-	// Validate email format using the value object
-	_, err = email.NewEmail(req.Email)
+	// Validate email format and domain policy with the same options the service applies
+	_, err = h.otpService.ParseEmail(req.Email)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondInvalidEmail(c, err)
 
 		return
 	}
 
-	// Verify the OTP
-	isValid, err := h.otpService.VerifyOTP(c.Request.Context(), req.Email, req.OTP)
+	// Verify the OTP, binding it to the requesting client if configured
+	ctx := requestContext(c)
+
+	isValid, err := h.otpService.VerifyOTP(ctx, req.Email, req.OTP)
 	if err != nil || !isValid {
 		// Log the error for internal tracking, but return a generic invalid OTP message to the client
-		log.Printf("OTP verification failed for %s: %v", req.Email, err)
+		logf(ctx, "OTP verification failed for %s: %v", req.Email, err)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired OTP"})
 
 		return
 	}
 
-	// Check if user exists in Firebase Auth
-	user, err := h.authService.GetUserByEmail(c.Request.Context(), req.Email)
+	// Check if user exists in the user directory
+	user, err := h.authService.GetUserByEmail(ctx, req.Email)
 	if err != nil {
 		// Use generic error message to prevent email enumeration attacks
-		log.Printf("Authentication failed for OTP verification: %v", err)
+		logf(ctx, "Authentication failed for OTP verification: %v", err)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication failed"})
 
 		return
 	}
 
-	// If OTP is valid and user exists, generate a custom Firebase token
-	customToken, err := h.authService.GenerateCustomToken(c.Request.Context(), user.UID)
+	// If OTP is valid and user exists, issue a sign-in token
+	customToken, err := h.authService.GenerateCustomToken(ctx, user)
 	if err != nil {
-		log.Printf("Error generating custom token for %s: %v", req.Email, err)
+		logf(ctx, "Error generating custom token for %s: %v", req.Email, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate authentication token"})
 
 		return
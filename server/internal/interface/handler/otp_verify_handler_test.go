@@ -2,6 +2,7 @@ package handler_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -10,10 +11,12 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/identity"
 	voemail "custom_auth_api/internal/domain/vo/email"
 	vootp "custom_auth_api/internal/domain/vo/otp"
 	"custom_auth_api/internal/infrastructure/emailsender"
 	"custom_auth_api/internal/infrastructure/persistence"
+	"custom_auth_api/internal/interface/handler"
 	"custom_auth_api/internal/usecase"
 )
 
@@ -38,7 +41,7 @@ func TestOTPVerifyHandler_VerifyOTP_Success(t *testing.T) {
 	createTestUser(t, authClient, email)
 
 	// Generate OTP
-	otpRepo := persistence.NewOTPSessionRepository(firestoreClient)
+	otpRepo := persistence.NewOTPSessionRepository(firestoreClient, newTestHasher(t))
 	emailSender := emailsender.NewDummyEmailSender()
 	otpService := usecase.NewOTPService(otpRepo, emailSender)
 
@@ -163,7 +166,7 @@ func TestOTPVerifyHandler_VerifyOTP_InvalidOTP(t *testing.T) {
 	// Arrange: Create test user and generate OTP
 	createTestUser(t, authClient, email)
 
-	otpRepo := persistence.NewOTPSessionRepository(firestoreClient)
+	otpRepo := persistence.NewOTPSessionRepository(firestoreClient, newTestHasher(t))
 	emailSender := emailsender.NewDummyEmailSender()
 	otpService := usecase.NewOTPService(otpRepo, emailSender)
 
@@ -284,7 +287,7 @@ func TestOTPVerifyHandler_VerifyOTP_UserNotFound(t *testing.T) {
 	})
 
 	// Arrange: Create OTP but no user
-	otpRepo := persistence.NewOTPSessionRepository(firestoreClient)
+	otpRepo := persistence.NewOTPSessionRepository(firestoreClient, newTestHasher(t))
 
 	// Create OTP session entity
 	userEmail, _ := voemail.NewEmail(email)
@@ -331,3 +334,71 @@ func TestOTPVerifyHandler_VerifyOTP_UserNotFound(t *testing.T) {
 		t.Errorf("Expected 'Authentication failed' error, got %v", response["error"])
 	}
 }
+
+func TestOTPVerifyHandler_VerifyOTP_WithFakeIdentityBackend(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		registered     bool
+		expectedStatus int
+	}{
+		{name: "issues a token for a registered user", registered: true, expectedStatus: http.StatusOK},
+		{name: "rejects an unknown user", registered: false, expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			const emailAddr = "fake-backend-user@example.com"
+
+			ctx := context.Background()
+			authService, directory, issuer := newFakeAuthService()
+			otpService := usecase.NewOTPService(
+				persistence.NewMemoryOTPSessionRepository(newTestHasher(t)),
+				emailsender.NewDummyEmailSender(),
+			)
+			otpVerifyHandler := handler.NewOTPVerifyHandler(otpService, authService)
+
+			if tc.registered {
+				_, err := directory.CreateUser(ctx, identity.UserToCreate{Email: emailAddr, EmailVerified: true, DisplayName: ""})
+				if err != nil {
+					t.Fatalf("Failed to create user: %v", err)
+				}
+			}
+
+			code, err := otpService.GenerateAndSendOTP(ctx, emailAddr)
+			if err != nil {
+				t.Fatalf("Failed to generate OTP: %v", err)
+			}
+
+			// Act
+			w := postJSON(t, otpVerifyHandler.VerifyOTP, "/auth/verify", map[string]string{"email": emailAddr, "otp": code})
+
+			// Assert
+			if w.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatus, w.Code, w.Body.String())
+			}
+
+			if !tc.registered {
+				return
+			}
+
+			var response struct {
+				Token string `json:"token"`
+			}
+
+			err = json.Unmarshal(w.Body.Bytes(), &response)
+			if err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+
+			issued, ok := issuer.Issued(response.Token)
+			if !ok || issued.User.Email != emailAddr || issued.Claims[usecase.AuthMethodsClaim] == nil {
+				t.Errorf("Expected a token with authentication claims for %s, got %+v", emailAddr, issued)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"context"
+	"log"
+
+	"custom_auth_api/internal/interface/middleware"
+	"custom_auth_api/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestContext returns the request's context carrying the client's usecase.RequestMetadata,
+// so the OTP service can record and check which client a session belongs to.
+func requestContext(c *gin.Context) context.Context {
+	return usecase.ContextWithRequestMetadata(c.Request.Context(), usecase.RequestMetadata{
+		ClientIP:  middleware.ClientIP(c),
+		UserAgent: c.Request.UserAgent(),
+		RequestID: middleware.RequestID(c),
+	})
+}
+
+// logf logs like log.Printf, prefixed with the request ID from ctx so background
+// work (async mode) can be correlated with the request that started it.
+func logf(ctx context.Context, format string, args ...any) {
+	if metadata, ok := usecase.RequestMetadataFromContext(ctx); ok && metadata.RequestID != "" {
+		format = "[" + metadata.RequestID + "] " + format
+	}
+
+	log.Printf(format, args...)
+}
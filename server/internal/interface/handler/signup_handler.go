@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"net/http"
+
+	"custom_auth_api/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegistrationMode selects who can create an account by verifying an OTP.
+type RegistrationMode string
+
+// Supported registration modes.
+const (
+	// RegistrationModeClosed disables sign-up; only existing users can sign in.
+	RegistrationModeClosed RegistrationMode = "closed"
+
+	// RegistrationModeOpen lets anyone sign up via POST /auth/signup.
+	RegistrationModeOpen RegistrationMode = "open"
+
+	// RegistrationModeInvite disables POST /auth/signup; only invited addresses can sign up.
+	RegistrationModeInvite RegistrationMode = "invite"
+)
+
+// SignupHandler handles sign-up by OTP.
+//
+// Responsibilities:
+// - Handle POST /auth/signup and POST /auth/signup/verify endpoints
+// - Validate email format and the email domain policy
+// - Send an OTP to any address, registered or not, so the response does not reveal which
+// - Create the user (email verified) on the first successful verification
+// - Issue a sign-in token (e.g. a Firebase custom token) for the new or existing user.
+type SignupHandler struct {
+	otpService  *usecase.OTPService
+	authService *usecase.AuthService
+	mode        RegistrationMode
+}
+
+// NewSignupHandler creates a new SignupHandler.
+// An empty mode behaves as RegistrationModeClosed.
+func NewSignupHandler(
+	otpService *usecase.OTPService,
+	authService *usecase.AuthService,
+	mode RegistrationMode,
+) *SignupHandler {
+	if mode == "" {
+		mode = RegistrationModeClosed
+	}
+
+	return &SignupHandler{
+		otpService:  otpService,
+		authService: authService,
+		mode:        mode,
+	}
+}
+
+// RequestSignup is a handler for sending a sign-up OTP.
+// Registered addresses receive an OTP too; verifying it signs the existing user in.
+func (h *SignupHandler) RequestSignup(c *gin.Context) {
+	var req struct {
+		Email  string `json:"email"`
+		Locale string `json:"locale"` // Optional per-user language preference (e.g. "ja", "en")
+	}
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+
+		return
+	}
+
+	if !h.registrationOpen(c) {
+		return
+	}
+
+	// Validate email format and domain policy with the same options the service applies
+	userEmail, err := h.otpService.ParseEmail(req.Email)
+	if err != nil {
+		respondInvalidEmail(c, err)
+
+		return
+	}
+
+	ctx := emailContext(c, req.Locale)
+
+	_, err = h.otpService.GenerateAndSendOTP(ctx, userEmail.Value)
+	if respondThrottled(c, err) {
+		return
+	}
+
+	if err != nil {
+		logf(ctx, "Error generating and saving sign-up OTP for %s: %v", userEmail.Value, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate and save OTP"})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "OTP sent successfully."})
+}
+
+// VerifySignup is a handler for verifying a sign-up OTP, creating the user if needed.
+func (h *SignupHandler) VerifySignup(c *gin.Context) {
+	var req struct {
+		Email       string `json:"email"`
+		OTP         string `json:"otp"`
+		DisplayName string `json:"displayName"` // Optional, only used when the user is created
+	}
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+
+		return
+	}
+
+	if !h.registrationOpen(c) {
+		return
+	}
+
+	userEmail, err := h.otpService.ParseEmail(req.Email)
+	if err != nil {
+		respondInvalidEmail(c, err)
+
+		return
+	}
+
+	// Reject a bad display name before the OTP is consumed, so the user can retry
+	err = usecase.ValidateDisplayName(req.DisplayName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	ctx := requestContext(c)
+
+	isValid, err := h.otpService.VerifyOTP(ctx, userEmail.Value, req.OTP)
+	if err != nil || !isValid {
+		logf(ctx, "Sign-up OTP verification failed for %s: %v", userEmail.Value, err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired OTP"})
+
+		return
+	}
+
+	user, created, err := h.authService.RegisterUser(ctx, userEmail.Value, req.DisplayName)
+	if err != nil {
+		logf(ctx, "Error registering user %s: %v", userEmail.Value, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register user"})
+
+		return
+	}
+
+	if created {
+		logf(ctx, "Registered new user %s", user.UID)
+	}
+
+	customToken, err := h.authService.GenerateCustomToken(ctx, user)
+	if err != nil {
+		logf(ctx, "Error generating custom token for %s: %v", userEmail.Value, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate authentication token"})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"token": customToken, "isNewUser": created})
+}
+
+// registrationOpen answers 403 unless sign-up is open, and reports whether it is.
+func (h *SignupHandler) registrationOpen(c *gin.Context) bool {
+	switch h.mode {
+	case RegistrationModeOpen:
+		return true
+	case RegistrationModeInvite:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Registration is by invitation only"})
+	default:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Registration is closed"})
+	}
+
+	return false
+}
@@ -0,0 +1,229 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"custom_auth_api/internal/domain/identity/identitytest"
+	"custom_auth_api/internal/infrastructure/emailsender"
+	"custom_auth_api/internal/infrastructure/persistence"
+	"custom_auth_api/internal/interface/handler"
+	"custom_auth_api/internal/usecase"
+)
+
+// postJSON sends a JSON body to a handler method.
+func postJSON(t *testing.T, handle gin.HandlerFunc, path string, body map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("Failed to marshal request body: %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, path, bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+
+	gin.SetMode(gin.TestMode)
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handle(c)
+
+	return w
+}
+
+func TestSignupHandler_RegistrationNotOpen(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		mode handler.RegistrationMode
+	}{
+		{name: "closed", mode: handler.RegistrationModeClosed},
+		{name: "invite only", mode: handler.RegistrationModeInvite},
+		{name: "unset defaults to closed", mode: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			otpService := usecase.NewOTPService(
+				persistence.NewMemoryOTPSessionRepository(newTestHasher(t)),
+				emailsender.NewDummyEmailSender(),
+			)
+			signupHandler := handler.NewSignupHandler(otpService, usecase.NewAuthService(persistence.NewMemoryUserDirectory(), identitytest.NewIssuer()), tc.mode)
+
+			// Act
+			request := postJSON(t, signupHandler.RequestSignup, "/auth/signup", map[string]string{
+				"email": "new-user@example.com",
+			})
+			verify := postJSON(t, signupHandler.VerifySignup, "/auth/signup/verify", map[string]string{
+				"email": "new-user@example.com",
+				"otp":   "123456",
+			})
+
+			// Assert
+			if request.Code != http.StatusForbidden {
+				t.Errorf("Expected status code %d for the sign-up request, got %d", http.StatusForbidden, request.Code)
+			}
+
+			if verify.Code != http.StatusForbidden {
+				t.Errorf("Expected status code %d for the sign-up verification, got %d", http.StatusForbidden, verify.Code)
+			}
+		})
+	}
+}
+
+func TestSignupHandler_VerifySignup_InvalidDisplayNameKeepsOTP(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	otpService := usecase.NewOTPService(
+		persistence.NewMemoryOTPSessionRepository(newTestHasher(t)),
+		emailsender.NewDummyEmailSender(),
+	)
+	signupHandler := handler.NewSignupHandler(otpService, usecase.NewAuthService(persistence.NewMemoryUserDirectory(), identitytest.NewIssuer()), handler.RegistrationModeOpen)
+
+	code, err := otpService.GenerateAndSendOTP(context.Background(), "new-user@example.com")
+	if err != nil {
+		t.Fatalf("Failed to generate OTP: %v", err)
+	}
+
+	// Act
+	w := postJSON(t, signupHandler.VerifySignup, "/auth/signup/verify", map[string]string{
+		"email":       "new-user@example.com",
+		"otp":         code,
+		"displayName": strings.Repeat("a", usecase.MaxDisplayNameLength+1),
+	})
+
+	// Assert
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	valid, err := otpService.VerifyOTP(context.Background(), "new-user@example.com", code)
+	if err != nil || !valid {
+		t.Errorf("Expected the OTP to remain usable after a rejected display name, got valid=%v err=%v", valid, err)
+	}
+}
+
+func TestSignupHandler_CreatesUserOnFirstVerification(t *testing.T) {
+	t.Parallel()
+
+	const newUser = "signup-new-user@example.com"
+
+	// Arrange
+	ctx := context.Background()
+	otpService := usecase.NewOTPService(
+		persistence.NewMemoryOTPSessionRepository(newTestHasher(t)),
+		emailsender.NewDummyEmailSender(),
+	)
+	authService, directory, issuer := newFakeAuthService()
+	signupHandler := handler.NewSignupHandler(otpService, authService, handler.RegistrationModeOpen)
+
+	request := postJSON(t, signupHandler.RequestSignup, "/auth/signup", map[string]string{"email": newUser})
+	if request.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d for the sign-up request, got %d", http.StatusOK, request.Code)
+	}
+
+	// The handler does not expose the code; issue a fresh one for the same address
+	code, err := otpService.GenerateAndSendOTP(ctx, newUser)
+	if err != nil {
+		t.Fatalf("Failed to generate OTP: %v", err)
+	}
+
+	// Act
+	w := postJSON(t, signupHandler.VerifySignup, "/auth/signup/verify", map[string]string{
+		"email":       newUser,
+		"otp":         code,
+		"displayName": "New User",
+	})
+
+	// Assert
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Token     string `json:"token"`
+		IsNewUser bool   `json:"isNewUser"`
+	}
+
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if !response.IsNewUser {
+		t.Errorf("Expected a new user, got %+v", response)
+	}
+
+	user, err := directory.GetUserByEmail(ctx, newUser)
+	if err != nil {
+		t.Fatalf("Expected the user to be created: %v", err)
+	}
+
+	if !user.EmailVerified || user.DisplayName != "New User" {
+		t.Errorf("Expected a verified user named %q, got verified=%v name=%q", "New User", user.EmailVerified, user.DisplayName)
+	}
+
+	issued, ok := issuer.Issued(response.Token)
+	if !ok || issued.User.UID != user.UID {
+		t.Errorf("Expected a token issued for user %s, got %+v", user.UID, issued)
+	}
+}
+
+func TestSignupHandler_CreatesFirebaseUser(t *testing.T) {
+	_, authClient, _, _, ctx := setupTestEnvironment(t) //nolint:dogsled // Only need authClient and ctx
+
+	const newUser = "signup-firebase-user@example.com"
+
+	t.Cleanup(func() {
+		cleanupUser(ctx, t, authClient, newUser)
+	})
+
+	// Arrange
+	otpService := usecase.NewOTPService(
+		persistence.NewMemoryOTPSessionRepository(newTestHasher(t)),
+		emailsender.NewDummyEmailSender(),
+	)
+	signupHandler := handler.NewSignupHandler(otpService, newFirebaseAuthService(authClient), handler.RegistrationModeOpen)
+
+	code, err := otpService.GenerateAndSendOTP(ctx, newUser)
+	if err != nil {
+		t.Fatalf("Failed to generate OTP: %v", err)
+	}
+
+	// Act
+	w := postJSON(t, signupHandler.VerifySignup, "/auth/signup/verify", map[string]string{
+		"email":       newUser,
+		"otp":         code,
+		"displayName": "New User",
+	})
+
+	// Assert
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	user, err := authClient.GetUserByEmail(ctx, newUser)
+	if err != nil {
+		t.Fatalf("Expected the user to be created: %v", err)
+	}
+
+	if !user.EmailVerified || user.DisplayName != "New User" {
+		t.Errorf("Expected a verified user named %q, got verified=%v name=%q", "New User", user.EmailVerified, user.DisplayName)
+	}
+}
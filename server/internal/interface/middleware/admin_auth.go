@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminIdentityContextKey is the gin context key of the authenticated admin's identity.
+const adminIdentityContextKey = "middleware.adminIdentity"
+
+// bearerPrefix precedes the token in the Authorization header.
+const bearerPrefix = "Bearer "
+
+// AdminVerifier verifies an ID token and reports whether its user is an administrator.
+// The returned identity (e.g. the email address) names the admin in audit trails.
+type AdminVerifier interface {
+	VerifyAdmin(ctx context.Context, idToken string) (identity string, isAdmin bool, err error)
+}
+
+// AdminAuthMiddleware admits only administrators. It expects an ID token in the
+// "Authorization: Bearer <token>" header and answers 401 when the token is missing or
+// invalid and 403 when its user is not an administrator.
+func AdminAuthMiddleware(verifier AdminVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authorization := c.GetHeader("Authorization")
+		if len(authorization) <= len(bearerPrefix) || !strings.EqualFold(authorization[:len(bearerPrefix)], bearerPrefix) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			c.Abort()
+
+			return
+		}
+
+		identity, isAdmin, err := verifier.VerifyAdmin(c.Request.Context(), authorization[len(bearerPrefix):])
+		if err != nil {
+			log.Printf("[%s] Admin token verification failed: %v", RequestID(c), err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			c.Abort()
+
+			return
+		}
+
+		if !isAdmin {
+			log.Printf("[%s] Admin access denied for %s", RequestID(c), identity)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Administrator access required"})
+			c.Abort()
+
+			return
+		}
+
+		c.Set(adminIdentityContextKey, identity)
+		c.Next()
+	}
+}
+
+// AdminIdentity returns the identity of the admin authenticated by AdminAuthMiddleware,
+// or "" without it.
+func AdminIdentity(c *gin.Context) string {
+	return c.GetString(adminIdentityContextKey)
+}
@@ -0,0 +1,95 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"custom_auth_api/internal/interface/middleware"
+)
+
+var errTokenRevoked = errors.New("token revoked")
+
+// fakeAdminVerifier accepts "admin-token" as an admin and "user-token" as a regular user.
+type fakeAdminVerifier struct{}
+
+func (fakeAdminVerifier) VerifyAdmin(_ context.Context, idToken string) (string, bool, error) {
+	switch idToken {
+	case "admin-token":
+		return "admin@example.com", true, nil
+	case "user-token":
+		return "user@example.com", false, nil
+	default:
+		return "", false, errTokenRevoked
+	}
+}
+
+func TestAdminAuthMiddleware(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		authorization    string
+		expectedStatus   int
+		expectedIdentity string
+	}{
+		{name: "missing header", authorization: "", expectedStatus: http.StatusUnauthorized, expectedIdentity: ""},
+		{name: "not a bearer token", authorization: "Basic YWRtaW4=", expectedStatus: http.StatusUnauthorized, expectedIdentity: ""},
+		{name: "empty bearer token", authorization: "Bearer ", expectedStatus: http.StatusUnauthorized, expectedIdentity: ""},
+		{name: "invalid token", authorization: "Bearer forged", expectedStatus: http.StatusUnauthorized, expectedIdentity: ""},
+		{name: "non-admin user", authorization: "Bearer user-token", expectedStatus: http.StatusForbidden, expectedIdentity: ""},
+		{
+			name:             "admin user",
+			authorization:    "Bearer admin-token",
+			expectedStatus:   http.StatusOK,
+			expectedIdentity: "admin@example.com",
+		},
+		{
+			name:             "case-insensitive scheme",
+			authorization:    "bearer admin-token",
+			expectedStatus:   http.StatusOK,
+			expectedIdentity: "admin@example.com",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			gin.SetMode(gin.TestMode)
+
+			var seen string
+
+			router := gin.New()
+			router.Use(middleware.AdminAuthMiddleware(fakeAdminVerifier{}))
+			router.GET("/", func(c *gin.Context) {
+				seen = middleware.AdminIdentity(c)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+
+			w := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(w, req)
+
+			// Assert
+			if w.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d", tc.expectedStatus, w.Code)
+			}
+
+			if seen != tc.expectedIdentity {
+				t.Errorf("expected admin identity %q, got %q", tc.expectedIdentity, seen)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Client IP headers a ClientIPResolver can read.
+const (
+	ClientIPHeaderXForwardedFor  = "X-Forwarded-For"
+	ClientIPHeaderXRealIP        = "X-Real-IP"
+	ClientIPHeaderForwarded      = "Forwarded"
+	ClientIPHeaderCFConnectingIP = "CF-Connecting-IP"
+)
+
+// clientIPContextKey is the gin context key of the resolved client IP.
+const clientIPContextKey = "middleware.clientIP"
+
+// ClientIPResolver determines the real client IP of a request.
+//
+// Headers are only believed when the TCP peer is a trusted proxy, so clients
+// connecting directly cannot spoof their address. For the list headers
+// (X-Forwarded-For, Forwarded) the hops are walked from the right and the first
+// address that is not a trusted proxy is the client; entries further left were
+// supplied by the client and are ignored.
+type ClientIPResolver struct {
+	trustedProxies []netip.Prefix
+	header         string
+}
+
+// NewClientIPResolver creates a new ClientIPResolver reading header
+// (one of the ClientIPHeader* constants) from the trusted proxies.
+// With no trusted proxies the TCP peer address is always used.
+func NewClientIPResolver(trustedProxies []netip.Prefix, header string) *ClientIPResolver {
+	return &ClientIPResolver{
+		trustedProxies: trustedProxies,
+		header:         http.CanonicalHeaderKey(header),
+	}
+}
+
+// Resolve returns the client IP of r.
+func (res *ClientIPResolver) Resolve(r *http.Request) string {
+	remote, ok := parseHostAddr(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+
+	if !res.isTrusted(remote) {
+		return remote.String()
+	}
+
+	switch res.header {
+	case http.CanonicalHeaderKey(ClientIPHeaderXForwardedFor):
+		return res.walkHops(remote, splitHeaderList(r.Header.Values(ClientIPHeaderXForwardedFor))).String()
+	case http.CanonicalHeaderKey(ClientIPHeaderForwarded):
+		return res.walkHops(remote, forwardedForValues(r.Header.Values(ClientIPHeaderForwarded))).String()
+	}
+
+	// Single-value headers set by the proxy itself (X-Real-IP, CF-Connecting-IP)
+	if addr, ok := parseHostAddr(strings.TrimSpace(r.Header.Get(res.header))); ok {
+		return addr.String()
+	}
+
+	return remote.String()
+}
+
+// walkHops returns the rightmost hop that is not a trusted proxy. If a hop is
+// malformed, the last trusted address seen is returned instead of guessing further.
+func (res *ClientIPResolver) walkHops(remote netip.Addr, hops []string) netip.Addr {
+	client := remote
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseHostAddr(hops[i])
+		if !ok {
+			return client
+		}
+
+		client = addr
+		if !res.isTrusted(addr) {
+			return client
+		}
+	}
+
+	return client
+}
+
+// isTrusted reports whether addr belongs to a trusted proxy.
+func (res *ClientIPResolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range res.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientIPMiddleware resolves the client IP once per request and stores it for ClientIP.
+// Register it before any middleware or handler that needs the client IP.
+func ClientIPMiddleware(resolver *ClientIPResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(clientIPContextKey, resolver.Resolve(c.Request))
+		c.Next()
+	}
+}
+
+// ClientIP returns the client IP resolved by ClientIPMiddleware.
+// Without the middleware it falls back to the TCP peer address, never to request headers.
+func ClientIP(c *gin.Context) string {
+	if ip := c.GetString(clientIPContextKey); ip != "" {
+		return ip
+	}
+
+	return c.RemoteIP()
+}
+
+// parseHostAddr parses an address with or without a port ("192.0.2.1", "192.0.2.1:80",
+// "2001:db8::1", "[2001:db8::1]:80"). IPv4-mapped IPv6 addresses are unmapped.
+func parseHostAddr(value string) (netip.Addr, bool) {
+	value = strings.Trim(value, `"`)
+
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+
+	addr, err := netip.ParseAddr(strings.Trim(value, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
+
+// splitHeaderList flattens comma-separated header values in order.
+func splitHeaderList(values []string) []string {
+	var items []string
+
+	for _, value := range values {
+		for item := range strings.SplitSeq(value, ",") {
+			items = append(items, strings.TrimSpace(item))
+		}
+	}
+
+	return items
+}
+
+// forwardedForValues extracts the "for" parameter of each RFC 7239 Forwarded element in order.
+// Elements without one yield an empty (malformed) hop.
+func forwardedForValues(values []string) []string {
+	elements := splitHeaderList(values)
+	hops := make([]string, 0, len(elements))
+
+	for _, element := range elements {
+		hop := ""
+
+		for pair := range strings.SplitSeq(element, ";") {
+			name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+			if found && strings.EqualFold(name, "for") {
+				hop = value
+			}
+		}
+
+		hops = append(hops, hop)
+	}
+
+	return hops
+}
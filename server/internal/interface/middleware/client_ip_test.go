@@ -0,0 +1,183 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"custom_auth_api/internal/interface/middleware"
+)
+
+func TestClientIPResolver_Resolve(t *testing.T) {
+	t.Parallel()
+
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("2001:db8:ffff::/48")}
+
+	testCases := []struct {
+		name       string
+		trusted    []netip.Prefix
+		header     string
+		remoteAddr string
+		headers    map[string][]string
+		expectedIP string
+	}{
+		{
+			name:       "ignores headers without trusted proxies",
+			trusted:    nil,
+			header:     middleware.ClientIPHeaderXForwardedFor,
+			remoteAddr: "203.0.113.9:5000",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1"}},
+			expectedIP: "203.0.113.9",
+		},
+		{
+			name:       "ignores headers from an untrusted peer",
+			trusted:    trusted,
+			header:     middleware.ClientIPHeaderXForwardedFor,
+			remoteAddr: "203.0.113.9:5000",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1"}},
+			expectedIP: "203.0.113.9",
+		},
+		{
+			name:       "uses X-Forwarded-For from a trusted proxy",
+			trusted:    trusted,
+			header:     middleware.ClientIPHeaderXForwardedFor,
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1"}},
+			expectedIP: "198.51.100.1",
+		},
+		{
+			name:       "skips trusted hops and ignores spoofed entries on the left",
+			trusted:    trusted,
+			header:     middleware.ClientIPHeaderXForwardedFor,
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.2.3.4, 198.51.100.1", "10.0.0.7"}},
+			expectedIP: "198.51.100.1",
+		},
+		{
+			name:       "stops at a malformed hop",
+			trusted:    trusted,
+			header:     middleware.ClientIPHeaderXForwardedFor,
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1, garbage"}},
+			expectedIP: "10.0.0.2",
+		},
+		{
+			name:       "uses the leftmost hop when every hop is trusted",
+			trusted:    trusted,
+			header:     middleware.ClientIPHeaderXForwardedFor,
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string][]string{"X-Forwarded-For": {"10.0.0.9, 10.0.0.8"}},
+			expectedIP: "10.0.0.9",
+		},
+		{
+			name:       "uses X-Real-IP from a trusted proxy",
+			trusted:    trusted,
+			header:     middleware.ClientIPHeaderXRealIP,
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string][]string{"X-Real-Ip": {"198.51.100.2"}},
+			expectedIP: "198.51.100.2",
+		},
+		{
+			name:       "uses CF-Connecting-IP from a trusted proxy",
+			trusted:    trusted,
+			header:     middleware.ClientIPHeaderCFConnectingIP,
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string][]string{"Cf-Connecting-Ip": {"2001:db8::5"}},
+			expectedIP: "2001:db8::5",
+		},
+		{
+			name:       "falls back to the peer for a malformed single-value header",
+			trusted:    trusted,
+			header:     middleware.ClientIPHeaderXRealIP,
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string][]string{"X-Real-Ip": {"unknown"}},
+			expectedIP: "10.0.0.2",
+		},
+		{
+			name:       "parses Forwarded elements with quoted IPv6 and ports",
+			trusted:    trusted,
+			header:     middleware.ClientIPHeaderForwarded,
+			remoteAddr: "[2001:db8:ffff::1]:5000",
+			headers: map[string][]string{
+				"Forwarded": {`for=192.0.2.43, for="[2001:db8:cafe::17]:4711";proto=https, for=10.0.0.3`},
+			},
+			expectedIP: "2001:db8:cafe::17",
+		},
+		{
+			name:       "treats Forwarded elements without for as malformed",
+			trusted:    trusted,
+			header:     middleware.ClientIPHeaderForwarded,
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string][]string{"Forwarded": {"for=192.0.2.43, proto=https"}},
+			expectedIP: "10.0.0.2",
+		},
+		{
+			name:       "unmaps IPv4-mapped peers",
+			trusted:    nil,
+			header:     middleware.ClientIPHeaderXForwardedFor,
+			remoteAddr: "[::ffff:192.0.2.1]:5000",
+			headers:    nil,
+			expectedIP: "192.0.2.1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			resolver := middleware.NewClientIPResolver(tc.trusted, tc.header)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+
+			for key, values := range tc.headers {
+				for _, value := range values {
+					req.Header.Add(key, value)
+				}
+			}
+
+			// Act
+			ip := resolver.Resolve(req)
+
+			// Assert
+			if ip != tc.expectedIP {
+				t.Errorf("expected client IP %s, got %s", tc.expectedIP, ip)
+			}
+		})
+	}
+}
+
+func TestClientIPMiddleware(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	gin.SetMode(gin.TestMode)
+
+	resolver := middleware.NewClientIPResolver(
+		[]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}, middleware.ClientIPHeaderXForwardedFor,
+	)
+
+	var resolved string
+
+	router := gin.New()
+	router.Use(middleware.ClientIPMiddleware(resolver))
+	router.GET("/", func(c *gin.Context) {
+		resolved = middleware.ClientIP(c)
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.2:5000"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+
+	// Act
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Assert
+	if resolved != "198.51.100.1" {
+		t.Errorf("expected handlers to see 198.51.100.1, got %q", resolved)
+	}
+}
@@ -1,66 +1,327 @@
 package middleware
 
 import (
+	"container/list"
+	"context"
+	"hash/maphash"
+	"log"
+	"math"
 	"net/http"
+	"net/netip"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
 )
 
-// IPRateLimiter manages rate limiters for different IP addresses.
+const (
+	// rateLimiterShards is the number of independently locked partitions of the key space.
+	rateLimiterShards = 16
+
+	// DefaultRateLimiterMaxKeys bounds the number of tracked keys when IPRateLimiterOptions.MaxKeys is zero.
+	DefaultRateLimiterMaxKeys = 100_000
+)
+
+// RateLimitResult is the outcome of a RateLimiter decision.
+type RateLimitResult struct {
+	Allowed bool
+
+	// Limit is the burst size: the number of requests a client with a full budget may make at once.
+	Limit int
+
+	// Remaining is the number of requests the client may still make right now.
+	Remaining int
+
+	// ResetAfter is how long until the client's budget is full again.
+	ResetAfter time.Duration
+
+	// RetryAfter is how long a rejected client must wait before its next request can succeed.
+	RetryAfter time.Duration
+}
+
+// RateLimiter decides whether a request identified by key may proceed.
+// Implementations: IPRateLimiter (process memory, the default) and RedisRateLimiter
+// (shared across replicas).
+type RateLimiter interface {
+	Allow(ctx context.Context, key string) (RateLimitResult, error)
+}
+
+// RateLimiterFactory creates the RateLimiter for a named policy with rate r (requests per second)
+// and burst b. Limiters created for different names must not share budgets; callers create one
+// limiter per name and reuse it wherever the policy applies.
+type RateLimiterFactory func(name string, r rate.Limit, b int) RateLimiter
+
+// IPRateLimiterOptions configures the memory bounds and eviction of an IPRateLimiter.
+type IPRateLimiterOptions struct {
+	// MaxKeys is the maximum number of keys tracked at once (DefaultRateLimiterMaxKeys when zero).
+	// When a shard is full, its least recently seen key is dropped to make room.
+	MaxKeys int
+
+	// IdleTimeout is how long a key must go unseen before the cleanup sweep may evict it.
+	IdleTimeout time.Duration
+}
+
+// RateLimiterStats is a snapshot of an IPRateLimiter's counters.
+type RateLimiterStats struct {
+	Keys              int    // Keys currently tracked
+	Allowed           uint64 // Requests allowed since creation
+	Rejected          uint64 // Requests rejected since creation
+	IdleEvictions     uint64 // Keys removed by the cleanup sweep
+	CapacityEvictions uint64 // Keys dropped because MaxKeys was reached
+}
+
+// rateLimiterEntry is the per-key state kept in a shard's LRU list.
+type rateLimiterEntry struct {
+	key      string
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimiterShard is an LRU of entries; the front of order is the most recently seen key.
+type rateLimiterShard struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// IPRateLimiter manages token-bucket rate limiters for different IP addresses in process memory.
+// Limits are per instance: N replicas allow N times the configured rate.
+//
+// Keys are spread over shards with their own locks and LRU order, so memory is bounded
+// by MaxKeys. The cleanup sweep only evicts keys that have been idle for IdleTimeout and
+// whose bucket has fully refilled; such a key behaves exactly like a new one, so eviction
+// never lets a client burst earlier than it otherwise could.
 type IPRateLimiter struct {
-	ips map[string]*rate.Limiter
-	mu  *sync.RWMutex
-	r   rate.Limit
-	b   int
+	shards      [rateLimiterShards]*rateLimiterShard
+	seed        maphash.Seed
+	r           rate.Limit
+	b           int
+	maxPerShard int
+	idleTimeout time.Duration
+
+	allowed           atomic.Uint64
+	rejected          atomic.Uint64
+	idleEvictions     atomic.Uint64
+	capacityEvictions atomic.Uint64
 }
 
 // NewIPRateLimiter creates a new IP-based rate limiter.
 // r: requests per second
-// b: burst size (maximum number of requests allowed in a burst)
-func NewIPRateLimiter(r rate.Limit, b int) *IPRateLimiter {
-	return &IPRateLimiter{
-		ips: make(map[string]*rate.Limiter),
-		mu:  &sync.RWMutex{},
-		r:   r,
-		b:   b,
+// b: burst size (maximum number of requests allowed in a burst).
+func NewIPRateLimiter(r rate.Limit, b int, options IPRateLimiterOptions) *IPRateLimiter {
+	maxKeys := options.MaxKeys
+	if maxKeys <= 0 {
+		maxKeys = DefaultRateLimiterMaxKeys
 	}
-}
 
-// AddIP creates a new rate limiter for the given IP and stores it in the map.
-func (i *IPRateLimiter) AddIP(ip string) *rate.Limiter {
-	i.mu.Lock()
-	defer i.mu.Unlock()
+	limiter := &IPRateLimiter{
+		seed:        maphash.MakeSeed(),
+		r:           r,
+		b:           b,
+		maxPerShard: max(1, (maxKeys+rateLimiterShards-1)/rateLimiterShards),
+		idleTimeout: options.IdleTimeout,
+	}
 
-	limiter := rate.NewLimiter(i.r, i.b)
-	i.ips[ip] = limiter
+	for i := range limiter.shards {
+		limiter.shards[i] = &rateLimiterShard{
+			entries: make(map[string]*list.Element),
+			order:   list.New(),
+		}
+	}
 
 	return limiter
 }
 
-// GetLimiter returns the rate limiter for the provided IP address if it exists.
-// Otherwise, it calls AddIP to create a new one.
-func (i *IPRateLimiter) GetLimiter(ip string) *rate.Limiter {
-	i.mu.RLock()
-	limiter, exists := i.ips[ip]
-	i.mu.RUnlock()
+// Allow reports whether a request for key may proceed now, consuming a token if so.
+// It never returns an error.
+func (i *IPRateLimiter) Allow(_ context.Context, key string) (RateLimitResult, error) {
+	now := time.Now()
+	shard := i.shardFor(key)
 
-	if !exists {
-		return i.AddIP(ip)
+	shard.mu.Lock()
+	entry := i.touch(shard, key, now)
+	// Reserve under the shard lock so the sweep cannot evict the entry in between
+	reservation := entry.limiter.ReserveN(now, 1)
+	retryAfter := reservation.DelayFrom(now)
+	if retryAfter > 0 {
+		reservation.CancelAt(now)
 	}
+	tokens := entry.limiter.TokensAt(now)
+	shard.mu.Unlock()
 
-	return limiter
+	result := RateLimitResult{
+		Allowed:    retryAfter == 0,
+		Limit:      i.b,
+		Remaining:  max(0, int(math.Floor(tokens))),
+		ResetAfter: i.refillTime(tokens),
+		RetryAfter: retryAfter,
+	}
+
+	if result.Allowed {
+		i.allowed.Add(1)
+	} else {
+		i.rejected.Add(1)
+	}
+
+	return result, nil
+}
+
+// refillTime returns how long a bucket holding tokens takes to become full.
+func (i *IPRateLimiter) refillTime(tokens float64) time.Duration {
+	missing := float64(i.b) - tokens
+	if missing <= 0 || i.r == rate.Inf || i.r <= 0 {
+		return 0
+	}
+
+	return time.Duration(missing / float64(i.r) * float64(time.Second))
 }
 
-// RateLimitMiddleware creates a Gin middleware for rate limiting based on IP address.
-func RateLimitMiddleware(limiter *IPRateLimiter) gin.HandlerFunc {
+// EvictIdle removes keys that have been idle for at least IdleTimeout and whose
+// bucket is full, and returns the number of keys removed.
+func (i *IPRateLimiter) EvictIdle() int {
+	now := time.Now()
+	evicted := 0
+
+	for _, shard := range i.shards {
+		shard.mu.Lock()
+		// Walk from the least recently seen key and stop at the first one that is not idle
+		for element := shard.order.Back(); element != nil; {
+			entry, _ := element.Value.(*rateLimiterEntry)
+			if now.Sub(entry.lastSeen) < i.idleTimeout {
+				break
+			}
+
+			previous := element.Prev()
+			if entry.limiter.TokensAt(now) >= float64(i.b) {
+				shard.order.Remove(element)
+				delete(shard.entries, entry.key)
+				evicted++
+			}
+			element = previous
+		}
+		shard.mu.Unlock()
+	}
+
+	i.idleEvictions.Add(uint64(evicted)) //nolint:gosec // evicted is never negative
+
+	return evicted
+}
+
+// RunCleanup evicts idle keys every interval until ctx is done.
+// It blocks, so run it in its own goroutine.
+func (i *IPRateLimiter) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			i.EvictIdle()
+		}
+	}
+}
+
+// Len returns the number of keys currently tracked.
+func (i *IPRateLimiter) Len() int {
+	total := 0
+
+	for _, shard := range i.shards {
+		shard.mu.Lock()
+		total += len(shard.entries)
+		shard.mu.Unlock()
+	}
+
+	return total
+}
+
+// Stats returns a snapshot of the limiter's counters.
+func (i *IPRateLimiter) Stats() RateLimiterStats {
+	return RateLimiterStats{
+		Keys:              i.Len(),
+		Allowed:           i.allowed.Load(),
+		Rejected:          i.rejected.Load(),
+		IdleEvictions:     i.idleEvictions.Load(),
+		CapacityEvictions: i.capacityEvictions.Load(),
+	}
+}
+
+// shardFor returns the shard that owns key.
+func (i *IPRateLimiter) shardFor(key string) *rateLimiterShard {
+	return i.shards[maphash.String(i.seed, key)%rateLimiterShards]
+}
+
+// touch returns the entry for key, creating it if needed, and marks it as most recently seen.
+// The caller must hold shard.mu.
+func (i *IPRateLimiter) touch(shard *rateLimiterShard, key string, now time.Time) *rateLimiterEntry {
+	if element, exists := shard.entries[key]; exists {
+		entry, _ := element.Value.(*rateLimiterEntry)
+		entry.lastSeen = now
+		shard.order.MoveToFront(element)
+
+		return entry
+	}
+
+	if shard.order.Len() >= i.maxPerShard {
+		oldest := shard.order.Back()
+		oldestEntry, _ := oldest.Value.(*rateLimiterEntry)
+		shard.order.Remove(oldest)
+		delete(shard.entries, oldestEntry.key)
+		i.capacityEvictions.Add(1)
+	}
+
+	entry := &rateLimiterEntry{key: key, limiter: rate.NewLimiter(i.r, i.b), lastSeen: now}
+	shard.entries[key] = shard.order.PushFront(entry)
+
+	return entry
+}
+
+// Rate limit response headers (draft-ietf-httpapi-ratelimit-headers), set on every limited response.
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRetryAfter         = "Retry-After"
+)
+
+// RateLimitHeaders lists the headers set by RateLimitMiddleware, for CORS exposure.
+var RateLimitHeaders = []string{HeaderRateLimitLimit, HeaderRateLimitRemaining, HeaderRateLimitReset, HeaderRetryAfter}
+
+// RateLimitMiddleware creates a Gin middleware for rate limiting based on the client IP
+// resolved by ClientIPMiddleware.
+// Every response carries RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset;
+// rejected requests also carry Retry-After. Durations are in whole seconds, rounded up.
+//
+// Clients whose IP falls in one of exemptCIDRs are never limited and get no headers.
+// If the limiter fails (e.g. a shared store is unreachable) the request is let through
+// without headers, so an outage of the store does not take authentication down with it.
+func RateLimitMiddleware(limiter RateLimiter, exemptCIDRs []netip.Prefix) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ip := c.ClientIP()
-		ipLimiter := limiter.GetLimiter(ip)
+		clientIP := ClientIP(c)
+		if isExempt(clientIP, exemptCIDRs) {
+			c.Next()
+
+			return
+		}
+
+		result, err := limiter.Allow(c.Request.Context(), clientIP)
+		if err != nil {
+			log.Printf("Rate limiter unavailable, allowing request: %v", err)
+			c.Next()
+
+			return
+		}
+
+		c.Header(HeaderRateLimitLimit, strconv.Itoa(result.Limit))
+		c.Header(HeaderRateLimitRemaining, strconv.Itoa(result.Remaining))
+		c.Header(HeaderRateLimitReset, strconv.Itoa(ceilSeconds(result.ResetAfter)))
 
-		if !ipLimiter.Allow() {
+		if !result.Allowed {
+			c.Header(HeaderRetryAfter, strconv.Itoa(ceilSeconds(result.RetryAfter)))
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error": "Rate limit exceeded. Please try again later.",
 			})
@@ -73,17 +334,31 @@ func RateLimitMiddleware(limiter *IPRateLimiter) gin.HandlerFunc {
 	}
 }
 
-// CleanupExpiredLimiters periodically removes limiters for IPs that haven't been used recently.
-// This prevents the map from growing indefinitely.
-func (i *IPRateLimiter) CleanupExpiredLimiters(interval time.Duration) {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+// isExempt reports whether ip falls in one of the exempt CIDRs.
+func isExempt(ip string, exemptCIDRs []netip.Prefix) bool {
+	if len(exemptCIDRs) == 0 {
+		return false
+	}
 
-	for range ticker.C {
-		i.mu.Lock()
-		// Simple cleanup: remove all limiters (they'll be recreated on next request)
-		// In production, you might want to track last access time
-		i.ips = make(map[string]*rate.Limiter)
-		i.mu.Unlock()
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
 	}
+
+	addr = addr.Unmap()
+
+	for _, prefix := range exemptCIDRs {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
 }
+
+// ceilSeconds converts a duration to whole seconds, rounding up.
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+var _ RateLimiter = (*IPRateLimiter)(nil)
@@ -0,0 +1,338 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+
+	"custom_auth_api/internal/interface/middleware"
+)
+
+func TestIPRateLimiter_Allow(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	limiter := middleware.NewIPRateLimiter(rate.Every(time.Hour), 2, middleware.IPRateLimiterOptions{})
+
+	ctx := context.Background()
+
+	// Act
+	first, _ := limiter.Allow(ctx, "192.0.2.1")
+	second, _ := limiter.Allow(ctx, "192.0.2.1")
+	third, _ := limiter.Allow(ctx, "192.0.2.1")
+	other, _ := limiter.Allow(ctx, "192.0.2.2")
+
+	// Assert
+	if !first.Allowed || !second.Allowed {
+		t.Error("expected requests within the burst to be allowed")
+	}
+
+	if third.Allowed {
+		t.Error("expected the request beyond the burst to be rejected")
+	}
+
+	if third.RetryAfter <= 59*time.Minute {
+		t.Errorf("expected RetryAfter close to one emission interval, got %v", third.RetryAfter)
+	}
+
+	if first.Limit != 2 || first.Remaining != 1 || second.Remaining != 0 || third.Remaining != 0 {
+		t.Errorf("unexpected limit/remaining: first=%+v second=%+v third=%+v", first, second, third)
+	}
+
+	if second.ResetAfter <= 119*time.Minute || second.ResetAfter > 2*time.Hour {
+		t.Errorf("expected ResetAfter close to two emission intervals, got %v", second.ResetAfter)
+	}
+
+	if !other.Allowed {
+		t.Error("expected a different key to have its own bucket")
+	}
+
+	stats := limiter.Stats()
+	if stats.Keys != 2 || stats.Allowed != 3 || stats.Rejected != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestIPRateLimiter_EvictIdle(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		limit         rate.Limit
+		idleTimeout   time.Duration
+		expectEvicted int
+	}{
+		{
+			name:          "evicts idle keys whose bucket has refilled",
+			limit:         rate.Every(time.Millisecond),
+			idleTimeout:   0,
+			expectEvicted: 1,
+		},
+		{
+			name:          "keeps keys whose bucket is not full",
+			limit:         rate.Every(time.Hour),
+			idleTimeout:   0,
+			expectEvicted: 0,
+		},
+		{
+			name:          "keeps keys seen within the idle timeout",
+			limit:         rate.Every(time.Millisecond),
+			idleTimeout:   time.Hour,
+			expectEvicted: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			limiter := middleware.NewIPRateLimiter(tc.limit, 1, middleware.IPRateLimiterOptions{
+				MaxKeys:     0,
+				IdleTimeout: tc.idleTimeout,
+			})
+			limiter.Allow(context.Background(), "192.0.2.1")
+			time.Sleep(5 * time.Millisecond)
+
+			// Act
+			evicted := limiter.EvictIdle()
+
+			// Assert
+			if evicted != tc.expectEvicted {
+				t.Errorf("expected %d evicted keys, got %d", tc.expectEvicted, evicted)
+			}
+
+			if limiter.Len() != 1-tc.expectEvicted {
+				t.Errorf("expected %d remaining keys, got %d", 1-tc.expectEvicted, limiter.Len())
+			}
+		})
+	}
+}
+
+func TestIPRateLimiter_EvictIdle_DoesNotResetExhaustedBucket(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	limiter := middleware.NewIPRateLimiter(rate.Every(time.Hour), 1, middleware.IPRateLimiterOptions{})
+	limiter.Allow(context.Background(), "192.0.2.1")
+
+	// Act
+	limiter.EvictIdle()
+
+	// Assert
+	result, _ := limiter.Allow(context.Background(), "192.0.2.1")
+	if result.Allowed {
+		t.Error("expected the exhausted bucket to survive the cleanup sweep")
+	}
+}
+
+func TestIPRateLimiter_MaxKeys(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	limiter := middleware.NewIPRateLimiter(rate.Every(time.Hour), 1, middleware.IPRateLimiterOptions{
+		MaxKeys:     32,
+		IdleTimeout: time.Hour,
+	})
+
+	// Act
+	for i := range 1000 {
+		limiter.Allow(context.Background(), "198.51.100."+strconv.Itoa(i))
+	}
+
+	// Assert
+	stats := limiter.Stats()
+	if stats.Keys > 32 {
+		t.Errorf("expected at most 32 tracked keys, got %d", stats.Keys)
+	}
+
+	if stats.CapacityEvictions != uint64(1000-stats.Keys) { //nolint:gosec // Keys is small and positive
+		t.Errorf("expected %d capacity evictions, got %d", 1000-stats.Keys, stats.CapacityEvictions)
+	}
+}
+
+func TestIPRateLimiter_RunCleanup(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	limiter := middleware.NewIPRateLimiter(rate.Every(time.Millisecond), 1, middleware.IPRateLimiterOptions{})
+	limiter.Allow(context.Background(), "192.0.2.1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	// Act
+	go func() {
+		limiter.RunCleanup(ctx, time.Millisecond)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for limiter.Len() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+
+	// Assert
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RunCleanup did not return after context cancellation")
+	}
+
+	if limiter.Len() != 0 {
+		t.Errorf("expected the idle key to be evicted, got %d keys", limiter.Len())
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	gin.SetMode(gin.TestMode)
+
+	limiter := middleware.NewIPRateLimiter(rate.Every(time.Hour), 2, middleware.IPRateLimiterOptions{})
+	router := gin.New()
+	router.Use(middleware.RateLimitMiddleware(limiter, nil))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	responses := make([]*httptest.ResponseRecorder, 0, 3)
+
+	// Act
+	for range 3 {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		responses = append(responses, w)
+	}
+
+	// Assert
+	testCases := []struct {
+		name       string
+		code       int
+		remaining  string
+		reset      string
+		retryAfter string
+	}{
+		{name: "first request", code: http.StatusOK, remaining: "1", reset: "3600", retryAfter: ""},
+		{name: "second request", code: http.StatusOK, remaining: "0", reset: "7200", retryAfter: ""},
+		{name: "rejected request", code: http.StatusTooManyRequests, remaining: "0", reset: "7200", retryAfter: "3600"},
+	}
+
+	for i, tc := range testCases {
+		w := responses[i]
+
+		if w.Code != tc.code {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.code, w.Code)
+		}
+
+		if w.Header().Get(middleware.HeaderRateLimitLimit) != "2" {
+			t.Errorf("%s: expected RateLimit-Limit 2, got %q", tc.name, w.Header().Get(middleware.HeaderRateLimitLimit))
+		}
+
+		if w.Header().Get(middleware.HeaderRateLimitRemaining) != tc.remaining {
+			t.Errorf("%s: expected RateLimit-Remaining %s, got %q",
+				tc.name, tc.remaining, w.Header().Get(middleware.HeaderRateLimitRemaining))
+		}
+
+		if w.Header().Get(middleware.HeaderRateLimitReset) != tc.reset {
+			t.Errorf("%s: expected RateLimit-Reset %s, got %q",
+				tc.name, tc.reset, w.Header().Get(middleware.HeaderRateLimitReset))
+		}
+
+		if w.Header().Get(middleware.HeaderRetryAfter) != tc.retryAfter {
+			t.Errorf("%s: expected Retry-After %q, got %q",
+				tc.name, tc.retryAfter, w.Header().Get(middleware.HeaderRetryAfter))
+		}
+	}
+}
+
+// failingRateLimiter is a RateLimiter whose store is always unavailable.
+type failingRateLimiter struct{}
+
+func (failingRateLimiter) Allow(context.Context, string) (middleware.RateLimitResult, error) {
+	return middleware.RateLimitResult{Allowed: false, Limit: 0, Remaining: 0, ResetAfter: 0, RetryAfter: 0}, errors.New("store unavailable")
+}
+
+func TestRateLimitMiddleware_FailsOpen(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.RateLimitMiddleware(failingRateLimiter{}, nil))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	// Act
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Assert
+	if w.Code != http.StatusOK {
+		t.Errorf("expected the request to be allowed when the limiter fails, got %d", w.Code)
+	}
+
+	if w.Header().Get(middleware.HeaderRateLimitLimit) != "" {
+		t.Error("expected no rate limit headers when the limiter fails")
+	}
+}
+
+func TestRateLimitMiddleware_ExemptCIDRs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		remoteAddr   string
+		expectExempt bool
+	}{
+		{name: "client inside an exempt CIDR", remoteAddr: "10.1.2.3:1234", expectExempt: true},
+		{name: "exempt single address", remoteAddr: "192.0.2.7:1234", expectExempt: true},
+		{name: "client outside the exempt CIDRs", remoteAddr: "192.0.2.8:1234", expectExempt: false},
+	}
+
+	exemptCIDRs := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.0.2.7/32")}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			gin.SetMode(gin.TestMode)
+
+			limiter := middleware.NewIPRateLimiter(rate.Every(time.Hour), 1, middleware.IPRateLimiterOptions{})
+			router := gin.New()
+			router.Use(middleware.RateLimitMiddleware(limiter, exemptCIDRs))
+			router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			var last *httptest.ResponseRecorder
+
+			// Act
+			for range 2 {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.RemoteAddr = tc.remoteAddr
+				last = httptest.NewRecorder()
+				router.ServeHTTP(last, req)
+			}
+
+			// Assert
+			if exempt := last.Code == http.StatusOK; exempt != tc.expectExempt {
+				t.Errorf("expected exempt=%v, got status %d", tc.expectExempt, last.Code)
+			}
+
+			if tc.expectExempt && last.Header().Get(middleware.HeaderRateLimitLimit) != "" {
+				t.Error("expected no rate limit headers for exempt clients")
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+)
+
+// redisRateLimitKeyPrefix namespaces rate limiter keys.
+const redisRateLimitKeyPrefix = "ratelimit:"
+
+// gcraScript implements the generic cell rate algorithm atomically on the server.
+// The key holds the theoretical arrival time (TAT) in microseconds and expires once
+// the bucket would be full again. Redis' clock is used so replicas agree on time.
+//
+// KEYS[1]: limiter key
+// ARGV[1]: emission interval in microseconds (time per token)
+// ARGV[2]: burst size
+// Returns {allowed (0|1), remaining, reset after and retry after in microseconds}.
+var gcraScript = redis.NewScript(`
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+local tat = tonumber(redis.call("GET", KEYS[1])) or now
+if tat < now then
+	tat = now
+end
+
+local new_tat = tat + interval
+local allow_at = new_tat - burst * interval
+
+if now < allow_at then
+	return {0, 0, tat - now, allow_at - now}
+end
+
+-- Format explicitly: Lua would print microsecond timestamps in exponent notation
+redis.call("SET", KEYS[1], string.format("%.0f", new_tat), "PX", string.format("%.0f", math.ceil((new_tat - now) / 1000)))
+
+return {1, math.floor((now - allow_at) / interval), new_tat - now, 0}
+`)
+
+// RedisRateLimiter is a RateLimiter whose state lives in Redis, so the limit holds
+// across all API replicas sharing the Redis instance.
+//
+// It uses GCRA (generic cell rate algorithm), which behaves like a token bucket with
+// rate r and burst b but needs a single timestamp per key.
+type RedisRateLimiter struct {
+	client    redis.UniversalClient
+	keyPrefix string
+	interval  time.Duration
+	burst     int
+}
+
+// NewRedisRateLimiter creates a new RedisRateLimiter.
+// name: policy name, part of every key so policies keep separate budgets
+// r: requests per second
+// b: burst size (maximum number of requests allowed in a burst).
+func NewRedisRateLimiter(client redis.UniversalClient, name string, r rate.Limit, b int) *RedisRateLimiter {
+	return &RedisRateLimiter{
+		client:    client,
+		keyPrefix: redisRateLimitKeyPrefix + name + ":",
+		interval:  time.Duration(float64(time.Second) / float64(r)),
+		burst:     b,
+	}
+}
+
+// NewRedisRateLimiterFactory returns a RateLimiterFactory creating RedisRateLimiters on client.
+func NewRedisRateLimiterFactory(client redis.UniversalClient) RateLimiterFactory {
+	return func(name string, r rate.Limit, b int) RateLimiter {
+		return NewRedisRateLimiter(client, name, r, b)
+	}
+}
+
+// Allow reports whether a request for key may proceed now, consuming a token if so.
+func (l *RedisRateLimiter) Allow(ctx context.Context, key string) (RateLimitResult, error) {
+	values, err := gcraScript.Run(ctx, l.client,
+		[]string{l.keyPrefix + key},
+		l.interval.Microseconds(), l.burst,
+	).Int64Slice()
+	if err != nil {
+		return RateLimitResult{Allowed: false, Limit: 0, Remaining: 0, ResetAfter: 0, RetryAfter: 0},
+			fmt.Errorf("failed to evaluate rate limit: %w", err)
+	}
+
+	return RateLimitResult{
+		Allowed:    values[0] == 1,
+		Limit:      l.burst,
+		Remaining:  int(values[1]),
+		ResetAfter: time.Duration(values[2]) * time.Microsecond,
+		RetryAfter: time.Duration(values[3]) * time.Microsecond,
+	}, nil
+}
+
+var _ RateLimiter = (*RedisRateLimiter)(nil)
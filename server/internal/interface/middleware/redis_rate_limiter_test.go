@@ -0,0 +1,148 @@
+package middleware_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+
+	"custom_auth_api/internal/interface/middleware"
+)
+
+// setupRedisRateLimiter creates two limiters sharing one in-process miniredis server,
+// standing in for two API replicas.
+func setupRedisRateLimiter(t *testing.T, r rate.Limit, b int) (*middleware.RedisRateLimiter, *middleware.RedisRateLimiter, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+
+	newLimiter := func() *middleware.RedisRateLimiter {
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() {
+			err := client.Close()
+			if err != nil {
+				t.Logf("Failed to close Redis client: %v", err)
+			}
+		})
+
+		return middleware.NewRedisRateLimiter(client, "test", r, b)
+	}
+
+	return newLimiter(), newLimiter(), server
+}
+
+func TestRedisRateLimiter_Allow(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	limiter, _, _ := setupRedisRateLimiter(t, rate.Every(time.Minute), 2)
+	ctx := context.Background()
+
+	// Act
+	first, err1 := limiter.Allow(ctx, "192.0.2.1")
+	second, err2 := limiter.Allow(ctx, "192.0.2.1")
+	third, err3 := limiter.Allow(ctx, "192.0.2.1")
+	other, err4 := limiter.Allow(ctx, "192.0.2.2")
+
+	// Assert
+	for _, err := range []error{err1, err2, err3, err4} {
+		if err != nil {
+			t.Fatalf("Allow() returned an error: %v", err)
+		}
+	}
+
+	if !first.Allowed || !second.Allowed {
+		t.Error("expected requests within the burst to be allowed")
+	}
+
+	if third.Allowed {
+		t.Error("expected the request beyond the burst to be rejected")
+	}
+
+	if third.RetryAfter <= 50*time.Second || third.RetryAfter > time.Minute {
+		t.Errorf("expected RetryAfter close to one emission interval, got %v", third.RetryAfter)
+	}
+
+	if first.Limit != 2 || first.Remaining != 1 || second.Remaining != 0 || third.Remaining != 0 {
+		t.Errorf("unexpected limit/remaining: first=%+v second=%+v third=%+v", first, second, third)
+	}
+
+	if second.ResetAfter <= 110*time.Second || second.ResetAfter > 2*time.Minute {
+		t.Errorf("expected ResetAfter close to two emission intervals, got %v", second.ResetAfter)
+	}
+
+	if !other.Allowed {
+		t.Error("expected a different key to have its own bucket")
+	}
+}
+
+func TestRedisRateLimiter_SharedAcrossReplicas(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	replicaA, replicaB, _ := setupRedisRateLimiter(t, rate.Every(time.Minute), 1)
+	ctx := context.Background()
+
+	// Act
+	first, err := replicaA.Allow(ctx, "192.0.2.1")
+	if err != nil {
+		t.Fatalf("Allow() returned an error: %v", err)
+	}
+
+	second, err := replicaB.Allow(ctx, "192.0.2.1")
+	if err != nil {
+		t.Fatalf("Allow() returned an error: %v", err)
+	}
+
+	// Assert
+	if !first.Allowed {
+		t.Error("expected the first request to be allowed")
+	}
+
+	if second.Allowed {
+		t.Error("expected the other replica to see the consumed token")
+	}
+}
+
+func TestRedisRateLimiter_KeyExpiresWhenBucketIsFull(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	limiter, _, server := setupRedisRateLimiter(t, rate.Every(time.Minute), 1)
+
+	// Act
+	_, err := limiter.Allow(context.Background(), "192.0.2.1")
+	if err != nil {
+		t.Fatalf("Allow() returned an error: %v", err)
+	}
+
+	// Assert
+	keys := server.Keys()
+	if len(keys) != 1 || keys[0] != "ratelimit:test:192.0.2.1" {
+		t.Fatalf("expected a single ratelimit key, got %v", keys)
+	}
+
+	ttl := server.TTL(keys[0])
+	if ttl <= 0 || ttl > time.Minute {
+		t.Errorf("expected the key to expire within one emission interval, got %v", ttl)
+	}
+}
+
+func TestRedisRateLimiter_StoreUnavailable(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	limiter, _, server := setupRedisRateLimiter(t, rate.Every(time.Minute), 1)
+	server.Close()
+
+	// Act
+	_, err := limiter.Allow(context.Background(), "192.0.2.1")
+
+	// Assert
+	if err == nil {
+		t.Error("expected an error when Redis is unreachable")
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderRequestID carries the request ID in both directions.
+const HeaderRequestID = "X-Request-ID"
+
+// maxRequestIDLength bounds request IDs accepted from upstream so they cannot bloat logs.
+const maxRequestIDLength = 128
+
+// requestIDContextKey is the gin context key of the request ID.
+const requestIDContextKey = "middleware.requestID"
+
+// RequestIDMiddleware assigns every request an ID for log correlation and echoes it in
+// the X-Request-ID response header. An X-Request-ID supplied by the client or a proxy is
+// kept if it consists of printable ASCII without spaces; otherwise a random ID is generated.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(HeaderRequestID)
+		if !isValidRequestID(requestID) {
+			requestID = newRequestID()
+		}
+
+		c.Set(requestIDContextKey, requestID)
+		c.Header(HeaderRequestID, requestID)
+		c.Next()
+	}
+}
+
+// RequestID returns the request ID assigned by RequestIDMiddleware, or "" without it.
+func RequestID(c *gin.Context) string {
+	return c.GetString(requestIDContextKey)
+}
+
+// isValidRequestID reports whether an upstream request ID is safe to log and echo.
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range requestID {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newRequestID returns 16 random bytes, hex encoded.
+func newRequestID() string {
+	var buf [16]byte
+
+	_, _ = rand.Read(buf[:]) // crypto/rand.Read never returns an error
+
+	return hex.EncodeToString(buf[:])
+}
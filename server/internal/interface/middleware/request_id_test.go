@@ -0,0 +1,68 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"custom_auth_api/internal/interface/middleware"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		incoming       string
+		expectIncoming bool
+	}{
+		{name: "generates an ID when none is supplied", incoming: "", expectIncoming: false},
+		{name: "keeps a well-formed upstream ID", incoming: "abc-123_DEF", expectIncoming: true},
+		{name: "replaces an ID containing spaces", incoming: "abc 123", expectIncoming: false},
+		{name: "replaces an overly long ID", incoming: strings.Repeat("a", 129), expectIncoming: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			gin.SetMode(gin.TestMode)
+
+			var seen string
+
+			router := gin.New()
+			router.Use(middleware.RequestIDMiddleware())
+			router.GET("/", func(c *gin.Context) {
+				seen = middleware.RequestID(c)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.incoming != "" {
+				req.Header.Set(middleware.HeaderRequestID, tc.incoming)
+			}
+
+			w := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(w, req)
+
+			// Assert
+			if seen == "" {
+				t.Fatal("expected handlers to see a request ID")
+			}
+
+			if w.Header().Get(middleware.HeaderRequestID) != seen {
+				t.Errorf("expected response header %q, got %q", seen, w.Header().Get(middleware.HeaderRequestID))
+			}
+
+			if (seen == tc.incoming) != tc.expectIncoming {
+				t.Errorf("expected upstream ID kept=%v, got %q", tc.expectIncoming, seen)
+			}
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package router
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"slices"
 	"time"
 
 	"custom_auth_api/internal/config"
@@ -18,21 +20,43 @@ import (
 type Handlers struct {
 	OTPRequest *handler.OTPRequestHandler
 	OTPVerify  *handler.OTPVerifyHandler
+	Signup     *handler.SignupHandler
+	Invitation *handler.InvitationHandler
+
+	// JWKS publishes the token signing keys. Optional: only the standalone identity backend has one.
+	JWKS *handler.JWKSHandler
+
+	// AdminAuth authenticates administrators, e.g. middleware.AdminAuthMiddleware.
+	AdminAuth gin.HandlerFunc
 }
 
 // NewRouter creates and configures a new Gin router with all middleware and routes.
 // This function encapsulates all router setup logic including CORS, rate limiting, and route registration.
-func NewRouter(env *config.Env, handlers *Handlers) *gin.Engine {
+// Background work started for the router (rate limiter cleanup) stops when ctx is done.
+// newRateLimiter creates the limiter of each rate limit policy; nil selects in-memory IPRateLimiters.
+func NewRouter(
+	ctx context.Context,
+	env *config.Env,
+	handlers *Handlers,
+	newRateLimiter middleware.RateLimiterFactory,
+) *gin.Engine {
 	router := gin.Default()
 
+	// Resolve the client IP ourselves; gin must not trust forwarding headers on its own
+	_ = router.SetTrustedProxies(nil)
+	router.Use(middleware.ClientIPMiddleware(middleware.NewClientIPResolver(env.TrustedProxies, env.ClientIPHeader)))
+	router.Use(middleware.RequestIDMiddleware())
+
 	// Setup CORS middleware
 	router.Use(setupCORS(env))
 
 	// Setup rate limiting
-	rateLimiter := setupRateLimiter(env)
+	if newRateLimiter == nil {
+		newRateLimiter = memoryRateLimiterFactory(ctx, env)
+	}
 
 	// Register routes
-	registerRoutes(router, rateLimiter, handlers)
+	registerRoutes(router, env, newRateLimiter, handlers)
 
 	return router
 }
@@ -54,30 +78,71 @@ func setupCORS(env *config.Env) gin.HandlerFunc {
 	}
 
 	corsConfig.AllowCredentials = true
-	corsConfig.AllowHeaders = []string{"Content-Type", "Authorization"}
+	corsConfig.AllowHeaders = []string{"Content-Type", "Authorization", middleware.HeaderRequestID}
+	// Let browser clients read how long to wait after a 429 and which request to report
+	corsConfig.ExposeHeaders = append(slices.Clone(middleware.RateLimitHeaders), middleware.HeaderRequestID)
 
 	return cors.New(corsConfig)
 }
 
-// setupRateLimiter creates and configures the IP-based rate limiter.
-// Starts a background cleanup routine to prevent memory leaks.
-func setupRateLimiter(env *config.Env) *middleware.IPRateLimiter {
-	requestsPerMinute := env.RateLimitRequestsPerMinute
+// memoryRateLimiterFactory creates IP-based rate limiters in process memory.
+// Each limiter starts a background cleanup routine, stopped by ctx, that evicts idle keys.
+func memoryRateLimiterFactory(ctx context.Context, env *config.Env) middleware.RateLimiterFactory {
 	cleanupInterval := time.Duration(env.RateLimitCleanupIntervalMinutes) * time.Minute
 
-	rateLimiter := middleware.NewIPRateLimiter(
-		rate.Every(time.Minute/time.Duration(requestsPerMinute)),
-		requestsPerMinute,
-	)
+	return func(_ string, r rate.Limit, b int) middleware.RateLimiter {
+		rateLimiter := middleware.NewIPRateLimiter(r, b, middleware.IPRateLimiterOptions{
+			MaxKeys:     env.RateLimitMaxKeys,
+			IdleTimeout: cleanupInterval,
+		})
+
+		// Start cleanup routine to bound memory without resetting active buckets
+		go rateLimiter.RunCleanup(ctx, cleanupInterval)
+
+		return rateLimiter
+	}
+}
+
+// rateLimits creates the rate limiting middleware of named policies (config.RateLimitPolicy*).
+// The limiter of each policy is created once, so every route using a policy shares its budget.
+type rateLimits struct {
+	env            *config.Env
+	newRateLimiter middleware.RateLimiterFactory
+	middlewares    map[string]gin.HandlerFunc
+}
+
+func newRateLimits(env *config.Env, newRateLimiter middleware.RateLimiterFactory) *rateLimits {
+	return &rateLimits{
+		env:            env,
+		newRateLimiter: newRateLimiter,
+		middlewares:    make(map[string]gin.HandlerFunc),
+	}
+}
+
+// policy returns the rate limiting middleware of the named policy.
+func (l *rateLimits) policy(name string) gin.HandlerFunc {
+	if mw, ok := l.middlewares[name]; ok {
+		return mw
+	}
 
-	// Start cleanup routine to prevent memory leak
-	go rateLimiter.CleanupExpiredLimiters(cleanupInterval)
+	policy := l.env.RateLimitPolicy(name)
+	limiter := l.newRateLimiter(name, rate.Every(time.Minute/time.Duration(policy.RequestsPerMinute)), policy.Burst)
+	mw := middleware.RateLimitMiddleware(limiter, l.env.RateLimitExemptCIDRs)
+	l.middlewares[name] = mw
 
-	return rateLimiter
+	return mw
 }
 
 // registerRoutes registers all application routes with appropriate middleware.
-func registerRoutes(router *gin.Engine, rateLimiter *middleware.IPRateLimiter, handlers *Handlers) {
+// Admin endpoints must be rate limited with the config.RateLimitPolicyAdmin policy.
+func registerRoutes(
+	router *gin.Engine,
+	env *config.Env,
+	newRateLimiter middleware.RateLimiterFactory,
+	handlers *Handlers,
+) {
+	limits := newRateLimits(env, newRateLimiter)
+
 	// Health check endpoint (no rate limiting)
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -85,11 +150,37 @@ func registerRoutes(router *gin.Engine, rateLimiter *middleware.IPRateLimiter, h
 		})
 	})
 
-	// Authentication endpoints with rate limiting
+	// Token signing keys (no rate limiting, verifiers cache them)
+	if handlers.JWKS != nil {
+		router.GET("/.well-known/jwks.json", handlers.JWKS.GetJWKS)
+	}
+
+	// Authentication endpoints; routes sharing a rate limit policy share its budget
 	authGroup := router.Group("/auth")
-	authGroup.Use(middleware.RateLimitMiddleware(rateLimiter))
 	{
-		authGroup.POST("/otp", handlers.OTPRequest.RequestOTP)
-		authGroup.POST("/verify", handlers.OTPVerify.VerifyOTP)
+		authGroup.POST("/otp", limits.policy(config.RateLimitPolicyOTP), handlers.OTPRequest.RequestOTP)
+		authGroup.POST("/verify", limits.policy(config.RateLimitPolicyVerify), handlers.OTPVerify.VerifyOTP)
+		authGroup.POST("/signup", limits.policy(config.RateLimitPolicyOTP), handlers.Signup.RequestSignup)
+		authGroup.POST(
+			"/signup/verify",
+			limits.policy(config.RateLimitPolicyVerify),
+			handlers.Signup.VerifySignup,
+		)
+		authGroup.POST(
+			"/invitations/otp",
+			limits.policy(config.RateLimitPolicyOTP),
+			handlers.Invitation.RequestInvitationOTP,
+		)
+		authGroup.POST(
+			"/invitations/accept",
+			limits.policy(config.RateLimitPolicyVerify),
+			handlers.Invitation.AcceptInvitation,
+		)
+	}
+
+	// Admin endpoints, rate limited before authentication so token verification cannot be flooded
+	adminGroup := router.Group("/admin", limits.policy(config.RateLimitPolicyAdmin), handlers.AdminAuth)
+	{
+		adminGroup.POST("/invitations", handlers.Invitation.CreateInvitation)
 	}
 }
@@ -3,10 +3,16 @@ package router_test
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"custom_auth_api/internal/config"
+	"custom_auth_api/internal/domain/identity/identitytest"
+	"custom_auth_api/internal/infrastructure/jwt"
+	"custom_auth_api/internal/infrastructure/persistence"
 	"custom_auth_api/internal/interface/handler"
+	"custom_auth_api/internal/interface/middleware"
 	"custom_auth_api/internal/interface/router"
 	"custom_auth_api/internal/usecase"
 )
@@ -24,11 +30,14 @@ func TestNewRouter_HealthCheck(t *testing.T) {
 
 	// Create mock handlers (nil services for health check test)
 	handlers := &router.Handlers{
-		OTPRequest: handler.NewOTPRequestHandler(nil, nil),
+		OTPRequest: handler.NewOTPRequestHandler(nil, nil, handler.OTPRequestOptions{}),
 		OTPVerify:  handler.NewOTPVerifyHandler(nil, nil),
+		Signup:     handler.NewSignupHandler(nil, nil, handler.RegistrationModeClosed),
+		Invitation: handler.NewInvitationHandler(nil, nil, nil, handler.RegistrationModeClosed),
+		AdminAuth:  middleware.AdminAuthMiddleware(usecase.NewAuthService(persistence.NewMemoryUserDirectory(), identitytest.NewIssuer())),
 	}
 
-	r := router.NewRouter(env, handlers)
+	r := router.NewRouter(t.Context(), env, handlers, nil)
 
 	// Act
 	req := httptest.NewRequest(http.MethodGet, "/health", nil)
@@ -57,13 +66,16 @@ func TestNewRouter_RoutesRegistered(t *testing.T) {
 	}
 
 	// Create mock auth service
-	mockAuthService := usecase.NewAuthService(nil)
+	mockAuthService := usecase.NewAuthService(persistence.NewMemoryUserDirectory(), identitytest.NewIssuer())
 	handlers := &router.Handlers{
-		OTPRequest: handler.NewOTPRequestHandler(nil, mockAuthService),
+		OTPRequest: handler.NewOTPRequestHandler(nil, mockAuthService, handler.OTPRequestOptions{}),
 		OTPVerify:  handler.NewOTPVerifyHandler(nil, mockAuthService),
+		Signup:     handler.NewSignupHandler(nil, mockAuthService, handler.RegistrationModeClosed),
+		Invitation: handler.NewInvitationHandler(nil, nil, mockAuthService, handler.RegistrationModeClosed),
+		AdminAuth:  middleware.AdminAuthMiddleware(mockAuthService),
 	}
 
-	r := router.NewRouter(env, handlers)
+	r := router.NewRouter(t.Context(), env, handlers, nil)
 
 	testCases := []struct {
 		name       string
@@ -89,6 +101,36 @@ func TestNewRouter_RoutesRegistered(t *testing.T) {
 			path:       "/auth/verify",
 			shouldFind: true,
 		},
+		{
+			name:       "sign-up endpoint exists",
+			method:     http.MethodPost,
+			path:       "/auth/signup",
+			shouldFind: true,
+		},
+		{
+			name:       "sign-up verify endpoint exists",
+			method:     http.MethodPost,
+			path:       "/auth/signup/verify",
+			shouldFind: true,
+		},
+		{
+			name:       "invitation OTP endpoint exists",
+			method:     http.MethodPost,
+			path:       "/auth/invitations/otp",
+			shouldFind: true,
+		},
+		{
+			name:       "invitation accept endpoint exists",
+			method:     http.MethodPost,
+			path:       "/auth/invitations/accept",
+			shouldFind: true,
+		},
+		{
+			name:       "admin invitation endpoint exists",
+			method:     http.MethodPost,
+			path:       "/admin/invitations",
+			shouldFind: true,
+		},
 		{
 			name:       "non-existent endpoint returns 404",
 			method:     http.MethodGet,
@@ -119,3 +161,242 @@ func TestNewRouter_RoutesRegistered(t *testing.T) {
 		})
 	}
 }
+
+func TestNewRouter_RateLimitHeaders(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	env := &config.Env{
+		Environment:                     "development",
+		RateLimitRequestsPerMinute:      5,
+		RateLimitCleanupIntervalMinutes: 10,
+	}
+
+	handlers := &router.Handlers{
+		OTPRequest: handler.NewOTPRequestHandler(nil, nil, handler.OTPRequestOptions{}),
+		OTPVerify:  handler.NewOTPVerifyHandler(nil, nil),
+		Signup:     handler.NewSignupHandler(nil, nil, handler.RegistrationModeClosed),
+		Invitation: handler.NewInvitationHandler(nil, nil, nil, handler.RegistrationModeClosed),
+		AdminAuth:  middleware.AdminAuthMiddleware(usecase.NewAuthService(persistence.NewMemoryUserDirectory(), identitytest.NewIssuer())),
+	}
+
+	r := router.NewRouter(t.Context(), env, handlers, nil)
+
+	testCases := []struct {
+		name          string
+		method        string
+		path          string
+		expectHeaders bool
+	}{
+		{name: "auth endpoints carry rate limit headers", method: http.MethodPost, path: "/auth/otp", expectHeaders: true},
+		{name: "admin endpoints carry rate limit headers", method: http.MethodPost, path: "/admin/invitations", expectHeaders: true},
+		{name: "health check is not rate limited", method: http.MethodGet, path: "/health", expectHeaders: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Header.Set("Origin", "http://localhost:5173")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			// Assert
+			hasHeaders := w.Header().Get("RateLimit-Limit") != ""
+			if hasHeaders != tc.expectHeaders {
+				t.Errorf("expected rate limit headers: %v, got headers %v", tc.expectHeaders, w.Header())
+			}
+
+			exposed := w.Header().Get("Access-Control-Expose-Headers")
+			if tc.expectHeaders && !strings.Contains(exposed, "Retry-After") {
+				t.Errorf("expected rate limit headers to be exposed to browsers, got %q", exposed)
+			}
+		})
+	}
+}
+
+func TestNewRouter_RateLimitPolicies(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	env := &config.Env{
+		Environment:                     "development",
+		RateLimitRequestsPerMinute:      5,
+		RateLimitCleanupIntervalMinutes: 10,
+		RateLimitPolicies: map[string]config.RateLimitPolicy{
+			config.RateLimitPolicyOTP:    {RequestsPerMinute: 1, Burst: 1},
+			config.RateLimitPolicyVerify: {RequestsPerMinute: 3, Burst: 3},
+		},
+	}
+
+	handlers := &router.Handlers{
+		OTPRequest: handler.NewOTPRequestHandler(nil, nil, handler.OTPRequestOptions{}),
+		OTPVerify:  handler.NewOTPVerifyHandler(nil, nil),
+		Signup:     handler.NewSignupHandler(nil, nil, handler.RegistrationModeClosed),
+		Invitation: handler.NewInvitationHandler(nil, nil, nil, handler.RegistrationModeClosed),
+		AdminAuth:  middleware.AdminAuthMiddleware(usecase.NewAuthService(persistence.NewMemoryUserDirectory(), identitytest.NewIssuer())),
+	}
+
+	r := router.NewRouter(t.Context(), env, handlers, nil)
+
+	send := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		return w
+	}
+
+	// Act
+	send("/auth/otp")
+	otpResponse := send("/auth/otp")
+	verifyResponse := send("/auth/verify")
+
+	// Assert
+	if otpResponse.Code != http.StatusTooManyRequests {
+		t.Errorf("expected the otp policy to reject the second request, got %d", otpResponse.Code)
+	}
+
+	if verifyResponse.Code == http.StatusTooManyRequests {
+		t.Error("expected the verify policy to have its own budget")
+	}
+
+	if limit := verifyResponse.Header().Get("RateLimit-Limit"); limit != "3" {
+		t.Errorf("expected the verify policy burst of 3, got %q", limit)
+	}
+}
+
+func TestNewRouter_RateLimitPolicySharedAcrossRoutes(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	env := &config.Env{
+		Environment:                     "development",
+		RateLimitRequestsPerMinute:      5,
+		RateLimitCleanupIntervalMinutes: 10,
+		RateLimitPolicies: map[string]config.RateLimitPolicy{
+			config.RateLimitPolicyOTP: {RequestsPerMinute: 2, Burst: 2},
+		},
+	}
+
+	handlers := &router.Handlers{
+		OTPRequest: handler.NewOTPRequestHandler(nil, nil, handler.OTPRequestOptions{}),
+		OTPVerify:  handler.NewOTPVerifyHandler(nil, nil),
+		Signup:     handler.NewSignupHandler(nil, nil, handler.RegistrationModeClosed),
+		Invitation: handler.NewInvitationHandler(nil, nil, nil, handler.RegistrationModeClosed),
+		AdminAuth:  middleware.AdminAuthMiddleware(usecase.NewAuthService(persistence.NewMemoryUserDirectory(), identitytest.NewIssuer())),
+	}
+
+	r := router.NewRouter(t.Context(), env, handlers, nil)
+
+	send := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.RemoteAddr = "192.0.2.10:1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		return w
+	}
+
+	// Act
+	first := send("/auth/otp")
+	second := send("/auth/signup")
+	third := send("/auth/otp")
+	fourth := send("/auth/signup")
+
+	// Assert
+	if first.Code == http.StatusTooManyRequests || second.Code == http.StatusTooManyRequests {
+		t.Fatalf("expected the first two requests within the burst, got %d and %d", first.Code, second.Code)
+	}
+
+	if third.Code != http.StatusTooManyRequests || fourth.Code != http.StatusTooManyRequests {
+		t.Errorf("expected /auth/otp and /auth/signup to share the otp budget, got %d and %d", third.Code, fourth.Code)
+	}
+}
+
+func TestNewRouter_AdminRequiresAuthentication(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	env := &config.Env{
+		Environment:                     "development",
+		RateLimitRequestsPerMinute:      5,
+		RateLimitCleanupIntervalMinutes: 10,
+	}
+
+	handlers := &router.Handlers{
+		OTPRequest: handler.NewOTPRequestHandler(nil, nil, handler.OTPRequestOptions{}),
+		OTPVerify:  handler.NewOTPVerifyHandler(nil, nil),
+		Signup:     handler.NewSignupHandler(nil, nil, handler.RegistrationModeClosed),
+		Invitation: handler.NewInvitationHandler(nil, nil, nil, handler.RegistrationModeInvite),
+		AdminAuth:  middleware.AdminAuthMiddleware(usecase.NewAuthService(persistence.NewMemoryUserDirectory(), identitytest.NewIssuer())),
+	}
+
+	r := router.NewRouter(t.Context(), env, handlers, nil)
+
+	// Act
+	req := httptest.NewRequest(http.MethodPost, "/admin/invitations", strings.NewReader(`{"email":"invitee@example.com"}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// Assert
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 without an ID token, got %d", w.Code)
+	}
+}
+
+func TestNewRouter_JWKSRegisteredOnlyWithHandler(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	env := &config.Env{
+		Environment:                     "development",
+		RateLimitRequestsPerMinute:      5,
+		RateLimitCleanupIntervalMinutes: 10,
+	}
+
+	signingKey, err := jwt.GenerateSigningKey("k1")
+	if err != nil {
+		t.Fatalf("GenerateSigningKey() returned an error: %v", err)
+	}
+
+	issuer, err := jwt.NewIssuer("k1", []*jwt.SigningKey{signingKey}, jwt.Options{
+		Issuer:   "http://localhost:8000",
+		Audience: "custom-auth-api",
+		TTL:      time.Minute,
+		Now:      nil,
+	})
+	if err != nil {
+		t.Fatalf("NewIssuer() returned an error: %v", err)
+	}
+
+	newHandlers := func(jwks *handler.JWKSHandler) *router.Handlers {
+		return &router.Handlers{
+			OTPRequest: handler.NewOTPRequestHandler(nil, nil, handler.OTPRequestOptions{}),
+			OTPVerify:  handler.NewOTPVerifyHandler(nil, nil),
+			Signup:     handler.NewSignupHandler(nil, nil, handler.RegistrationModeClosed),
+			Invitation: handler.NewInvitationHandler(nil, nil, nil, handler.RegistrationModeClosed),
+			JWKS:       jwks,
+			AdminAuth:  middleware.AdminAuthMiddleware(usecase.NewAuthService(persistence.NewMemoryUserDirectory(), issuer)),
+		}
+	}
+
+	withJWKS := router.NewRouter(t.Context(), env, newHandlers(handler.NewJWKSHandler(issuer)), nil)
+	withoutJWKS := router.NewRouter(t.Context(), env, newHandlers(nil), nil)
+
+	// Act
+	withRecorder := httptest.NewRecorder()
+	withJWKS.ServeHTTP(withRecorder, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	withoutRecorder := httptest.NewRecorder()
+	withoutJWKS.ServeHTTP(withoutRecorder, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	// Assert
+	if withRecorder.Code != http.StatusOK || !strings.Contains(withRecorder.Body.String(), `"kid":"k1"`) {
+		t.Errorf("expected the key set, got %d %s", withRecorder.Code, withRecorder.Body.String())
+	}
+
+	if withoutRecorder.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 without a JWKS handler, got %d", withoutRecorder.Code)
+	}
+}
@@ -0,0 +1,108 @@
+// Package filereload keeps a value parsed from a file and reloads it when the file changes.
+package filereload
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ParseFunc parses the contents of a watched file.
+type ParseFunc[T any] func(r io.Reader) (*T, error)
+
+// File is a value parsed from a file that can be reloaded while in use.
+//
+// Reload swaps in the new value atomically, so Load never sees a partially
+// parsed file. If the file becomes unreadable or invalid, the last good value is kept.
+type File[T any] struct {
+	path    string
+	what    string // Describes the file in errors, e.g. "role mapping"
+	parse   ParseFunc[T]
+	current atomic.Pointer[T]
+
+	mu      sync.Mutex // Serializes reloads
+	modTime time.Time
+	size    int64
+}
+
+// New loads the file at path with parse. what describes the file in errors (e.g. "domain list").
+func New[T any](path, what string, parse ParseFunc[T]) (*File[T], error) {
+	file := &File[T]{
+		path:    path,
+		what:    what,
+		parse:   parse,
+		current: atomic.Pointer[T]{},
+		mu:      sync.Mutex{},
+		modTime: time.Time{},
+		size:    0,
+	}
+
+	_, err := file.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// Load returns the most recently loaded value.
+func (f *File[T]) Load() *T {
+	return f.current.Load()
+}
+
+// Reload re-reads the file if its modification time or size changed since the last load
+// and reports whether a new value was loaded.
+func (f *File[T]) Reload() (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat %s %s: %w", f.what, f.path, err)
+	}
+
+	if f.current.Load() != nil && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return false, nil
+	}
+
+	file, err := os.Open(f.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to open %s %s: %w", f.what, f.path, err)
+	}
+	defer file.Close()
+
+	value, err := f.parse(file)
+	if err != nil {
+		return false, fmt.Errorf("failed to load %s %s: %w", f.what, f.path, err)
+	}
+
+	f.current.Store(value)
+	f.modTime = info.ModTime()
+	f.size = info.Size()
+
+	return true, nil
+}
+
+// RunReload checks the file for changes every interval until ctx is done.
+// It blocks, so run it in its own goroutine. Failures are reported to onError, which may be nil;
+// the previous value stays in effect.
+func (f *File[T]) RunReload(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := f.Reload()
+			if err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package filereload_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"custom_auth_api/internal/pkg/filereload"
+)
+
+var errEmpty = errors.New("empty file")
+
+// parseText returns the trimmed file contents and rejects empty files.
+func parseText(r io.Reader) (*string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	text := strings.TrimSpace(string(data))
+	if text == "" {
+		return nil, errEmpty
+	}
+
+	return &text, nil
+}
+
+func writeFile(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	// Set the time explicitly: two writes within the file system's timestamp resolution look unchanged
+	err = os.Chtimes(path, modTime, modTime)
+	if err != nil {
+		t.Fatalf("failed to set modification time: %v", err)
+	}
+}
+
+func TestFile_Reload(t *testing.T) {
+	t.Parallel()
+
+	t.Run("loads changes", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		path := filepath.Join(t.TempDir(), "value.txt")
+		start := time.Now().Add(-time.Hour)
+		writeFile(t, path, "first", start)
+
+		file, err := filereload.New(path, "value", parseText)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+
+		writeFile(t, path, "second", start.Add(time.Minute))
+
+		// Act
+		reloaded, err := file.Reload()
+
+		// Assert
+		if err != nil || !reloaded {
+			t.Fatalf("expected the changed file to be reloaded, got reloaded=%v err=%v", reloaded, err)
+		}
+
+		if got := *file.Load(); got != "second" {
+			t.Errorf("expected the new value, got %q", got)
+		}
+	})
+
+	t.Run("skips unchanged files", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		path := filepath.Join(t.TempDir(), "value.txt")
+		writeFile(t, path, "first", time.Now().Add(-time.Hour))
+
+		file, err := filereload.New(path, "value", parseText)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+
+		// Act
+		reloaded, err := file.Reload()
+
+		// Assert
+		if err != nil || reloaded {
+			t.Errorf("expected an unchanged file not to be reloaded, got reloaded=%v err=%v", reloaded, err)
+		}
+	})
+
+	t.Run("keeps the previous value when the file becomes invalid", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		path := filepath.Join(t.TempDir(), "value.txt")
+		start := time.Now().Add(-time.Hour)
+		writeFile(t, path, "first", start)
+
+		file, err := filereload.New(path, "value", parseText)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+
+		writeFile(t, path, "\n", start.Add(time.Minute))
+
+		// Act
+		_, err = file.Reload()
+
+		// Assert
+		if !errors.Is(err, errEmpty) {
+			t.Fatalf("expected the parse error, got %v", err)
+		}
+
+		if got := *file.Load(); got != "first" {
+			t.Errorf("expected the previous value to stay in effect, got %q", got)
+		}
+	})
+
+	t.Run("fails for a missing file", func(t *testing.T) {
+		t.Parallel()
+
+		// Act
+		_, err := filereload.New(filepath.Join(t.TempDir(), "missing.txt"), "value", parseText)
+
+		// Assert
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected an error for a missing file, got %v", err)
+		}
+	})
+}
+
+func TestFile_RunReload(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	path := filepath.Join(t.TempDir(), "value.txt")
+	start := time.Now().Add(-time.Hour)
+	writeFile(t, path, "first", start)
+
+	file, err := filereload.New(path, "value", parseText)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		file.RunReload(ctx, time.Millisecond, nil)
+	}()
+
+	// Act
+	writeFile(t, path, "second", start.Add(time.Minute))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for *file.Load() != "second" && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	<-done
+
+	// Assert
+	if got := *file.Load(); got != "second" {
+		t.Errorf("expected the change to be picked up, got %q", got)
+	}
+}
@@ -0,0 +1,88 @@
+// Package keyset holds rotatable HMAC-SHA256 key sets.
+package keyset
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MinKeyLength is the minimum HMAC key length in bytes (256 bits).
+const MinKeyLength = 32
+
+// Key set errors.
+var (
+	ErrKeyRequired       = errors.New("at least one key is required")
+	ErrKeyTooShort       = errors.New("key must be at least 32 bytes")
+	ErrInvalidKeyID      = errors.New("key id must be non-empty and must not contain the separator")
+	ErrActiveKeyNotFound = errors.New("active key id is not in the key set")
+)
+
+// Keyset is a set of HMAC-SHA256 keys identified by key ID, one of which is active.
+//
+// New MACs are always computed with the active key. Verification looks up the key
+// by the ID stored next to the MAC, so rotating keys only requires adding the new
+// key, making it active, and removing the old key once nothing signed with it is in use.
+type Keyset struct {
+	activeKeyID string
+	keys        map[string][]byte
+}
+
+// New creates a Keyset from keys and the ID of the active key. Key IDs must be
+// non-empty and must not contain separator, the string callers put between the
+// key ID and the MAC. The keys are copied.
+func New(activeKeyID string, keys map[string][]byte, separator string) (*Keyset, error) {
+	if len(keys) == 0 {
+		return nil, ErrKeyRequired
+	}
+
+	copied := make(map[string][]byte, len(keys))
+
+	for keyID, key := range keys {
+		if keyID == "" || strings.Contains(keyID, separator) {
+			return nil, fmt.Errorf("%w %q: %q", ErrInvalidKeyID, separator, keyID)
+		}
+
+		if len(key) < MinKeyLength {
+			return nil, fmt.Errorf("%w: key %q", ErrKeyTooShort, keyID)
+		}
+
+		copied[keyID] = append([]byte(nil), key...)
+	}
+
+	if _, ok := copied[activeKeyID]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrActiveKeyNotFound, activeKeyID)
+	}
+
+	return &Keyset{activeKeyID: activeKeyID, keys: copied}, nil
+}
+
+// ActiveKeyID returns the ID of the key used for new MACs.
+func (k *Keyset) ActiveKeyID() string {
+	return k.activeKeyID
+}
+
+// Sum returns the HMAC-SHA256 of message under the active key.
+func (k *Keyset) Sum(message string) []byte {
+	return sum(k.keys[k.activeKeyID], message)
+}
+
+// SumWith returns the HMAC-SHA256 of message under the key with ID keyID.
+// Returns false if the key is not in the set.
+func (k *Keyset) SumWith(keyID, message string) ([]byte, bool) {
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, false
+	}
+
+	return sum(key, message), true
+}
+
+func sum(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,119 @@
+package keyset_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"custom_auth_api/internal/pkg/keyset"
+)
+
+var (
+	testKeyV1 = bytes.Repeat([]byte("1"), keyset.MinKeyLength)
+	testKeyV2 = bytes.Repeat([]byte("2"), keyset.MinKeyLength)
+)
+
+func TestNew_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		activeKeyID string
+		keys        map[string][]byte
+		wantErr     error
+	}{
+		{name: "no keys", activeKeyID: "v1", keys: nil, wantErr: keyset.ErrKeyRequired},
+		{
+			name:        "key too short",
+			activeKeyID: "v1",
+			keys:        map[string][]byte{"v1": []byte("short")},
+			wantErr:     keyset.ErrKeyTooShort,
+		},
+		{
+			name:        "empty key id",
+			activeKeyID: "",
+			keys:        map[string][]byte{"": testKeyV1},
+			wantErr:     keyset.ErrInvalidKeyID,
+		},
+		{
+			name:        "key id contains separator",
+			activeKeyID: "v:1",
+			keys:        map[string][]byte{"v:1": testKeyV1},
+			wantErr:     keyset.ErrInvalidKeyID,
+		},
+		{
+			name:        "active key missing",
+			activeKeyID: "v2",
+			keys:        map[string][]byte{"v1": testKeyV1},
+			wantErr:     keyset.ErrActiveKeyNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			keys, err := keyset.New(tt.activeKeyID, tt.keys, ":")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if keys != nil {
+				t.Error("New() should return a nil key set on error")
+			}
+		})
+	}
+}
+
+func TestKeyset_Sum(t *testing.T) {
+	t.Parallel()
+
+	keys, err := keyset.New("v2", map[string][]byte{"v1": testKeyV1, "v2": testKeyV2}, ":")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	t.Run("uses the active key", func(t *testing.T) {
+		t.Parallel()
+
+		active, ok := keys.SumWith("v2", "message")
+		if !ok || !bytes.Equal(keys.Sum("message"), active) {
+			t.Error("expected Sum() to use the active key")
+		}
+	})
+
+	t.Run("distinguishes keys", func(t *testing.T) {
+		t.Parallel()
+
+		old, ok := keys.SumWith("v1", "message")
+		if !ok || bytes.Equal(old, keys.Sum("message")) {
+			t.Error("expected different keys to produce different MACs")
+		}
+	})
+
+	t.Run("reports unknown keys", func(t *testing.T) {
+		t.Parallel()
+
+		if _, ok := keys.SumWith("v3", "message"); ok {
+			t.Error("expected SumWith() to report an unknown key")
+		}
+	})
+
+	t.Run("copies the keys", func(t *testing.T) {
+		t.Parallel()
+
+		source := map[string][]byte{"v1": bytes.Clone(testKeyV1)}
+
+		copied, err := keyset.New("v1", source, ":")
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+
+		before := copied.Sum("message")
+		source["v1"][0] ^= 0xff
+
+		if !bytes.Equal(before, copied.Sum("message")) {
+			t.Error("expected the key set to be unaffected by later changes to the source keys")
+		}
+	})
+}
@@ -2,33 +2,100 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
-	"firebase.google.com/go/v4/auth"
+	"custom_auth_api/internal/domain/claims"
+	"custom_auth_api/internal/domain/identity"
 )
 
-// AuthService handles Firebase Authentication related business logic.
+// MaxDisplayNameLength is the maximum display name length in characters.
+const MaxDisplayNameLength = 128
+
+// Custom claims managed by the service.
+const (
+	// AdminClaim marks users allowed to call admin endpoints when set to true.
+	AdminClaim = claims.AdminClaim
+
+	// RolesClaim lists the roles granted to a user, e.g. by accepting an invitation.
+	RolesClaim = claims.RolesClaim
+
+	// AuthMethodsClaim lists how the user authenticated.
+	AuthMethodsClaim = claims.AuthMethodsClaim
+
+	// AuthTimeClaim is when the user authenticated.
+	AuthTimeClaim = claims.AuthTimeClaim
+)
+
+// Authentication methods listed in AuthMethodsClaim.
+const (
+	AuthMethodOTP   = "otp"   // A one-time password was verified
+	AuthMethodEmail = "email" // Control of the email address was proven
+)
+
+// Auth service errors.
+var (
+	// ErrUserNotFound is returned when no user is registered with the requested email address.
+	ErrUserNotFound = identity.ErrUserNotFound
+
+	// ErrInvalidDisplayName is returned for display names that are too long or contain control characters.
+	ErrInvalidDisplayName = errors.New("display name must be at most 128 characters without control characters")
+
+	// ErrReservedClaim is returned when a claims provider sets a claim the service sets itself.
+	ErrReservedClaim = errors.New("claims provider must not set auth_methods or authenticated_at")
+)
+
+// AuthService handles the user account side of authentication.
 //
 // Responsibilities:
-// - Retrieve user information from Firebase Auth
-// - Generate Firebase custom tokens for authenticated users
+// - Retrieve users from the identity.UserDirectory
+// - Register users whose email address has been verified by OTP
+// - Grant invited roles
+// - Verify tokens of administrators
+// - Issue tokens for authenticated users, with claims from a claims.Provider
 //
 // Note:
 // - OTP generation, sending, and verification are handled by OTPService
-// - This service acts as a wrapper around the Firebase Auth SDK.
+// - The identity backend (e.g. Firebase Authentication) is reached through identity interfaces.
 type AuthService struct {
-	authClient *auth.Client
+	users          identity.UserDirectory
+	tokens         identity.TokenIssuer
+	claimsProvider claims.Provider
+}
+
+// AuthServiceOptions configures an AuthService.
+type AuthServiceOptions struct {
+	// ClaimsProvider supplies claims (e.g. roles) for every issued token. Nil adds none.
+	ClaimsProvider claims.Provider
+}
+
+// NewAuthService creates a new AuthService without a claims provider.
+func NewAuthService(users identity.UserDirectory, tokens identity.TokenIssuer) *AuthService {
+	return NewAuthServiceWithOptions(users, tokens, AuthServiceOptions{ClaimsProvider: nil})
 }
 
-// NewAuthService creates a new AuthService.
-func NewAuthService(authClient *auth.Client) *AuthService {
-	return &AuthService{authClient: authClient}
+// NewAuthServiceWithOptions creates a new AuthService configured by options.
+func NewAuthServiceWithOptions(
+	users identity.UserDirectory,
+	tokens identity.TokenIssuer,
+	options AuthServiceOptions,
+) *AuthService {
+	return &AuthService{
+		users:          users,
+		tokens:         tokens,
+		claimsProvider: options.ClaimsProvider,
+	}
 }
 
 // GetUserByEmail retrieves a user by email address.
-// Returns the user record if found, or an error if the user does not exist.
-func (s *AuthService) GetUserByEmail(ctx context.Context, email string) (*auth.UserRecord, error) {
-	user, err := s.authClient.GetUserByEmail(ctx, email)
+// Returns the user if found, or an error wrapping ErrUserNotFound if the user does not exist.
+func (s *AuthService) GetUserByEmail(ctx context.Context, email string) (*identity.User, error) {
+	user, err := s.users.GetUserByEmail(ctx, email)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user by email: %w", err)
 	}
@@ -36,12 +103,188 @@ func (s *AuthService) GetUserByEmail(ctx context.Context, email string) (*auth.U
 	return user, nil
 }
 
-// GenerateCustomToken generates a custom Firebase authentication token for the given user ID (UID).
-func (s *AuthService) GenerateCustomToken(ctx context.Context, uid string) (string, error) {
-	customToken, err := s.authClient.CustomToken(ctx, uid)
+// RegisterUser returns the user registered with email, creating it if there is none.
+// Call it only after the address has been verified (e.g. by OTP): new users are created
+// with EmailVerified set. displayName is optional and is ignored for existing users.
+// The returned bool reports whether the user was created.
+// Returns an error wrapping ErrInvalidDisplayName if displayName is not acceptable.
+func (s *AuthService) RegisterUser(ctx context.Context, email, displayName string) (*identity.User, bool, error) {
+	displayName = strings.TrimSpace(displayName)
+
+	err := ValidateDisplayName(displayName)
+	if err != nil {
+		return nil, false, err
+	}
+
+	user, err := s.GetUserByEmail(ctx, email)
+	if err == nil {
+		return user, false, nil
+	}
+
+	if !errors.Is(err, ErrUserNotFound) {
+		return nil, false, err
+	}
+
+	user, err = s.users.CreateUser(ctx, identity.UserToCreate{
+		Email:         email,
+		EmailVerified: true,
+		DisplayName:   displayName,
+	})
+	if err != nil {
+		if errors.Is(err, identity.ErrEmailAlreadyExists) {
+			// A concurrent registration won the race; use its user
+			user, err = s.GetUserByEmail(ctx, email)
+			if err != nil {
+				return nil, false, err
+			}
+
+			return user, false, nil
+		}
+
+		return nil, false, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user, true, nil
+}
+
+// ProvisionUser registers the user like RegisterUser and grants roles.
+// Roles the user already has are kept. The returned bool reports whether the user was created.
+func (s *AuthService) ProvisionUser(
+	ctx context.Context,
+	email, displayName string,
+	roles []string,
+) (*identity.User, bool, error) {
+	user, created, err := s.RegisterUser(ctx, email, displayName)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(roles) == 0 {
+		return user, created, nil
+	}
+
+	merged := mergeRoles(user.Roles, roles)
+
+	err = s.users.SetRoles(ctx, user.UID, merged)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to grant roles: %w", err)
+	}
+
+	user.Roles = merged
+
+	return user, created, nil
+}
+
+// VerifyAdmin verifies a token presented by a signed-in user (a Firebase ID token, or the
+// access token itself with the standalone identity backend).
+// It returns who the token belongs to (the email address, or the UID if the user has none)
+// and whether the user has the AdminClaim. Returns an error if the token is not valid.
+func (s *AuthService) VerifyAdmin(ctx context.Context, idToken string) (string, bool, error) {
+	token, err := s.tokens.VerifyToken(ctx, idToken)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate custom token: %w", err)
+		return "", false, fmt.Errorf("failed to verify token: %w", err)
+	}
+
+	who := token.UID
+	if token.Email != "" {
+		who = token.Email
+	}
+
+	isAdmin, _ := token.Claims[AdminClaim].(bool)
+
+	return who, isAdmin, nil
+}
+
+// mergeRoles returns roles followed by the added roles it does not contain yet.
+func mergeRoles(roles, added []string) []string {
+	merged := slices.Clone(roles)
+
+	for _, role := range added {
+		if !slices.Contains(merged, role) {
+			merged = append(merged, role)
+		}
+	}
+
+	return merged
+}
+
+// claimStrings returns the strings in a decoded list claim, ignoring other values.
+func claimStrings(value any) []string {
+	switch list := value.(type) {
+	case []string:
+		return slices.Clone(list)
+	case []any:
+		values := make([]string, 0, len(list))
+
+		for _, item := range list {
+			if text, ok := item.(string); ok {
+				values = append(values, text)
+			}
+		}
+
+		return values
+	default:
+		return nil
 	}
+}
+
+// ValidateDisplayName checks an optional display name. Empty names are accepted.
+// Returns an error wrapping ErrInvalidDisplayName otherwise.
+func ValidateDisplayName(displayName string) error {
+	if utf8.RuneCountInString(displayName) > MaxDisplayNameLength || strings.ContainsFunc(displayName, unicode.IsControl) {
+		return fmt.Errorf("%w (got %q)", ErrInvalidDisplayName, displayName)
+	}
+
+	return nil
+}
+
+// GenerateCustomToken issues a sign-in token for user (a Firebase custom token with the
+// Firebase identity backend, a signed access JWT with the standalone one). Call it only
+// after the user has verified an OTP sent to their address: the token records that in
+// AuthMethodsClaim and AuthTimeClaim, next to the claims of the claims provider. Roles
+// from the provider are combined with the user's roles.
+// Returns an error wrapping ErrReservedClaim if the provider sets one of those claims.
+func (s *AuthService) GenerateCustomToken(ctx context.Context, user *identity.User) (string, error) {
+	tokenClaims, err := s.tokenClaims(ctx, user)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := s.tokens.IssueToken(ctx, user, tokenClaims)
+	if err != nil {
+		return "", fmt.Errorf("failed to issue token: %w", err)
+	}
+
+	return token, nil
+}
+
+// tokenClaims returns the claims provider's claims for user plus the authentication claims.
+func (s *AuthService) tokenClaims(ctx context.Context, user *identity.User) (map[string]any, error) {
+	tokenClaims := map[string]any{}
+
+	if s.claimsProvider != nil {
+		provided, err := s.claimsProvider.Claims(ctx, claims.Subject{UID: user.UID, Email: user.Email})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get claims: %w", err)
+		}
+
+		for _, name := range []string{AuthMethodsClaim, AuthTimeClaim} {
+			if _, ok := provided[name]; ok {
+				return nil, fmt.Errorf("%w (got %q)", ErrReservedClaim, name)
+			}
+		}
+
+		for name, value := range provided {
+			tokenClaims[name] = value
+		}
+
+		if providedRoles, ok := provided[RolesClaim]; ok {
+			tokenClaims[RolesClaim] = mergeRoles(user.Roles, claimStrings(providedRoles))
+		}
+	}
+
+	tokenClaims[AuthMethodsClaim] = []string{AuthMethodOTP, AuthMethodEmail}
+	tokenClaims[AuthTimeClaim] = time.Now().Unix()
 
-	return customToken, nil
+	return tokenClaims, nil
 }
@@ -0,0 +1,220 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"custom_auth_api/internal/domain/claims"
+	"custom_auth_api/internal/domain/identity"
+	"custom_auth_api/internal/domain/identity/identitytest"
+	"custom_auth_api/internal/infrastructure/persistence"
+	"custom_auth_api/internal/usecase"
+)
+
+var errClaimsUnavailable = errors.New("role store unavailable")
+
+func TestAuthService_RegisterUser(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	service := usecase.NewAuthService(persistence.NewMemoryUserDirectory(), identitytest.NewIssuer())
+
+	// Act
+	created, isNew, err := service.RegisterUser(ctx, "new-user@example.com", "  New User ")
+	again, isNewAgain, againErr := service.RegisterUser(ctx, "new-user@example.com", "Other Name")
+	_, _, invalidErr := service.RegisterUser(ctx, "other@example.com", "bad\nname")
+
+	// Assert
+	if err != nil || againErr != nil {
+		t.Fatalf("RegisterUser() returned errors %v and %v", err, againErr)
+	}
+
+	if !isNew || !created.EmailVerified || created.DisplayName != "New User" {
+		t.Errorf("Expected a new verified user named %q, got %+v (new: %v)", "New User", created, isNew)
+	}
+
+	if isNewAgain || again.UID != created.UID || again.DisplayName != "New User" {
+		t.Errorf("Expected the existing user unchanged, got %+v (new: %v)", again, isNewAgain)
+	}
+
+	if !errors.Is(invalidErr, usecase.ErrInvalidDisplayName) {
+		t.Errorf("Expected ErrInvalidDisplayName, got %v", invalidErr)
+	}
+}
+
+func TestAuthService_ProvisionUser_MergesRoles(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	directory := persistence.NewMemoryUserDirectory()
+	service := usecase.NewAuthService(directory, identitytest.NewIssuer())
+
+	_, _, err := service.ProvisionUser(ctx, "member@example.com", "", []string{"member", "billing"})
+	if err != nil {
+		t.Fatalf("ProvisionUser() returned an error: %v", err)
+	}
+
+	// Act
+	user, created, err := service.ProvisionUser(ctx, "member@example.com", "", []string{"admin", "member"})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("ProvisionUser() returned an error: %v", err)
+	}
+
+	if created {
+		t.Error("Expected the second invitation to reuse the user")
+	}
+
+	stored, err := directory.GetUserByEmail(ctx, "member@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail() returned an error: %v", err)
+	}
+
+	expected := []string{"member", "billing", "admin"}
+	if !slices.Equal(user.Roles, expected) || !slices.Equal(stored.Roles, expected) {
+		t.Errorf("Expected roles %v, got %v (stored %v)", expected, user.Roles, stored.Roles)
+	}
+}
+
+func TestAuthService_GenerateCustomToken_Claims(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	issuer := identitytest.NewIssuer()
+	provider := claims.ProviderFunc(func(_ context.Context, subject claims.Subject) (map[string]any, error) {
+		return map[string]any{usecase.RolesClaim: []string{"staff", "member"}, "tenant": subject.UID + "-tenant"}, nil
+	})
+	service := usecase.NewAuthServiceWithOptions(
+		persistence.NewMemoryUserDirectory(),
+		issuer,
+		usecase.AuthServiceOptions{ClaimsProvider: provider},
+	)
+	user := &identity.User{UID: "uid-1", Email: "user@example.com", EmailVerified: true, DisplayName: "", Roles: []string{"member"}}
+
+	// Act
+	token, err := service.GenerateCustomToken(ctx, user)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("GenerateCustomToken() returned an error: %v", err)
+	}
+
+	issued, ok := issuer.Issued(token)
+	if !ok || issued.User.UID != "uid-1" {
+		t.Fatalf("Expected a token issued for uid-1, got %+v", issued)
+	}
+
+	if roles, _ := issued.Claims[usecase.RolesClaim].([]string); !slices.Equal(roles, []string{"member", "staff"}) {
+		t.Errorf("Expected the stored and provided roles [member staff], got %v", issued.Claims[usecase.RolesClaim])
+	}
+
+	if issued.Claims["tenant"] != "uid-1-tenant" {
+		t.Errorf("Expected the provider's tenant claim, got %v", issued.Claims["tenant"])
+	}
+
+	methods, _ := issued.Claims[usecase.AuthMethodsClaim].([]string)
+	if !slices.Equal(methods, []string{usecase.AuthMethodOTP, usecase.AuthMethodEmail}) {
+		t.Errorf("Unexpected authentication methods %v", issued.Claims[usecase.AuthMethodsClaim])
+	}
+
+	if authTime, _ := issued.Claims[usecase.AuthTimeClaim].(int64); authTime <= 0 {
+		t.Errorf("Expected an authentication time, got %v", issued.Claims[usecase.AuthTimeClaim])
+	}
+}
+
+func TestAuthService_GenerateCustomToken_RejectsProviderClaims(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		provider claims.ProviderFunc
+		expected error
+	}{
+		{
+			name: "provider error",
+			provider: func(context.Context, claims.Subject) (map[string]any, error) {
+				return nil, errClaimsUnavailable
+			},
+			expected: errClaimsUnavailable,
+		},
+		{
+			name: "reserved auth methods claim",
+			provider: func(context.Context, claims.Subject) (map[string]any, error) {
+				return map[string]any{usecase.AuthMethodsClaim: []string{"password"}}, nil
+			},
+			expected: usecase.ErrReservedClaim,
+		},
+		{
+			name: "reserved auth time claim",
+			provider: func(context.Context, claims.Subject) (map[string]any, error) {
+				return map[string]any{usecase.AuthTimeClaim: 0}, nil
+			},
+			expected: usecase.ErrReservedClaim,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			service := usecase.NewAuthServiceWithOptions(
+				persistence.NewMemoryUserDirectory(),
+				identitytest.NewIssuer(),
+				usecase.AuthServiceOptions{ClaimsProvider: tc.provider},
+			)
+			user := &identity.User{UID: "uid-1", Email: "user@example.com", EmailVerified: true, DisplayName: "", Roles: nil}
+
+			// Act
+			token, err := service.GenerateCustomToken(context.Background(), user)
+
+			// Assert
+			if !errors.Is(err, tc.expected) {
+				t.Errorf("Expected error %v, got %v", tc.expected, err)
+			}
+
+			if token != "" {
+				t.Errorf("Expected no token, got %q", token)
+			}
+		})
+	}
+}
+
+func TestAuthService_VerifyAdmin(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	issuer := identitytest.NewIssuer()
+	service := usecase.NewAuthService(persistence.NewMemoryUserDirectory(), issuer)
+
+	admin := &identity.User{UID: "uid-admin", Email: "admin@example.com", EmailVerified: true, DisplayName: "", Roles: nil}
+	member := &identity.User{UID: "uid-member", Email: "", EmailVerified: false, DisplayName: "", Roles: nil}
+
+	adminToken, _ := issuer.IssueToken(ctx, admin, map[string]any{usecase.AdminClaim: true})
+	memberToken, _ := issuer.IssueToken(ctx, member, map[string]any{})
+
+	// Act
+	adminIdentity, isAdmin, adminErr := service.VerifyAdmin(ctx, adminToken)
+	memberIdentity, memberIsAdmin, memberErr := service.VerifyAdmin(ctx, memberToken)
+	_, _, forgedErr := service.VerifyAdmin(ctx, "forged")
+
+	// Assert
+	if adminErr != nil || adminIdentity != "admin@example.com" || !isAdmin {
+		t.Errorf("Expected admin@example.com as admin, got %q admin=%v err=%v", adminIdentity, isAdmin, adminErr)
+	}
+
+	if memberErr != nil || memberIdentity != "uid-member" || memberIsAdmin {
+		t.Errorf("Expected uid-member as non-admin, got %q admin=%v err=%v", memberIdentity, memberIsAdmin, memberErr)
+	}
+
+	if !errors.Is(forgedErr, identity.ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken, got %v", forgedErr)
+	}
+}
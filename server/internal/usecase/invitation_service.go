@@ -0,0 +1,154 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"custom_auth_api/internal/domain/emailsender"
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/repository"
+	"custom_auth_api/internal/domain/vo/email"
+	"custom_auth_api/internal/domain/vo/invitetoken"
+)
+
+// invitationTokenParam is the query parameter carrying the token in accept links.
+const invitationTokenParam = "token"
+
+// ErrInvalidAcceptURL is returned when the invitation accept URL is not an absolute http(s) URL.
+var ErrInvalidAcceptURL = errors.New("invitation accept url must be an absolute http or https url")
+
+// InvitationService handles invitation-based registration.
+//
+// Responsibilities:
+// - Create invitations and email their signed accept links
+// - Resolve invitation tokens back to pending invitations
+// - Accept invitations exactly once
+//
+// Business Rules (delegated to Invitation entity):
+// - Expiry: 7 days by default (entity.DefaultInvitationExpiration)
+// - Single use: an accepted invitation can never be accepted again
+//
+// Note:
+// - Address validation and the domain policy are applied by OTPService.ParseEmail
+// - Proving control of the invited address (OTP) is handled by OTPService
+// - Creating the user and granting roles is handled by AuthService.
+type InvitationService struct {
+	repo        repository.InvitationRepository
+	signer      *invitetoken.Signer
+	emailSender emailsender.EmailSender
+	ttl         time.Duration
+	acceptURL   *url.URL
+}
+
+// InvitationServiceOptions configures an InvitationService.
+type InvitationServiceOptions struct {
+	// TTL is how long invitations can be accepted; zero selects entity.DefaultInvitationExpiration.
+	TTL time.Duration
+
+	// AcceptURL is the page invitees are sent to. The token is added as the "token" query parameter.
+	AcceptURL string
+}
+
+// NewInvitationService creates a new InvitationService.
+// Returns an error wrapping ErrInvalidAcceptURL if options.AcceptURL is not an absolute http(s) URL.
+func NewInvitationService(
+	repo repository.InvitationRepository,
+	signer *invitetoken.Signer,
+	emailSender emailsender.EmailSender,
+	options InvitationServiceOptions,
+) (*InvitationService, error) {
+	acceptURL, err := url.Parse(options.AcceptURL)
+	if err != nil || (acceptURL.Scheme != "http" && acceptURL.Scheme != "https") || acceptURL.Host == "" {
+		return nil, fmt.Errorf("%w (got %q)", ErrInvalidAcceptURL, options.AcceptURL)
+	}
+
+	return &InvitationService{
+		repo:        repo,
+		signer:      signer,
+		emailSender: emailSender,
+		ttl:         options.TTL,
+		acceptURL:   acceptURL,
+	}, nil
+}
+
+// Create stores an invitation for userEmail granting roles and emails its accept link.
+// The invitation is removed again if the email cannot be sent.
+// Returns entity.ErrInviterRequired or an error wrapping entity.ErrInvalidRole for invalid input.
+func (s *InvitationService) Create(
+	ctx context.Context,
+	userEmail *email.Email,
+	inviter string,
+	roles []string,
+) (*entity.Invitation, error) {
+	invitation, err := entity.NewInvitation(userEmail, inviter, roles, s.ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.signer.Sign(invitetoken.Claims{InvitationID: invitation.ID(), ExpiresAt: invitation.ExpiresAt()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign invitation token: %w", err)
+	}
+
+	err = s.repo.Save(ctx, invitation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save invitation: %w", err)
+	}
+
+	err = s.emailSender.SendInvitation(ctx, userEmail.Value, emailsender.Invitation{
+		Inviter:   inviter,
+		AcceptURL: s.acceptLink(token),
+		ExpiresAt: invitation.ExpiresAt(),
+	})
+	if err != nil {
+		// An invitation nobody received would only linger until it expires
+		deleteErr := s.repo.Delete(context.WithoutCancel(ctx), invitation.ID())
+
+		return nil, fmt.Errorf("failed to send invitation email: %w", errors.Join(err, deleteErr))
+	}
+
+	return invitation, nil
+}
+
+// Resolve verifies an invitation token and returns the invitation if it can still be accepted.
+// Returns invitetoken.ErrInvalidToken or invitetoken.ErrTokenExpired for bad tokens,
+// entity.ErrInvitationNotFound if the invitation no longer exists, or
+// entity.ErrInvitationExpired / entity.ErrInvitationAlreadyUsed if it cannot be accepted.
+func (s *InvitationService) Resolve(ctx context.Context, token string) (*entity.Invitation, error) {
+	claims, err := s.signer.Verify(token, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := s.repo.FindByID(ctx, claims.InvitationID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = invitation.CanAccept()
+	if err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// Accept marks the invitation as accepted. Call it only after the invitee has proven
+// control of the invited address. Concurrent calls succeed exactly once; the others
+// get entity.ErrInvitationAlreadyUsed.
+func (s *InvitationService) Accept(ctx context.Context, invitation *entity.Invitation) (*entity.Invitation, error) {
+	return s.repo.Accept(ctx, invitation.ID())
+}
+
+// acceptLink returns the accept URL with the token added to its query.
+func (s *InvitationService) acceptLink(token string) string {
+	link := *s.acceptURL
+	query := link.Query()
+	query.Set(invitationTokenParam, token)
+	link.RawQuery = query.Encode()
+
+	return link.String()
+}
@@ -0,0 +1,231 @@
+package usecase_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	domainemailsender "custom_auth_api/internal/domain/emailsender"
+	"custom_auth_api/internal/domain/entity"
+	emailvo "custom_auth_api/internal/domain/vo/email"
+	"custom_auth_api/internal/domain/vo/invitetoken"
+	"custom_auth_api/internal/infrastructure/emailsender"
+	"custom_auth_api/internal/infrastructure/persistence"
+	"custom_auth_api/internal/usecase"
+)
+
+const testAcceptURL = "https://app.example.com/invite?lang=en"
+
+var errSendFailed = errors.New("smtp unavailable")
+
+// invitationSender records invitation emails and optionally fails to send them.
+type invitationSender struct {
+	*emailsender.DummyEmailSender
+
+	sent []domainemailsender.Invitation
+	err  error
+}
+
+func (s *invitationSender) SendInvitation(_ context.Context, _ string, invitation domainemailsender.Invitation) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	s.sent = append(s.sent, invitation)
+
+	return nil
+}
+
+// recordingInvitationRepository remembers the IDs of saved invitations.
+type recordingInvitationRepository struct {
+	*persistence.MemoryInvitationRepository
+
+	saved []string
+}
+
+func (r *recordingInvitationRepository) Save(ctx context.Context, invitation *entity.Invitation) error {
+	r.saved = append(r.saved, invitation.ID())
+
+	return r.MemoryInvitationRepository.Save(ctx, invitation)
+}
+
+// newTestInvitationService creates an InvitationService storing invitations in memory.
+func newTestInvitationService(
+	t *testing.T,
+	sender *invitationSender,
+) (*usecase.InvitationService, *recordingInvitationRepository) {
+	t.Helper()
+
+	signer, err := invitetoken.NewSigner("test", map[string][]byte{
+		"test": bytes.Repeat([]byte("t"), invitetoken.MinSigningKeyLength),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create invitation token signer: %v", err)
+	}
+
+	repo := &recordingInvitationRepository{MemoryInvitationRepository: persistence.NewMemoryInvitationRepository(), saved: nil}
+
+	service, err := usecase.NewInvitationService(repo, signer, sender, usecase.InvitationServiceOptions{
+		TTL:       time.Hour,
+		AcceptURL: testAcceptURL,
+	})
+	if err != nil {
+		t.Fatalf("NewInvitationService() returned an error: %v", err)
+	}
+
+	return service, repo
+}
+
+// sentToken extracts the invitation token from the accept link of a sent invitation.
+func sentToken(t *testing.T, invitation domainemailsender.Invitation) string {
+	t.Helper()
+
+	link, err := url.Parse(invitation.AcceptURL)
+	if err != nil {
+		t.Fatalf("Failed to parse accept link %q: %v", invitation.AcceptURL, err)
+	}
+
+	return link.Query().Get("token")
+}
+
+func TestNewInvitationService_InvalidAcceptURL(t *testing.T) {
+	t.Parallel()
+
+	for _, acceptURL := range []string{"", "/invite", "ftp://example.com/invite", "https://"} {
+		// Act
+		_, err := usecase.NewInvitationService(nil, nil, nil, usecase.InvitationServiceOptions{TTL: 0, AcceptURL: acceptURL})
+
+		// Assert
+		if !errors.Is(err, usecase.ErrInvalidAcceptURL) {
+			t.Errorf("NewInvitationService(%q) error = %v, want ErrInvalidAcceptURL", acceptURL, err)
+		}
+	}
+}
+
+func TestInvitationService_CreateResolveAccept(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sender := &invitationSender{DummyEmailSender: emailsender.NewDummyEmailSender(), sent: nil, err: nil}
+	service, _ := newTestInvitationService(t, sender)
+	userEmail, _ := emailvo.NewEmail("invitee@example.com")
+	ctx := context.Background()
+
+	// Act
+	created, err := service.Create(ctx, userEmail, "admin@example.com", []string{"member"})
+	if err != nil {
+		t.Fatalf("Create() returned an error: %v", err)
+	}
+
+	// Assert
+	if len(sender.sent) != 1 {
+		t.Fatalf("expected 1 invitation email, got %d", len(sender.sent))
+	}
+
+	sent := sender.sent[0]
+	if !strings.HasPrefix(sent.AcceptURL, "https://app.example.com/invite?") || !strings.Contains(sent.AcceptURL, "lang=en") {
+		t.Errorf("AcceptURL = %q, want the configured page with its query kept", sent.AcceptURL)
+	}
+	if sent.Inviter != "admin@example.com" || !sent.ExpiresAt.Equal(created.ExpiresAt()) {
+		t.Errorf("sent invitation = %+v, want inviter and expiry of the invitation", sent)
+	}
+
+	resolved, err := service.Resolve(ctx, sentToken(t, sent))
+	if err != nil {
+		t.Fatalf("Resolve() returned an error: %v", err)
+	}
+	if resolved.ID() != created.ID() || !slices.Equal(resolved.Roles(), []string{"member"}) {
+		t.Errorf("Resolve() = %s %v, want %s [member]", resolved.ID(), resolved.Roles(), created.ID())
+	}
+
+	_, err = service.Accept(ctx, resolved)
+	if err != nil {
+		t.Fatalf("Accept() returned an error: %v", err)
+	}
+
+	_, err = service.Resolve(ctx, sentToken(t, sent))
+	if !errors.Is(err, entity.ErrInvitationAlreadyUsed) {
+		t.Errorf("Resolve() after Accept error = %v, want ErrInvitationAlreadyUsed", err)
+	}
+}
+
+func TestInvitationService_Resolve_Rejects(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sender := &invitationSender{DummyEmailSender: emailsender.NewDummyEmailSender(), sent: nil, err: nil}
+	service, repo := newTestInvitationService(t, sender)
+	userEmail, _ := emailvo.NewEmail("invitee@example.com")
+	ctx := context.Background()
+
+	created, err := service.Create(ctx, userEmail, "admin@example.com", nil)
+	if err != nil {
+		t.Fatalf("Create() returned an error: %v", err)
+	}
+
+	token := sentToken(t, sender.sent[0])
+
+	// Act
+	_, tamperedErr := service.Resolve(ctx, strings.Replace(token, created.ID(), strings.Repeat("0", len(created.ID())), 1))
+
+	_ = repo.Delete(ctx, created.ID())
+	_, deletedErr := service.Resolve(ctx, token)
+
+	// Assert
+	if !errors.Is(tamperedErr, invitetoken.ErrInvalidToken) {
+		t.Errorf("Resolve() with a tampered token error = %v, want ErrInvalidToken", tamperedErr)
+	}
+	if !errors.Is(deletedErr, entity.ErrInvitationNotFound) {
+		t.Errorf("Resolve() for a deleted invitation error = %v, want ErrInvitationNotFound", deletedErr)
+	}
+}
+
+func TestInvitationService_Create_SendFailureRemovesInvitation(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sender := &invitationSender{DummyEmailSender: emailsender.NewDummyEmailSender(), sent: nil, err: errSendFailed}
+	service, repo := newTestInvitationService(t, sender)
+	userEmail, _ := emailvo.NewEmail("invitee@example.com")
+
+	// Act
+	_, err := service.Create(context.Background(), userEmail, "admin@example.com", nil)
+
+	// Assert
+	if !errors.Is(err, errSendFailed) {
+		t.Fatalf("Create() error = %v, want the send error", err)
+	}
+	if len(repo.saved) != 1 {
+		t.Fatalf("expected 1 saved invitation, got %d", len(repo.saved))
+	}
+
+	_, err = repo.FindByID(context.Background(), repo.saved[0])
+	if !errors.Is(err, entity.ErrInvitationNotFound) {
+		t.Errorf("FindByID() after failed send error = %v, want ErrInvitationNotFound", err)
+	}
+}
+
+func TestInvitationService_Create_InvalidRole(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sender := &invitationSender{DummyEmailSender: emailsender.NewDummyEmailSender(), sent: nil, err: nil}
+	service, _ := newTestInvitationService(t, sender)
+	userEmail, _ := emailvo.NewEmail("invitee@example.com")
+
+	// Act
+	_, err := service.Create(context.Background(), userEmail, "admin@example.com", []string{"not a role"})
+
+	// Assert
+	if !errors.Is(err, entity.ErrInvalidRole) {
+		t.Errorf("Create() error = %v, want ErrInvalidRole", err)
+	}
+	if len(sender.sent) != 0 {
+		t.Errorf("expected no invitation email, got %d", len(sender.sent))
+	}
+}
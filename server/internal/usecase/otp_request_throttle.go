@@ -0,0 +1,187 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"custom_auth_api/internal/domain/vo/email"
+)
+
+// OTPThrottleWindow is the rolling window of the per-address daily cap.
+const OTPThrottleWindow = 24 * time.Hour
+
+// Reasons reported by OTPThrottleError.
+const (
+	OTPThrottleReasonCooldown = "cooldown"
+	OTPThrottleReasonDailyCap = "daily_cap"
+	OTPThrottleReasonGlobal   = "global"
+)
+
+// ErrOTPRequestThrottled is matched (via errors.Is) by every OTPThrottleError.
+var ErrOTPRequestThrottled = errors.New("too many OTP requests")
+
+// OTPThrottleError is returned when an OTP email is refused by OTPRequestThrottle.
+// RetryAfter is how long the caller must wait before the request can succeed.
+type OTPThrottleError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *OTPThrottleError) Error() string {
+	return fmt.Sprintf("%s (%s, retry after %s)", ErrOTPRequestThrottled, e.Reason, e.RetryAfter)
+}
+
+// Is reports whether target is ErrOTPRequestThrottled.
+func (e *OTPThrottleError) Is(target error) bool {
+	return target == ErrOTPRequestThrottled
+}
+
+// OTPRequestPolicy limits how often OTP emails are sent. Zero values disable a limit.
+type OTPRequestPolicy struct {
+	// Cooldown is the minimum time between two emails to the same address.
+	Cooldown time.Duration
+
+	// DailyLimit is the maximum number of emails per address in a rolling 24 hours.
+	DailyLimit int
+
+	// GlobalPerMinute is the maximum number of emails per minute across all addresses.
+	GlobalPerMinute int
+}
+
+// OTPThrottle decides whether an OTP email may be sent to an address and records it if so.
+// Implementations: OTPRequestThrottle (process memory, limits apply per instance) and
+// persistence.RedisOTPRequestThrottle (shared across replicas).
+//
+// Limits are keyed by userEmail.Canonical(), the same form that keys OTP sessions, so
+// every spelling and alias of one mailbox shares them.
+type OTPThrottle interface {
+	// Allow records an email to userEmail if the policy permits it.
+	// Returns an *OTPThrottleError if it does not; refused requests are not counted.
+	Allow(ctx context.Context, userEmail *email.Email) error
+}
+
+// otpThrottleRecord tracks the emails sent to one address.
+type otpThrottleRecord struct {
+	lastSent    time.Time
+	windowStart time.Time
+	count       int
+}
+
+// OTPRequestThrottle limits OTP emails per address and, optionally, globally, so
+// rotating client IPs cannot be used to flood one inbox.
+// State is kept in process memory, so with several replicas every limit applies per
+// instance; use persistence.RedisOTPRequestThrottle to share them.
+type OTPRequestThrottle struct {
+	mu      sync.Mutex
+	records map[string]*otpThrottleRecord
+	policy  OTPRequestPolicy
+	global  *rate.Limiter
+}
+
+// NewOTPRequestThrottle creates a new OTPRequestThrottle enforcing the policy.
+func NewOTPRequestThrottle(policy OTPRequestPolicy) *OTPRequestThrottle {
+	var global *rate.Limiter
+	if policy.GlobalPerMinute > 0 {
+		global = rate.NewLimiter(rate.Every(time.Minute/time.Duration(policy.GlobalPerMinute)), policy.GlobalPerMinute)
+	}
+
+	return &OTPRequestThrottle{
+		mu:      sync.Mutex{},
+		records: make(map[string]*otpThrottleRecord),
+		policy:  policy,
+		global:  global,
+	}
+}
+
+// Allow records an email to userEmail if the policy permits it.
+// Returns an *OTPThrottleError otherwise; refused requests are not counted.
+func (t *OTPRequestThrottle) Allow(_ context.Context, userEmail *email.Email) error {
+	now := time.Now()
+	key := userEmail.Canonical()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	record, exists := t.records[key]
+	if exists && now.Sub(record.windowStart) >= OTPThrottleWindow {
+		// The daily window has passed; keep only the cooldown
+		record.windowStart = now
+		record.count = 0
+	}
+
+	if exists {
+		if wait := record.lastSent.Add(t.policy.Cooldown).Sub(now); wait > 0 {
+			return &OTPThrottleError{Reason: OTPThrottleReasonCooldown, RetryAfter: wait}
+		}
+
+		if t.policy.DailyLimit > 0 && record.count >= t.policy.DailyLimit {
+			return &OTPThrottleError{
+				Reason:     OTPThrottleReasonDailyCap,
+				RetryAfter: record.windowStart.Add(OTPThrottleWindow).Sub(now),
+			}
+		}
+	}
+
+	if t.global != nil {
+		reservation := t.global.ReserveN(now, 1)
+		if wait := reservation.DelayFrom(now); wait > 0 {
+			reservation.CancelAt(now)
+
+			return &OTPThrottleError{Reason: OTPThrottleReasonGlobal, RetryAfter: wait}
+		}
+	}
+
+	if !exists {
+		record = &otpThrottleRecord{lastSent: now, windowStart: now, count: 0}
+		t.records[key] = record
+	}
+
+	record.lastSent = now
+	record.count++
+
+	return nil
+}
+
+// EvictStale removes addresses whose cooldown and daily window have both passed,
+// and returns the number of addresses removed.
+func (t *OTPRequestThrottle) EvictStale() int {
+	now := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	evicted := 0
+
+	for key, record := range t.records {
+		if now.Sub(record.windowStart) >= OTPThrottleWindow && now.Sub(record.lastSent) >= t.policy.Cooldown {
+			delete(t.records, key)
+			evicted++
+		}
+	}
+
+	return evicted
+}
+
+// RunCleanup evicts stale addresses every interval until ctx is done.
+// It blocks, so run it in its own goroutine.
+func (t *OTPRequestThrottle) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.EvictStale()
+		}
+	}
+}
+
+var _ OTPThrottle = (*OTPRequestThrottle)(nil)
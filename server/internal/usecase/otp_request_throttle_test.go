@@ -0,0 +1,176 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"custom_auth_api/internal/domain/entity"
+	"custom_auth_api/internal/domain/vo/email"
+	"custom_auth_api/internal/infrastructure/emailsender"
+	"custom_auth_api/internal/infrastructure/persistence"
+	"custom_auth_api/internal/usecase"
+)
+
+// throttleEmail parses address with the Gmail rule, so aliases share one canonical form.
+func throttleEmail(t *testing.T, address string) *email.Email {
+	t.Helper()
+
+	userEmail, err := email.NewEmailWithOptions(address, email.Options{
+		ProviderRules:        []email.ProviderRule{email.GmailRule()},
+		AllowQuotedLocalPart: false,
+		AllowUTF8LocalPart:   false,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create email: %v", err)
+	}
+
+	return userEmail
+}
+
+func TestOTPRequestThrottle_Allow(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		policy       usecase.OTPRequestPolicy
+		requests     []string
+		expectReason string
+		minRetry     time.Duration
+	}{
+		{
+			name:         "allows a first request",
+			policy:       usecase.OTPRequestPolicy{Cooldown: time.Minute, DailyLimit: 1, GlobalPerMinute: 1},
+			requests:     []string{"user@example.com"},
+			expectReason: "",
+			minRetry:     0,
+		},
+		{
+			name:         "refuses a resend during the cooldown",
+			policy:       usecase.OTPRequestPolicy{Cooldown: time.Minute, DailyLimit: 0, GlobalPerMinute: 0},
+			requests:     []string{"user@example.com", "user@example.com"},
+			expectReason: usecase.OTPThrottleReasonCooldown,
+			minRetry:     59 * time.Second,
+		},
+		{
+			name:         "treats case variants as the same address",
+			policy:       usecase.OTPRequestPolicy{Cooldown: time.Minute, DailyLimit: 0, GlobalPerMinute: 0},
+			requests:     []string{"user@example.com", "User@Example.COM"},
+			expectReason: usecase.OTPThrottleReasonCooldown,
+			minRetry:     59 * time.Second,
+		},
+		{
+			name:         "treats provider aliases as the same address",
+			policy:       usecase.OTPRequestPolicy{Cooldown: time.Minute, DailyLimit: 0, GlobalPerMinute: 0},
+			requests:     []string{"j.doe@gmail.com", "JDoe+otp@googlemail.com"},
+			expectReason: usecase.OTPThrottleReasonCooldown,
+			minRetry:     59 * time.Second,
+		},
+		{
+			name:         "refuses requests beyond the daily cap",
+			policy:       usecase.OTPRequestPolicy{Cooldown: 0, DailyLimit: 2, GlobalPerMinute: 0},
+			requests:     []string{"user@example.com", "user@example.com", "user@example.com"},
+			expectReason: usecase.OTPThrottleReasonDailyCap,
+			minRetry:     23 * time.Hour,
+		},
+		{
+			name:         "refuses requests beyond the global limit",
+			policy:       usecase.OTPRequestPolicy{Cooldown: time.Minute, DailyLimit: 0, GlobalPerMinute: 1},
+			requests:     []string{"first@example.com", "second@example.com"},
+			expectReason: usecase.OTPThrottleReasonGlobal,
+			minRetry:     59 * time.Second,
+		},
+		{
+			name:         "allows other addresses during a cooldown",
+			policy:       usecase.OTPRequestPolicy{Cooldown: time.Minute, DailyLimit: 1, GlobalPerMinute: 0},
+			requests:     []string{"first@example.com", "second@example.com"},
+			expectReason: "",
+			minRetry:     0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			throttle := usecase.NewOTPRequestThrottle(tc.policy)
+
+			var err error
+
+			// Act
+			for _, emailAddr := range tc.requests {
+				err = throttle.Allow(context.Background(), throttleEmail(t, emailAddr))
+			}
+
+			// Assert
+			if tc.expectReason == "" {
+				if err != nil {
+					t.Fatalf("expected the last request to be allowed, got %v", err)
+				}
+
+				return
+			}
+
+			var throttleErr *usecase.OTPThrottleError
+			if !errors.As(err, &throttleErr) {
+				t.Fatalf("expected *OTPThrottleError, got %v", err)
+			}
+
+			if !errors.Is(err, usecase.ErrOTPRequestThrottled) {
+				t.Error("expected the error to match ErrOTPRequestThrottled")
+			}
+
+			if throttleErr.Reason != tc.expectReason {
+				t.Errorf("expected reason %q, got %q", tc.expectReason, throttleErr.Reason)
+			}
+
+			if throttleErr.RetryAfter < tc.minRetry {
+				t.Errorf("expected RetryAfter of at least %v, got %v", tc.minRetry, throttleErr.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestOTPService_GenerateAndSendOTP_Throttled(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	throttle := usecase.NewOTPRequestThrottle(usecase.OTPRequestPolicy{
+		Cooldown:        time.Minute,
+		DailyLimit:      0,
+		GlobalPerMinute: 0,
+	})
+	service := usecase.NewOTPServiceWithOptions(
+		persistence.NewMemoryOTPSessionRepository(newTestHasher(t)),
+		emailsender.NewDummyEmailSender(),
+		usecase.OTPServiceOptions{
+			Throttle:     throttle,
+			Binding:      entity.BindingNone,
+			IPHasher:     nil,
+			Email:        email.Options{ProviderRules: nil, AllowQuotedLocalPart: false, AllowUTF8LocalPart: false},
+			DomainPolicy: nil,
+		},
+	)
+	ctx := context.Background()
+
+	firstCode, err := service.GenerateAndSendOTP(ctx, "throttled@example.com")
+	if err != nil {
+		t.Fatalf("expected the first request to succeed, got %v", err)
+	}
+
+	// Act
+	_, err = service.GenerateAndSendOTP(ctx, "throttled@example.com")
+
+	// Assert
+	if !errors.Is(err, usecase.ErrOTPRequestThrottled) {
+		t.Fatalf("expected ErrOTPRequestThrottled, got %v", err)
+	}
+
+	// The refused request must not replace the session created by the first one
+	valid, err := service.VerifyOTP(ctx, "throttled@example.com", firstCode)
+	if err != nil || !valid {
+		t.Errorf("expected the first code to remain valid, got valid=%v err=%v", valid, err)
+	}
+}
@@ -2,12 +2,15 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"custom_auth_api/internal/domain/emailpolicy"
 	"custom_auth_api/internal/domain/emailsender"
 	"custom_auth_api/internal/domain/entity"
 	"custom_auth_api/internal/domain/repository"
 	"custom_auth_api/internal/domain/vo/email"
+	"custom_auth_api/internal/domain/vo/ipaddress"
 	"custom_auth_api/internal/domain/vo/otp"
 )
 
@@ -23,40 +26,125 @@ import (
 // - Maximum verification attempts: 3 (defined in entity.MaxVerificationAttempts)
 // - One-time use: Session deleted after successful verification
 // - Timing-safe comparison for OTP verification
+// - Client binding: verification must match the requesting client (entity.BindingPolicy)
 //
 // Note:
 // - User existence validation is handled by AuthService
-// - Email format validation is handled by email value object.
+// - Email format validation is handled by email value object
+// - Allowed, denied and disposable domains are decided by an optional emailpolicy.Policy
+// - Per-address and global email limits are enforced by an optional OTPThrottle.
 type OTPService struct {
 	sessionRepo repository.OTPSessionRepository
 	emailSender emailsender.EmailSender
+	throttle    OTPThrottle
+	binding     entity.BindingPolicy
+	ipHasher    *ipaddress.Hasher
+	emailOpts   email.Options
+	policy      *emailpolicy.Policy
 }
 
-// NewOTPService creates a new OTPService.
+// OTPServiceOptions configures optional OTPService behaviour.
+type OTPServiceOptions struct {
+	// Throttle is consulted before every email sent. Nil disables throttling.
+	Throttle OTPThrottle
+
+	// Binding selects which client properties a verification must share with the
+	// OTP request. Empty behaves as entity.BindingNone.
+	Binding entity.BindingPolicy
+
+	// IPHasher hashes client IPs recorded on sessions. Nil records no IP, and then
+	// IP binding rejects sessions whose IP was recorded by another instance.
+	IPHasher *ipaddress.Hasher
+
+	// Email configures the canonical form that identifies a mailbox for sessions
+	// and throttling (e.g. Gmail alias folding).
+	Email email.Options
+
+	// DomainPolicy rejects addresses by domain before any email is sent. Nil accepts every domain.
+	DomainPolicy *emailpolicy.Policy
+}
+
+// NewOTPService creates a new OTPService without email throttling or client binding.
 func NewOTPService(sessionRepo repository.OTPSessionRepository, emailSender emailsender.EmailSender) *OTPService {
+	return NewOTPServiceWithOptions(sessionRepo, emailSender, OTPServiceOptions{
+		Throttle:     nil,
+		Binding:      entity.BindingNone,
+		IPHasher:     nil,
+		Email:        email.Options{ProviderRules: nil, AllowQuotedLocalPart: false, AllowUTF8LocalPart: false},
+		DomainPolicy: nil,
+	})
+}
+
+// NewOTPServiceWithOptions creates a new OTPService configured by options.
+func NewOTPServiceWithOptions(
+	sessionRepo repository.OTPSessionRepository,
+	emailSender emailsender.EmailSender,
+	options OTPServiceOptions,
+) *OTPService {
+	if options.Binding == "" {
+		options.Binding = entity.BindingNone
+	}
+
 	return &OTPService{
 		sessionRepo: sessionRepo,
 		emailSender: emailSender,
+		throttle:    options.Throttle,
+		binding:     options.Binding,
+		ipHasher:    options.IPHasher,
+		emailOpts:   options.Email,
+		policy:      options.DomainPolicy,
+	}
+}
+
+// ParseEmail validates emailAddr with the service's email options and domain policy.
+// Returns an *email.FormatError (matching email.ErrInvalidEmailFormat) describing why a malformed
+// address was rejected, or an *emailpolicy.RejectionError (matching emailpolicy.ErrDomainRejected)
+// if the domain policy rejects it.
+func (s *OTPService) ParseEmail(emailAddr string) (*email.Email, error) {
+	userEmail, err := email.NewEmailWithOptions(emailAddr, s.emailOpts)
+	if err != nil {
+		return nil, err
 	}
+
+	if s.policy != nil {
+		err = s.policy.Check(userEmail)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return userEmail, nil
 }
 
 // GenerateAndSendOTP generates a new OTP session and sends the OTP code via email.
 // Returns the generated OTP code string (for testing purposes).
+// Returns an *OTPThrottleError (matching ErrOTPRequestThrottled) if the address is
+// in its resend cooldown or has reached its daily cap; the existing session is kept.
+// Returns an error matching emailpolicy.ErrDomainRejected if the domain policy rejects the address.
+// If ctx carries RequestMetadata, the client IP (keyed hash) and User-Agent are recorded on the session.
 func (s *OTPService) GenerateAndSendOTP(ctx context.Context, emailAddr string) (string, error) {
-	// Validate and create email value object
-	userEmail, err := email.NewEmail(emailAddr)
+	// Validate the address and its domain
+	userEmail, err := s.ParseEmail(emailAddr)
 	if err != nil {
 		return "", fmt.Errorf("invalid email address: %w", err)
 	}
 
+	err = s.allow(ctx, userEmail)
+	if err != nil {
+		return "", err
+	}
+
 	// Generate OTP code
 	otpCode, err := otp.NewOTP()
 	if err != nil {
 		return "", fmt.Errorf("failed to generate OTP: %w", err)
 	}
 
-	// Create new OTP session entity
+	// Create new OTP session entity, recording the requesting client when known
 	session := entity.NewOTPSession(userEmail, otpCode)
+	if metadata, ok := RequestMetadataFromContext(ctx); ok {
+		session = entity.NewOTPSessionWithContext(userEmail, otpCode, s.hashIP(metadata.ClientIP), metadata.UserAgent)
+	}
 
 	// Persist the session
 	err = s.sessionRepo.Save(ctx, session)
@@ -65,7 +153,10 @@ func (s *OTPService) GenerateAndSendOTP(ctx context.Context, emailAddr string) (
 	}
 
 	// Send OTP via email
-	err = s.emailSender.SendOTP(ctx, emailAddr, otpCode.String())
+	err = s.emailSender.SendOTP(ctx, userEmail.Value, emailsender.OTP{
+		Code:      otpCode.String(),
+		ExpiresAt: session.ExpiresAt(),
+	})
 	if err != nil {
 		return "", fmt.Errorf("failed to send OTP email: %w", err)
 	}
@@ -73,45 +164,102 @@ func (s *OTPService) GenerateAndSendOTP(ctx context.Context, emailAddr string) (
 	return otpCode.String(), nil
 }
 
+// SendSignInNotice emails an address with no account that someone tried to sign in with it.
+// No session is created. Notices count against the same limits as OTP emails.
+func (s *OTPService) SendSignInNotice(ctx context.Context, emailAddr string) error {
+	userEmail, err := s.ParseEmail(emailAddr)
+	if err != nil {
+		return fmt.Errorf("invalid email address: %w", err)
+	}
+
+	err = s.allow(ctx, userEmail)
+	if err != nil {
+		return err
+	}
+
+	err = s.emailSender.SendSignInNotice(ctx, userEmail.Value)
+	if err != nil {
+		return fmt.Errorf("failed to send sign-in notice email: %w", err)
+	}
+
+	return nil
+}
+
 // VerifyOTP validates the provided OTP code against the stored session.
 // Returns true if verification succeeds, false otherwise.
 // Automatically handles:
 // - Expiration checking (via entity)
-// - Attempt counting (via entity)
-// - Session deletion on success.
+// - Attempt counting (via entity, persisted atomically)
+// - Session deletion on success (exactly once under concurrency)
+// - Client binding against the RequestMetadata in ctx (entity.ErrSessionBindingMismatch).
 func (s *OTPService) VerifyOTP(ctx context.Context, emailAddr, inputCode string) (bool, error) {
 	// Validate and create email value object
-	userEmail, err := email.NewEmail(emailAddr)
+	userEmail, err := email.NewEmailWithOptions(emailAddr, s.emailOpts)
 	if err != nil {
 		return false, fmt.Errorf("invalid email address: %w", err)
 	}
 
-	// Retrieve session from repository
-	session, err := s.sessionRepo.FindByEmail(ctx, userEmail)
+	err = s.checkBinding(ctx, userEmail)
 	if err != nil {
-		return false, fmt.Errorf("failed to retrieve OTP session: %w", err)
+		return false, err
 	}
 
-	// Verify OTP using entity business logic
-	err = session.Verify(inputCode)
+	// Verify and consume atomically: the repository runs the entity's Verify
+	// inside a transaction, so concurrent guesses cannot bypass the attempt limit
+	// and a valid code cannot be redeemed twice.
+	err = s.sessionRepo.VerifyAndConsume(ctx, userEmail, inputCode)
 	if err != nil {
-		// Save updated attempts count (entity incremented it on failure)
-		saveErr := s.sessionRepo.Save(ctx, session)
-		if saveErr != nil {
-			// Log warning but return the original verification error
-			return false, fmt.Errorf("verification failed (%w) and save failed: %w", err, saveErr)
+		if errors.Is(err, entity.ErrSessionNotFound) {
+			return false, fmt.Errorf("failed to retrieve OTP session: %w", err)
 		}
 
 		return false, fmt.Errorf("OTP verification failed: %w", err)
 	}
 
-	// Successful verification - delete session (one-time use)
-	err = s.sessionRepo.Delete(ctx, userEmail)
+	return true, nil
+}
+
+// checkBinding rejects verification from a client other than the one that requested the OTP.
+// A mismatch does not count as a failed attempt: the caller never got to guess a code.
+func (s *OTPService) checkBinding(ctx context.Context, userEmail *email.Email) error {
+	if s.binding == entity.BindingNone {
+		return nil
+	}
+
+	session, err := s.sessionRepo.FindByEmail(ctx, userEmail)
+	if err != nil {
+		if errors.Is(err, entity.ErrSessionNotFound) {
+			return fmt.Errorf("failed to retrieve OTP session: %w", err)
+		}
+
+		return fmt.Errorf("OTP verification failed: %w", err)
+	}
+
+	metadata, _ := RequestMetadataFromContext(ctx)
+
+	err = session.CheckBinding(s.binding, s.ipHasher, metadata.ClientIP, metadata.UserAgent)
 	if err != nil {
-		// Verification succeeded but cleanup failed
-		// The session will eventually expire naturally
-		return true, fmt.Errorf("OTP verified but cleanup failed: %w", err)
+		return fmt.Errorf("OTP verification failed: %w", err)
 	}
 
-	return true, nil
+	return nil
+}
+
+// hashIP returns the keyed hash of a client IP, or nil if IPs are not recorded.
+func (s *OTPService) hashIP(ipAddress string) *ipaddress.Hash {
+	if s.ipHasher == nil || ipAddress == "" {
+		return nil
+	}
+
+	return s.ipHasher.Hash(ipAddress)
+}
+
+// allow consults the throttle, if any, before an email is sent to userEmail.
+// Limits are keyed by the canonical address, so aliases of one mailbox share them.
+func (s *OTPService) allow(ctx context.Context, userEmail *email.Email) error {
+	if s.throttle == nil {
+		return nil
+	}
+
+	return s.throttle.Allow(ctx, userEmail)
 }
@@ -1,6 +1,7 @@
 package usecase_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"os"
@@ -8,7 +9,12 @@ import (
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"custom_auth_api/internal/domain/emailpolicy"
+	domainemailsender "custom_auth_api/internal/domain/emailsender"
 	"custom_auth_api/internal/domain/entity"
+	emailvo "custom_auth_api/internal/domain/vo/email"
+	"custom_auth_api/internal/domain/vo/ipaddress"
+	otpvo "custom_auth_api/internal/domain/vo/otp"
 	"custom_auth_api/internal/infrastructure/emailsender"
 	"custom_auth_api/internal/infrastructure/persistence"
 	"custom_auth_api/internal/usecase"
@@ -32,6 +38,33 @@ func setupFirestoreEmulator(t *testing.T) *firestore.Client {
 	return client
 }
 
+// newTestHasher creates an OTP hasher with a fixed key so that separately
+// constructed repositories in the same test can verify each other's digests.
+func newTestHasher(t *testing.T) *otpvo.Hasher {
+	t.Helper()
+
+	hasher, err := otpvo.NewHasher("test", map[string][]byte{"test": bytes.Repeat([]byte("k"), otpvo.MinHashKeyLength)})
+	if err != nil {
+		t.Fatalf("Failed to create OTP hasher: %v", err)
+	}
+
+	return hasher
+}
+
+// newTestIPHasher creates an IP hasher with a fixed key.
+func newTestIPHasher(t *testing.T) *ipaddress.Hasher {
+	t.Helper()
+
+	hasher, err := ipaddress.NewHasher(
+		"test", map[string][]byte{"test": bytes.Repeat([]byte("i"), ipaddress.MinHashKeyLength)}, ipaddress.HasherOptions{},
+	)
+	if err != nil {
+		t.Fatalf("Failed to create IP hasher: %v", err)
+	}
+
+	return hasher
+}
+
 // cleanupOTP deletes the OTP document for the given email.
 func cleanupOTP(ctx context.Context, t *testing.T, client *firestore.Client, email string) {
 	t.Helper()
@@ -52,7 +85,7 @@ func TestOTPService_GenerateAndSendOTP(t *testing.T) {
 		}
 	}()
 
-	repo := persistence.NewOTPSessionRepository(client)
+	repo := persistence.NewOTPSessionRepository(client, newTestHasher(t))
 	sender := emailsender.NewDummyEmailSender()
 	service := usecase.NewOTPService(repo, sender)
 
@@ -117,13 +150,23 @@ func TestOTPService_GenerateAndSendOTP(t *testing.T) {
 
 				data := doc.Data()
 
-				savedOTP, ok := data["otp"].(string)
+				// Verify only the keyed digest of the OTP was saved
+				if _, exists := data["otp"]; exists {
+					t.Error("plaintext 'otp' field must not be stored")
+				}
+
+				savedHash, ok := data["otpHash"].(string)
 				if !ok {
-					t.Fatal("'otp' field is not a string")
+					t.Fatal("'otpHash' field is not a string")
 				}
 
-				if savedOTP != otp {
-					t.Errorf("Saved OTP = %s, returned OTP = %s", savedOTP, otp)
+				digest, err := otpvo.ParseDigest(savedHash)
+				if err != nil {
+					t.Fatalf("Failed to parse saved digest: %v", err)
+				}
+
+				if !newTestHasher(t).Matches(digest, doc.Ref.ID, otp) {
+					t.Errorf("Saved digest %s does not match returned OTP %s", savedHash, otp)
 				}
 
 				// Verify attempts initialized to 0
@@ -160,7 +203,7 @@ func TestOTPService_VerifyOTP_Success(t *testing.T) {
 		}
 	}()
 
-	repo := persistence.NewOTPSessionRepository(client)
+	repo := persistence.NewOTPSessionRepository(client, newTestHasher(t))
 	sender := emailsender.NewDummyEmailSender()
 	service := usecase.NewOTPService(repo, sender)
 
@@ -206,7 +249,7 @@ func TestOTPService_VerifyOTP_InvalidOTP(t *testing.T) {
 		}
 	}()
 
-	repo := persistence.NewOTPSessionRepository(client)
+	repo := persistence.NewOTPSessionRepository(client, newTestHasher(t))
 	sender := emailsender.NewDummyEmailSender()
 	service := usecase.NewOTPService(repo, sender)
 
@@ -270,7 +313,7 @@ func TestOTPService_VerifyOTP_ExpiredOTP(t *testing.T) {
 		}
 	}()
 
-	repo := persistence.NewOTPSessionRepository(client)
+	repo := persistence.NewOTPSessionRepository(client, newTestHasher(t))
 	sender := emailsender.NewDummyEmailSender()
 	service := usecase.NewOTPService(repo, sender)
 
@@ -321,7 +364,7 @@ func TestOTPService_VerifyOTP_TooManyAttempts(t *testing.T) {
 		}
 	}()
 
-	repo := persistence.NewOTPSessionRepository(client)
+	repo := persistence.NewOTPSessionRepository(client, newTestHasher(t))
 	sender := emailsender.NewDummyEmailSender()
 	service := usecase.NewOTPService(repo, sender)
 
@@ -371,7 +414,7 @@ func TestOTPService_VerifyOTP_NotFound(t *testing.T) {
 		}
 	}()
 
-	repo := persistence.NewOTPSessionRepository(client)
+	repo := persistence.NewOTPSessionRepository(client, newTestHasher(t))
 	sender := emailsender.NewDummyEmailSender()
 	service := usecase.NewOTPService(repo, sender)
 
@@ -405,7 +448,7 @@ func TestOTPService_VerifyOTP_MultipleFailedAttempts(t *testing.T) {
 		}
 	}()
 
-	repo := persistence.NewOTPSessionRepository(client)
+	repo := persistence.NewOTPSessionRepository(client, newTestHasher(t))
 	sender := emailsender.NewDummyEmailSender()
 	service := usecase.NewOTPService(repo, sender)
 
@@ -495,7 +538,7 @@ func TestOTPService_VerifyOTP_SuccessAfterFailedAttempt(t *testing.T) {
 		}
 	}()
 
-	repo := persistence.NewOTPSessionRepository(client)
+	repo := persistence.NewOTPSessionRepository(client, newTestHasher(t))
 	sender := emailsender.NewDummyEmailSender()
 	service := usecase.NewOTPService(repo, sender)
 
@@ -541,3 +584,299 @@ func TestOTPService_VerifyOTP_SuccessAfterFailedAttempt(t *testing.T) {
 		t.Error("OTP should be deleted after successful verification")
 	}
 }
+
+func TestOTPService_GenerateAndSendOTP_RecordsRequestMetadata(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	repo := persistence.NewMemoryOTPSessionRepository(newTestHasher(t))
+	ipHasher := newTestIPHasher(t)
+	service := usecase.NewOTPServiceWithOptions(repo, emailsender.NewDummyEmailSender(), usecase.OTPServiceOptions{
+		Throttle:     nil,
+		Binding:      entity.BindingNone,
+		IPHasher:     ipHasher,
+		Email:        emailvo.Options{ProviderRules: nil, AllowQuotedLocalPart: false, AllowUTF8LocalPart: false},
+		DomainPolicy: nil,
+	})
+	ctx := usecase.ContextWithRequestMetadata(context.Background(), usecase.RequestMetadata{
+		ClientIP:  "192.0.2.1",
+		UserAgent: "curl/8.5.0",
+		RequestID: "req-1",
+	})
+
+	// Act
+	_, err := service.GenerateAndSendOTP(ctx, "metadata@example.com")
+	if err != nil {
+		t.Fatalf("GenerateAndSendOTP() error = %v", err)
+	}
+
+	// Assert
+	userEmail, _ := emailvo.NewEmail("metadata@example.com")
+
+	session, err := repo.FindByEmail(context.Background(), userEmail)
+	if err != nil {
+		t.Fatalf("FindByEmail() error = %v", err)
+	}
+
+	if session.IPAddressHash().KeyID() != ipHasher.ActiveKeyID() || !ipHasher.Matches(session.IPAddressHash(), "192.0.2.1") {
+		t.Errorf("expected the keyed client IP hash to be recorded, got %q", session.IPAddressHash().String())
+	}
+
+	if session.UserAgent() != "curl/8.5.0" {
+		t.Errorf("expected user agent %q, got %q", "curl/8.5.0", session.UserAgent())
+	}
+}
+
+// otpSender records OTP emails.
+type otpSender struct {
+	*emailsender.DummyEmailSender
+
+	sent []domainemailsender.OTP
+}
+
+func (s *otpSender) SendOTP(_ context.Context, _ string, otp domainemailsender.OTP) error {
+	s.sent = append(s.sent, otp)
+
+	return nil
+}
+
+func TestOTPService_GenerateAndSendOTP_SendsSessionExpiry(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	repo := persistence.NewMemoryOTPSessionRepository(newTestHasher(t))
+	sender := &otpSender{DummyEmailSender: emailsender.NewDummyEmailSender(), sent: nil}
+	service := usecase.NewOTPService(repo, sender)
+
+	// Act
+	code, err := service.GenerateAndSendOTP(context.Background(), "expiry@example.com")
+	if err != nil {
+		t.Fatalf("GenerateAndSendOTP() error = %v", err)
+	}
+
+	// Assert
+	userEmail, _ := emailvo.NewEmail("expiry@example.com")
+
+	session, err := repo.FindByEmail(context.Background(), userEmail)
+	if err != nil {
+		t.Fatalf("FindByEmail() error = %v", err)
+	}
+
+	if len(sender.sent) != 1 {
+		t.Fatalf("expected one OTP email, got %d", len(sender.sent))
+	}
+
+	if sender.sent[0].Code != code || !sender.sent[0].ExpiresAt.Equal(session.ExpiresAt()) {
+		t.Errorf("expected code %s expiring at %v, got %+v", code, session.ExpiresAt(), sender.sent[0])
+	}
+}
+
+func TestOTPService_VerifyOTP_Binding(t *testing.T) {
+	t.Parallel()
+
+	requester := usecase.RequestMetadata{ClientIP: "192.0.2.1", UserAgent: "curl/8.5.0", RequestID: "req-1"}
+
+	testCases := []struct {
+		name      string
+		binding   entity.BindingPolicy
+		verifier  usecase.RequestMetadata
+		expectErr error
+	}{
+		{
+			name:      "no binding accepts another client",
+			binding:   entity.BindingNone,
+			verifier:  usecase.RequestMetadata{ClientIP: "198.51.100.7", UserAgent: "Wget/1.21", RequestID: "req-2"},
+			expectErr: nil,
+		},
+		{
+			name:      "ip binding accepts the same address",
+			binding:   entity.BindingIP,
+			verifier:  usecase.RequestMetadata{ClientIP: "192.0.2.1", UserAgent: "Wget/1.21", RequestID: "req-2"},
+			expectErr: nil,
+		},
+		{
+			name:      "ip binding rejects another address",
+			binding:   entity.BindingIP,
+			verifier:  usecase.RequestMetadata{ClientIP: "198.51.100.7", UserAgent: "curl/8.5.0", RequestID: "req-2"},
+			expectErr: entity.ErrSessionBindingMismatch,
+		},
+		{
+			name:      "strict binding rejects another user agent family",
+			binding:   entity.BindingStrict,
+			verifier:  usecase.RequestMetadata{ClientIP: "192.0.2.1", UserAgent: "Wget/1.21", RequestID: "req-2"},
+			expectErr: entity.ErrSessionBindingMismatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			service := usecase.NewOTPServiceWithOptions(
+				persistence.NewMemoryOTPSessionRepository(newTestHasher(t)),
+				emailsender.NewDummyEmailSender(),
+				usecase.OTPServiceOptions{
+					Throttle:     nil,
+					Binding:      tc.binding,
+					IPHasher:     newTestIPHasher(t),
+					Email:        emailvo.Options{ProviderRules: nil, AllowQuotedLocalPart: false, AllowUTF8LocalPart: false},
+					DomainPolicy: nil,
+				},
+			)
+
+			code, err := service.GenerateAndSendOTP(
+				usecase.ContextWithRequestMetadata(context.Background(), requester), "binding@example.com",
+			)
+			if err != nil {
+				t.Fatalf("GenerateAndSendOTP() error = %v", err)
+			}
+
+			// Act
+			valid, err := service.VerifyOTP(
+				usecase.ContextWithRequestMetadata(context.Background(), tc.verifier), "binding@example.com", code,
+			)
+
+			// Assert
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+
+			if valid != (tc.expectErr == nil) {
+				t.Errorf("expected valid=%v, got %v", tc.expectErr == nil, valid)
+			}
+		})
+	}
+}
+
+func TestOTPService_CanonicalEmail(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	throttle := usecase.NewOTPRequestThrottle(usecase.OTPRequestPolicy{
+		Cooldown:        time.Minute,
+		DailyLimit:      0,
+		GlobalPerMinute: 0,
+	})
+	service := usecase.NewOTPServiceWithOptions(
+		persistence.NewMemoryOTPSessionRepository(newTestHasher(t)),
+		emailsender.NewDummyEmailSender(),
+		usecase.OTPServiceOptions{
+			Throttle: throttle,
+			Binding:  entity.BindingNone,
+			IPHasher: nil,
+			Email: emailvo.Options{
+				ProviderRules:        []emailvo.ProviderRule{emailvo.GmailRule()},
+				AllowQuotedLocalPart: false,
+				AllowUTF8LocalPart:   false,
+			},
+			DomainPolicy: nil,
+		},
+	)
+	ctx := context.Background()
+
+	code, err := service.GenerateAndSendOTP(ctx, "J.Doe@gmail.com")
+	if err != nil {
+		t.Fatalf("GenerateAndSendOTP() error = %v", err)
+	}
+
+	// Act
+	_, aliasErr := service.GenerateAndSendOTP(ctx, "jdoe+news@googlemail.com")
+	valid, verifyErr := service.VerifyOTP(ctx, "jdoe@GMAIL.com", code)
+
+	// Assert
+	if !errors.Is(aliasErr, usecase.ErrOTPRequestThrottled) {
+		t.Errorf("expected an alias of the same mailbox to share the cooldown, got %v", aliasErr)
+	}
+
+	if verifyErr != nil || !valid {
+		t.Errorf("expected the code to verify for another spelling of the address, got valid=%v err=%v", valid, verifyErr)
+	}
+}
+
+func TestOTPService_ParseEmail(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		allowUTF8   bool
+		expectedErr error
+	}{
+		{name: "rejects SMTPUTF8 local parts by default", allowUTF8: false, expectedErr: emailvo.ErrUTF8LocalPart},
+		{name: "accepts SMTPUTF8 local parts when enabled", allowUTF8: true, expectedErr: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			service := usecase.NewOTPServiceWithOptions(
+				persistence.NewMemoryOTPSessionRepository(newTestHasher(t)),
+				emailsender.NewDummyEmailSender(),
+				usecase.OTPServiceOptions{
+					Throttle:     nil,
+					Binding:      entity.BindingNone,
+					IPHasher:     nil,
+					Email:        emailvo.Options{ProviderRules: nil, AllowQuotedLocalPart: false, AllowUTF8LocalPart: tc.allowUTF8},
+					DomainPolicy: nil,
+				},
+			)
+
+			// Act
+			_, err := service.ParseEmail("用户@example.com")
+
+			// Assert
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestOTPService_DomainPolicy(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	deny, err := emailpolicy.NewDomainSet([]string{"*.blocked.example"})
+	if err != nil {
+		t.Fatalf("NewDomainSet() error = %v", err)
+	}
+
+	sender := emailsender.NewDummyEmailSender()
+	service := usecase.NewOTPServiceWithOptions(
+		persistence.NewMemoryOTPSessionRepository(newTestHasher(t)),
+		sender,
+		usecase.OTPServiceOptions{
+			Throttle: nil,
+			Binding:  entity.BindingNone,
+			IPHasher: nil,
+			Email:    emailvo.Options{ProviderRules: nil, AllowQuotedLocalPart: false, AllowUTF8LocalPart: false},
+			DomainPolicy: emailpolicy.NewPolicy(emailpolicy.PolicyOptions{
+				Allow:         nil,
+				Deny:          deny,
+				Disposable:    emailpolicy.BundledDisposableDomains(),
+				AllowlistOnly: false,
+			}),
+		},
+	)
+	ctx := context.Background()
+
+	// Act
+	_, deniedErr := service.GenerateAndSendOTP(ctx, "user@mail.blocked.example")
+	noticeErr := service.SendSignInNotice(ctx, "user@mailinator.com")
+	_, allowedErr := service.GenerateAndSendOTP(ctx, "user@example.com")
+
+	// Assert
+	if !errors.Is(deniedErr, emailpolicy.ErrDomainRejected) {
+		t.Errorf("expected a denied domain to be rejected, got %v", deniedErr)
+	}
+
+	if !errors.Is(noticeErr, emailpolicy.ErrDomainRejected) {
+		t.Errorf("expected no notice to be sent to a disposable domain, got %v", noticeErr)
+	}
+
+	if allowedErr != nil {
+		t.Errorf("expected other domains to be accepted, got %v", allowedErr)
+	}
+}
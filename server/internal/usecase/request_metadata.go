@@ -0,0 +1,32 @@
+package usecase
+
+import "context"
+
+// RequestMetadata describes the HTTP client behind a request. The interface layer
+// attaches it to the context; OTPService records it on new sessions and checks it
+// against the session's binding policy on verification.
+type RequestMetadata struct {
+	// ClientIP is the resolved client IP (never persisted in clear).
+	ClientIP string
+
+	// UserAgent is the raw User-Agent header.
+	UserAgent string
+
+	// RequestID correlates log lines of one request.
+	RequestID string
+}
+
+// requestMetadataContextKey is the context key for RequestMetadata.
+type requestMetadataContextKey struct{}
+
+// ContextWithRequestMetadata returns a copy of ctx carrying metadata.
+func ContextWithRequestMetadata(ctx context.Context, metadata RequestMetadata) context.Context {
+	return context.WithValue(ctx, requestMetadataContextKey{}, metadata)
+}
+
+// RequestMetadataFromContext returns the request metadata stored in ctx, if any.
+func RequestMetadataFromContext(ctx context.Context) (RequestMetadata, bool) {
+	metadata, ok := ctx.Value(requestMetadataContextKey{}).(RequestMetadata)
+
+	return metadata, ok
+}
@@ -1,11 +1,13 @@
 package tests_test
 
 import (
+	"bytes"
 	"context"
 	"os"
 	"testing"
 	"time"
 
+	"custom_auth_api/internal/domain/vo/otp"
 	"custom_auth_api/internal/infrastructure/emailsender"
 	"custom_auth_api/internal/infrastructure/persistence"
 	"custom_auth_api/internal/usecase"
@@ -35,6 +37,19 @@ func setupIntegrationTest(t *testing.T) *firestore.Client {
 	return client
 }
 
+// newTestHasher creates an OTP hasher with a fixed key so that separately
+// constructed repositories in the same test can verify each other's digests.
+func newTestHasher(t *testing.T) *otp.Hasher {
+	t.Helper()
+
+	hasher, err := otp.NewHasher("test", map[string][]byte{"test": bytes.Repeat([]byte("k"), otp.MinHashKeyLength)})
+	if err != nil {
+		t.Fatalf("Failed to create OTP hasher: %v", err)
+	}
+
+	return hasher
+}
+
 func TestOTPService_Integration_GenerateAndSendOTP(t *testing.T) {
 	client := setupIntegrationTest(t)
 	t.Cleanup(func() {
@@ -44,7 +59,7 @@ func TestOTPService_Integration_GenerateAndSendOTP(t *testing.T) {
 		}
 	})
 
-	otpRepo := persistence.NewOTPSessionRepository(client)
+	otpRepo := persistence.NewOTPSessionRepository(client, newTestHasher(t))
 	emailSender := emailsender.NewDummyEmailSender()
 	otpService := usecase.NewOTPService(otpRepo, emailSender)
 
@@ -73,13 +88,22 @@ func TestOTPService_Integration_GenerateAndSendOTP(t *testing.T) {
 
 		data := doc.Data()
 
-		savedOTP, ok := data["otp"].(string)
+		if _, exists := data["otp"]; exists {
+			t.Error("plaintext 'otp' field must not be stored in Firestore")
+		}
+
+		savedHash, ok := data["otpHash"].(string)
 		if !ok {
-			t.Fatal("'otp' field is not a string in Firestore document")
+			t.Fatal("'otpHash' field is not a string in Firestore document")
+		}
+
+		digest, err := otp.ParseDigest(savedHash)
+		if err != nil {
+			t.Fatalf("failed to parse saved digest: %v", err)
 		}
 
-		if savedOTP != generatedOTP {
-			t.Errorf("expected saved OTP to be %s, but got %s", generatedOTP, savedOTP)
+		if !newTestHasher(t).Matches(digest, doc.Ref.ID, generatedOTP) {
+			t.Errorf("expected saved digest to match OTP %s, but got %s", generatedOTP, savedHash)
 		}
 
 		expiresAt, ok := data["expiresAt"].(time.Time)
@@ -93,3 +117,29 @@ func TestOTPService_Integration_GenerateAndSendOTP(t *testing.T) {
 		}
 	})
 }
+
+func TestOTPService_Integration_MemoryStore(t *testing.T) {
+	t.Parallel()
+
+	otpRepo := persistence.NewMemoryOTPSessionRepository(newTestHasher(t))
+	otpService := usecase.NewOTPService(otpRepo, emailsender.NewDummyEmailSender())
+
+	ctx := context.Background()
+	testEmail := "memory-integration-test@example.com"
+
+	generatedOTP, err := otpService.GenerateAndSendOTP(ctx, testEmail)
+	if err != nil {
+		t.Fatalf("GenerateAndSendOTP failed: %v", err)
+	}
+
+	verified, err := otpService.VerifyOTP(ctx, testEmail, generatedOTP)
+	if err != nil || !verified {
+		t.Fatalf("VerifyOTP failed: verified=%v, err=%v", verified, err)
+	}
+
+	// The session is one-time use
+	verified, err = otpService.VerifyOTP(ctx, testEmail, generatedOTP)
+	if err == nil || verified {
+		t.Error("expected reusing the OTP to fail")
+	}
+}